@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// exitTooLargeCode is the exit code of a run that passed max_output_size:
+// exitTooLarge in CI mode, and 1 like any error otherwise.
+func exitTooLargeCode(out outputOptions) int {
+	if out.ci {
+		return exitTooLarge
+	}
+	return 1
+}
+
+// inCI reports whether the CI environment variable, which GitHub Actions
+// and most other CI services set, says textify runs in CI. It is the
+// default of start --ci.
+func inCI() bool {
+	switch strings.ToLower(os.Getenv("CI")) {
+	case "", "0", "false", "no":
+		return false
+	}
+	return true
+}
+
+// annotate prints a GitHub Actions workflow command, such as ::warning, that
+// the runner turns into an annotation of the run, in CI mode. title names
+// the kind of event.
+func annotate(out outputOptions, level, title, message string) {
+	if !out.ci {
+		return
+	}
+	fmt.Printf("::%s title=textify %s::%s\n", level, escapeProperty(title), escapeData(message))
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
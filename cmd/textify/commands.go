@@ -0,0 +1,236 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// command describes a textify subcommand. The registry drives dispatch, help
+// output, and shell completion, so new commands only need to be added here.
+type command struct {
+	name string
+
+	// args is the positional argument synopsis shown in help.
+	args string
+
+	summary string
+
+	// flags returns the command's flag set, or nil if it takes no flags.
+	flags func() *flag.FlagSet
+
+	// completions are the positional words offered by shell completion.
+	completions []string
+
+	// usesConfig commands run from the project root, found by looking for
+	// textify.yaml in the parent folders (see enterProjectRoot).
+	usesConfig bool
+
+	run func(args []string)
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			name:    "init",
+			summary: "Scans folders and generates textify.yaml",
+			run:     func([]string) { runInit() },
+		},
+		{
+			name:       "scan",
+			usesConfig: true,
+			summary:    "Detects new folders and updates textify.yaml",
+			run:        func([]string) { runScan() },
+		},
+		{
+			name:       "start",
+			usesConfig: true,
+			summary:    "Generates the output file based on config",
+			flags: func() *flag.FlagSet {
+				fs, _ := newStartFlags()
+				return fs
+			},
+			run: runStart,
+		},
+		{
+			name:       "pick",
+			usesConfig: true,
+			summary:    "Interactively selects files, then generates the output",
+			flags: func() *flag.FlagSet {
+				fs, _ := newPickFlags()
+				return fs
+			},
+			run: runPick,
+		},
+		{
+			name:       "list",
+			usesConfig: true,
+			summary:    "Lists the files start would include",
+			flags: func() *flag.FlagSet {
+				fs, _ := newListFlags()
+				return fs
+			},
+			run: runList,
+		},
+		{
+			name:       "export",
+			usesConfig: true,
+			args:       "<destdir>",
+			summary:    "Copies the files start would include to a folder",
+			flags: func() *flag.FlagSet {
+				fs, _ := newExportFlags()
+				return fs
+			},
+			run: runExport,
+		},
+		{
+			name:    "diff",
+			args:    "<old> <new>",
+			summary: "Compares two outputs file by file",
+			flags: func() *flag.FlagSet {
+				fs, _ := newDiffFlags()
+				return fs
+			},
+			run: runDiff,
+		},
+		{
+			name:       "extract",
+			usesConfig: true,
+			args:       "<path-or-glob>...",
+			summary:    "Prints files' sections from an existing output",
+			flags: func() *flag.FlagSet {
+				fs, _ := newExtractFlags()
+				return fs
+			},
+			run: runExtract,
+		},
+		{
+			name:       "estimate",
+			usesConfig: true,
+			summary:    "Estimates the output's tokens against model context windows",
+			flags: func() *flag.FlagSet {
+				fs, _ := newEstimateFlags()
+				return fs
+			},
+			run: runEstimate,
+		},
+		{
+			name:       "count",
+			usesConfig: true,
+			summary:    "Same as estimate; count --costs adds what sending the output costs",
+			flags: func() *flag.FlagSet {
+				fs, _ := newEstimateFlags()
+				return fs
+			},
+			run: runEstimate,
+		},
+		{
+			name:       "exclude",
+			usesConfig: true,
+			args:       "<path-or-glob>",
+			summary:    "Adds an exclude pattern to textify.yaml",
+			run:        runExclude,
+		},
+		{
+			name:       "include",
+			usesConfig: true,
+			args:       "<path-or-glob>",
+			summary:    "Adds a force-include pattern to textify.yaml",
+			run:        runInclude,
+		},
+		{
+			name:       "disable",
+			usesConfig: true,
+			args:       "<directory>",
+			summary:    "Disables a directory in textify.yaml",
+			run:        runDisable,
+		},
+		{
+			name:       "enable",
+			usesConfig: true,
+			args:       "<directory>",
+			summary:    "Enables a directory in textify.yaml",
+			run:        runEnable,
+		},
+		{
+			name:       "rule",
+			usesConfig: true,
+			args:       "<directory>",
+			summary:    "Shows which rule applies to a directory and why",
+			run:        runRule,
+		},
+		{
+			name:       "check",
+			usesConfig: true,
+			summary:    "Validates textify.yaml",
+			run:        runCheck,
+		},
+		{
+			name:    "schema",
+			summary: "Prints the JSON Schema for textify.yaml",
+			run:     runSchema,
+		},
+		{
+			name:        "cache",
+			usesConfig:  true,
+			args:        "clear",
+			summary:     "Deletes the cache file configured by cache_file",
+			completions: []string{"clear"},
+			run:         runCache,
+		},
+		{
+			name:        "completion",
+			args:        "bash|zsh|fish",
+			summary:     "Prints a shell completion script",
+			completions: []string{"bash", "zsh", "fish"},
+			run:         runCompletion,
+		},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// flagNames returns the "--name" form of every flag a command accepts.
+func (c command) flagNames() []string {
+	if c.flags == nil {
+		return nil
+	}
+	var names []string
+	c.flags().VisitAll(func(f *flag.Flag) {
+		names = append(names, "--"+f.Name)
+	})
+	return names
+}
+
+func printHelp() {
+	fmt.Println("Textify - Turn your codebase into AI-ready text")
+	fmt.Println("\nUsage:")
+	for _, cmd := range commands {
+		fmt.Printf("  textify %-24s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+
+	fmt.Println("\nGlobal Flags:")
+	fmt.Printf("  %-26s %s\n", noParentLookupFlag, "Don't look for textify.yaml in parent folders")
+
+	printExitCodes()
+
+	for _, cmd := range commands {
+		if cmd.flags == nil {
+			continue
+		}
+		fmt.Printf("\n%s Flags:\n", strings.ToUpper(cmd.name[:1])+cmd.name[1:])
+		cmd.flags().VisitAll(func(f *flag.Flag) {
+			name, usage := flag.UnquoteUsage(f)
+			fmt.Printf("  --%-24s %s\n", strings.TrimSpace(f.Name+" "+name), usage)
+		})
+	}
+}
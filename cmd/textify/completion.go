@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: textify completion bash|zsh|fish")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout)
+	case "zsh":
+		writeZshCompletion(os.Stdout)
+	case "fish":
+		writeFishCompletion(os.Stdout)
+	default:
+		fmt.Printf("Unsupported shell: %s (expected bash, zsh, or fish)\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// completionWords returns the flags and positional words offered after cmd.
+func completionWords(cmd command) []string {
+	words := cmd.flagNames()
+	if cmd.usesConfig {
+		words = append(words, noParentLookupFlag)
+	}
+	return append(words, cmd.completions...)
+}
+
+func commandNames() []string {
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# bash completion for textify")
+	fmt.Fprintln(w, "# Install: source <(textify completion bash)")
+	fmt.Fprintln(w, "_textify() {")
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintln(w, `    if [ "$COMP_CWORD" -eq 1 ]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    case "${COMP_WORDS[1]}" in`)
+	for _, cmd := range commands {
+		if words := completionWords(cmd); len(words) > 0 {
+			fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", cmd.name, strings.Join(words, " "))
+		}
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o default -F _textify textify")
+}
+
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintln(w, "#compdef textify")
+	fmt.Fprintln(w, "# Install: textify completion zsh > \"${fpath[1]}/_textify\"")
+	fmt.Fprintln(w, "_textify() {")
+	fmt.Fprintln(w, "    local -a commands")
+	fmt.Fprintln(w, "    commands=(")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "        '%s:%s'\n", cmd.name, zshEscape(cmd.summary))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w, "    if (( CURRENT == 2 )); then")
+	fmt.Fprintln(w, "        _describe 'command' commands")
+	fmt.Fprintln(w, "        return")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, `    case "$words[2]" in`)
+	for _, cmd := range commands {
+		if words := completionWords(cmd); len(words) > 0 {
+			fmt.Fprintf(w, "        %s) compadd -- %s ;;\n", cmd.name, strings.Join(words, " "))
+		}
+	}
+	fmt.Fprintln(w, "        *) _files ;;")
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, `_textify "$@"`)
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# fish completion for textify")
+	fmt.Fprintln(w, "# Install: textify completion fish > ~/.config/fish/completions/textify.fish")
+	fmt.Fprintln(w, "complete -c textify -f")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c textify -n __fish_use_subcommand -a %s -d '%s'\n", cmd.name, fishEscape(cmd.summary))
+	}
+	for _, cmd := range commands {
+		condition := "__fish_seen_subcommand_from " + cmd.name
+		if cmd.flags != nil {
+			cmd.flags().VisitAll(func(f *flag.Flag) {
+				_, usage := flag.UnquoteUsage(f)
+				fmt.Fprintf(w, "complete -c textify -n '%s' -l %s -d '%s'\n", condition, f.Name, fishEscape(usage))
+			})
+		}
+		for _, word := range cmd.completions {
+			fmt.Fprintf(w, "complete -c textify -n '%s' -a %s\n", condition, word)
+		}
+	}
+}
+
+// zshEscape escapes a description for a single-quoted _describe entry.
+func zshEscape(s string) string {
+	s = strings.ReplaceAll(s, "'", `'\''`)
+	return strings.ReplaceAll(s, ":", `\:`)
+}
+
+// fishEscape escapes a description for a single-quoted fish string.
+func fishEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "'", `\'`)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/JohnEsleyer/textify/internal/linediff"
+	"github.com/JohnEsleyer/textify/internal/scanner"
+)
+
+// diffContext is how many unchanged lines surround each change in --show.
+const diffContext = 3
+
+type diffOptions struct {
+	show string
+}
+
+func newDiffFlags() (*flag.FlagSet, *diffOptions) {
+	opts := &diffOptions{}
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.StringVar(&opts.show, "show", "", "Print a unified diff of the section of the file at `path`")
+	return fs, opts
+}
+
+// runDiff compares two generated outputs file by file: which files were
+// added, removed, or modified, with the lines changed in each.
+func runDiff(args []string) {
+	fs, opts := newDiffFlags()
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fmt.Println("Usage: textify diff [--show <path>] <old> <new>")
+		os.Exit(1)
+	}
+	oldName, newName := fs.Arg(0), fs.Arg(1)
+	oldFiles, oldOrder := readSections(oldName)
+	newFiles, newOrder := readSections(newName)
+
+	if opts.show != "" {
+		showSection(opts.show, oldName, newName, oldFiles, newFiles)
+		return
+	}
+
+	fmt.Printf("Comparing %s (%d files) with %s (%d files)\n\n", oldName, len(oldOrder), newName, len(newOrder))
+	var added, removed, modified int
+	for _, relPath := range mergedOrder(oldOrder, newOrder) {
+		oldContent, inOld := oldFiles[relPath]
+		newContent, inNew := newFiles[relPath]
+		ops := linediff.Lines(linediff.Split(oldContent), linediff.Split(newContent))
+		inserted, deleted := linediff.Counts(ops)
+		switch {
+		case !inOld:
+			added++
+			fmt.Printf("  added     %s (+%d)\n", relPath, inserted)
+		case !inNew:
+			removed++
+			fmt.Printf("  removed   %s (-%d)\n", relPath, deleted)
+		case oldContent != newContent:
+			modified++
+			fmt.Printf("  modified  %s (+%d -%d)\n", relPath, inserted, deleted)
+		}
+	}
+	unchanged := len(oldOrder) - removed - modified
+	if added+removed+modified > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("%d added, %d removed, %d modified, %d unchanged\n", added, removed, modified, unchanged)
+}
+
+// readSections parses an output file into its sections by path, and the
+// paths in order. A path listed twice, as in appended scans, keeps its last
+// section.
+func readSections(name string) (map[string]string, []string) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	sections, err := scanner.ParseOutput(data)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	files := make(map[string]string, len(sections))
+	var order []string
+	for _, s := range sections {
+		if _, seen := files[s.Path]; !seen {
+			order = append(order, s.Path)
+		}
+		files[s.Path] = s.Content
+	}
+	return files, order
+}
+
+// mergedOrder lists every path of either output: those of the old one in
+// its order, then those only in the new one, sorted.
+func mergedOrder(oldOrder, newOrder []string) []string {
+	paths := append([]string{}, oldOrder...)
+	seen := make(map[string]bool, len(oldOrder))
+	for _, p := range oldOrder {
+		seen[p] = true
+	}
+	var added []string
+	for _, p := range newOrder {
+		if !seen[p] {
+			added = append(added, p)
+		}
+	}
+	sort.Strings(added)
+	return append(paths, added...)
+}
+
+// showSection prints a unified diff of one file's sections.
+func showSection(relPath, oldName, newName string, oldFiles, newFiles map[string]string) {
+	oldContent, inOld := oldFiles[relPath]
+	newContent, inNew := newFiles[relPath]
+	if !inOld && !inNew {
+		fmt.Printf("Error: %s is in neither output\n", relPath)
+		os.Exit(1)
+	}
+	if oldContent == newContent && inOld == inNew {
+		fmt.Printf("%s is unchanged\n", relPath)
+		return
+	}
+
+	fmt.Printf("--- %s:%s\n+++ %s:%s\n", oldName, relPath, newName, relPath)
+	ops := linediff.Lines(linediff.Split(oldContent), linediff.Split(newContent))
+	if err := linediff.Unified(os.Stdout, ops, diffContext); err != nil {
+		fmt.Printf("Error writing diff: %v\n", err)
+		os.Exit(1)
+	}
+}
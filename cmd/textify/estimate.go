@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+	"github.com/JohnEsleyer/textify/internal/scanner"
+	"github.com/JohnEsleyer/textify/internal/tokens"
+)
+
+type estimateOptions struct {
+	costs bool
+}
+
+func newEstimateFlags() (*flag.FlagSet, *estimateOptions) {
+	opts := &estimateOptions{}
+	fs := flag.NewFlagSet("estimate", flag.ExitOnError)
+	fs.BoolVar(&opts.costs, "costs", false, "Also estimate the input cost of sending the output once, per model of costs")
+	return fs, opts
+}
+
+// runEstimate generates the output in memory and reports its estimated token
+// count against common model context windows, without writing any file.
+func runEstimate(args []string) {
+	fs, opts := newEstimateFlags()
+	fs.Parse(args)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error getting current directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	// The cache and other outputs would be written as a side effect; an
+	// estimate changes nothing, and counts every file in the one total,
+	// however large
+	cfg.CacheFile = ""
+	cfg.Outputs = nil
+	cfg.MaxOutputSize = ""
+	for dir, rule := range cfg.Dirs {
+		rule.OutputFile = ""
+		cfg.Dirs[dir] = rule
+	}
+
+	scanner.Progress = io.Discard
+	counter := &byteCounter{}
+	result, err := scanner.Scan(cwd, cfg, counter)
+	if err != nil {
+		fmt.Printf("Scan error: %v\n", err)
+		os.Exit(1)
+	}
+
+	n := tokens.Estimate(counter.n)
+	fmt.Printf("Estimated tokens: %d (%s, %d files)\n\n", n, fileutil.FormatSize(counter.n), result.Included)
+
+	windows := tokens.Windows(cfg.ContextWindows)
+	width := 0
+	for _, w := range windows {
+		if label := len(w.Name) + len(w.Label()) + 3; label > width {
+			width = label
+		}
+	}
+	for _, w := range windows {
+		percent, fits := w.Usage(n)
+		verdict := "OK"
+		if !fits {
+			verdict = "TOO LARGE"
+		}
+		label := fmt.Sprintf("%s (%s):", w.Name, w.Label())
+		fmt.Printf("  %-*s %s (%d%% used)\n", width+1, label, verdict, percent)
+	}
+
+	if opts.costs && !printCosts(cfg, n, nil) {
+		fmt.Printf("\nNo prices to estimate costs with; add them under costs in %s (e.g., gpt-4o-input: 2.50per1M)\n", configFile)
+	}
+}
+
+// printCosts prints, after a blank line, what sending the output once costs
+// for each model of costs with a price, and reports whether there was any.
+// Each model is priced by the count of its tokenizer family among counts,
+// when that tokenizer counted the output, or else by n, the chars/4
+// estimate, labelled as such.
+func printCosts(cfg *config.Config, n int64, counts []tokens.FamilyCount) bool {
+	prices, problems := cfg.Prices()
+	for _, p := range problems {
+		fmt.Printf("Warning: %s; leaving it out of the costs\n", p)
+	}
+	if len(prices) == 0 {
+		return false
+	}
+	counted := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		if c.Family != tokens.Heuristic && !c.Estimated {
+			counted[c.Family] = c.Tokens
+		}
+	}
+	models := make([]string, 0, len(prices))
+	width := 0
+	for name := range prices {
+		models = append(models, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	sort.Strings(models)
+	fmt.Println()
+	fmt.Println("  Estimated input cost of sending it once:")
+	for _, name := range models {
+		priced, figure := n, fmt.Sprintf("~%d tokens, %s estimate", n, tokens.Heuristic)
+		if family := tokens.ModelFamily(name); family != "" {
+			if c, ok := counted[family]; ok {
+				priced, figure = c, fmt.Sprintf("%d %s tokens", c, family)
+			}
+		}
+		fmt.Printf("    %-*s %s  (%s)\n", width, name, tokens.FormatCost(prices[name].Cost(priced)), figure)
+	}
+	return true
+}
+
+// byteCounter is a writer that only counts what is written to it.
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// printTokenFamilies counts the tokens of the output file at outPath for
+// each of token_families and prints them as a table, with the context
+// windows each count is too large for. Families whose tokenizer data can't
+// be loaded are marked as estimates, with a note on how to count them
+// offline.
+func printTokenFamilies(cfg *config.Config, outPath string) []tokens.FamilyCount {
+	if len(cfg.TokenFamilies) == 0 {
+		return nil
+	}
+	f, err := os.Open(outPath)
+	if err != nil {
+		fmt.Printf("Warning: could not count tokens per family: %v\n", err)
+		return nil
+	}
+	defer f.Close()
+	cacheDir, err := tokens.CacheDir()
+	if err != nil {
+		cacheDir = ""
+	}
+	counts, err := tokens.CountReader(cfg.TokenFamilies, f, cacheDir)
+	if err != nil {
+		fmt.Printf("Warning: could not count tokens per family: %v\n", err)
+		return nil
+	}
+
+	windows := tokens.Windows(cfg.ContextWindows)
+	width := 0
+	for _, c := range counts {
+		if len(c.Family) > width {
+			width = len(c.Family)
+		}
+	}
+	fmt.Println("  Tokens per model family:")
+	var notes []string
+	for _, c := range counts {
+		var tooLarge []string
+		for _, w := range windows {
+			if _, fits := w.Usage(c.Tokens); !fits {
+				tooLarge = append(tooLarge, fmt.Sprintf("%s (%s)", w.Name, w.Label()))
+			}
+		}
+		verdict := "fits every window"
+		if len(tooLarge) > 0 {
+			verdict = "too large for " + strings.Join(tooLarge, ", ")
+		}
+		n := fmt.Sprint(c.Tokens)
+		if c.Estimated {
+			n = "~" + n
+			notes = append(notes, fmt.Sprintf("%s is a %s", c.Family, c.Note))
+		}
+		fmt.Printf("    %-*s %10s  %s\n", width, c.Family, n, verdict)
+	}
+	for _, note := range notes {
+		fmt.Printf("    Note: %s\n", note)
+	}
+	if len(notes) > 0 && cacheDir != "" {
+		fmt.Printf("    To count offline, place <family>.tiktoken files in %s\n", cacheDir)
+	}
+	return counts
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/tokens"
+)
+
+func TestPrintCostsPerFamily(t *testing.T) {
+	cfg := &config.Config{Costs: map[string]string{
+		"gpt-4o-input":  "1per1K",
+		"gpt-4-input":   "1per1K",
+		"claude-input":  "1per1K",
+		"mistyped-cost": "2.5 a token",
+	}}
+	counts := []tokens.FamilyCount{
+		{Family: "cl100k_base", Tokens: 3000},
+		{Family: "o200k_base", Tokens: 2000, Estimated: true},
+	}
+	var ok bool
+	out := captureStdout(t, func() { ok = printCosts(cfg, 4000, counts) })
+	if !ok {
+		t.Fatal("Expected costs to be printed")
+	}
+	for _, line := range []string{
+		// o200k_base fell back to the estimate, so its count isn't used
+		"gpt-4o-input $4.00  (~4000 tokens, chars/4 estimate)",
+		"gpt-4-input  $3.00  (3000 cl100k_base tokens)",
+		"claude-input $4.00  (~4000 tokens, chars/4 estimate)",
+		`Warning: costs["mistyped-cost"]: invalid price`,
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in:\n%s", line, out)
+		}
+	}
+}
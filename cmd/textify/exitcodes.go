@@ -0,0 +1,41 @@
+package main
+
+import "fmt"
+
+// Exit codes other than 0 and 1, so scripts can tell these outcomes from
+// other errors. Each has its own number; exitCodes lists them for the help.
+const (
+	// exitChanged is the exit code of a run with --exit-code-on-change that
+	// changed an output, so pipelines can skip the steps that follow
+	// otherwise.
+	exitChanged = 2
+	// exitNoFiles is the exit code of a run that included no files, usually
+	// a misconfiguration.
+	exitNoFiles = 3
+	// exitTooLarge is the exit code of a run in CI mode that passed
+	// max_output_size, so workflows can tell a budget overrun apart.
+	exitTooLarge = 4
+	// exitCancelled is the exit code of a run stopped with Ctrl-C, as shells
+	// report for SIGINT.
+	exitCancelled = 130
+)
+
+// exitCodes describes every exit code, in order, for printHelp.
+var exitCodes = []struct {
+	code    int
+	meaning string
+}{
+	{0, "success"},
+	{1, "error"},
+	{exitChanged, "an output changed (start --exit-code-on-change)"},
+	{exitNoFiles, "no files were included (start without --allow-empty)"},
+	{exitTooLarge, "the output passed max_output_size (start --ci)"},
+	{exitCancelled, "stopped with Ctrl-C"},
+}
+
+func printExitCodes() {
+	fmt.Println("\nExit Codes:")
+	for _, c := range exitCodes {
+		fmt.Printf("  %-26d %s\n", c.code, c.meaning)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExitCodes(t *testing.T) {
+	seen := map[int]string{}
+	for _, c := range exitCodes {
+		if other, ok := seen[c.code]; ok {
+			t.Errorf("Exit code %d means both %q and %q", c.code, other, c.meaning)
+		}
+		seen[c.code] = c.meaning
+	}
+	for _, code := range []int{exitChanged, exitNoFiles, exitTooLarge, exitCancelled} {
+		if _, ok := seen[code]; !ok {
+			t.Errorf("Exit code %d is missing from exitCodes", code)
+		}
+	}
+
+	// A budget overrun keeps its code next to --exit-code-on-change
+	if got := exitTooLargeCode(outputOptions{ci: true, exitCodeOnChange: true}); got != exitTooLarge {
+		t.Errorf("Expected exit code %d for an overrun in CI mode, got %d", exitTooLarge, got)
+	}
+	if got := exitTooLargeCode(outputOptions{}); got != 1 {
+		t.Errorf("Expected exit code 1 for an overrun outside CI mode, got %d", got)
+	}
+
+	help := captureStdout(t, printHelp)
+	_, codes, ok := strings.Cut(help, "Exit Codes:")
+	if !ok {
+		t.Fatalf("Expected the help to list exit codes, got:\n%s", help)
+	}
+	for _, c := range exitCodes {
+		if !strings.Contains(codes, strconv.Itoa(c.code)+" ") || !strings.Contains(codes, c.meaning) {
+			t.Errorf("Expected the help to list exit code %d (%s)", c.code, c.meaning)
+		}
+	}
+}
+
+// captureStdout returns what f prints.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	f()
+	os.Stdout = stdout
+	w.Close()
+	return <-done
+}
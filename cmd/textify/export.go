@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+	"github.com/JohnEsleyer/textify/internal/scanner"
+)
+
+type exportOptions struct {
+	maxDepth int
+	excludes stringList
+}
+
+func newExportFlags() (*flag.FlagSet, *exportOptions) {
+	opts := &exportOptions{}
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.IntVar(&opts.maxDepth, "max-depth", -1, "Only export files up to `n` levels below the root (0 = root files only)")
+	fs.Var(&opts.excludes, "exclude", "Skip files and folders matching `glob` (repeatable)")
+	return fs, opts
+}
+
+// runExport writes the files 'textify start' would include to a folder, as
+// a copy of the project's tree, instead of one output file.
+func runExport(args []string) {
+	fs, opts := newExportFlags()
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: textify export [flags] <destdir>")
+		os.Exit(1)
+	}
+	dest := invocationPath(fs.Arg(0))
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error getting current directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	printKeyWarnings(cfg)
+	applyMaxDepth(cfg, opts.maxDepth)
+	cfg.AddExcludes(opts.excludes)
+
+	fmt.Printf("Exporting project to %s using %s...\n", dest, configFile)
+	result, err := scanner.Export(cwd, cfg, dest)
+	if errors.Is(err, scanner.ErrOutputTooLarge) {
+		fmt.Printf("Error: %v\nThe export in %s is incomplete. Exclude the folders that don't belong, or raise max_output_size.\n", err, dest)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Export error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n✔ Done! Exported %d files (%s) to: %s\n", result.Included, fileutil.FormatSize(result.Bytes), dest)
+	if n := result.Skipped[scanner.ReasonBinary]; n > 0 {
+		fmt.Printf("  Skipped %d binary files\n", n)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/scanner"
+)
+
+// maxSuggestions is how many close matches are listed for a path that isn't
+// in the output.
+const maxSuggestions = 5
+
+type extractOptions struct {
+	from string
+	raw  bool
+}
+
+func newExtractFlags() (*flag.FlagSet, *extractOptions) {
+	opts := &extractOptions{}
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	fs.StringVar(&opts.from, "from", "", "Read the output `file` (default output_file from textify.yaml, or codebase.txt)")
+	fs.BoolVar(&opts.raw, "raw", false, "Print only the files' content, without their headers")
+	return fs, opts
+}
+
+// runExtract prints the sections of an existing output for the given paths
+// or glob patterns, in the order they appear in the output.
+func runExtract(args []string) {
+	fs, opts := newExtractFlags()
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println("Usage: textify extract [--raw] [--from <file>] <path-or-glob>...")
+		os.Exit(1)
+	}
+	from := invocationPath(opts.from)
+	if from == "" {
+		from = defaultOutputFile()
+	}
+
+	data, err := os.ReadFile(from)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", from, err)
+		os.Exit(1)
+	}
+	sections, err := scanner.ParseOutput(data)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", from, err)
+		os.Exit(1)
+	}
+
+	var patterns []string
+	for _, arg := range fs.Args() {
+		p, err := cleanRulePath(arg)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		patterns = append(patterns, p)
+	}
+
+	// Every pattern must match; nothing is printed otherwise
+	matched := make([]bool, len(sections))
+	missing := false
+	for _, p := range patterns {
+		found := false
+		for i, s := range sections {
+			if extractMatches(p, s.Path) {
+				matched[i] = true
+				found = true
+			}
+		}
+		if !found {
+			missing = true
+			fmt.Fprintf(os.Stderr, "Error: %s is not in %s\n", p, from)
+			if close := closeMatches(p, sections); len(close) > 0 {
+				fmt.Fprintf(os.Stderr, "  Did you mean: %s\n", strings.Join(close, ", "))
+			}
+		}
+	}
+	if missing {
+		os.Exit(1)
+	}
+
+	for i, s := range sections {
+		if !matched[i] {
+			continue
+		}
+		if err := writeExtracted(os.Stdout, s, opts.raw); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", s.Path, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// extractMatches reports whether the section of relPath is selected by p: a
+// path, a glob, or a folder ending in "/" for everything in it.
+func extractMatches(p, relPath string) bool {
+	if strings.HasSuffix(p, "/") {
+		return strings.HasPrefix(relPath, p)
+	}
+	ok, _ := path.Match(p, relPath)
+	return ok || p == relPath
+}
+
+// defaultOutputFile is the output of the project's config, or the default
+// one when there is no config.
+func defaultOutputFile() string {
+	if _, err := os.Stat(configFile); err != nil {
+		return config.DefaultConfig().OutputFile
+	}
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	return cfg.OutputFile
+}
+
+// writeExtracted writes a section with its header, or only its content.
+func writeExtracted(w io.Writer, s scanner.Section, raw bool) error {
+	if !raw {
+		return scanner.WriteSection(w, s)
+	}
+	_, err := io.WriteString(w, s.Content)
+	return err
+}
+
+// closeMatches returns the paths in sections closest to p: those with the
+// same file name first, then those within a few edits of it.
+func closeMatches(p string, sections []scanner.Section) []string {
+	type candidate struct {
+		path     string
+		distance int
+	}
+	var candidates []candidate
+	for _, s := range sections {
+		d := editDistance(p, s.Path)
+		if path.Base(s.Path) == path.Base(p) {
+			d = 0
+		}
+		if d <= len(p)/3+1 {
+			candidates = append(candidates, candidate{s.Path, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	var paths []string
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		paths = append(paths, candidates[i].path)
+	}
+	return paths
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
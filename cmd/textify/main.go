@@ -1,15 +1,32 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/JohnEsleyer/textify/internal/cache"
 	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+	"github.com/JohnEsleyer/textify/internal/gitutil"
+	"github.com/JohnEsleyer/textify/internal/post"
 	"github.com/JohnEsleyer/textify/internal/scanner"
+	"github.com/JohnEsleyer/textify/internal/tokens"
 )
 
-const configFile = "textify.yaml"
+const configFile = config.FileName
 
 func main() {
 	if len(os.Args) < 2 {
@@ -17,20 +34,86 @@ func main() {
 		os.Exit(1)
 	}
 
-	command := os.Args[1]
-
-	switch command {
-	case "init":
-		runInit()
-	case "scan":
-		runScan() // New Command
-	case "start":
-		runStart()
-	default:
-		fmt.Printf("Unknown command: %s\n", command)
+	cmd, ok := findCommand(os.Args[1])
+	if !ok {
+		fmt.Printf("Unknown command: %s\n", os.Args[1])
 		printHelp()
 		os.Exit(1)
 	}
+	args := os.Args[2:]
+	if cmd.usesConfig {
+		args = enterProjectRoot(args)
+	}
+	cmd.run(args)
+}
+
+// noParentLookupFlag keeps a command in the current folder, as before
+// textify looked for its config in the parent folders.
+const noParentLookupFlag = "--no-parent-lookup"
+
+// invokedDir is the folder textify was started in, before
+// enterProjectRoot moved to the project root. Paths given on the command
+// line are relative to it (see invocationPath), project paths and patterns
+// included (see cleanRulePath).
+var invokedDir string
+
+// enterProjectRoot makes the project root the current folder: the closest
+// folder holding textify.yaml, from the current one up to the root of the
+// git repository (see config.FindRoot). The config's folder is the scan
+// root, so the keys of dirs resolve as they do from there. Without a config
+// on the way, or with --no-parent-lookup, the current folder is kept. It
+// returns args without --no-parent-lookup.
+func enterProjectRoot(args []string) []string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error getting current directory: %v\n", err)
+		os.Exit(1)
+	}
+	invokedDir = cwd
+
+	var rest []string
+	lookup := true
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == noParentLookupFlag || arg == noParentLookupFlag[1:] {
+			lookup = false
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	if !lookup {
+		return rest
+	}
+
+	root, ok := config.FindRoot(cwd)
+	if !ok || root == cwd {
+		return rest
+	}
+	if err := os.Chdir(root); err != nil {
+		fmt.Printf("Error entering %s: %v\n", root, err)
+		os.Exit(1)
+	}
+	// On stderr, so the output of list and extract can still be piped
+	fmt.Fprintf(os.Stderr, "Using %s\n", filepath.Join(root, configFile))
+	return rest
+}
+
+// invocationPath returns p, given on the command line, relative to the
+// current folder once enterProjectRoot ran.
+func invocationPath(p string) string {
+	if p == "" || filepath.IsAbs(p) || invokedDir == "" {
+		return p
+	}
+	p = filepath.Join(invokedDir, p)
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, p); err == nil {
+			return rel
+		}
+	}
+	return p
 }
 
 func runInit() {
@@ -46,6 +129,13 @@ func runInit() {
 
 	fmt.Println("Initializing and scanning project structure...")
 
+	for _, eco := range config.DetectEcosystems(cwd) {
+		fmt.Printf("  Detected %s project, applying its defaults\n", eco.Name)
+	}
+	for _, ws := range config.DetectWorkspaces(cwd) {
+		fmt.Printf("  Detected workspace in %s, adding a rule per member (%d)\n", ws.File, len(ws.Members))
+	}
+
 	// Run Discovery with no existing config
 	cfg, err := config.Discover(cwd, nil)
 	if err != nil {
@@ -59,6 +149,8 @@ func runInit() {
 		os.Exit(1)
 	}
 
+	writeSchemaFile()
+
 	fmt.Printf("✔ Generated %s with %d directory rules.\n", configFile, len(cfg.Dirs))
 }
 
@@ -69,7 +161,7 @@ func runScan() {
 	}
 
 	// 1. Load existing
-	existingCfg, err := config.Load(configFile)
+	existingCfg, err := config.LoadRaw(configFile)
 	if err != nil {
 		fmt.Printf("Error loading %s: %v\n", configFile, err)
 		return
@@ -90,10 +182,124 @@ func runScan() {
 		os.Exit(1)
 	}
 
+	writeSchemaFile()
+
 	fmt.Printf("✔ Updated %s. Total rules: %d\n", configFile, len(newCfg.Dirs))
 }
 
-func runStart() {
+// writeSchemaFile refreshes the JSON Schema that the config's modeline points
+// editors at. A failure only costs editor support, so it is just a warning.
+func writeSchemaFile() {
+	if err := config.WriteSchema(config.SchemaFile); err != nil {
+		fmt.Printf("Warning: could not write %s: %v\n", config.SchemaFile, err)
+	}
+}
+
+// runSchema prints the JSON Schema for textify.yaml.
+func runSchema([]string) {
+	data, err := json.MarshalIndent(config.GenerateSchema(), "", "  ")
+	if err != nil {
+		fmt.Printf("Error generating schema: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+// runCheck validates textify.yaml against the schema and checks that its
+// values make sense, exiting non-zero if anything is wrong.
+func runCheck([]string) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+
+	problems, err := config.ValidateSchema(data)
+	if err != nil {
+		fmt.Printf("Error parsing %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	// Semantic checks need a config that loads
+	if len(problems) == 0 {
+		cfg, err := config.LoadRaw(configFile)
+		if err != nil {
+			fmt.Printf("Error loading %s: %v\n", configFile, err)
+			os.Exit(1)
+		}
+		problems = cfg.Check()
+		if _, err := scanner.ParseModifiedSince(cfg.ModifiedSince, time.Now()); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("%s has %d problem(s):\n", configFile, len(problems))
+		for _, p := range problems {
+			fmt.Printf("  %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("✔ %s is valid.\n", configFile)
+}
+
+// startOptions holds the flags accepted by 'textify start'.
+type startOptions struct {
+	grep          string
+	grepV         string
+	modifiedSince string
+	changedSince  string
+	paranoid      bool
+	output        outputOptions
+	prune         bool
+	stdinList     bool
+	maxDepth      int
+	excludes      stringList
+	warnSize      string
+	maxSize       string
+	maxLineBytes  int
+}
+
+// stringList is a flag value that collects every occurrence of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func newStartFlags() (*flag.FlagSet, *startOptions) {
+	opts := &startOptions{}
+	fs := flag.NewFlagSet("start", flag.ExitOnError)
+	fs.StringVar(&opts.grep, "grep", "", "Only include files whose content matches the `regex`")
+	fs.StringVar(&opts.grepV, "grep-v", "", "Skip files whose content matches the `regex`")
+	fs.StringVar(&opts.modifiedSince, "modified-since", "", "Only include files modified since `when` (a duration like 48h or a date like 2024-05-01)")
+	fs.StringVar(&opts.changedSince, "since", "", "Only include files changed since the git `ref` (e.g., main)")
+	fs.BoolVar(&opts.paranoid, "paranoid", false, "Verify cached results against content hashes")
+	fs.IntVar(&opts.maxDepth, "max-depth", -1, "Only include files up to `n` levels below the root (0 = root files only)")
+	fs.BoolVar(&opts.output.force, "force", false, "Overwrite the output file even if textify didn't write it")
+	fs.BoolVar(&opts.output.append, "append", false, "Add this scan to the end of the output file instead of replacing it")
+	fs.BoolVar(&opts.output.update, "update", false, "Copy the files that didn't change from the previous output file, by the manifest kept beside it")
+	fs.BoolVar(&opts.output.ci, "ci", inCI(), "CI mode: no per-file progress, GitHub Actions annotations, and exit code 4 when max_output_size is passed (default true when CI is set)")
+	fs.BoolVar(&opts.output.verbose, "verbose", false, "Also name the extensions most often left out by the extensions lists")
+	fs.BoolVar(&opts.output.allowEmpty, "allow-empty", false, "Succeed even when no files were included")
+	fs.BoolVar(&opts.output.exitCodeOnChange, "exit-code-on-change", false, "Exit with code 2 when an output changed (0 when all were up to date)")
+	fs.StringVar(&opts.output.report, "report", "", "Also write a JSON report of the run (totals, skips by reason, folders, warnings) to `file`")
+	fs.StringVar(&opts.output.post, "post", "", "Also stream the output to `url` in a chunked POST, with the project name and the output's SHA-256")
+	fs.BoolVar(&opts.stdinList, "stdin-list", false, "Write exactly the files listed on stdin, one path per line, instead of walking the project")
+	fs.BoolVar(&opts.prune, "prune", false, "Remove rules for directories that no longer exist from textify.yaml")
+	fs.Var(&opts.excludes, "exclude", "Skip files and folders matching `glob` (repeatable)")
+	fs.StringVar(&opts.warnSize, "output-warn-size", "", "Warn once the output passes `size` (default output_warn_size, or "+config.DefaultOutputWarnSize+"; 0 = never)")
+	fs.StringVar(&opts.maxSize, "max-output-size", "", "Abort and remove the output once it passes `size` (e.g., 200MB; default max_output_size, or no limit)")
+	fs.IntVar(&opts.maxLineBytes, "max-line-bytes", 0, "Cut lines longer than `n` bytes, with a note (default max_line_bytes, or 1048576)")
+	return fs, opts
+}
+
+func runStart(args []string) {
+	fs, opts := newStartFlags()
+	fs.Parse(args)
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		fmt.Printf("Error getting current directory: %v\n", err)
@@ -105,33 +311,797 @@ func runStart() {
 		fmt.Printf("Error loading %s: %v\n", configFile, err)
 		os.Exit(1)
 	}
+	printKeyWarnings(cfg)
 
-	outPath := cfg.OutputFile
-	if !filepath.IsAbs(outPath) {
-		outPath = filepath.Join(cwd, outPath)
+	checkMissingDirs(cwd, cfg, opts.prune)
+
+	applyContentFlags(cfg, opts.grep, opts.grepV)
+	if opts.modifiedSince != "" {
+		cfg.ModifiedSince = opts.modifiedSince
+	}
+	if opts.changedSince != "" {
+		cfg.ChangedSince = opts.changedSince
+	}
+	if opts.paranoid {
+		cfg.Paranoid = true
+	}
+	applyMaxDepth(cfg, opts.maxDepth)
+	for i, pattern := range opts.excludes {
+		if opts.excludes[i], err = cleanRulePath(pattern); err != nil {
+			fmt.Printf("Error: --exclude: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	cfg.AddExcludes(opts.excludes)
+	if opts.warnSize != "" {
+		cfg.OutputWarnSize = opts.warnSize
+	}
+	if opts.maxSize != "" {
+		cfg.MaxOutputSize = opts.maxSize
+	}
+	if opts.maxLineBytes > 0 {
+		cfg.MaxLineBytes = opts.maxLineBytes
+	}
+	if _, _, err := cfg.OutputLimits(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if opts.output.ci {
+		scanner.Progress = io.Discard
+	}
+	opts.output.report = invocationPath(opts.output.report)
+	excludeReport(cwd, cfg, opts.output.report)
+
+	if opts.stdinList {
+		if opts.output.list, err = readList(os.Stdin); err != nil {
+			fmt.Printf("Error reading the file list: %v\n", err)
+			os.Exit(1)
+		}
+		for i, p := range opts.output.list {
+			opts.output.list[i] = invocationPath(p)
+		}
+	}
+	generate(cwd, cfg, opts.output)
+}
+
+// checkMissingDirs warns about rules for directories that don't exist, which
+// silently do nothing, or removes them from the config when prune is set.
+func checkMissingDirs(cwd string, cfg *config.Config, prune bool) {
+	missing := cfg.MissingDirs(cwd)
+	if len(missing) == 0 {
+		return
+	}
+	if !prune {
+		fmt.Printf("Warning: %s has rules for directories that don't exist (run 'textify start --prune' to remove them):\n", configFile)
+		for _, dir := range missing {
+			fmt.Printf("  %s\n", dir)
+		}
+		return
 	}
 
-	f, err := os.Create(outPath)
+	doc := loadDocument()
+	var removed, kept []string
+	for _, dir := range missing {
+		if !doc.RemoveRule(dir) {
+			kept = append(kept, dir)
+			continue
+		}
+		removed = append(removed, dir)
+		delete(cfg.Dirs, dir)
+	}
+	if len(removed) > 0 {
+		saveDocument(doc)
+		fmt.Printf("✔ Removed %d rule(s) for missing directories from %s: %s\n", len(removed), configFile, strings.Join(removed, ", "))
+	}
+	if len(kept) > 0 {
+		fmt.Printf("Warning: could not find the rules for these missing directories in %s; remove them by hand: %s\n", configFile, strings.Join(kept, ", "))
+	}
+}
+
+// outputOptions controls what generate does with an existing output file.
+type outputOptions struct {
+	// force overwrites a file that textify didn't write without asking.
+	force bool
+
+	// append adds the scan to the end of the file, after a boundary line,
+	// instead of replacing it.
+	append bool
+
+	// update copies the sections of the files that didn't change from the
+	// previous output file, which previous holds once its manifest was
+	// found current, and writes the manifest again.
+	update   bool
+	previous *scanner.Previous
+
+	// allowEmpty succeeds even when no file was included.
+	allowEmpty bool
+
+	// verbose names the extensions most often skipped in the summary.
+	verbose bool
+
+	// ci is CI mode (start --ci): no per-file progress, workflow
+	// annotations for the warnings and failures of the run, and
+	// exitTooLarge when it passes max_output_size.
+	ci bool
+
+	// list, if not nil, holds the files to write instead of walking the
+	// project (start --stdin-list).
+	list []string
+
+	// post, if set, is a URL the output is also streamed to (start --post).
+	post string
+
+	// exitCodeOnChange exits with exitChanged when the run changed an
+	// output.
+	exitCodeOnChange bool
+
+	// report, if set, is the file the JSON report of the run is written to
+	// (start --report), and started is when the run began.
+	report  string
+	started time.Time
+}
+
+// scanOutput writes the output for cfg to w: the listed files, if there is a
+// list, or the files the walk selects. With out.post, the output is streamed
+// to that URL as it is written, and a failed request fails the run.
+// Ctrl-C stops the scan.
+func scanOutput(cwd string, cfg *config.Config, w io.Writer, out outputOptions) (*scanner.Result, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var stream *post.Stream
+	if out.post != "" {
+		var err error
+		if stream, err = post.Start(ctx, out.post, filepath.Base(cwd), contentType(cfg.Format)); err != nil {
+			return nil, fmt.Errorf("posting to %s: %w", out.post, err)
+		}
+		w = io.MultiWriter(w, stream)
+	}
+
+	var result *scanner.Result
+	var err error
+	if out.list != nil {
+		result, err = scanner.ScanFilesContext(ctx, cwd, cfg, out.list, w)
+	} else if out.previous != nil {
+		result, err = scanner.UpdateContext(ctx, cwd, cfg, w, out.previous)
+	} else {
+		result, err = scanner.ScanContext(ctx, cwd, cfg, w, scanner.Hooks{})
+	}
+	if stream == nil {
+		return result, err
+	}
+	if err != nil {
+		stream.Abort(err)
+		return result, err
+	}
+	status, err := stream.Finish()
+	if err != nil {
+		return result, fmt.Errorf("posting to %s: %w", out.post, err)
+	}
+	fmt.Printf("Posted to %s: %s\n", out.post, status)
+	return result, nil
+}
+
+// contentType is the media type of an output format, for --post.
+func contentType(format string) string {
+	switch format {
+	case config.FormatMarkdownDoc:
+		return "text/markdown; charset=utf-8"
+	case config.FormatJSON:
+		return "application/json"
+	case config.FormatHTML:
+		return "text/html; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// readList reads a newline-separated list of paths, ignoring blank lines.
+func readList(r io.Reader) ([]string, error) {
+	list := []string{}
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		if line := strings.TrimSpace(lines.Text()); line != "" {
+			list = append(list, line)
+		}
+	}
+	return list, lines.Err()
+}
+
+// checkIncluded exits with exitNoFiles unless a run included files or empty
+// output is allowed. The output is written either way.
+func checkIncluded(included int, out outputOptions) {
+	if included > 0 || out.allowEmpty {
+		return
+	}
+	fmt.Println("Error: no files were included. Run 'textify list --verbose' to see why files were left out, or pass --allow-empty to accept an empty output.")
+	annotate(out, "error", "no files", "no files were included")
+	os.Exit(exitNoFiles)
+}
+
+// generate writes the output file for cfg and prints a summary of the run.
+// Unless forced, a file at the output path that textify didn't write is only
+// replaced after confirmation; appending to the top-level output never needs
+// it.
+func generate(cwd string, cfg *config.Config, out outputOptions) {
+	out.started = time.Now()
+	expandOutputNames(cwd, cfg)
+	outPath := resolveOutput(cwd, cfg.OutputFile)
+
+	// A JSON object, an index, or an HTML page can't be continued, and the
+	// other formats of the outputs list would only hold the appended part
+	if out.append && out.update {
+		fmt.Println("Error: --append and --update can't be combined")
+		os.Exit(1)
+	}
+	if out.append && (cfg.Format == config.FormatJSON || cfg.Format == config.FormatIndex || cfg.Format == config.FormatHTML || len(cfg.Outputs) > 0) {
+		fmt.Println("Error: --append only works with a single text or markdown-doc output")
+		os.Exit(1)
+	}
+
+	// Rule outputs are replaced even when appending
+	if !out.force {
+		names := cfg.OutputFiles()
+		if out.append {
+			names = names[1:]
+		}
+		for _, name := range names {
+			checkOverwrite(cwd, name)
+		}
+	}
+
+	if out.append {
+		appendOutput(cwd, cfg, outPath, out)
+		return
+	}
+
+	// Without a current manifest, nothing is known of the previous output
+	if out.update {
+		previous, err := scanner.OpenPrevious(outPath, cfg)
+		if err != nil {
+			fmt.Printf("Note: %v; writing it in full\n", err)
+		} else {
+			defer previous.Close()
+			out.previous = previous
+		}
+	}
+
+	// The output is written to a temporary file, and only put in place,
+	// after rotating the previous versions, once done and if it changed
+	f, err := createOutput(outPath)
 	if err != nil {
 		fmt.Printf("Error creating output file: %v\n", err)
 		os.Exit(1)
 	}
 	defer f.Close()
+	discard := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	if out.previous != nil {
+		fmt.Printf("Updating %s using %s...\n", cfg.OutputFile, configFile)
+	} else {
+		fmt.Printf("Textifying project using %s...\n", configFile)
+	}
+
+	result, err := scanOutput(cwd, cfg, f, out)
+	if errors.Is(err, scanner.ErrOutputTooLarge) {
+		// The other outputs were never put in place
+		discard()
+		fmt.Printf("Error: %v\nLeft %s as it was. Exclude the folders that don't belong, or raise max_output_size.\n", err, cfg.OutputFile)
+		annotate(out, "error", "max_output_size", err.Error())
+		os.Exit(exitTooLargeCode(out))
+	}
+	if errors.Is(err, context.Canceled) {
+		discard()
+		fmt.Printf("\nCancelled after %d files; left %s as it was\n", result.Included, cfg.OutputFile)
+		os.Exit(exitCancelled)
+	}
+	if err != nil {
+		discard()
+		fmt.Printf("Scan error: %v\n", err)
+		os.Exit(1)
+	}
+
+	changed, err := replaceOutput(f, outPath, cfg.KeepPrevious)
+	if err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+	if out.update && result.Manifest != nil {
+		if err := scanner.WriteManifest(outPath, result.Manifest); err != nil {
+			fmt.Printf("Warning: could not write manifest: %v\n", err)
+		}
+	}
+	if changed && out.previous != nil {
+		fmt.Printf("\n✔ Done! Updated %s: kept %d files, rewrote %d\n", cfg.OutputFile, result.Reused, result.Included-result.Reused)
+	} else if changed {
+		fmt.Printf("\n✔ Done! Output saved to: %s\n", cfg.OutputFile)
+	} else {
+		fmt.Printf("\n✔ Done! No changes: %s is up to date\n", cfg.OutputFile)
+	}
+	summarize(cwd, cfg, out, result, changed)
+	exitOnChange(out, changed, result)
+}
+
+// replaceOutput puts the finished temporary file f in place of the output at
+// outPath, rotating the previous versions first (keep_previous), and reports
+// whether it changed. An output identical to the one in place is dropped,
+// leaving the file and its modification time untouched.
+func replaceOutput(f *os.File, outPath string, keep int) (bool, error) {
+	err := f.Close()
+	same := false
+	if err == nil {
+		same, err = fileutil.SameContent(f.Name(), outPath)
+	}
+	if err == nil && !same {
+		err = fileutil.Rotate(outPath, keep)
+	}
+	if err == nil && !same {
+		err = os.Rename(f.Name(), outPath)
+	}
+	if err != nil || same {
+		os.Remove(f.Name())
+	}
+	return !same, err
+}
+
+// exitOnChange exits with exitChanged if out asks for it and the run changed
+// the output or one of the rule and extra outputs.
+func exitOnChange(out outputOptions, changed bool, result *scanner.Result) {
+	if !out.exitCodeOnChange {
+		return
+	}
+	for _, r := range result.Outputs {
+		changed = changed || !r.Unchanged
+	}
+	if changed {
+		os.Exit(exitChanged)
+	}
+}
+
+// createOutput creates a temporary file next to outPath, to be renamed into
+// place once written.
+func createOutput(outPath string) (*os.File, error) {
+	f, err := os.CreateTemp(filepath.Dir(outPath), fileutil.TempPattern(outPath))
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// summarize prints what a run of generate wrote, writes its report, and
+// fails it if no file was included. changed tells whether the output file
+// changed.
+func summarize(cwd string, cfg *config.Config, out outputOptions, result *scanner.Result, changed bool) {
+	outPath := resolveOutput(cwd, cfg.OutputFile)
+
+	if cfg.OutputChecksum {
+		if err := scanner.WriteChecksum(outPath, result.Hash); err != nil {
+			fmt.Printf("Warning: could not write checksum: %v\n", err)
+		}
+		for name, r := range result.Outputs {
+			if err := scanner.WriteChecksum(resolveOutput(cwd, name), r.Hash); err != nil {
+				fmt.Printf("Warning: could not write checksum: %v\n", err)
+			}
+		}
+	}
+
+	fmt.Printf("  Included %d files\n", result.Included)
+	fmt.Printf("  Total word count: %d (~%d tokens)\n", result.Words, tokens.Estimate(result.Size))
+	counts := printTokenFamilies(cfg, outPath)
+	printCosts(cfg, tokens.Estimate(result.Size), counts)
+	included := result.Included
+	for _, name := range cfg.RuleOutputs() {
+		if r := result.Outputs[name]; r != nil {
+			fmt.Printf("  Included %d files in %s\n", r.Included, name)
+			included += r.Included
+		}
+	}
+	if len(cfg.Outputs) > 0 {
+		printOutputSize(cfg.OutputFile, cfg.Format, result)
+		for _, o := range cfg.Outputs {
+			if r := result.Outputs[o.File]; r != nil {
+				printOutputSize(o.File, o.Format, r)
+			}
+		}
+	}
+	if n := result.Skipped[scanner.ReasonContentFilter]; n > 0 {
+		fmt.Printf("  Skipped %d files by content filter\n", n)
+	}
+	if n := result.Skipped[scanner.ReasonMIMEFilter]; n > 0 {
+		fmt.Printf("  Skipped %d files by MIME type\n", n)
+	}
+	if n := result.Skipped[scanner.ReasonTooOld]; n > 0 {
+		fmt.Printf("  Skipped %d files not modified since %s\n", n, cfg.ModifiedSince)
+	}
+	if n := result.Skipped[scanner.ReasonOtherLanguage]; n > 0 {
+		fmt.Printf("  Skipped %d files in other languages than %s\n", n, strings.Join(cfg.Languages, ", "))
+	}
+	if n := result.Skipped[scanner.ReasonUnchanged]; n > 0 {
+		fmt.Printf("  Skipped %d files unchanged since %s\n", n, cfg.ChangedSince)
+	}
+	if n := result.Skipped[scanner.ReasonTextifyDump]; n > 0 {
+		fmt.Printf("  Skipped %d files that look like textify output\n", n)
+	}
+	if n := result.Skipped[scanner.ReasonArtifact]; n > 0 {
+		fmt.Printf("  Skipped %d build artifacts (set include_artifacts: true to keep them)\n", n)
+	}
+	if dirs := result.VendoredDirs; len(dirs) > 0 {
+		fmt.Printf("  Skipped %d files in %d vendored folders: %s (set include_vendored: true to keep them)\n", result.VendoredFiles, len(dirs), summarizePaths(dirs, maxListedDirs))
+	}
+	if result.CollapsedBytes > 0 {
+		fmt.Printf("  Collapsed repetitive lines, saving %s\n", fileutil.FormatSize(result.CollapsedBytes))
+	}
+	if result.TruncatedLines > 0 {
+		fmt.Printf("  Truncated %d line(s) longer than %d bytes (max_line_bytes)\n", result.TruncatedLines, cfg.EffectiveMaxLineBytes())
+	}
+	if result.TruncatedFiles > 0 {
+		fmt.Printf("  Cut %d file(s) at max_file_tokens\n", result.TruncatedFiles)
+	}
+	for _, dir := range result.CappedDirNames() {
+		fmt.Printf("  Line cap reached in %s (max_dir_lines: %d); %d files left out\n", dir, cfg.Dirs[dir].MaxDirLines, result.CappedDirs[dir])
+	}
+	printSkipReasons(result, included, out.verbose)
+	printPatternMatches(result.Patterns)
+	writeReport(cwd, cfg, out, result, changed, counts)
+	checkIncluded(included, out)
+}
+
+// topSkippedExtensions is how many extensions the skip summary names.
+const topSkippedExtensions = 5
+
+// printSkipReasons sums up what the run left out, by reason. When no rule
+// allowed the extension of many files, compared to the included ones, it
+// hints at the extensions lists, which verbose names the most common of.
+func printSkipReasons(result *scanner.Result, included int, verbose bool) {
+	if len(result.Skipped) == 0 {
+		return
+	}
+	fmt.Printf("  Skipped %d files and %d folders: %s\n", result.SkippedFiles, result.SkippedFolders(), result.SkipBreakdown())
+	n := result.Skipped[scanner.ReasonExtNotAllowed]
+	if n == 0 {
+		return
+	}
+	if verbose {
+		fmt.Printf("  Extensions not allowed, most common first: %s\n", topSkippedExtensionList(result))
+	} else if n >= included {
+		fmt.Printf("Hint: %d files were left out because no rule's extensions list allows them. Run with --verbose to see which extensions, and add those that belong.\n", n)
+	}
+}
+
+// topSkippedExtensionList names the extensions no rule allowed, most files
+// first, with their counts.
+func topSkippedExtensionList(result *scanner.Result) string {
+	exts := result.TopSkippedExtensions(topSkippedExtensions)
+	parts := make([]string, len(exts))
+	for i, e := range exts {
+		name := "." + e.Ext
+		if e.Ext == "" {
+			name = "(no extension)"
+		}
+		parts[i] = fmt.Sprintf("%s (%d)", name, e.Files)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printPatternMatches reports how many entries each include and exclude
+// pattern matched, and warns about those that matched none, which are
+// probably stale.
+func printPatternMatches(patterns []scanner.PatternCount) {
+	if len(patterns) == 0 {
+		return
+	}
+	fmt.Println("  Pattern matches:")
+	for _, p := range patterns {
+		fmt.Printf("    dirs[%q].%s %q: %d\n", p.Dir, p.Kind, p.Pattern, p.Matches)
+	}
+	for _, p := range patterns {
+		if p.Matches == 0 {
+			fmt.Printf("Warning: dirs[%q].%s pattern %q matched nothing; it may be stale\n", p.Dir, p.Kind, p.Pattern)
+		}
+	}
+}
+
+// maxListedDirs is how many folders a summary line names before "and N
+// more".
+const maxListedDirs = 5
+
+// summarizePaths joins paths for a summary line, naming at most max of them.
+func summarizePaths(paths []string, max int) string {
+	if len(paths) <= max {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s, and %d more", strings.Join(paths[:max], ", "), len(paths)-max)
+}
+
+// printOutputSize reports the size of one of the formats an output was
+// written in.
+func printOutputSize(name, format string, r *scanner.Result) {
+	if format == "" {
+		format = config.FormatText
+	}
+	fmt.Printf("  Wrote %s (%s): %s, %d words, ~%d tokens\n", name, format, fileutil.FormatSize(r.Size), r.Words, tokens.Estimate(r.Size))
+}
+
+// checkOverwrite exits unless the output file name (relative to cwd) can be
+// replaced: textify wrote it, or the user confirms.
+func checkOverwrite(cwd, name string) {
+	generated, err := scanner.LooksGenerated(resolveOutput(cwd, name))
+	if err != nil {
+		fmt.Printf("Error reading output file: %v\n", err)
+		os.Exit(1)
+	}
+	if !generated && !confirm(fmt.Sprintf("%s exists and was not written by textify. Overwrite it?", name)) {
+		fmt.Printf("Error: refusing to overwrite %s, which was not written by textify. Use --force to overwrite it.\n", name)
+		os.Exit(1)
+	}
+}
+
+// printKeyWarnings warns about dirs keys dropped at load for naming the same
+// directory as another.
+func printKeyWarnings(cfg *config.Config) {
+	for _, w := range cfg.KeyWarnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+}
+
+// resolveOutput resolves a configured output path against the project root.
+func resolveOutput(cwd, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(cwd, name)
+}
+
+// expandOutputNames fills in the placeholders of the output names (see
+// config.ExpandOutputNames) and creates the folders they name, exiting on
+// error. The time is taken once, so every output of the run agrees.
+func expandOutputNames(cwd string, cfg *config.Config) {
+	now := time.Now()
+	value := func(placeholder string) (string, error) {
+		switch placeholder {
+		case config.PlaceholderRepo:
+			return filepath.Base(cwd), nil
+		case config.PlaceholderDate:
+			return now.Format("2006-01-02"), nil
+		case config.PlaceholderTime:
+			return now.Format("150405"), nil
+		case config.PlaceholderBranch:
+			branch, err := gitutil.CurrentBranch(cwd)
+			if err != nil {
+				return "", fmt.Errorf("%s needs git: %v", placeholder, err)
+			}
+			return branch, nil
+		}
+		return "", fmt.Errorf("unknown placeholder %s", placeholder)
+	}
+	if err := cfg.ExpandOutputNames(value); err != nil {
+		fmt.Printf("Error: output file name: %v\n", err)
+		os.Exit(1)
+	}
+	for _, name := range cfg.OutputFiles() {
+		if err := os.MkdirAll(filepath.Dir(resolveOutput(cwd, name)), 0755); err != nil {
+			fmt.Printf("Error creating the folder of %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// appendOutput adds a scan to the end of the output file, after a boundary
+// naming the scanned root, and reports both what it added and the new total.
+// The header summary is left out, since it would describe only the new part.
+func appendOutput(cwd string, cfg *config.Config, outPath string, out outputOptions) {
+	var before int64
+	if info, err := os.Stat(outPath); err == nil {
+		before = info.Size()
+	}
+
+	f, err := os.OpenFile(outPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		fmt.Printf("Error opening output file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	fmt.Printf("Appending project to %s using %s...\n", cfg.OutputFile, configFile)
 
-	fmt.Printf("Textifying project using %s...\n", configFile)
-	
-	if err := scanner.Scan(cwd, cfg, f); err != nil {
+	if before > 0 {
+		if _, err := io.WriteString(f, scanner.AppendBoundary(cwd, cfg.Format)); err != nil {
+			fmt.Printf("Error writing output file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	cfg.HeaderSummary = false
+	result, err := scanOutput(cwd, cfg, f, out)
+	if errors.Is(err, scanner.ErrOutputTooLarge) {
+		// Only what this run appended is removed
+		f.Truncate(before)
+		fmt.Printf("Error: %v\nRemoved the appended scan from %s. Exclude the folders that don't belong, or raise max_output_size.\n", err, cfg.OutputFile)
+		annotate(out, "error", "max_output_size", err.Error())
+		os.Exit(exitTooLargeCode(out))
+	}
+	if errors.Is(err, context.Canceled) {
+		f.Truncate(before)
+		fmt.Printf("\nCancelled after %d files; removed the appended scan from %s\n", result.Included, cfg.OutputFile)
+		os.Exit(exitCancelled)
+	}
+	if err != nil {
 		fmt.Printf("Scan error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("\n✔ Done! Output saved to: %s\n", cfg.OutputFile)
+	after := before
+	if info, err := f.Stat(); err == nil {
+		after = info.Size()
+	}
+	if cfg.OutputChecksum {
+		// The scan's hash covers only the appended part
+		if err := writeFileChecksum(outPath); err != nil {
+			fmt.Printf("Warning: could not write checksum: %v\n", err)
+		}
+	}
+
+	fmt.Printf("\n✔ Done! Appended to: %s\n", cfg.OutputFile)
+	fmt.Printf("  Included %d files\n", result.Included)
+	fmt.Printf("  Added %s (%d words, ~%d tokens); the output now holds %s (~%d tokens)\n",
+		fileutil.FormatSize(after-before), result.Words, tokens.Estimate(after-before), fileutil.FormatSize(after), tokens.Estimate(after))
+	counts := printTokenFamilies(cfg, outPath)
+	printCosts(cfg, tokens.Estimate(after), counts)
+	writeReport(cwd, cfg, out, result, after > before, counts)
+	checkIncluded(result.Included, out)
+	exitOnChange(out, after > before, result)
+}
+
+// writeFileChecksum hashes the whole output file and writes its sidecar.
+func writeFileChecksum(outPath string) error {
+	f, err := os.Open(outPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+	return scanner.WriteChecksum(outPath, hex.EncodeToString(hasher.Sum(nil)))
+}
+
+// confirm asks a yes/no question on the terminal. Without a terminal to ask
+// on, the answer is no.
+func confirm(question string) bool {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// listOptions holds the flags accepted by 'textify list'.
+type listOptions struct {
+	maxDepth int
+	verbose  bool
+}
+
+func newListFlags() (*flag.FlagSet, *listOptions) {
+	opts := &listOptions{}
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.IntVar(&opts.maxDepth, "max-depth", -1, "Only list files up to `n` levels below the root (0 = root files only)")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Also count what was left out, by reason")
+	return fs, opts
 }
 
-func printHelp() {
-	fmt.Println("Textify - Turn your codebase into AI-ready text")
-	fmt.Println("\nUsage:")
-	fmt.Println("  textify init   Scans folders and generates textify.yaml")
-	fmt.Println("  textify scan   Detects new folders and updates textify.yaml")
-	fmt.Println("  textify start  Generates the output file based on config")
+// runList prints the files 'textify start' would include, without reading them.
+func runList(args []string) {
+	fs, opts := newListFlags()
+	fs.Parse(args)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error getting current directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	printKeyWarnings(cfg)
+	applyMaxDepth(cfg, opts.maxDepth)
+
+	paths, result, err := scanner.List(cwd, cfg)
+	if err != nil {
+		fmt.Printf("Scan error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, p := range paths {
+		fmt.Println(p)
+	}
+	if opts.verbose {
+		printSkipped(len(paths), result)
+	}
+}
+
+// printSkipped counts what list left out, by reason. It goes to stderr so
+// the list itself can still be piped.
+func printSkipped(listed int, result *scanner.Result) {
+	fmt.Fprintf(os.Stderr, "\n%d files listed; %d files left out by the path rules\n", listed, result.SkippedFiles)
+	reasons := make([]string, 0, len(result.Skipped))
+	for reason := range result.Skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(os.Stderr, "  %s: %d\n", reason, result.Skipped[reason])
+	}
+	if len(reasons) > 0 {
+		fmt.Fprintln(os.Stderr, "Counts include skipped folders, whose files aren't looked at.")
+	}
+	if len(result.SkippedExtensions) > 0 {
+		fmt.Fprintf(os.Stderr, "Extensions not allowed, most common first: %s\n", topSkippedExtensionList(result))
+	}
+}
+
+// applyMaxDepth applies the --max-depth flag. A negative value leaves the
+// config alone; otherwise the stricter of the flag and max_depth wins.
+func applyMaxDepth(cfg *config.Config, depth int) {
+	if depth < 0 {
+		return
+	}
+	if cfg.MaxDepth == nil || depth < *cfg.MaxDepth {
+		cfg.MaxDepth = &depth
+	}
+}
+
+// applyContentFlags applies the --grep/--grep-v flags to every directory rule,
+// taking precedence over the values in the config file.
+func applyContentFlags(cfg *config.Config, include, exclude string) {
+	if include == "" && exclude == "" {
+		return
+	}
+	if _, ok := cfg.Dirs["."]; !ok {
+		cfg.Dirs["."] = config.DirRule{Enabled: true}
+	}
+	for dir, rule := range cfg.Dirs {
+		if include != "" {
+			rule.ContentIncludeRegex = include
+		}
+		if exclude != "" {
+			rule.ContentExcludeRegex = exclude
+		}
+		cfg.Dirs[dir] = rule
+	}
+}
+
+func runCache(args []string) {
+	if len(args) != 1 || args[0] != "clear" {
+		fmt.Println("Usage: textify cache clear")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	if cfg.CacheFile == "" {
+		fmt.Printf("No cache_file is configured in %s.\n", configFile)
+		return
+	}
+
+	if err := cache.Clear(cfg.CacheFile); err != nil {
+		fmt.Printf("Error clearing cache: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✔ Cleared %s\n", cfg.CacheFile)
 }
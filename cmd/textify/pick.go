@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/picker"
+	"github.com/JohnEsleyer/textify/internal/scanner"
+)
+
+// pickOptions holds the flags accepted by 'textify pick'.
+type pickOptions struct {
+	save   bool
+	output outputOptions
+}
+
+func newPickFlags() (*flag.FlagSet, *pickOptions) {
+	opts := &pickOptions{}
+	fs := flag.NewFlagSet("pick", flag.ExitOnError)
+	fs.BoolVar(&opts.save, "save", false, "Save the selection to textify.yaml as 'only' patterns")
+	fs.BoolVar(&opts.output.force, "force", false, "Overwrite the output file even if textify didn't write it")
+	return fs, opts
+}
+
+// runPick lets the user choose files interactively, then generates the output
+// from just those files.
+func runPick(args []string) {
+	fs, opts := newPickFlags()
+	fs.Parse(args)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error getting current directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	printKeyWarnings(cfg)
+
+	// Offer every file the rules allow, even if a selection was saved before
+	cfg.Only = nil
+	paths, _, err := scanner.List(cwd, cfg)
+	if err != nil {
+		fmt.Printf("Scan error: %v\n", err)
+		os.Exit(1)
+	}
+	items := make([]picker.Item, 0, len(paths))
+	for _, p := range paths {
+		item := picker.Item{Path: p}
+		if info, err := os.Stat(filepath.Join(cwd, filepath.FromSlash(p))); err == nil {
+			item.Size = info.Size()
+		}
+		items = append(items, item)
+	}
+
+	selected, err := picker.Run(items)
+	if err == picker.ErrCancelled {
+		fmt.Println("Cancelled; nothing was generated.")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(selected) == 0 {
+		fmt.Println("No files selected; nothing was generated.")
+		return
+	}
+
+	cfg.Only = make([]string, len(selected))
+	for i, item := range selected {
+		cfg.Only[i] = item.Path
+	}
+
+	if opts.save {
+		saved, err := config.LoadRaw(configFile)
+		if err != nil {
+			fmt.Printf("Error loading %s: %v\n", configFile, err)
+			os.Exit(1)
+		}
+		saved.Only = cfg.Only
+		if err := saved.Save(configFile); err != nil {
+			fmt.Printf("Error saving config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✔ Saved %d selected files to %s\n", len(cfg.Only), configFile)
+	}
+
+	generate(cwd, cfg, opts.output)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/scanner"
+	"github.com/JohnEsleyer/textify/internal/tokens"
+)
+
+// writeReport writes the JSON report of a run to out.report, if set (start
+// --report), from the result the summary printed, and in CI mode annotates
+// its warnings. counts are the token counts of the output per family. A
+// report that can't be written fails the run, since CI would read a stale
+// one otherwise.
+func writeReport(cwd string, cfg *config.Config, out outputOptions, result *scanner.Result, changed bool, counts []tokens.FamilyCount) {
+	if out.report == "" && !out.ci {
+		return
+	}
+	inputs := scanner.ReportInputs{
+		Root:           cwd,
+		Config:         cfg.Path,
+		ConfigSHA256:   configHash(cfg),
+		TextifyVersion: textifyVersion(),
+	}
+	report := scanner.NewReport(result, cfg, inputs, changed, time.Since(out.started))
+	report.AddTokenCounts(counts)
+	if out.ci {
+		for _, w := range report.Warnings {
+			annotate(out, "warning", w.Kind, w.Message)
+		}
+	}
+	if out.report == "" {
+		return
+	}
+	if err := scanner.WriteReport(out.report, report); err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("  Report written to: %s\n", out.report)
+}
+
+// configHash is the hex-encoded SHA-256 of the config file and of its
+// overlay, if any, as loaded; empty without a config file.
+func configHash(cfg *config.Config) string {
+	if cfg.Path == "" {
+		return ""
+	}
+	h := sha256.New()
+	for _, p := range []string{cfg.Path, cfg.OverlayPath} {
+		if p == "" {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return ""
+		}
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// textifyVersion is the version of the textify module the binary was built
+// from: a tag with go install, "(devel)" for a local build.
+func textifyVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// excludeReport keeps the report out of the scan when it is written inside
+// the project at cwd.
+func excludeReport(cwd string, cfg *config.Config, report string) {
+	if report == "" {
+		return
+	}
+	rel := report
+	if filepath.IsAbs(report) {
+		var err error
+		if rel, err = filepath.Rel(cwd, report); err != nil {
+			return
+		}
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return
+	}
+	cfg.SystemExcludes = append(cfg.SystemExcludes, "/"+rel)
+}
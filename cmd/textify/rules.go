@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/walker"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runExclude and runInclude add a pattern to the exclude or include list of
+// the rule that governs it.
+func runExclude(args []string) { addRulePattern("exclude", args) }
+
+func runInclude(args []string) { addRulePattern("include", args) }
+
+// runDisable and runEnable switch a directory's rule off or on.
+func runDisable(args []string) { setDirEnabled(false, args) }
+
+func runEnable(args []string) { setDirEnabled(true, args) }
+
+func addRulePattern(key string, args []string) {
+	if len(args) != 1 {
+		fmt.Printf("Usage: textify %s <path-or-glob>\n", key)
+		os.Exit(1)
+	}
+	pattern, err := cleanRulePath(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	doc := loadDocument()
+	cfg := documentConfig(doc)
+
+	// Patterns go to the rule in effect where they match; folders with their
+	// own rule don't inherit patterns from the root
+	dir, _ := cfg.RuleFor(config.PatternDir(pattern))
+	added, err := doc.AddPattern(dir, key, pattern)
+	if err != nil {
+		fmt.Printf("Error updating %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	if !added {
+		fmt.Printf("%q is already in the %s list of %s.\n", pattern, key, ruleLabel(dir))
+		return
+	}
+	saveDocument(doc)
+
+	fmt.Printf("✔ Added %q to the %s list of %s.\n", pattern, key, ruleLabel(dir))
+	printRule(doc, dir)
+}
+
+func setDirEnabled(enabled bool, args []string) {
+	verb := map[bool]string{true: "enable", false: "disable"}[enabled]
+	if len(args) != 1 {
+		fmt.Printf("Usage: textify %s <directory>\n", verb)
+		os.Exit(1)
+	}
+	dir, err := cleanRulePath(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if strings.ContainsAny(dir, "*?[") {
+		fmt.Printf("Error: %s takes a directory, not a pattern; use 'textify exclude %s' instead.\n", verb, dir)
+		os.Exit(1)
+	}
+	if dir == "." {
+		fmt.Printf("Error: refusing to %s the project root; edit %s if you really mean it.\n", verb, configFile)
+		os.Exit(1)
+	}
+	info, err := os.Stat(filepath.FromSlash(dir))
+	if err != nil {
+		fmt.Printf("Error: %s is not a directory in this project.\n", dir)
+		os.Exit(1)
+	}
+	if !info.IsDir() {
+		fmt.Printf("Error: %s is a file; use 'textify exclude %s' or 'textify include %s' instead.\n", dir, dir, dir)
+		os.Exit(1)
+	}
+
+	doc := loadDocument()
+	cfg := documentConfig(doc)
+
+	// A folder inside a disabled folder is never reached, so enabling it alone would do nothing
+	if enabled {
+		for parent := path.Dir(dir); parent != "."; parent = path.Dir(parent) {
+			if rule, ok := cfg.Dirs[parent]; ok && !rule.Enabled {
+				fmt.Printf("Error: %s is inside %s, which is disabled; run 'textify enable %s' first.\n", dir, parent, parent)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if _, rule := cfg.RuleFor(dir); rule.Enabled == enabled {
+		fmt.Printf("%s is already %sd.\n", dir, verb)
+		return
+	}
+
+	if err := doc.SetEnabled(dir, enabled); err != nil {
+		fmt.Printf("Error updating %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	saveDocument(doc)
+
+	fmt.Printf("✔ %s is now %sd.\n", dir, verb)
+	printRule(doc, dir)
+}
+
+// runRule prints how the rule for a directory is resolved, layer by layer,
+// using the same resolution as the walker.
+func runRule(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: textify rule <directory>")
+		os.Exit(1)
+	}
+	dir, err := cleanRulePath(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	dir = strings.TrimSuffix(dir, "/")
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	if info, err := os.Stat(filepath.FromSlash(dir)); err != nil || !info.IsDir() {
+		fmt.Printf("Note: %s is not a directory in this project; showing the rule it would get.\n\n", dir)
+	}
+
+	res := walker.Resolve(cfg.Dirs, dir)
+	width := len("(defaults)")
+	for _, layer := range res.Layers {
+		if len(layer.Dir) > width {
+			width = len(layer.Dir)
+		}
+	}
+
+	fmt.Printf("Rule chain for %s:\n", dir)
+	if _, ok := cfg.Dirs["."]; !ok {
+		fmt.Printf("  %-*s  enabled, all extensions\n", width, "(defaults)")
+	}
+	for _, layer := range res.Layers {
+		switch {
+		case layer.Rule == nil:
+			fmt.Printf("  %-*s  no rule, inherits\n", width, layer.Dir)
+		case layer.Dir == res.RuleDir:
+			fmt.Printf("  %-*s  own rule (applies)\n", width, layer.Dir)
+		default:
+			fmt.Printf("  %-*s  own rule (replaced by a deeper one)\n", width, layer.Dir)
+		}
+	}
+	if res.DisabledAt != "" {
+		fmt.Printf("\n%s is disabled, so %s is never scanned.\n", res.DisabledAt, dir)
+	}
+
+	source := "defaults"
+	if res.RuleDir != "" {
+		source = fmt.Sprintf("%q", res.RuleDir)
+	}
+	data, err := yaml.Marshal(res.Rule)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nEffective rule (from %s):\n%s", source, indent(string(data), "  "))
+}
+
+// cleanRulePath normalizes a path or pattern given on the command line,
+// relative to the folder textify was started in, to the slash-separated,
+// root-relative form used in the config, rejecting ones that can't be
+// expressed there.
+func cleanRulePath(p string) (string, error) {
+	if filepath.IsAbs(p) {
+		return "", fmt.Errorf("%s is absolute; use a relative path", p)
+	}
+	trailing := strings.HasSuffix(filepath.ToSlash(p), "/")
+	p = path.Clean(filepath.ToSlash(invocationPath(p)))
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("%s is outside the project", p)
+	}
+	// Keep "dir/" so the pattern only matches a directory
+	if trailing && p != "." {
+		p += "/"
+	}
+	return p, nil
+}
+
+func loadDocument() *config.Document {
+	doc, err := config.LoadDocument(configFile)
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	return doc
+}
+
+func documentConfig(doc *config.Document) *config.Config {
+	cfg, err := doc.Config()
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+func saveDocument(doc *config.Document) {
+	if err := doc.Save(configFile); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printRule prints the rule now in effect for dir.
+func printRule(doc *config.Document, dir string) {
+	_, rule := documentConfig(doc).RuleFor(dir)
+	data, err := yaml.Marshal(map[string]config.DirRule{dir: rule})
+	if err != nil {
+		return
+	}
+	fmt.Printf("\nEffective rule:\n%s", indent(string(data), "  "))
+}
+
+// ruleLabel names a rule for messages.
+func ruleLabel(dir string) string {
+	if dir == "." {
+		return "the root rule"
+	}
+	return "the " + dir + " rule"
+}
+
+// indent prefixes every line of s.
+func indent(s, prefix string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanRulePathFromSubfolder(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "textify_test_rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	root, err := filepath.EvalSymlinks(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(root, "internal", "internal"), 0755)
+
+	// As after 'cd internal' and enterProjectRoot moving to the root
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer func(dir string) { invokedDir = dir }(invokedDir)
+	invokedDir = filepath.Join(root, "internal")
+
+	for arg, want := range map[string]string{
+		"internal":  "internal/internal",
+		".":         "internal",
+		"*.log":     "internal/*.log",
+		"gen/":      "internal/gen/",
+		"../go.mod": "go.mod",
+	} {
+		got, err := cleanRulePath(arg)
+		if err != nil || got != want {
+			t.Errorf("cleanRulePath(%q) = %q (%v), want %q", arg, got, err, want)
+		}
+	}
+	if _, err := cleanRulePath("../../elsewhere"); err == nil {
+		t.Error("Expected a path outside the project to be rejected")
+	}
+}
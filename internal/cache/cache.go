@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// version is bumped whenever the on-disk format changes; older caches are discarded.
+const version = 1
+
+// Entry is the cached analysis of a single file.
+type Entry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Binary  bool   `json:"binary"`
+	Hash    string `json:"hash,omitempty"`
+}
+
+// Cache stores per-file analysis results between runs, keyed by relative
+// path. An entry is only valid while the file's size and mtime are unchanged.
+type Cache struct {
+	path    string
+	entries map[string]Entry
+	dirty   bool
+}
+
+type cacheFile struct {
+	Version int              `json:"version"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Load reads the cache at path. A missing, unreadable, or outdated cache
+// yields an empty cache rather than an error.
+func Load(path string) *Cache {
+	c := &Cache{path: path, entries: make(map[string]Entry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != version {
+		return c
+	}
+	if f.Entries != nil {
+		c.entries = f.Entries
+	}
+	return c
+}
+
+// Lookup returns the entry for relPath if its size and mtime still match info.
+func (c *Cache) Lookup(relPath string, info os.FileInfo) (Entry, bool) {
+	e, ok := c.entries[relPath]
+	if !ok || e.Size != info.Size() || e.ModTime != info.ModTime().UnixNano() {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// Store records the analysis of relPath, keyed by the size and mtime in info.
+func (c *Cache) Store(relPath string, info os.FileInfo, binary bool, hash string) {
+	c.entries[relPath] = Entry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Binary:  binary,
+		Hash:    hash,
+	}
+	c.dirty = true
+}
+
+// Forget drops the entry for relPath.
+func (c *Cache) Forget(relPath string) {
+	if _, ok := c.entries[relPath]; ok {
+		delete(c.entries, relPath)
+		c.dirty = true
+	}
+}
+
+// Save writes the cache back to disk if anything changed.
+func (c *Cache) Save() error {
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(cacheFile{Version: version, Entries: c.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Clear removes the cache file at path. A missing file is not an error.
+func Clear(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
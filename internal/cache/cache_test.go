@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLookupInvalidatesOnChange(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filePath := filepath.Join(tempDir, "a.txt")
+	cachePath := filepath.Join(tempDir, "cache.json")
+	os.WriteFile(filePath, []byte("hello"), 0644)
+	info, _ := os.Stat(filePath)
+
+	c := Load(cachePath)
+	c.Store("a.txt", info, false, "abc")
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Entries survive a reload while size and mtime are unchanged
+	reloaded := Load(cachePath)
+	if e, ok := reloaded.Lookup("a.txt", info); !ok || e.Hash != "abc" {
+		t.Errorf("Expected cached entry with hash abc, got %+v (hit: %v)", e, ok)
+	}
+
+	// A new mtime invalidates the entry
+	later := info.ModTime().Add(time.Second)
+	os.Chtimes(filePath, later, later)
+	info, _ = os.Stat(filePath)
+	if _, ok := reloaded.Lookup("a.txt", info); ok {
+		t.Error("Expected a miss after the mtime changed")
+	}
+
+	if err := Clear(cachePath); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Error("Expected the cache file to be removed")
+	}
+	if err := Clear(cachePath); err != nil {
+		t.Errorf("Clearing a missing cache should not fail: %v", err)
+	}
+}
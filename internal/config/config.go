@@ -1,28 +1,119 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/ignore"
+	"github.com/JohnEsleyer/textify/internal/tokens"
 
 	"gopkg.in/yaml.v3"
 )
 
-// configHeader is the comment block added to the top of textify.yaml
+// configHeader is the comment block added to the top of textify.yaml. It
+// stays short, since every save writes it; the keys are described by
+// keyDocs, in the schema.
 const configHeader = `# Textify Configuration
 #
-# output_file: Path where the merged codebase text will be saved.
+# Every key is described in the README and in textify.schema.json, which
+# YAML-aware editors use for autocomplete; print it with 'textify schema'.
+#
+# Usage:
+#   - Run 'textify scan' to detect new folders and update this file.
+#   - Run 'textify start' to generate the output file.
+
+`
+
+// keyDocs describes every key, one "# key: description" line each, with the
+// rule keys after the "# Rule Options:" line. GenerateSchema takes its
+// descriptions from it.
+const keyDocs = `# output_file: Path where the merged codebase text will be saved. May hold {repo}, {date}, {time}, and {branch} (e.g., snapshots/{repo}-{date}.txt).
+# format:      (optional) 'text' (default), 'markdown-doc' for a single Markdown document with a table of contents, 'json', 'index' for one line per file (path, lines, bytes, language) without content, or 'html' for a browsable page.
+# index_sizes_only: (optional) Leave the lines column of index output empty (-), so files' contents aren't read.
+# outputs:     (optional) More files to write the same output to in one run, each in its own format (e.g., [{file: codebase.json, format: json}]).
+# output_checksum: (optional) Write the output's SHA-256 to a .sha256 sidecar for change detection.
+# keep_previous: (optional) Keep this many previous versions of each output (codebase.1.txt is the latest), instead of overwriting it.
+# output_warn_size: (optional) Warn during the run once the output passes this size (default 50MB; 0 turns the warning off).
+# max_output_size: (optional) Abort the run and remove the output once it passes this size (e.g., 200MB); no limit by default.
+# include_tree: (optional) Write the project structure at the top of the output.
+# tree_mode:   (optional) 'included' (default) or 'all' to also show left-out files and folders in the tree, marked [excluded] or [binary].
+# tree_sort:   (optional) 'lexicographic' (default) or 'natural' to sort names case-insensitively with numbers by value (file2 before file10), in the tree and the output.
+# header_summary: (optional) Start the output with a line counting the included files, lines, and tokens, and the excluded files.
+# max_depth:   (optional) Only include files up to this many levels below the root (0 = root files only).
+# languages:   (optional) Only include files in these languages (e.g., [go, typescript]), by their known extensions.
+# only:        (optional) Only include files matching these paths/globs (written by 'textify pick --save').
+# order:       (optional) 'path' (default) or 'git-hot' to put frequently changed files first.
+# docs_first:  (optional) Put documentation (README*, docs/, root *.md) before the code.
+# group_by:    (optional) 'language' or 'extension' to gather files in a section per language or extension.
+# include_git_blame: (optional) Add each file's primary author and last-modified date to its header.
+# modified_since: (optional) Only include files modified within a duration (48h) or since a date (2024-05-01).
+# changed_since: (optional) Only include files that git reports as changed since a ref (e.g., main).
+# include_file_meta: (optional) Add file permissions and symlink targets to each file header.
+# include_checksums: (optional) Add each file's SHA-256 and size to its header, so the dump can be checked against the source.
+# encoded_data_fraction: (optional) Collapse base64 runs in files that are more than this fraction (0-1) encoded data.
+# encoded_run_length: (optional) Minimum length of a base64 run for encoded_data_fraction (default 200).
+# collapse_repetition: (optional) Collapse long runs of near-identical lines, as in generated code.
+# repetition_similarity: (optional) How similar (0-1) lines must be to count as repetitive (default 0.9).
+# repetition_min_run: (optional) Shortest run of similar lines that is collapsed (default 8).
+# max_line_bytes: (optional) Longest line, in bytes, written in full; longer lines are cut there with a note (default 1048576).
+# indent_style: (optional) 'preserve' (default), 'spaces:N' to turn leading tabs into N-column spaces, or 'tabs' / 'tabs:N' to turn leading N spaces (default 4) into tabs.
+# file_gap: (optional) Newlines after each file's content in text output, before the next header (default 2; 0 for the densest output).
+# system_excludes: (optional) Extra paths/globs (e.g., [.idea/, .DS_Store]) always skipped, before any rule; added to the defaults (.git, textify.yaml, textify.local.yaml, textify.schema.json, codebase.txt).
+# override_system_excludes: (optional) Use system_excludes in place of the defaults instead of adding to them.
+# exclude_dirs: (optional) Directory names (e.g., [node_modules, __pycache__]) skipped at any depth.
+# include_overrides_dir_excludes: (optional) Let include patterns reach files inside exclude_dirs directories.
+# ignore_sources: (optional) Ignore files applied to the walk, first listed taking precedence: gitignore (default), dockerignore, textifyignore (.textifyignore, in .gitignore syntax).
+# use_export_ignore: (optional) Skip what .gitattributes files (at the root and in subdirectories) mark export-ignore, such as fixtures and CI scripts.
+# always_include_dirs: (optional) Directories (e.g., [docs, api-specs]) whose contents are included even if gitignored; other rules still apply.
+# include_artifacts: (optional) Keep build artifacts (dist/, build/, .next/, *.map, *.min.js, ...), skipped by default.
+# include_well_known: (optional) Include well-known files without a matching extension (Dockerfile, Makefile, .gitignore, LICENSE, ...) under extensions lists; true by default.
+# include_vendored: (optional) Keep vendored folders (vendor/, node_modules/, third_party/, .venv/, Pods/, ...), skipped by default.
+# skip_textify_dumps: (optional) Skip files that start like textify output, such as a dump copied into the project under another name.
+# dump_markers: (optional) Extra starts of files (e.g., ["# CONTEXT DUMP"]) that skip_textify_dumps treats as dumps.
+# keep_linked_duplicates: (optional) Write the full content of files that are hardlinks or symlinks to a file already in the output, instead of a note naming it.
+# follow_symlinks: (optional) Walk symlinked directories under the link's path instead of skipping them. Linked files show the target's content, unless the target is included under its own path.
+# force_text:  (optional) Extensions (e.g., [tpl, dat]) always treated as text, whatever the binary check says.
+# force_binary: (optional) Extensions (e.g., [svg]) always treated as binary, whatever the binary check says.
+# deep_binary_check: (optional) Also sample the middle and end of large files in the binary check, not just their first 512 bytes.
+# sanitize_invalid_utf8: (optional) Output text files that aren't valid UTF-8 (e.g., Latin-1), converted to UTF-8, instead of skipping them as binary.
+# preprocessors: (optional) Commands that convert files to text by extension (e.g., drawio: [python3, tools/drawio2txt.py, "{path}"]); needs allow_preprocessors.
+# allow_preprocessors: (optional) Allow running the preprocessors commands, which execute programs named in this file; TEXTIFY_ALLOW_PREPROCESSORS=1 must be set too.
+# list_binaries: (optional) List binary files with their size and type instead of silently skipping them.
+# cache_file:  (optional) Cache file for binary detection results between runs (e.g., .textify-cache.json).
+# paranoid:    (optional) Verify cached results against content hashes instead of size and mtime.
+# mask_env:    (optional) Mask the values in .env files so secrets never reach the output.
+# env_keep_keys: (optional) Keys (e.g., [NODE_ENV, LOG_LEVEL]) whose values stay visible when mask_env is on.
+# extension_groups: (optional) Named extension lists (e.g., proto: [proto, graphql]) usable as $proto in extension lists.
+# context_windows: (optional) Extra model context windows in tokens (e.g., local-llama: 32768) for 'textify estimate'.
+# costs:       (optional) Input prices per model (e.g., gpt-4o-input: 2.50per1M) to estimate what sending the output once costs.
+# token_families: (optional) Tokenizers (cl100k_base, o200k_base, chars/4) whose token counts of the output the summary and report list.
 # dirs:        Directory-specific configurations. Keys are paths relative to root.
 #
 # Rule Options:
 #   enabled:            (bool)   If false, this directory and its children are skipped.
 #   include:            ([list]) Specific files/globs to Force Include (overrides gitignore & extensions).
 #   exclude:            ([list]) Specific files/globs to Force Exclude (highest priority).
-#   extensions:         ([list]) Allow-list of extensions (e.g., [go, js]). If empty, all text files are allowed.
-#   exclude_extensions: ([list]) Block-list of extensions (e.g., [log, tmp]).
-#
-# Usage:
-#   - Run 'textify scan' to detect new folders and update this file.
-#   - Run 'textify start' to generate the output file.
-
+#   extensions:         ([list]) Allow-list of extensions (e.g., [go, $web]). If empty, all text files are allowed.
+#   exclude_extensions: ([list]) Block-list of extensions (e.g., [log, tmp, .test.ts]); entries with a dot or glob match the whole name.
+#   mime_include:       ([list]) Only include files whose sniffed content type matches (e.g., [text/*]).
+#   mime_exclude:       ([list]) Skip files whose sniffed content type matches (e.g., [text/csv]).
+#   Extension lists accept groups: $web, $go, $docs, $config, or any defined in extension_groups.
+#   exclude_dirs:       ([list]) Directory names skipped at any depth below this directory.
+#   ignore_git:         (bool)   If true, .gitignore is not applied inside this directory.
+#   content_include_regex: (string) Only include files whose content matches this regex.
+#   content_exclude_regex: (string) Skip files whose content matches this regex.
+#   max_dir_lines:      (int)    Stop including files from this directory once it has contributed this many lines.
+#   max_file_tokens:    (int)    Cut each file of this directory after about this many tokens, with a note.
+#   max_depth:          (int)    Only include files up to this many levels below the directory (0 = its own files).
+#   output_file:        (string) Write this rule's files to their own output (e.g., backend-context.txt) instead of the top-level one.
+#   format:             (string) Write this rule's files as in 'text' (raw) or 'markdown-doc' (fenced) output, whatever the output's format.
 `
 
 // DirRule defines filtering rules for a specific directory.
@@ -31,12 +122,15 @@ type DirRule struct {
 	Enabled bool `yaml:"enabled"`
 
 	// Extensions is a list of file extensions to include (e.g., ["go", "md"]).
-	// If empty, all text files are considered (subject to exclusions).
+	// If empty, all text files are considered (subject to exclusions). Entries
+	// starting with a dot or holding a glob character (".test.ts", "*.min.js")
+	// are matched against the whole file name.
 	Extensions []string `yaml:"extensions,omitempty"`
 
-	// ExcludeExtensions is a list of file extensions to specifically ignore.
+	// ExcludeExtensions is a list of file extensions to specifically ignore,
+	// with the same suffix patterns as Extensions.
 	ExcludeExtensions []string `yaml:"exclude_extensions,omitempty"`
-	
+
 	// Include is a list of specific files or patterns to force-include
 	// regardless of extension or gitignore rules.
 	Include []string `yaml:"include,omitempty"`
@@ -44,12 +138,556 @@ type DirRule struct {
 	// Exclude is a list of specific files or patterns to force-exclude.
 	// This takes precedence over Include.
 	Exclude []string `yaml:"exclude,omitempty"`
+
+	// MimeInclude, if set, only includes files whose content type, sniffed
+	// from their first 512 bytes, matches one of these patterns (e.g.,
+	// "text/*"). MIME rules apply after the extension rules, and files
+	// matched by an include pattern bypass them.
+	MimeInclude []string `yaml:"mime_include,omitempty"`
+
+	// MimeExclude skips files whose sniffed content type matches one of these
+	// patterns (e.g., "text/csv"). It takes precedence over MimeInclude.
+	MimeExclude []string `yaml:"mime_exclude,omitempty"`
+
+	// ExcludeDirs lists directory names (e.g., "node_modules") skipped at any
+	// depth within this rule's scope, in addition to the global exclude_dirs.
+	ExcludeDirs []string `yaml:"exclude_dirs,omitempty"`
+
+	// IgnoreGit makes this directory (and subdirectories inheriting the rule)
+	// ignore .gitignore, e.g. to dump a normally ignored generated/ folder.
+	IgnoreGit bool `yaml:"ignore_git,omitempty"`
+
+	// ContentIncludeRegex, if set, only includes files whose content matches it.
+	// Only the first megabyte of each file is inspected.
+	ContentIncludeRegex string `yaml:"content_include_regex,omitempty"`
+
+	// ContentExcludeRegex, if set, skips files whose content matches it.
+	ContentExcludeRegex string `yaml:"content_exclude_regex,omitempty"`
+
+	// MaxDirLines caps the total lines included from this rule's directory
+	// (including subdirectories that inherit the rule). Once the running
+	// total reaches the cap, remaining files are skipped. Zero means no cap.
+	MaxDirLines int `yaml:"max_dir_lines,omitempty"`
+
+	// MaxFileTokens cuts the content of each file this rule governs after
+	// this many tokens, as estimated by the tokens package, with a note of
+	// where it was cut. Zero means no cap. Files matched by Include are
+	// never cut.
+	MaxFileTokens int `yaml:"max_file_tokens,omitempty"`
+
+	// MaxDepth limits how deep below this rule's directory files are
+	// included: 0 means only files directly inside it. Nil means unlimited.
+	MaxDepth *int `yaml:"max_depth,omitempty"`
+
+	// OutputFile, if set, sends the files this rule governs to their own
+	// output (relative to the project root) instead of the top-level one.
+	// Like every rule field, it is not inherited by folders with a rule of
+	// their own.
+	OutputFile string `yaml:"output_file,omitempty"`
+
+	// Format, if set, writes the content of this rule's files as the format
+	// would, in any text or markdown-doc output: FormatText leaves it raw,
+	// and FormatMarkdownDoc puts it in a fenced code block.
+	Format string `yaml:"format,omitempty"`
+}
+
+// Output orderings accepted by Config.Order.
+const (
+	// OrderPath emits files in directory walk order (the default).
+	OrderPath = "path"
+
+	// OrderGitHot emits the most frequently changed files first, based on
+	// the last six months of git history.
+	OrderGitHot = "git-hot"
+)
+
+// Groupings accepted by Config.GroupBy.
+const (
+	// GroupByLanguage gathers files by the language of their extension
+	// (see LanguageOf), with the rest last.
+	GroupByLanguage = "language"
+
+	// GroupByExtension gathers files by extension, with files that have
+	// none last.
+	GroupByExtension = "extension"
+)
+
+// Output formats accepted by Config.Format.
+const (
+	// FormatText writes each file under a plain-text header (the default).
+	FormatText = "text"
+
+	// FormatMarkdownDoc writes a single Markdown document: a title, a linked
+	// table of contents, the project tree, and a section per file.
+	FormatMarkdownDoc = "markdown-doc"
+
+	// FormatJSON writes a JSON object with the title, summary, and tree, and
+	// the path, notes, and content of every file, for tooling.
+	FormatJSON = "json"
+
+	// FormatIndex writes one line per file, its path, lines, bytes, and
+	// language, without content: the layout of the project in the fewest
+	// tokens.
+	FormatIndex = "index"
+
+	// FormatHTML writes a single self-contained HTML page for browsing: the
+	// tree as a sidebar and a collapsible section per file.
+	FormatHTML = "html"
+)
+
+// knownFormat reports whether format is empty or one of the output formats.
+func knownFormat(format string) bool {
+	return format == "" || format == FormatText || format == FormatMarkdownDoc || format == FormatJSON || format == FormatIndex || format == FormatHTML
+}
+
+func knownTokenFamily(name string) bool {
+	for _, f := range tokens.FamilyNames() {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputSpec is an extra rendering of the top-level output.
+type OutputSpec struct {
+	// File is where it is written, relative to the project root.
+	File string `yaml:"file"`
+
+	// Format is its layout, like Config.Format; empty means text.
+	Format string `yaml:"format,omitempty"`
 }
 
+// Ignore files accepted by Config.IgnoreSources; see the ignore package.
+const (
+	// IgnoreGitignore is the project's .gitignore (the default).
+	IgnoreGitignore = ignore.Gitignore
+
+	// IgnoreDockerignore is the project's .dockerignore, read with Docker's
+	// syntax.
+	IgnoreDockerignore = ignore.Dockerignore
+
+	// IgnoreTextifyignore is the project's .textifyignore, in .gitignore
+	// syntax, for what only textify should leave out.
+	IgnoreTextifyignore = ignore.Textifyignore
+)
+
+// Tree modes accepted by Config.TreeMode.
+const (
+	// TreeModeIncluded shows only the files included in the output (the
+	// default).
+	TreeModeIncluded = "included"
+
+	// TreeModeAll also shows the files and folders that were left out,
+	// marked as [excluded] or [binary].
+	TreeModeAll = "all"
+)
+
+// Name orders accepted by Config.TreeSort.
+const (
+	// TreeSortLexicographic sorts the entries of each folder byte by byte,
+	// so uppercase names come before lowercase ones and "file10" before
+	// "file2" (the default).
+	TreeSortLexicographic = "lexicographic"
+
+	// TreeSortNatural sorts them case-insensitively, with numbers compared
+	// by value.
+	TreeSortNatural = "natural"
+)
+
 // Config represents the top-level structure of the textify.yaml file.
 type Config struct {
-	OutputFile string             `yaml:"output_file"`
-	Dirs       map[string]DirRule `yaml:"dirs"`
+	// Path is the absolute path the config was loaded from, if any. The
+	// scanner never outputs that file, wherever it is and whatever its name.
+	Path string `yaml:"-"`
+
+	// OverlayPath is the absolute path of the overlay merged on top of the
+	// config at Path, if any. It is never output either.
+	OverlayPath string `yaml:"-"`
+
+	// KeyWarnings reports the dirs keys that named the same directory as
+	// another once normalized, and were dropped at load.
+	KeyWarnings []string `yaml:"-"`
+
+	OutputFile string `yaml:"output_file"`
+
+	// Format selects the layout of the output (text, markdown-doc, json, or
+	// index).
+	Format string `yaml:"format,omitempty"`
+
+	// IndexSizesOnly skips counting lines in index output, so files are
+	// only stat'ed and, for the binary check and content filters, sniffed.
+	IndexSizesOnly bool `yaml:"index_sizes_only,omitempty"`
+
+	// Outputs are more files the output is written to in the same run, each
+	// in its own format, so the project is only read once.
+	Outputs []OutputSpec `yaml:"outputs,omitempty"`
+
+	// OutputChecksum writes the SHA-256 of the output to a sidecar file
+	// (e.g., codebase.txt.sha256) so tools can detect changes cheaply.
+	OutputChecksum bool `yaml:"output_checksum,omitempty"`
+
+	// KeepPrevious keeps up to this many previous versions of each output,
+	// rotated to numbered names (see fileutil.RotatedName) once the new
+	// version is ready. They are never scanned.
+	KeepPrevious int `yaml:"keep_previous,omitempty"`
+
+	// OutputWarnSize is the size (e.g., "50MB") past which a run warns that
+	// its output is suspiciously large, naming the folders that contributed
+	// most. Empty means DefaultOutputWarnSize; "0" turns the warning off.
+	OutputWarnSize string `yaml:"output_warn_size,omitempty"`
+
+	// MaxOutputSize is the size past which a run aborts and removes what it
+	// wrote. Empty means no limit.
+	MaxOutputSize string `yaml:"max_output_size,omitempty"`
+
+	// IncludeTree writes the project structure at the top of the output.
+	IncludeTree bool `yaml:"include_tree,omitempty"`
+
+	// TreeMode selects what the tree shows (included or all).
+	TreeMode string `yaml:"tree_mode,omitempty"`
+
+	// TreeSort selects how the entries of each folder are sorted, in the
+	// tree and in the walk that orders the output (see TreeSortNatural).
+	TreeSort string `yaml:"tree_sort,omitempty"`
+
+	// HeaderSummary starts the output with a one-line summary of its scope:
+	// the files, lines, and estimated tokens included and the files excluded.
+	HeaderSummary bool `yaml:"header_summary,omitempty"`
+
+	// MaxDepth limits how deep below the root files are included: 0 means
+	// root files only. Nil means unlimited. Per-rule max_depth also applies.
+	MaxDepth *int `yaml:"max_depth,omitempty"`
+
+	// Languages, if set, restricts the output to files with the extensions
+	// of these languages (e.g., "typescript" for .ts and .tsx), on top of
+	// each rule's own extension lists. Force-included files are kept.
+	Languages []string `yaml:"languages,omitempty"`
+
+	// Only, if set, restricts the output to files matching one of these paths
+	// or glob patterns (e.g., a selection saved by 'textify pick'). The usual
+	// rules still apply to the files it lets through.
+	Only []string `yaml:"only,omitempty"`
+
+	// Order controls the order files are emitted in (path or git-hot).
+	Order string `yaml:"order,omitempty"`
+
+	// SystemExcludes lists gitignore-style patterns that are skipped before
+	// any rule is applied, in addition to DefaultSystemExcludes.
+	SystemExcludes []string `yaml:"system_excludes,omitempty"`
+
+	// OverrideSystemExcludes makes SystemExcludes replace the defaults
+	// instead of adding to them. The output file and textify.yaml stay
+	// excluded regardless.
+	OverrideSystemExcludes bool `yaml:"override_system_excludes,omitempty"`
+
+	// DocsFirst emits documentation (READMEs, docs/, root Markdown files)
+	// before all other files, in a section of its own.
+	DocsFirst bool `yaml:"docs_first,omitempty"`
+
+	// GroupBy gathers files into a section per language or per extension
+	// (see GroupByLanguage), in place of docs_first's sections. Within a
+	// section, files keep the order of Order.
+	GroupBy string `yaml:"group_by,omitempty"`
+
+	// IncludeGitBlame adds each file's primary author and last-modified date
+	// from git history to its header.
+	IncludeGitBlame bool `yaml:"include_git_blame,omitempty"`
+
+	// ModifiedSince skips files whose modification time is older than the
+	// given duration (e.g., "48h") or date (e.g., "2024-05-01").
+	ModifiedSince string `yaml:"modified_since,omitempty"`
+
+	// ChangedSince restricts the output to files changed since a git ref
+	// (e.g., "main"): committed on the current branch since it forked from
+	// the ref, changed in the working tree, or untracked.
+	ChangedSince string `yaml:"changed_since,omitempty"`
+
+	// IncludeFileMeta adds each file's permissions (e.g., -rwxr-xr-x) and,
+	// for symlinks, the link target to its header.
+	IncludeFileMeta bool `yaml:"include_file_meta,omitempty"`
+
+	// IncludeChecksums adds the SHA-256 and size in bytes of each file, as
+	// read from disk, to its header.
+	IncludeChecksums bool `yaml:"include_checksums,omitempty"`
+
+	// EncodedDataFraction collapses long base64 runs (embedded images, data
+	// URIs, fixture payloads) in files where such runs make up more than this
+	// fraction of the content (0-1). Zero turns the check off. Files matched
+	// by an include pattern are always written in full.
+	EncodedDataFraction float64 `yaml:"encoded_data_fraction,omitempty"`
+
+	// EncodedRunLength is the minimum length of a base64 run counted by
+	// EncodedDataFraction. Defaults to 200.
+	EncodedRunLength int `yaml:"encoded_run_length,omitempty"`
+
+	// CollapseRepetition shortens runs of near-identical consecutive lines
+	// (typical of generated code) to a short sample and a note of how many
+	// lines were left out.
+	CollapseRepetition bool `yaml:"collapse_repetition,omitempty"`
+
+	// RepetitionSimilarity is how similar (0-1, by edit distance) a line must
+	// be to the first line of a run to join it. Defaults to 0.9.
+	RepetitionSimilarity float64 `yaml:"repetition_similarity,omitempty"`
+
+	// RepetitionMinRun is the shortest run that gets collapsed. Defaults to 8.
+	RepetitionMinRun int `yaml:"repetition_min_run,omitempty"`
+
+	// MaxLineBytes is the longest line written in full. Longer lines, as in
+	// minified files, are cut at that length with a note of how much was
+	// left out, so nothing downstream holds a multi-megabyte line. Defaults
+	// to DefaultMaxLineBytes.
+	MaxLineBytes int `yaml:"max_line_bytes,omitempty"`
+
+	// IndentStyle normalizes the leading whitespace of every line, so mixed
+	// indentation costs the same tokens everywhere: "preserve" (the default)
+	// leaves it alone, "spaces:N" expands tabs to stops every N columns, and
+	// "tabs" or "tabs:N" turns every N columns (default 4) into a tab.
+	IndentStyle string `yaml:"indent_style,omitempty"`
+
+	// FileGap is how many newlines follow each file's content in text
+	// output, before the next file's header. Nil means DefaultFileGap. A
+	// file whose last line has no newline always gets one.
+	FileGap *int `yaml:"file_gap,omitempty"`
+
+	// ExcludeDirs lists directory names (e.g., "node_modules", "__pycache__")
+	// that are skipped wherever they appear, without walking their contents.
+	ExcludeDirs []string `yaml:"exclude_dirs,omitempty"`
+
+	// IncludeOverridesDirExcludes lets include patterns reach files inside
+	// directories skipped by exclude_dirs. Only files matching an include
+	// pattern are kept there.
+	IncludeOverridesDirExcludes bool `yaml:"include_overrides_dir_excludes,omitempty"`
+
+	// IgnoreSources lists the ignore files at the project root that the walk
+	// applies, in order of precedence (see EffectiveIgnoreSources).
+	IgnoreSources []string `yaml:"ignore_sources,omitempty"`
+
+	// UseExportIgnore skips the paths that .gitattributes files mark
+	// export-ignore, which maintainers leave out of release archives as
+	// non-essential.
+	UseExportIgnore bool `yaml:"use_export_ignore,omitempty"`
+
+	// AlwaysIncludeDirs lists directories, relative to the project root,
+	// whose subtrees skip the .gitignore check, such as generated docs that
+	// are ignored by git but essential context. Excludes, the binary check,
+	// and every other rule still apply.
+	AlwaysIncludeDirs []string `yaml:"always_include_dirs,omitempty"`
+
+	// IncludeArtifacts keeps build artifacts (dist/, build/, .next/, source
+	// maps, minified and bundled files), which are skipped by default.
+	IncludeArtifacts bool `yaml:"include_artifacts,omitempty"`
+
+	// IncludeVendored keeps third-party code folders (vendor/, node_modules/,
+	// bower_components/, third_party/, .venv/, site-packages/, Pods/), which
+	// are pruned by default even when they aren't gitignored.
+	IncludeVendored bool `yaml:"include_vendored,omitempty"`
+
+	// IncludeWellKnown lets well-known project files that have no matching
+	// extension, such as Dockerfile, Makefile, or LICENSE, past the
+	// extensions lists of rules. Nil means true.
+	IncludeWellKnown *bool `yaml:"include_well_known,omitempty"`
+
+	// SkipTextifyDumps skips files whose first bytes look like textify
+	// output in any format, so a dump copied into the project under another
+	// name isn't included in the next one.
+	SkipTextifyDumps bool `yaml:"skip_textify_dumps,omitempty"`
+
+	// DumpMarkers are extra prefixes that mark a file as a dump for
+	// SkipTextifyDumps, such as the first line of a wrapper script's output.
+	DumpMarkers []string `yaml:"dump_markers,omitempty"`
+
+	// KeepLinkedDuplicates writes files that are the same file on disk as
+	// one already written (a hardlink, or a symlink to it) in full. By
+	// default they get their header and a line naming the first path.
+	KeepLinkedDuplicates bool `yaml:"keep_linked_duplicates,omitempty"`
+
+	// FollowSymlinks walks symlinked directories under the link's path, as
+	// if they were directories of their own; without it they are skipped. A
+	// file reached through a link is written under the link's path with the
+	// target's content, unless the target is also included under its own
+	// path; then the link's path only gets the linked-duplicate note.
+	FollowSymlinks bool `yaml:"follow_symlinks,omitempty"`
+
+	// ForceText lists extensions (without the dot) whose files are always
+	// treated as text, overriding the binary check.
+	ForceText []string `yaml:"force_text,omitempty"`
+
+	// ForceBinary lists extensions whose files are always treated as binary,
+	// overriding the binary check.
+	ForceBinary []string `yaml:"force_binary,omitempty"`
+
+	// DeepBinaryCheck samples windows at 25%, 50%, and 75% of each file and
+	// at its end in the binary check, besides the first 512 bytes, to catch
+	// files that start as text and turn binary further in.
+	DeepBinaryCheck bool `yaml:"deep_binary_check,omitempty"`
+
+	// SanitizeInvalidUTF8 outputs files without NUL bytes that aren't valid
+	// UTF-8, converted from a guessed encoding (Windows-1252, or UTF-8 with the
+	// invalid bytes replaced by U+FFFD), instead of skipping them as binary.
+	SanitizeInvalidUTF8 bool `yaml:"sanitize_invalid_utf8,omitempty"`
+
+	// Preprocessors maps extensions to commands whose output replaces a
+	// file's content, for formats a script can turn into readable text. Each
+	// "{path}" in the arguments becomes the file's path; without one, the
+	// file is piped to stdin. They only run with AllowPreprocessors.
+	Preprocessors map[string][]string `yaml:"preprocessors,omitempty"`
+
+	// AllowPreprocessors opts in to running Preprocessors, which executes
+	// programs named in the config. Since a cloned repository brings its own
+	// config, the environment must opt in too; see PreprocessorsAllowed.
+	AllowPreprocessors bool `yaml:"allow_preprocessors,omitempty"`
+
+	// ListBinaries writes a one-line placeholder with size and MIME type for
+	// binary files instead of silently leaving them out.
+	ListBinaries bool `yaml:"list_binaries,omitempty"`
+
+	// CacheFile, if set, persists binary verdicts and content hashes between
+	// runs so unchanged files (same size and mtime) aren't re-analyzed.
+	CacheFile string `yaml:"cache_file,omitempty"`
+
+	// Paranoid makes the cache verify content hashes instead of trusting
+	// size and mtime alone.
+	Paranoid bool `yaml:"paranoid,omitempty"`
+
+	// MaskEnv replaces the values in dotenv files (.env, .env.*) with a
+	// placeholder so secrets never reach the output.
+	MaskEnv bool `yaml:"mask_env,omitempty"`
+
+	// EnvKeepKeys lists dotenv keys (exact names) whose values are left
+	// visible when MaskEnv is on, e.g. NODE_ENV or LOG_LEVEL.
+	EnvKeepKeys []string `yaml:"env_keep_keys,omitempty"`
+
+	// ExtensionGroups defines named extension lists that rules can reference
+	// as "$name" in extensions and exclude_extensions, alongside the built-in
+	// groups ($web, $go, $docs, $config). A group here replaces a built-in
+	// one of the same name.
+	ExtensionGroups map[string][]string `yaml:"extension_groups,omitempty"`
+
+	// ContextWindows adds model context windows (name to size in tokens) to
+	// the ones 'textify estimate' reports, replacing a built-in window of the
+	// same name.
+	ContextWindows map[string]int `yaml:"context_windows,omitempty"`
+
+	// TokenFamilies lists tokenizers, among tokens.FamilyNames, whose token
+	// counts of the output file the summary and report list, against the
+	// context windows.
+	TokenFamilies []string `yaml:"token_families,omitempty"`
+
+	// Costs are input prices by model name, as tokens.ParsePrice reads them
+	// (e.g., "2.50per1M"), which the summary and 'textify estimate --costs'
+	// turn into the estimated cost of sending the output once. Models
+	// without a price are left out.
+	Costs map[string]string `yaml:"costs,omitempty"`
+
+	Dirs map[string]DirRule `yaml:"dirs"`
+
+	// outputTemplates are the output names ExpandOutputNames expanded, as
+	// written.
+	outputTemplates []string
+}
+
+// FileName is the name of the config file in the project root.
+const FileName = "textify.yaml"
+
+// DefaultSystemExcludes are skipped in every scan unless
+// override_system_excludes replaces them: git metadata, textify's own files,
+// and the default output.
+var DefaultSystemExcludes = []string{".git", FileName, OverlayFile(FileName), SchemaFile, "codebase.txt"}
+
+// VendoredDirs are third-party code folders, pruned by the walker unless
+// include_vendored is set, since they are often committed and so not
+// gitignored. Discover writes no rules for them.
+var VendoredDirs = []string{"vendor", "node_modules", "bower_components", "third_party", ".venv", "site-packages", "Pods"}
+
+// AllowPreprocessorsEnv is the environment variable that, set to 1, lets
+// allow_preprocessors run the commands a config names.
+const AllowPreprocessorsEnv = "TEXTIFY_ALLOW_PREPROCESSORS"
+
+// PreprocessorsAllowed reports whether Preprocessors may run: the config
+// sets allow_preprocessors, and the person running textify set
+// AllowPreprocessorsEnv to 1, so a repository's own config can't run
+// programs on its own.
+func (c *Config) PreprocessorsAllowed() bool {
+	return c.AllowPreprocessors && os.Getenv(AllowPreprocessorsEnv) == "1"
+}
+
+// EffectiveSystemExcludes returns the patterns skipped before any rule: the
+// defaults followed by system_excludes, or system_excludes alone when
+// override_system_excludes is set. Either way, the files that templated
+// output names write (see ExpandOutputNames) are skipped too.
+func (c *Config) EffectiveSystemExcludes() []string {
+	excludes := append([]string{}, c.SystemExcludes...)
+	if !c.OverrideSystemExcludes {
+		excludes = append(append([]string{}, DefaultSystemExcludes...), excludes...)
+	}
+	return append(excludes, c.outputTemplateExcludes()...)
+}
+
+// EffectiveIgnoreSources returns ignore_sources, or just gitignore when it
+// isn't set.
+func (c *Config) EffectiveIgnoreSources() []string {
+	if len(c.IgnoreSources) == 0 {
+		return []string{IgnoreGitignore}
+	}
+	return c.IgnoreSources
+}
+
+// EffectiveIncludeWellKnown returns include_well_known, which is on unless
+// set to false.
+func (c *Config) EffectiveIncludeWellKnown() bool {
+	return c.IncludeWellKnown == nil || *c.IncludeWellKnown
+}
+
+// Prices returns the prices of costs by model name, leaving out models
+// without one, and the problems of those whose price doesn't parse, which
+// are left out too.
+func (c *Config) Prices() (map[string]tokens.Price, []string) {
+	models := make([]string, 0, len(c.Costs))
+	for name := range c.Costs {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+	prices := make(map[string]tokens.Price, len(c.Costs))
+	var problems []string
+	for _, name := range models {
+		price := strings.TrimSpace(c.Costs[name])
+		if price == "" {
+			continue
+		}
+		p, err := tokens.ParsePrice(price)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("costs[%q]: %v", name, err))
+			continue
+		}
+		prices[name] = p
+	}
+	return prices, problems
+}
+
+// DefaultFileGap is the number of newlines after each file's content when
+// file_gap isn't set: two blank lines after a file that ends with a newline.
+const DefaultFileGap = 2
+
+// EffectiveFileGap returns file_gap, or DefaultFileGap when it isn't set.
+func (c *Config) EffectiveFileGap() int {
+	if c.FileGap == nil {
+		return DefaultFileGap
+	}
+	return *c.FileGap
+}
+
+// DefaultMaxLineBytes is the longest line written in full when
+// max_line_bytes isn't set.
+const DefaultMaxLineBytes = 1 << 20
+
+// EffectiveMaxLineBytes returns max_line_bytes, or DefaultMaxLineBytes when
+// it isn't set.
+func (c *Config) EffectiveMaxLineBytes() int {
+	if c.MaxLineBytes <= 0 {
+		return DefaultMaxLineBytes
+	}
+	return c.MaxLineBytes
 }
 
 // DefaultConfig returns a barebones config.
@@ -60,32 +698,603 @@ func DefaultConfig() Config {
 	}
 }
 
-// Load reads and parses the configuration file from the given path.
+// OverlayFile returns the personal overlay of the config file at path: the
+// file next to it with .local before the extension, as textify.local.yaml
+// is for textify.yaml.
+func OverlayFile(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".local" + ext
+}
+
+// FindRoot returns the project root for dir: the closest of dir and its
+// parents that holds a FileName, as git and npm look for theirs. The search
+// stops at the first directory holding .git, the root of the repository,
+// after checking it. ok is false when no config is found.
+func FindRoot(dir string) (root string, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, FileName)); err == nil {
+			return dir, true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Load reads and parses the configuration file from the given path, with
+// extension groups expanded. Its overlay (see OverlayFile) is merged on top
+// when it exists.
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	overlay := OverlayFile(path)
+	if _, err := os.Stat(overlay); err != nil {
+		overlay = ""
+	}
+	return LoadWithOverlay(path, overlay)
+}
+
+// LoadWithOverlay is Load with the overlay at overlay merged on top of the
+// config at path, or none if it is "". The overlay is deep-merged: its
+// scalars and lists replace the base's, and its mappings are merged key by
+// key, so a dirs rule in the overlay only replaces the fields it sets.
+func LoadWithOverlay(path, overlay string) (*Config, error) {
+	cfg, err := loadLayers(path, overlay)
 	if err != nil {
 		return nil, err
 	}
+	if err := cfg.ExpandExtensionGroups(); err != nil {
+		return nil, err
+	}
+	if _, err := cfg.LanguageExtensions(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadRaw reads and parses the configuration file as written, leaving
+// extension group references in place. Commands that save the config back
+// use it so the references survive.
+// The overlay is left out, so it never ends up in the shared file.
+func LoadRaw(path string) (*Config, error) {
+	return loadLayers(path, "")
+}
+
+// loadLayers parses the config at path with the overlay, if any, merged on
+// top.
+func loadLayers(path, overlay string) (*Config, error) {
+	root, err := readNode(path)
+	if err != nil {
+		return nil, err
+	}
+	if overlay != "" {
+		top, err := readNode(overlay)
+		if err != nil {
+			return nil, err
+		}
+		root = mergeDocuments(root, top)
+	}
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := root.Decode(&cfg); err != nil {
 		return nil, err
 	}
 	// Ensure map is initialized
 	if cfg.Dirs == nil {
 		cfg.Dirs = make(map[string]DirRule)
 	}
+	cfg.KeyWarnings = cfg.NormalizeDirKeys()
+	if cfg.Path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+	if overlay != "" {
+		if cfg.OverlayPath, err = filepath.Abs(overlay); err != nil {
+			return nil, err
+		}
+	}
 	return &cfg, nil
 }
 
+// readNode parses a YAML file into its node tree. An empty file is an empty
+// mapping.
+func readNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// mergeDocuments merges the overlay's top-level mapping into the base's and
+// returns the result. A document that isn't a mapping replaces the other,
+// so decoding reports it.
+func mergeDocuments(base, overlay *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+	mergeMapping(base, overlay, true, false)
+	return base
+}
+
+// mergeMapping merges the overlay mapping into base: new keys are added,
+// mappings under the same key are merged in turn, and any other value
+// replaces the base's. top is set for the top-level mapping, whose dirs
+// mapping compares its keys as directories (dirKeys), so "./src" in the
+// overlay merges with "src" in the base.
+func mergeMapping(base, overlay *yaml.Node, top, dirKeys bool) {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		var existing *yaml.Node
+		for j := 0; j+1 < len(base.Content); j += 2 {
+			k := base.Content[j].Value
+			if k == key.Value || (dirKeys && CleanDirKey(k) == CleanDirKey(key.Value)) {
+				existing = base.Content[j+1]
+			}
+		}
+		switch {
+		case existing == nil:
+			base.Content = append(base.Content, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeMapping(existing, value, false, top && key.Value == "dirs")
+		default:
+			*existing = *value
+		}
+	}
+}
+
 // Save marshals the configuration and writes it to the given path with a header.
 func (c *Config) Save(path string) error {
-	data, err := yaml.Marshal(c)
+	var doc yaml.Node
+	if err := doc.Encode(c); err != nil {
+		return err
+	}
+	if dirs := mappingValue(&doc, "dirs"); dirs != nil {
+		ruleRootFirst(dirs)
+	}
+	data, err := yaml.Marshal(&doc)
 	if err != nil {
 		return err
 	}
 
-	// Combine the header comments with the generated YAML
-	content := append([]byte(configHeader), data...)
+	// Combine the schema modeline and header comments with the generated YAML
+	content := append([]byte(schemaModeline+configHeader), data...)
 
 	return os.WriteFile(path, content, 0644)
-}
\ No newline at end of file
+}
+
+// ruleRootFirst moves the root rule of a dirs mapping node to the front.
+// The other rules keep the order yaml.v3 encodes map keys in, which is
+// sorted, with numbers in natural order, so the same config is always
+// saved the same way.
+func ruleRootFirst(dirs *yaml.Node) {
+	for i := 0; i+1 < len(dirs.Content); i += 2 {
+		if dirs.Content[i].Value == "." {
+			key, value := dirs.Content[i], dirs.Content[i+1]
+			copy(dirs.Content[2:i+2], dirs.Content[:i])
+			dirs.Content[0], dirs.Content[1] = key, value
+			return
+		}
+	}
+}
+
+// RuleOutputs returns, sorted and without duplicates, the output files set
+// by rules in addition to the top-level output_file.
+func (c *Config) RuleOutputs() []string {
+	seen := make(map[string]bool)
+	var outputs []string
+	for _, rule := range c.Dirs {
+		if rule.OutputFile != "" && !seen[rule.OutputFile] {
+			seen[rule.OutputFile] = true
+			outputs = append(outputs, rule.OutputFile)
+		}
+	}
+	sort.Strings(outputs)
+	return outputs
+}
+
+// OutputFiles returns every file a run writes: output_file, then the rule
+// outputs, then the outputs list.
+func (c *Config) OutputFiles() []string {
+	files := append([]string{c.OutputFile}, c.RuleOutputs()...)
+	for _, out := range c.Outputs {
+		files = append(files, out.File)
+	}
+	return files
+}
+
+// Indent styles accepted by Config.IndentStyle, before the optional ":N".
+const (
+	IndentPreserve = "preserve"
+	IndentSpaces   = "spaces"
+	IndentTabs     = "tabs"
+)
+
+// defaultTabWidth is the tab width of "tabs" without a width.
+const defaultTabWidth = 4
+
+// ParseIndentStyle parses an indent_style value into whether indentation is
+// converted to tabs and the tab width. A width of 0 means indentation is
+// preserved.
+func ParseIndentStyle(value string) (tabs bool, width int, err error) {
+	style, n, hasWidth := strings.Cut(value, ":")
+	switch style {
+	case "", IndentPreserve:
+		if !hasWidth {
+			return false, 0, nil
+		}
+	case IndentSpaces, IndentTabs:
+		width = defaultTabWidth
+		if hasWidth {
+			width, err = strconv.Atoi(n)
+		}
+		if err == nil && width > 0 && (hasWidth || style == IndentTabs) {
+			return style == IndentTabs, width, nil
+		}
+	}
+	return false, 0, fmt.Errorf("invalid indent style %q: use preserve, spaces:N, or tabs[:N]", value)
+}
+
+// DefaultOutputWarnSize is the output_warn_size used when none is set.
+const DefaultOutputWarnSize = "50MB"
+
+// ParseSize parses a size such as 500KB, 50MB, 2GB, or a plain number of
+// bytes. Units are powers of 1024. An empty value is 0.
+func ParseSize(value string) (int64, error) {
+	number := strings.TrimSpace(strings.ToUpper(value))
+	if number == "" {
+		return 0, nil
+	}
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(number, u.suffix) {
+			number, unit = strings.TrimSpace(strings.TrimSuffix(number, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) || n*float64(unit) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: use a number of bytes or a size like 500KB, 50MB, or 2GB", value)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// OutputLimits returns output_warn_size and max_output_size in bytes, 0
+// meaning no warning or no limit.
+func (c *Config) OutputLimits() (warn, max int64, err error) {
+	warnSize := c.OutputWarnSize
+	if warnSize == "" {
+		warnSize = DefaultOutputWarnSize
+	}
+	if warn, err = ParseSize(warnSize); err != nil {
+		return 0, 0, fmt.Errorf("output_warn_size: %w", err)
+	}
+	if max, err = ParseSize(c.MaxOutputSize); err != nil {
+		return 0, 0, fmt.Errorf("max_output_size: %w", err)
+	}
+	return warn, max, nil
+}
+
+// cleanOutput normalizes an output path so spellings of the same file compare
+// equal.
+func cleanOutput(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// CleanDirKey returns the form of a dirs key that the walker looks up:
+// slash-separated, without "./", a trailing "/", or redundant elements.
+// Backslashes are separators too, whatever the OS, so a config written on
+// Windows works everywhere.
+func CleanDirKey(key string) string {
+	return path.Clean(strings.ReplaceAll(key, `\`, "/"))
+}
+
+// slashPattern turns the backslashes separating the segments of a pattern
+// written on Windows ("docs\drafts\*.md") into forward slashes. A backslash
+// before a character the gitignore syntax lets it escape ("\#", "\!", "\[",
+// a space, another backslash) is kept as an escape. "*" and "?" can't be
+// in Windows file names, so a backslash before them is a separator, as is a
+// trailing one ("testdata\" for the folder).
+func slashPattern(p string) string {
+	if !strings.Contains(p, `\`) {
+		return p
+	}
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] != '\\' {
+			b.WriteByte(p[i])
+			continue
+		}
+		if i+1 < len(p) && strings.IndexByte(`#! []\`, p[i+1]) >= 0 {
+			b.WriteString(p[i : i+2])
+			i++
+			continue
+		}
+		b.WriteByte('/')
+	}
+	return b.String()
+}
+
+func slashPatterns(patterns []string) []string {
+	if patterns == nil {
+		return nil
+	}
+	out := make([]string, len(patterns))
+	for i, p := range patterns {
+		out[i] = slashPattern(p)
+	}
+	return out
+}
+
+// NormalizeDirKeys rewrites the dirs keys with CleanDirKey, so "./src",
+// "src/", and "src\" apply to src, and the separators of the rules'
+// include and exclude patterns with slashPattern. When several keys name
+// the same directory, one already in clean form wins, or else the first in
+// sorted order, and the others are dropped; the returned warnings name them.
+func (c *Config) NormalizeDirKeys() []string {
+	keys := make([]string, 0, len(c.Dirs))
+	for key := range c.Dirs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		// Clean keys first, so they win
+		iClean, jClean := keys[i] == CleanDirKey(keys[i]), keys[j] == CleanDirKey(keys[j])
+		if iClean != jClean {
+			return iClean
+		}
+		return keys[i] < keys[j]
+	})
+
+	var warnings []string
+	dirs := make(map[string]DirRule, len(c.Dirs))
+	kept := make(map[string]string, len(c.Dirs))
+	for _, key := range keys {
+		clean := CleanDirKey(key)
+		if first, ok := kept[clean]; ok {
+			warnings = append(warnings, fmt.Sprintf("dirs: %q and %q are the same directory; the rule for %q is ignored", first, key, key))
+			continue
+		}
+		kept[clean] = key
+		rule := c.Dirs[key]
+		rule.Include, rule.Exclude = slashPatterns(rule.Include), slashPatterns(rule.Exclude)
+		dirs[clean] = rule
+	}
+	c.Dirs = dirs
+	sort.Strings(warnings)
+	return warnings
+}
+
+// MissingDirs returns, sorted, the rule keys that don't name a directory
+// under root, such as rules left behind for deleted folders. Those rules
+// silently do nothing.
+func (c *Config) MissingDirs(root string) []string {
+	var missing []string
+	for dir := range c.Dirs {
+		if dir == "." {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(dir))); err != nil || !info.IsDir() {
+			missing = append(missing, dir)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// AddExcludes appends runtime exclude patterns (e.g., from the --exclude flag)
+// to every directory rule, creating an enabled root rule if there is none.
+// Rules don't inherit excludes from their parents, so adding them only to the
+// root would miss folders with a rule of their own.
+func (c *Config) AddExcludes(patterns []string) {
+	if len(patterns) == 0 {
+		return
+	}
+	if c.Dirs == nil {
+		c.Dirs = make(map[string]DirRule)
+	}
+	if _, ok := c.Dirs["."]; !ok {
+		c.Dirs["."] = DirRule{Enabled: true}
+	}
+	for dir, rule := range c.Dirs {
+		rule.Exclude = append(append([]string{}, rule.Exclude...), patterns...)
+		c.Dirs[dir] = rule
+	}
+}
+
+// AlwaysIncludePaths returns always_include_dirs as clean paths relative to
+// the project root, with forward slashes.
+func (c *Config) AlwaysIncludePaths() []string {
+	var paths []string
+	for _, dir := range c.AlwaysIncludeDirs {
+		if dir != "" {
+			paths = append(paths, CleanDirKey(dir))
+		}
+	}
+	return paths
+}
+
+// Check reports values that parse but can't work, such as an unknown order
+// or an invalid content regex. Problems are returned in a stable order.
+func (c *Config) Check() []string {
+	var problems []string
+	problems = append(problems, c.KeyWarnings...)
+	if c.OutputFile == "" {
+		problems = append(problems, "output_file: must not be empty")
+	}
+	if !knownFormat(c.Format) {
+		problems = append(problems, fmt.Sprintf("format: unknown format %q", c.Format))
+	}
+	files := map[string]bool{cleanOutput(c.OutputFile): true}
+	for _, out := range c.RuleOutputs() {
+		files[cleanOutput(out)] = true
+	}
+	for i, out := range c.Outputs {
+		switch {
+		case out.File == "":
+			problems = append(problems, fmt.Sprintf("outputs[%d].file: must not be empty", i))
+		case files[cleanOutput(out.File)]:
+			problems = append(problems, fmt.Sprintf("outputs[%d].file: %s is already an output", i, out.File))
+		}
+		files[cleanOutput(out.File)] = true
+		if !knownFormat(out.Format) {
+			problems = append(problems, fmt.Sprintf("outputs[%d].format: unknown format %q", i, out.Format))
+		}
+	}
+	if c.TreeMode != "" && c.TreeMode != TreeModeIncluded && c.TreeMode != TreeModeAll {
+		problems = append(problems, fmt.Sprintf("tree_mode: unknown tree mode %q", c.TreeMode))
+	}
+	if c.TreeSort != "" && c.TreeSort != TreeSortLexicographic && c.TreeSort != TreeSortNatural {
+		problems = append(problems, fmt.Sprintf("tree_sort: unknown tree sort %q", c.TreeSort))
+	}
+	if c.Order != "" && c.Order != OrderPath && c.Order != OrderGitHot {
+		problems = append(problems, fmt.Sprintf("order: unknown order %q", c.Order))
+	}
+	switch c.GroupBy {
+	case "":
+	case GroupByLanguage, GroupByExtension:
+		if c.DocsFirst {
+			problems = append(problems, "group_by: can't be combined with docs_first")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("group_by: unknown grouping %q", c.GroupBy))
+	}
+	seenSources := make(map[string]bool)
+	for _, source := range c.IgnoreSources {
+		switch {
+		case source != IgnoreGitignore && source != IgnoreDockerignore && source != IgnoreTextifyignore:
+			problems = append(problems, fmt.Sprintf("ignore_sources: unknown ignore source %q", source))
+		case seenSources[source]:
+			problems = append(problems, fmt.Sprintf("ignore_sources: %s is listed twice", source))
+		}
+		seenSources[source] = true
+	}
+	if c.MaxDepth != nil && *c.MaxDepth < 0 {
+		problems = append(problems, "max_depth: must not be negative")
+	}
+	if _, err := c.LanguageExtensions(); err != nil {
+		problems = append(problems, "languages: "+err.Error())
+	}
+	if _, _, err := ParseIndentStyle(c.IndentStyle); err != nil {
+		problems = append(problems, "indent_style: "+err.Error())
+	}
+	if c.FileGap != nil && *c.FileGap < 0 {
+		problems = append(problems, "file_gap: must not be negative")
+	}
+	if c.MaxLineBytes < 0 {
+		problems = append(problems, "max_line_bytes: must not be negative")
+	}
+	if c.KeepPrevious < 0 {
+		problems = append(problems, "keep_previous: must not be negative")
+	}
+	if _, _, err := c.OutputLimits(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.EncodedDataFraction < 0 || c.EncodedDataFraction > 1 {
+		problems = append(problems, "encoded_data_fraction: must be between 0 and 1")
+	}
+	if c.RepetitionSimilarity < 0 || c.RepetitionSimilarity > 1 {
+		problems = append(problems, "repetition_similarity: must be between 0 and 1")
+	}
+
+	windows := make([]string, 0, len(c.ContextWindows))
+	for name := range c.ContextWindows {
+		windows = append(windows, name)
+	}
+	sort.Strings(windows)
+	for _, name := range windows {
+		if c.ContextWindows[name] <= 0 {
+			problems = append(problems, fmt.Sprintf("context_windows[%q]: must be positive", name))
+		}
+	}
+	_, priceProblems := c.Prices()
+	problems = append(problems, priceProblems...)
+	families := make(map[string]bool, len(c.TokenFamilies))
+	for _, name := range c.TokenFamilies {
+		switch {
+		case !knownTokenFamily(name):
+			problems = append(problems, fmt.Sprintf("token_families: unknown family %q (known: %s)", name, strings.Join(tokens.FamilyNames(), ", ")))
+		case families[name]:
+			problems = append(problems, fmt.Sprintf("token_families: %s is listed twice", name))
+		}
+		families[name] = true
+	}
+	exts := make([]string, 0, len(c.Preprocessors))
+	for ext := range c.Preprocessors {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		if len(c.Preprocessors[ext]) == 0 || c.Preprocessors[ext][0] == "" {
+			problems = append(problems, fmt.Sprintf("preprocessors[%q]: command must not be empty", ext))
+		}
+	}
+	if len(c.Preprocessors) > 0 && !c.AllowPreprocessors {
+		problems = append(problems, "preprocessors: ignored unless allow_preprocessors is true")
+	}
+	for _, dir := range c.AlwaysIncludeDirs {
+		if clean := path.Clean(filepath.ToSlash(dir)); dir == "" || filepath.IsAbs(dir) || clean == ".." || strings.HasPrefix(clean, "../") {
+			problems = append(problems, fmt.Sprintf("always_include_dirs: %q is not a directory inside the project", dir))
+		}
+	}
+	for _, ext := range c.ForceText {
+		if containsString(c.ForceBinary, ext) {
+			problems = append(problems, fmt.Sprintf("force_text: %q is also in force_binary", ext))
+		}
+	}
+
+	_, groupProblems := c.expandedDirs()
+	problems = append(problems, groupProblems...)
+
+	dirs := make([]string, 0, len(c.Dirs))
+	for dir := range c.Dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		rule := c.Dirs[dir]
+		for _, re := range []string{rule.ContentIncludeRegex, rule.ContentExcludeRegex} {
+			if re == "" {
+				continue
+			}
+			if _, err := regexp.Compile(re); err != nil {
+				problems = append(problems, fmt.Sprintf("dirs[%q]: invalid content regex: %v", dir, err))
+			}
+		}
+		for _, pattern := range append(append([]string{}, rule.MimeInclude...), rule.MimeExclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				problems = append(problems, fmt.Sprintf("dirs[%q]: invalid MIME pattern %q", dir, pattern))
+			}
+		}
+		if rule.MaxDepth != nil && *rule.MaxDepth < 0 {
+			problems = append(problems, fmt.Sprintf("dirs[%q].max_depth: must not be negative", dir))
+		}
+		if rule.MaxDirLines < 0 {
+			problems = append(problems, fmt.Sprintf("dirs[%q].max_dir_lines: must not be negative", dir))
+		}
+		if rule.MaxFileTokens < 0 {
+			problems = append(problems, fmt.Sprintf("dirs[%q].max_file_tokens: must not be negative", dir))
+		}
+		if rule.Format != "" && rule.Format != FormatText && rule.Format != FormatMarkdownDoc {
+			problems = append(problems, fmt.Sprintf("dirs[%q].format: must be %q or %q, not %q", dir, FormatText, FormatMarkdownDoc, rule.Format))
+		}
+		if rule.OutputFile != "" && cleanOutput(rule.OutputFile) == cleanOutput(c.OutputFile) {
+			problems = append(problems, fmt.Sprintf("dirs[%q].output_file: same as the top-level output_file; leave it out instead", dir))
+		}
+	}
+	return problems
+}
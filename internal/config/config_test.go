@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -63,3 +65,602 @@ func TestLoadAndSave(t *testing.T) {
 		t.Errorf("Loaded dirs do not match saved dirs.\nExpected: %+v\nGot: %+v", originalCfg.Dirs, loadedCfg.Dirs)
 	}
 }
+
+func TestSaveRuleOrder(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_save_order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cfg := Config{OutputFile: "codebase.txt", Dirs: make(map[string]DirRule)}
+	for _, key := range []string{"web", "-gen", "api/v2", ".", "api", "Docs", "_build", "zz", "api-client", "pkg10", "pkg9"} {
+		cfg.Dirs[key] = DirRule{Enabled: true}
+	}
+
+	var saved [][]byte
+	for i := 0; i < 2; i++ {
+		filePath := filepath.Join(tempDir, fmt.Sprintf("textify%d.yaml", i))
+		if err := cfg.Save(filePath); err != nil {
+			t.Fatalf("Failed to save config: %v", err)
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, data)
+	}
+	if string(saved[0]) != string(saved[1]) {
+		t.Errorf("Expected identical saves, got:\n%s\nand:\n%s", saved[0], saved[1])
+	}
+
+	doc, err := LoadDocument(filepath.Join(tempDir, "textify0.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	dirs := mappingValue(doc.root.Content[0], "dirs")
+	for i := 0; i < len(dirs.Content); i += 2 {
+		keys = append(keys, dirs.Content[i].Value)
+	}
+	// The root rule comes first, and the rest in yaml.v3's order, with
+	// numbers in natural order
+	expected := []string{".", "-gen", "_build", "Docs", "api", "api-client", "api/v2", "pkg9", "pkg10", "web", "zz"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Unexpected rule order.\nExpected: %q\nGot:      %q", expected, keys)
+	}
+}
+
+func TestAddExcludes(t *testing.T) {
+	cfg := &Config{
+		Dirs: map[string]DirRule{
+			"src":    {Enabled: true, Exclude: []string{"*.gen.go"}},
+			"vendor": {Enabled: false},
+		},
+	}
+	cfg.AddExcludes([]string{"*_test.go", "src/legacy"})
+
+	expected := map[string]DirRule{
+		".":      {Enabled: true, Exclude: []string{"*_test.go", "src/legacy"}},
+		"src":    {Enabled: true, Exclude: []string{"*.gen.go", "*_test.go", "src/legacy"}},
+		"vendor": {Enabled: false, Exclude: []string{"*_test.go", "src/legacy"}},
+	}
+	if !reflect.DeepEqual(cfg.Dirs, expected) {
+		t.Errorf("Unexpected rules after AddExcludes.\nExpected: %+v\nGot:      %+v", expected, cfg.Dirs)
+	}
+}
+
+func TestDiscoverAppliesEcosystemDefaults(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_ecosystem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.WriteFile(filepath.Join(tempDir, "package.json"), []byte("{}"), 0644)
+	os.MkdirAll(filepath.Join(tempDir, "node_modules", "left-pad"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "node_modules", "left-pad", "index.js"), []byte(""), 0644)
+	os.Mkdir(filepath.Join(tempDir, "src"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "src", "app.ts"), []byte(""), 0644)
+
+	detected := DetectEcosystems(tempDir)
+	if len(detected) != 1 || detected[0].Name != "Node" {
+		t.Fatalf("Expected only the Node ecosystem, got %+v", detected)
+	}
+
+	cfg, err := Discover(tempDir, nil)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	if rule, ok := cfg.Dirs["node_modules"]; !ok || rule.Enabled {
+		t.Errorf("Expected node_modules to be disabled, got %+v (exists: %v)", rule, ok)
+	}
+
+	src := cfg.Dirs["src"]
+	if !src.Enabled || !reflect.DeepEqual(src.Extensions, []string{"ts"}) {
+		t.Errorf("Expected src to be enabled with [ts], got %+v", src)
+	}
+	if !containsString(cfg.Dirs["."].Exclude, "package-lock.json") {
+		t.Errorf("Expected root rule to exclude lockfiles, got %v", cfg.Dirs["."].Exclude)
+	}
+	if containsString(cfg.Dirs["."].Extensions, "js") {
+		t.Errorf("Expected node_modules to be left out of root extensions, got %v", cfg.Dirs["."].Extensions)
+	}
+}
+
+func TestDiscoverSkipsNoiseFolders(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_noise")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, file := range []string{"main.go", "src/app.ts", "tmp/cache/blob.bin", "src/tmp/x.log", "third_party/lib/lib.c", "Pods/Pod.swift"} {
+		os.MkdirAll(filepath.Join(tempDir, filepath.Dir(file)), 0755)
+		os.WriteFile(filepath.Join(tempDir, file), []byte(""), 0644)
+	}
+
+	cfg, err := Discover(tempDir, &Config{ExcludeDirs: []string{"tmp"}})
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	// tmp is never walked, so it gets no rule and adds no extensions
+	if rule, ok := cfg.Dirs["tmp"]; ok {
+		t.Errorf("Expected no rule for tmp, got %+v", rule)
+	}
+	// Nor do vendored folders, which a rule would keep from being pruned
+	for _, dir := range []string{"third_party", "Pods"} {
+		if rule, ok := cfg.Dirs[dir]; ok {
+			t.Errorf("Expected no rule for %s, got %+v", dir, rule)
+		}
+	}
+	if exts := cfg.Dirs["."].Extensions; !reflect.DeepEqual(exts, []string{"go", "ts"}) {
+		t.Errorf("Expected root extensions [go ts], got %v", exts)
+	}
+	if exts := cfg.Dirs["src"].Extensions; !reflect.DeepEqual(exts, []string{"ts"}) {
+		t.Errorf("Expected src extensions [ts], got %v", exts)
+	}
+
+	// include_vendored keeps them, rules and all
+	cfg, err = Discover(tempDir, &Config{IncludeVendored: true})
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if exts := cfg.Dirs["third_party"].Extensions; !reflect.DeepEqual(exts, []string{"c"}) {
+		t.Errorf("Expected third_party extensions [c] with include_vendored, got %v", exts)
+	}
+}
+
+func BenchmarkDiscover(b *testing.B) {
+	tempDir, err := os.MkdirTemp("", "config_bench_discover")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// 20 top-level folders x 5 subfolders x 20 files, plus ignored output
+	os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("*.log\nout/\n"), 0644)
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 5; j++ {
+			dir := filepath.Join(tempDir, fmt.Sprintf("pkg%d", i), fmt.Sprintf("sub%d", j))
+			os.MkdirAll(filepath.Join(dir, "out"), 0755)
+			for k := 0; k < 20; k++ {
+				os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.go", k)), nil, 0644)
+				os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.log", k)), nil, 0644)
+			}
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Discover(tempDir, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMissingDirs(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "src", "api"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "notes"), []byte("a file"), 0644)
+
+	cfg := &Config{Dirs: map[string]DirRule{
+		".":       {Enabled: true},
+		"src":     {Enabled: true},
+		"src/api": {Enabled: true},
+		"src/old": {Enabled: true},
+		"legacy":  {Enabled: false},
+		"notes":   {Enabled: true},
+	}}
+	expected := []string{"legacy", "notes", "src/old"}
+	if missing := cfg.MissingDirs(tempDir); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("Expected missing dirs %v, got %v", expected, missing)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"512", 512},
+		{"500KB", 500 << 10},
+		{"50MB", 50 << 20},
+		{"1.5 gb", 3 << 29},
+	}
+	for _, tt := range tests {
+		if got, err := ParseSize(tt.value); err != nil || got != tt.expected {
+			t.Errorf("ParseSize(%q): expected %d, got %d (%v)", tt.value, tt.expected, got, err)
+		}
+	}
+	for _, value := range []string{"big", "-1MB", "10TB", "inf", "+InfGB", "NaN", "1e30GB"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Errorf("ParseSize(%q): expected an error", value)
+		}
+	}
+}
+
+func TestDuplicateDirKeys(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filePath := filepath.Join(tempDir, "textify.yaml")
+	data := "output_file: codebase.txt\ndirs:\n" +
+		"  ./src:\n    enabled: true\n    extensions: [js]\n" +
+		"  src:\n    enabled: true\n    extensions: [go]\n" +
+		"  lib/:\n    enabled: true\n"
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(filePath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Dirs) != 2 {
+		t.Errorf("Expected the rules for src and lib, got %v", cfg.Dirs)
+	}
+	if rule := cfg.Dirs["src"]; !reflect.DeepEqual(rule.Extensions, []string{"go"}) {
+		t.Errorf("Expected the rule keyed src to win, got %+v", rule)
+	}
+	if _, ok := cfg.Dirs["lib"]; !ok {
+		t.Errorf("Expected lib/ to be normalized to lib, got %v", cfg.Dirs)
+	}
+
+	warning := `dirs: "src" and "./src" are the same directory; the rule for "./src" is ignored`
+	if !reflect.DeepEqual(cfg.KeyWarnings, []string{warning}) {
+		t.Errorf("Expected warning %q, got %q", warning, cfg.KeyWarnings)
+	}
+	found := false
+	for _, problem := range cfg.Check() {
+		found = found || problem == warning
+	}
+	if !found {
+		t.Errorf("Expected Check to report %q, got %q", warning, cfg.Check())
+	}
+}
+
+func TestLoadOverlay(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	base := filepath.Join(tempDir, FileName)
+	os.WriteFile(base, []byte("output_file: codebase.txt\ninclude_tree: true\nexclude_dirs: [node_modules]\n"+
+		"dirs:\n"+
+		"  .:\n    enabled: true\n    extensions: [go, md]\n    exclude: [TODO.md]\n"+
+		"  src:\n    enabled: true\n    extensions: [go]\n"), 0644)
+	overlay := OverlayFile(base)
+	if filepath.Base(overlay) != "textify.local.yaml" {
+		t.Fatalf("Expected the overlay next to the config, got %s", overlay)
+	}
+	os.WriteFile(overlay, []byte("output_file: /tmp/mine.txt\nmask_env: true\nexclude_dirs: [tmp]\n"+
+		"dirs:\n"+
+		"  .:\n    exclude: [notes.md]\n"+
+		"  ./src:\n    include: [gen/keep.go]\n"+
+		"  scratch:\n    enabled: false\n"), 0644)
+
+	cfg, err := Load(base)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	// Scalars and lists in the overlay replace the base's
+	if cfg.OutputFile != "/tmp/mine.txt" || !cfg.MaskEnv || !cfg.IncludeTree {
+		t.Errorf("Unexpected scalars: output_file %q, mask_env %v, include_tree %v", cfg.OutputFile, cfg.MaskEnv, cfg.IncludeTree)
+	}
+	if !reflect.DeepEqual(cfg.ExcludeDirs, []string{"tmp"}) {
+		t.Errorf("Expected exclude_dirs from the overlay, got %v", cfg.ExcludeDirs)
+	}
+	// Rules merge field by field, matching keys as directories
+	expected := map[string]DirRule{
+		".":       {Enabled: true, Extensions: []string{"go", "md"}, Exclude: []string{"notes.md"}},
+		"src":     {Enabled: true, Extensions: []string{"go"}, Include: []string{"gen/keep.go"}},
+		"scratch": {Enabled: false},
+	}
+	if !reflect.DeepEqual(cfg.Dirs, expected) {
+		t.Errorf("Unexpected rules.\nExpected: %+v\nGot:      %+v", expected, cfg.Dirs)
+	}
+	if len(cfg.KeyWarnings) != 0 {
+		t.Errorf("Expected no key warnings, got %q", cfg.KeyWarnings)
+	}
+	if cfg.OverlayPath != overlay {
+		t.Errorf("Expected OverlayPath %s, got %s", overlay, cfg.OverlayPath)
+	}
+
+	// Commands that save the config back never see the overlay
+	raw, err := LoadRaw(base)
+	if err != nil {
+		t.Fatalf("LoadRaw failed: %v", err)
+	}
+	if raw.OutputFile != "codebase.txt" || raw.OverlayPath != "" || len(raw.Dirs) != 2 {
+		t.Errorf("Expected the base config alone, got %+v", raw)
+	}
+}
+
+func TestDiscoverWorkspaces(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_workspaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	write := func(rel, content string) {
+		full := filepath.Join(tempDir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+	write("package.json", `{"private": true, "workspaces": ["packages/*", "apps/**", "!packages/legacy"]}`)
+	write("packages/ui/package.json", "{}")
+	write("packages/ui/src/button.tsx", "")
+	write("packages/legacy/package.json", "{}")
+	write("packages/legacy/index.js", "")
+	write("packages/notes/README.md", "") // no package.json, not a member
+	write("apps/web/admin/package.json", "{}")
+	write("apps/web/admin/main.ts", "")
+	write("apps/web/node_modules/dep/package.json", "{}")
+	write("go.work", "go 1.21\n\nuse (\n\t./services/api // the API\n\t./tools\n)\nuse ./services/worker\n")
+	write("services/api/main.go", "")
+	write("services/worker/main.go", "")
+	write("tools/gen.py", "")
+	write("scripts/build.sh", "")
+
+	workspaces := DetectWorkspaces(tempDir)
+	expectedWorkspaces := []Workspace{
+		{File: "package.json", Members: []string{"apps/web/admin", "packages/ui"}},
+		{File: "go.work", Members: []string{"services/api", "services/worker", "tools"}},
+	}
+	if !reflect.DeepEqual(workspaces, expectedWorkspaces) {
+		t.Fatalf("Unexpected workspaces.\nExpected: %+v\nGot:      %+v", expectedWorkspaces, workspaces)
+	}
+
+	cfg, err := Discover(tempDir, nil)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	var keys []string
+	for key := range cfg.Dirs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// Folders holding members get no flat rule; others keep theirs
+	expectedKeys := []string{".", "apps/web/admin", "packages/ui", "scripts", "services/api", "services/worker", "tools"}
+	if !reflect.DeepEqual(keys, expectedKeys) {
+		t.Errorf("Expected rules for %v, got %v", expectedKeys, keys)
+	}
+	if rule := cfg.Dirs["packages/ui"]; !rule.Enabled || !reflect.DeepEqual(rule.Extensions, []string{"json", "tsx"}) {
+		t.Errorf("Expected packages/ui to be enabled with [json tsx], got %+v", rule)
+	}
+	if rule := cfg.Dirs["services/api"]; !reflect.DeepEqual(rule.Extensions, []string{"go"}) {
+		t.Errorf("Expected services/api to have [go], got %+v", rule)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	// Resolve /tmp links so the returned paths compare equal
+	if tempDir, err = filepath.EvalSymlinks(tempDir); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := filepath.Join(tempDir, "repo")
+	deep := filepath.Join(repo, "services", "api", "handlers")
+	nested := filepath.Join(repo, "tools", "gen")
+	for _, dir := range []string{deep, filepath.Join(repo, ".git"), filepath.Join(nested, ".git")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A config above the repository is never picked up
+	for _, dir := range []string{tempDir, repo} {
+		if err := os.WriteFile(filepath.Join(dir, FileName), []byte("dirs: {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dir  string
+		root string
+		ok   bool
+	}{
+		{repo, repo, true},
+		{deep, repo, true},
+		{nested, "", false},
+	}
+	for _, tt := range tests {
+		root, ok := FindRoot(tt.dir)
+		if root != tt.root || ok != tt.ok {
+			t.Errorf("FindRoot(%s) = %q, %v; want %q, %v", tt.dir, root, ok, tt.root, tt.ok)
+		}
+	}
+}
+
+func TestDiscoverParentGitignores(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_parent_gitignores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	write := func(rel, content string) {
+		full := filepath.Join(tempDir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+	// Initialized two levels below the repository root
+	os.MkdirAll(filepath.Join(tempDir, "repo", ".git"), 0755)
+	write("repo/.gitignore", "coverage/\n")
+	write("repo/services/.gitignore", "/api/generated/\n")
+	write("repo/services/api/handlers/user.go", "")
+	write("repo/services/api/coverage/report.html", "")
+	write("repo/services/api/generated/models.go", "")
+
+	root := filepath.Join(tempDir, "repo", "services", "api")
+	cfg, err := Discover(root, nil)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	var dirs []string
+	for dir := range cfg.Dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	if expected := []string{".", "handlers"}; !reflect.DeepEqual(dirs, expected) {
+		t.Errorf("Expected rules for %q, got %q", expected, dirs)
+	}
+}
+
+func TestDiscoverIgnoreSources(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_ignore_sources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, file := range []string{"src/main.go", "fixtures/big.json", "scratch/notes.md"} {
+		os.MkdirAll(filepath.Join(tempDir, filepath.Dir(file)), 0755)
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("scratch/\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, ".textifyignore"), []byte("fixtures/\n"), 0644)
+
+	// Discovery leaves out what the scan will, by the same ignore files
+	for _, tt := range []struct {
+		sources  []string
+		expected []string
+	}{
+		{nil, []string{".", "fixtures", "src"}},
+		{[]string{IgnoreTextifyignore}, []string{".", "scratch", "src"}},
+		{[]string{IgnoreGitignore, IgnoreTextifyignore}, []string{".", "src"}},
+	} {
+		cfg, err := Discover(tempDir, &Config{IgnoreSources: tt.sources})
+		if err != nil {
+			t.Fatalf("Discover failed: %v", err)
+		}
+		var dirs []string
+		for dir := range cfg.Dirs {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		if !reflect.DeepEqual(dirs, tt.expected) {
+			t.Errorf("With %v: expected rules for %q, got %q", tt.sources, tt.expected, dirs)
+		}
+	}
+}
+
+func TestBackslashDirKeys(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_backslash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// A config written on Windows
+	filePath := filepath.Join(tempDir, "textify.yaml")
+	data := "dirs:\n" +
+		"  'internal\\scanner':\n    enabled: true\n    exclude: ['testdata\\*.go', '\\#notes.md', 'gen\\[id\\].go']\n" +
+		"  'docs\\':\n    enabled: false\n"
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(filePath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	rule, ok := cfg.Dirs["internal/scanner"]
+	if !ok {
+		t.Fatalf(`Expected internal\scanner to be keyed internal/scanner, got %v`, cfg.Dirs)
+	}
+	// Escapes of the gitignore syntax are kept
+	expected := []string{"testdata/*.go", `\#notes.md`, `gen\[id\].go`}
+	if !reflect.DeepEqual(rule.Exclude, expected) {
+		t.Errorf("Expected excludes %q, got %q", expected, rule.Exclude)
+	}
+	if _, ok := cfg.Dirs["docs"]; !ok || len(cfg.KeyWarnings) > 0 {
+		t.Errorf(`Expected docs\ to be keyed docs, got %v (%q)`, cfg.Dirs, cfg.KeyWarnings)
+	}
+
+	// Edits find the rule under the key as written
+	doc, err := LoadDocument(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetEnabled("internal/scanner", false); err != nil {
+		t.Fatal(err)
+	}
+	edited, err := doc.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edited.Dirs) != 2 || edited.Dirs["internal/scanner"].Enabled {
+		t.Errorf("Expected the existing rule to be disabled, got %+v", edited.Dirs)
+	}
+}
+
+func TestExpandOutputNames(t *testing.T) {
+	cfg := &Config{
+		OutputFile:     "snapshots/{repo}-{date}.txt",
+		Outputs:        []OutputSpec{{File: "snapshots/{repo}-{branch}.json", Format: FormatJSON}},
+		SystemExcludes: []string{".idea"},
+		Dirs: map[string]DirRule{
+			".":   {Enabled: true},
+			"api": {Enabled: true, OutputFile: "api-{time}.txt"},
+		},
+	}
+	values := map[string]string{
+		PlaceholderRepo:   "demo",
+		PlaceholderDate:   "2024-05-01",
+		PlaceholderTime:   "093000",
+		PlaceholderBranch: "feature/login",
+	}
+	err := cfg.ExpandOutputNames(func(p string) (string, error) { return values[p], nil })
+	if err != nil {
+		t.Fatalf("ExpandOutputNames failed: %v", err)
+	}
+	expected := []string{"snapshots/demo-2024-05-01.txt", "api-093000.txt", "snapshots/demo-feature-login.json"}
+	if files := cfg.OutputFiles(); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected outputs %q, got %q", expected, files)
+	}
+
+	// Snapshots of earlier runs are skipped, not only this run's
+	excludes := cfg.EffectiveSystemExcludes()
+	for _, pattern := range []string{
+		".idea",
+		"/snapshots/*-[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9].txt",
+		"/snapshots/*-[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9].txt.sha256",
+		"/api-[0-9][0-9][0-9][0-9][0-9][0-9].txt",
+		"/snapshots/*-*.json",
+	} {
+		found := false
+		for _, exclude := range excludes {
+			found = found || exclude == pattern
+		}
+		if !found {
+			t.Errorf("Expected %q among the system excludes, got %q", pattern, excludes)
+		}
+	}
+
+	// A failing placeholder stops the expansion
+	cfg = &Config{OutputFile: "{branch}.txt"}
+	err = cfg.ExpandOutputNames(func(p string) (string, error) { return "", os.ErrNotExist })
+	if err == nil {
+		t.Error("Expected the placeholder's error")
+	}
+}
@@ -4,13 +4,20 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/JohnEsleyer/textify/internal/ignore"
+
 	"github.com/monochromegane/go-gitignore"
 )
 
 // Discover populates the Config.Dirs map by scanning ONLY top-level directories.
 // It aggregates extensions from subdirectories to ensure the top-level rule covers children.
+// Newly generated rules are pre-populated with the defaults of any ecosystems
+// (Go, Node, Python, Rust) detected at the root. In a monorepo (see
+// DetectWorkspaces), each workspace member gets a rule of its own instead,
+// and top-level folders holding members get none.
 func Discover(root string, existingCfg *Config) (*Config, error) {
 	cfg := DefaultConfig()
 	if existingCfg != nil {
@@ -20,15 +27,30 @@ func Discover(root string, existingCfg *Config) (*Config, error) {
 		}
 	}
 
-	ignoreMatcher := getIgnoreMatcher(root)
+	ignoreMatcher := ignore.NewMatcher(root, cfg.EffectiveIgnoreSources())
+
+	ecosystems := DetectEcosystems(root)
+	excludes := ecosystemExcludes(ecosystems)
+	skipDirs := append([]string{}, excludes...)
+	for _, eco := range ecosystems {
+		skipDirs = append(skipDirs, eco.DisabledDirs...)
+	}
+	skipDirs = append(skipDirs, cfg.ExcludeDirs...)
+	if !cfg.IncludeVendored {
+		skipDirs = append(skipDirs, VendoredDirs...)
+	}
+
+	members := workspaceMembers(DetectWorkspaces(root))
+
+	// Walk the project once, collecting extensions for the root fallback and
+	// for each top-level directory and workspace member at the same time
+	rootExtensions, dirExtensions := scanExtensions(root, ignoreMatcher, skipDirs, members)
 
 	// 1. Update Root (.) Rule
-	// We scan the *entire* project to find common extensions for the root fallback
-	rootExtensions := deepScanExtensions(root, root, ignoreMatcher)
-	
+
 	// Preserve existing root settings if they exist, otherwise update extensions
 	if val, ok := cfg.Dirs["."]; ok {
-		// Optional: Merge extensions if you want, or just keep user's. 
+		// Optional: Merge extensions if you want, or just keep user's.
 		// For now, let's trust the user if they edited it, or update if empty.
 		if len(val.Extensions) == 0 {
 			val.Extensions = rootExtensions
@@ -38,6 +60,7 @@ func Discover(root string, existingCfg *Config) (*Config, error) {
 		cfg.Dirs["."] = DirRule{
 			Enabled:    true,
 			Extensions: rootExtensions,
+			Exclude:    excludes,
 		}
 	}
 
@@ -55,76 +78,162 @@ func Discover(root string, existingCfg *Config) (*Config, error) {
 		// Check if ignored by git
 		fullPath := filepath.Join(root, entry.Name())
 		if ignoreMatcher.Match(fullPath, true) {
-			// If gitignored, DO NOT add to YAML. 
+			// If gitignored, DO NOT add to YAML.
 			// The runtime scanner will skip it automatically.
 			continue
 		}
 
 		relPath := entry.Name() // Since we are at root, name is relPath
 
-		// If rule exists, respect it
-		if _, exists := cfg.Dirs[relPath]; exists {
+		// If rule exists, respect it. Folders holding workspace members
+		// leave them their own rules.
+		if _, exists := cfg.Dirs[relPath]; exists || holdsMember(relPath, members) {
 			continue
 		}
 
-		// Deep scan this specific folder to find all extensions used inside it
-		dirExtensions := deepScanExtensions(fullPath, root, ignoreMatcher)
+		// Ecosystem defaults disable well-known noise folders (e.g., node_modules)
+		if isEcosystemDisabledDir(relPath, ecosystems) {
+			cfg.Dirs[relPath] = DirRule{Enabled: false}
+			continue
+		}
+
+		// Other noise folders were never walked, and are left to the root
+		// rule's excludes, to exclude_dirs, and to the walker's pruning of
+		// vendored folders, which a rule of their own would turn off
+		if containsString(skipDirs, relPath) {
+			continue
+		}
 
-		// Create the rule
+		// Create the rule, covering every extension used inside the folder
 		cfg.Dirs[relPath] = DirRule{
 			Enabled:    true,
-			Extensions: dirExtensions,
+			Extensions: dirExtensions[relPath],
+			Exclude:    excludes,
 		}
 	}
 
-	return &cfg, nil}
+	// 3. Workspace members, wherever they are
+	for _, member := range members {
+		if _, exists := cfg.Dirs[member]; exists || ignoredOnTheWay(root, member, ignoreMatcher, skipDirs) {
+			continue
+		}
+		cfg.Dirs[member] = DirRule{
+			Enabled:    true,
+			Extensions: dirExtensions[member],
+			Exclude:    excludes,
+		}
+	}
 
-// deepScanExtensions recursively walks a directory to find all unique file extensions
-// visible (not ignored by git).
-func deepScanExtensions(startPath, rootPath string, matcher gitignore.IgnoreMatcher) []string {
-	extMap := make(map[string]bool)
+	return &cfg, nil
+}
 
-	filepath.WalkDir(startPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil // ignore errors
+// holdsMember reports whether one of the workspace members is inside the
+// directory relDir.
+func holdsMember(relDir string, members []string) bool {
+	for _, member := range members {
+		if strings.HasPrefix(member, relDir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredOnTheWay reports whether the member directory, or one it is in, is
+// gitignored or one of skipDirs, so the walk would never reach it.
+func ignoredOnTheWay(root, member string, matcher gitignore.IgnoreMatcher, skipDirs []string) bool {
+	parts := strings.Split(member, "/")
+	for i := range parts {
+		if containsString(skipDirs, parts[i]) || matcher.Match(filepath.Join(root, filepath.FromSlash(strings.Join(parts[:i+1], "/"))), true) {
+			return true
 		}
+	}
+	return false
+}
 
-		// Skip .git
-		if d.IsDir() && d.Name() == ".git" {
-			return filepath.SkipDir
+// scanExtensions walks the project once and returns the unique file extensions
+// visible (not ignored by git) in the whole project, and within each top-level
+// directory and each of the workspace members. Each entry is matched against
+// gitignore exactly once and ignored directories are pruned without visiting
+// their contents. Directories named in skipDirs, at any depth, are not
+// descended into at all.
+func scanExtensions(root string, matcher gitignore.IgnoreMatcher, skipDirs, members []string) ([]string, map[string][]string) {
+	rootSet := make(map[string]bool)
+	dirSets := make(map[string]map[string]bool)
+
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return nil // ignore errors
 		}
 
-		// Check Gitignore
-		if matcher.Match(path, d.IsDir()) {
-			if d.IsDir() {
+		rel, _ := filepath.Rel(root, path)
+		top, _, nested := strings.Cut(filepath.ToSlash(rel), "/")
+
+		if d.IsDir() {
+			// Skip .git and gitignored folders with their whole subtree
+			if d.Name() == ".git" || matcher.Match(path, true) {
 				return filepath.SkipDir
 			}
+			// Ecosystem noise folders are never walked; Discover gives them
+			// no rule with extensions
+			if containsString(skipDirs, d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if matcher.Match(path, false) {
+			return nil
+		}
+
+		ext := filepath.Ext(d.Name())
+		if len(ext) <= 1 {
 			return nil
 		}
+		cleanExt := strings.TrimPrefix(ext, ".")
 
-		if !d.IsDir() {
-			ext := filepath.Ext(d.Name())
-			if len(ext) > 1 {
-				cleanExt := strings.TrimPrefix(ext, ".")
-				extMap[cleanExt] = true
+		if nested {
+			addExt(dirSets, top, cleanExt)
+		}
+		for _, member := range members {
+			if strings.HasPrefix(filepath.ToSlash(rel), member+"/") {
+				addExt(dirSets, member, cleanExt)
 			}
 		}
+		rootSet[cleanExt] = true
 		return nil
 	})
 
-	var extensions []string
-	for ext := range extMap {
-		extensions = append(extensions, ext)
+	dirExtensions := make(map[string][]string, len(dirSets))
+	for dir, set := range dirSets {
+		dirExtensions[dir] = sortedKeys(set)
 	}
-	return extensions
+	return sortedKeys(rootSet), dirExtensions
 }
 
-// getIgnoreMatcher attempts to load .gitignore from the root path.
-func getIgnoreMatcher(root string) gitignore.IgnoreMatcher {
-	gitignorePath := filepath.Join(root, ".gitignore")
-	matcher, err := gitignore.NewGitIgnore(gitignorePath)
-	if err != nil {
-		return gitignore.NewGitIgnoreFromReader(root, strings.NewReader(""))
+// addExt adds ext to the set of dir.
+func addExt(sets map[string]map[string]bool, dir, ext string) {
+	if sets[dir] == nil {
+		sets[dir] = make(map[string]bool)
+	}
+	sets[dir][ext] = true
+}
+
+// sortedKeys returns the keys of a set in sorted order, so generated configs
+// are stable from run to run.
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
 	}
-	return matcher
+	return false
 }
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Ecosystem describes the sensible defaults for a language toolchain.
+type Ecosystem struct {
+	// Name is the human readable name of the ecosystem.
+	Name string
+
+	// Markers are files at the project root that identify the ecosystem.
+	Markers []string
+
+	// DisabledDirs are top-level directories that should be disabled.
+	DisabledDirs []string
+
+	// Exclude are patterns added to every generated rule (e.g., lockfiles,
+	// cache folders that can appear at any depth).
+	Exclude []string
+}
+
+// Ecosystems is the built-in registry of ecosystem defaults.
+var Ecosystems = []Ecosystem{
+	{
+		Name:         "Go",
+		Markers:      []string{"go.mod", "go.work"},
+		DisabledDirs: []string{"vendor"},
+		Exclude:      []string{"go.sum"},
+	},
+	{
+		Name:         "Node",
+		Markers:      []string{"package.json"},
+		DisabledDirs: []string{"node_modules", "dist", "build", "coverage"},
+		Exclude:      []string{"node_modules", "package-lock.json", "yarn.lock", "pnpm-lock.yaml"},
+	},
+	{
+		Name:         "Python",
+		Markers:      []string{"pyproject.toml", "requirements.txt", "setup.py"},
+		DisabledDirs: []string{".venv", "venv", "build", "dist"},
+		Exclude:      []string{"__pycache__", "*.pyc", "*.egg-info", "poetry.lock"},
+	},
+	{
+		Name:         "Rust",
+		Markers:      []string{"Cargo.toml"},
+		DisabledDirs: []string{"target"},
+		Exclude:      []string{"Cargo.lock"},
+	},
+}
+
+// DetectEcosystems returns the ecosystems whose marker files exist in root.
+func DetectEcosystems(root string) []Ecosystem {
+	var detected []Ecosystem
+	for _, eco := range Ecosystems {
+		for _, marker := range eco.Markers {
+			if _, err := os.Stat(filepath.Join(root, marker)); err == nil {
+				detected = append(detected, eco)
+				break
+			}
+		}
+	}
+	return detected
+}
+
+// isEcosystemDisabledDir reports whether a top-level directory should be
+// disabled by default for any of the detected ecosystems.
+func isEcosystemDisabledDir(name string, ecosystems []Ecosystem) bool {
+	for _, eco := range ecosystems {
+		for _, dir := range eco.DisabledDirs {
+			if dir == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ecosystemExcludes collects the exclude patterns of the detected ecosystems.
+func ecosystemExcludes(ecosystems []Ecosystem) []string {
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, eco := range ecosystems {
+		for _, p := range eco.Exclude {
+			if !seen[p] {
+				seen[p] = true
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a textify.yaml file loaded for editing. Changes are made to the
+// parsed YAML tree rather than to a Config, so comments and key order
+// survive a save.
+type Document struct {
+	root yaml.Node
+}
+
+// LoadDocument reads a config file for editing.
+func LoadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{}
+	if err := yaml.Unmarshal(data, &d.root); err != nil {
+		return nil, err
+	}
+	if d.root.Kind != yaml.DocumentNode || len(d.root.Content) == 0 || d.root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s is not a textify config", path)
+	}
+	return d, nil
+}
+
+// Config decodes the document into a Config.
+func (d *Document) Config() (*Config, error) {
+	var cfg Config
+	if err := d.root.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Dirs == nil {
+		cfg.Dirs = make(map[string]DirRule)
+	}
+	cfg.KeyWarnings = cfg.NormalizeDirKeys()
+	return &cfg, nil
+}
+
+// Save writes the document back to path.
+func (d *Document) Save(path string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	if err := enc.Encode(&d.root); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// AddPattern appends pattern to the list under key ("include" or "exclude")
+// of the rule for dir, creating the rule or list if needed. A new rule starts
+// as a copy of the rule dir inherited, so nothing else changes for it. It
+// reports false if the pattern was already there.
+func (d *Document) AddPattern(dir, key, pattern string) (bool, error) {
+	rule, err := d.ruleNode(dir)
+	if err != nil {
+		return false, err
+	}
+	list := mappingValue(rule, key)
+	if list == nil {
+		list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(rule, key, list)
+	}
+	for _, item := range list.Content {
+		if item.Value == pattern {
+			return false, nil
+		}
+	}
+	list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: pattern})
+	return true, nil
+}
+
+// SetEnabled sets the enabled flag of the rule for dir, creating the rule
+// if needed.
+func (d *Document) SetEnabled(dir string, enabled bool) error {
+	rule, err := d.ruleNode(dir)
+	if err != nil {
+		return err
+	}
+	setMappingValue(rule, "enabled", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(enabled)})
+	return nil
+}
+
+// RemoveRule deletes the rule for dir, reporting false if there was none.
+func (d *Document) RemoveRule(dir string) bool {
+	dirs := mappingValue(d.root.Content[0], "dirs")
+	if dirs == nil || dirs.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(dirs.Content); i += 2 {
+		if CleanDirKey(dirs.Content[i].Value) == dir {
+			dirs.Content = append(dirs.Content[:i], dirs.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ruleNode returns the mapping node of the rule for dir, creating it (and
+// the dirs section) if needed.
+func (d *Document) ruleNode(dir string) (*yaml.Node, error) {
+	top := d.root.Content[0]
+	dirs := mappingValue(top, "dirs")
+	if dirs == nil || dirs.Kind != yaml.MappingNode {
+		dirs = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(top, "dirs", dirs)
+	}
+	// The key may be written in another form, such as "./src" or "src\"
+	for i := 0; i+1 < len(dirs.Content); i += 2 {
+		if CleanDirKey(dirs.Content[i].Value) == dir {
+			return dirs.Content[i+1], nil
+		}
+	}
+
+	// Start from the inherited rule so only the requested change takes effect
+	cfg, err := d.Config()
+	if err != nil {
+		return nil, err
+	}
+	_, inherited := cfg.RuleFor(dir)
+	rule := &yaml.Node{}
+	if err := rule.Encode(inherited); err != nil {
+		return nil, err
+	}
+	setMappingValue(dirs, dir, rule)
+	return rule, nil
+}
+
+// mappingValue returns the value for key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value for key in a mapping node, or appends
+// the key if it is missing.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// RuleFor returns the rule in effect for a directory (slash-separated,
+// relative to the root) and the key it comes from: the directory's own rule,
+// or else the nearest ancestor's, as the walker applies them.
+func (c *Config) RuleFor(dir string) (string, DirRule) {
+	for dir != "." && dir != "" {
+		if rule, ok := c.Dirs[dir]; ok {
+			return dir, rule
+		}
+		dir = path.Dir(dir)
+	}
+	if rule, ok := c.Dirs["."]; ok {
+		return ".", rule
+	}
+	// The walker's default when there is no root rule
+	return ".", DirRule{Enabled: true}
+}
+
+// PatternDir returns the directory whose entries a slash-separated pattern
+// targets: the directory part of its literal (non-glob) prefix, ignoring a
+// leading or trailing slash. For example, "internal/testdata/*" gives
+// "internal/testdata", while "internal/testdata" and "internal/testdata/"
+// give "internal", and "*.log" and "/build" give ".".
+func PatternDir(pattern string) string {
+	literal := strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "/")
+	if i := strings.IndexAny(literal, "*?["); i >= 0 {
+		literal = literal[:i]
+	}
+	return path.Dir(literal)
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDocumentEditsKeepComments(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "edit_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "textify.yaml")
+	original := `# my notes about this config
+output_file: out.txt # where it goes
+dirs:
+    .:
+        enabled: true
+        extensions: [go]
+    # the web app
+    web:
+        enabled: true
+        extensions: [js]
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := LoadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added, err := doc.AddPattern("web", "exclude", "web/dist/*"); err != nil || !added {
+		t.Fatalf("AddPattern failed: %v (added %v)", err, added)
+	}
+	if added, _ := doc.AddPattern("web", "exclude", "web/dist/*"); added {
+		t.Error("Expected a duplicate pattern not to be added again")
+	}
+	// A new rule starts from the rule it used to inherit
+	if err := doc.SetEnabled("web/legacy", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetEnabled("old", false); err != nil {
+		t.Fatal(err)
+	}
+	if !doc.RemoveRule("old") || doc.RemoveRule("old") {
+		t.Error("Expected RemoveRule to remove the rule exactly once")
+	}
+	if err := doc.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, comment := range []string{"# my notes about this config", "# where it goes", "# the web app"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("Expected comment %q to survive, got:\n%s", comment, data)
+		}
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]DirRule{
+		".":          {Enabled: true, Extensions: []string{"go"}},
+		"web":        {Enabled: true, Extensions: []string{"js"}, Exclude: []string{"web/dist/*"}},
+		"web/legacy": {Enabled: false, Extensions: []string{"js"}, Exclude: []string{"web/dist/*"}},
+	}
+	if !reflect.DeepEqual(cfg.Dirs, expected) {
+		t.Errorf("Unexpected rules.\nExpected: %+v\nGot:      %+v", expected, cfg.Dirs)
+	}
+}
+
+func TestRuleForAndPatternDir(t *testing.T) {
+	cfg := &Config{Dirs: map[string]DirRule{
+		".":        {Enabled: true},
+		"internal": {Enabled: false},
+	}}
+	cases := []struct {
+		pattern, dir, rule string
+	}{
+		{"*.log", ".", "."},
+		{".env.example", ".", "."},
+		{"internal", ".", "."},
+		{"internal/testdata/*", "internal/testdata", "internal"},
+		{"internal/testdata/", "internal", "internal"},
+		{"/build", ".", "."},
+		{"**/cache/", ".", "."},
+		{"cmd/app/*_test.go", "cmd/app", "."},
+	}
+	for _, c := range cases {
+		dir := PatternDir(c.pattern)
+		rule, _ := cfg.RuleFor(dir)
+		if dir != c.dir || rule != c.rule {
+			t.Errorf("%q: expected dir %q and rule %q, got %q and %q", c.pattern, c.dir, c.rule, dir, rule)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// groupPrefix marks an entry of an extension list as a group reference,
+// e.g. "$web".
+const groupPrefix = "$"
+
+// builtinExtensionGroups are the extension groups available in every config.
+// A group of the same name in extension_groups replaces the built-in one.
+var builtinExtensionGroups = map[string][]string{
+	"web":    {"js", "jsx", "mjs", "cjs", "ts", "tsx", "css", "scss", "sass", "less", "html", "htm", "vue", "svelte"},
+	"go":     {"go", "mod", "sum"},
+	"docs":   {"md", "mdx", "rst", "adoc", "txt"},
+	"config": {"json", "yaml", "yml", "toml", "ini"},
+}
+
+// ExpandExtensionGroups replaces group references ("$web") in every rule's
+// extensions and exclude_extensions with the extensions they stand for,
+// dropping duplicates. It fails on the first unknown group.
+func (c *Config) ExpandExtensionGroups() error {
+	dirs, problems := c.expandedDirs()
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", problems[0])
+	}
+	c.Dirs = dirs
+	return nil
+}
+
+// expandedDirs returns a copy of the rules with extension groups expanded,
+// along with a problem for every unknown or malformed group, in a stable order.
+func (c *Config) expandedDirs() (map[string]DirRule, []string) {
+	groups := make(map[string][]string, len(builtinExtensionGroups)+len(c.ExtensionGroups))
+	for name, exts := range builtinExtensionGroups {
+		groups[name] = exts
+	}
+	names := make([]string, 0, len(c.ExtensionGroups))
+	for name := range c.ExtensionGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []string
+	for _, name := range names {
+		exts := c.ExtensionGroups[name]
+		for _, ext := range exts {
+			if strings.HasPrefix(ext, groupPrefix) {
+				problems = append(problems, fmt.Sprintf("extension_groups[%q]: groups can't include other groups (%s)", name, ext))
+			}
+		}
+		groups[strings.TrimPrefix(name, groupPrefix)] = exts
+	}
+
+	keys := make([]string, 0, len(c.Dirs))
+	for dir := range c.Dirs {
+		keys = append(keys, dir)
+	}
+	sort.Strings(keys)
+	dirs := make(map[string]DirRule, len(c.Dirs))
+	for _, dir := range keys {
+		rule := c.Dirs[dir]
+		var unknown []string
+		rule.Extensions, unknown = expandGroups(rule.Extensions, groups)
+		for _, name := range unknown {
+			problems = append(problems, fmt.Sprintf("dirs[%q].extensions: unknown extension group %s%s (known: %s)", dir, groupPrefix, name, knownGroups(groups)))
+		}
+		rule.ExcludeExtensions, unknown = expandGroups(rule.ExcludeExtensions, groups)
+		for _, name := range unknown {
+			problems = append(problems, fmt.Sprintf("dirs[%q].exclude_extensions: unknown extension group %s%s (known: %s)", dir, groupPrefix, name, knownGroups(groups)))
+		}
+		dirs[dir] = rule
+	}
+	return dirs, problems
+}
+
+// expandGroups expands the group references in exts, returning the result
+// and the names of any groups that don't exist. Lists without references are
+// returned as they are.
+func expandGroups(exts []string, groups map[string][]string) ([]string, []string) {
+	hasGroup := false
+	for _, ext := range exts {
+		hasGroup = hasGroup || strings.HasPrefix(ext, groupPrefix)
+	}
+	if !hasGroup {
+		return exts, nil
+	}
+
+	var out, unknown []string
+	seen := make(map[string]bool)
+	add := func(ext string) {
+		if !seen[ext] {
+			seen[ext] = true
+			out = append(out, ext)
+		}
+	}
+	for _, ext := range exts {
+		if !strings.HasPrefix(ext, groupPrefix) {
+			add(ext)
+			continue
+		}
+		name := strings.TrimPrefix(ext, groupPrefix)
+		members, ok := groups[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		for _, member := range members {
+			add(member)
+		}
+	}
+	return out, unknown
+}
+
+// knownGroups lists the group names for error messages, e.g. "$docs, $go".
+func knownGroups(groups map[string][]string) string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, groupPrefix+name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandExtensionGroups(t *testing.T) {
+	cfg := &Config{
+		ExtensionGroups: map[string][]string{
+			"schema": {"proto", "graphql"},
+			"go":     {"go"},
+		},
+		Dirs: map[string]DirRule{
+			".":   {Enabled: true, Extensions: []string{"md", "$schema", "proto"}, ExcludeExtensions: []string{"$docs"}},
+			"cmd": {Enabled: true, Extensions: []string{"$go", "sh"}},
+			"web": {Enabled: true, Extensions: []string{"js"}},
+		},
+	}
+	if err := cfg.ExpandExtensionGroups(); err != nil {
+		t.Fatalf("ExpandExtensionGroups failed: %v", err)
+	}
+
+	expected := map[string]DirRule{
+		".":   {Enabled: true, Extensions: []string{"md", "proto", "graphql"}, ExcludeExtensions: []string{"md", "mdx", "rst", "adoc", "txt"}},
+		"cmd": {Enabled: true, Extensions: []string{"go", "sh"}},
+		"web": {Enabled: true, Extensions: []string{"js"}},
+	}
+	if !reflect.DeepEqual(cfg.Dirs, expected) {
+		t.Errorf("Unexpected rules.\nExpected: %+v\nGot:      %+v", expected, cfg.Dirs)
+	}
+}
+
+func TestUnknownExtensionGroup(t *testing.T) {
+	cfg := &Config{
+		OutputFile: "out.txt",
+		Dirs:       map[string]DirRule{"src": {Enabled: true, Extensions: []string{"$wbe"}}},
+	}
+	err := cfg.ExpandExtensionGroups()
+	if err == nil {
+		t.Fatal("Expected an unknown group to fail")
+	}
+	expected := `dirs["src"].extensions: unknown extension group $wbe (known: $config, $docs, $go, $web)`
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+	if problems := cfg.Check(); !reflect.DeepEqual(problems, []string{expected}) {
+		t.Errorf("Expected Check to report the unknown group, got %q", problems)
+	}
+}
+
+func TestLoadRawKeepsGroups(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "config_test_groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "textify.yaml")
+	data := "output_file: out.txt\ndirs:\n  .:\n    enabled: true\n    extensions: [$go]\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := raw.Dirs["."].Extensions; !reflect.DeepEqual(got, []string{"$go"}) {
+		t.Errorf("Expected LoadRaw to keep the reference, got %q", got)
+	}
+
+	// Saving a raw config writes the reference back, not its expansion
+	if err := raw.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := os.ReadFile(path)
+	if !strings.Contains(string(saved), "$go") {
+		t.Errorf("Expected the saved config to keep $go:\n%s", saved)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Dirs["."].Extensions; !reflect.DeepEqual(got, []string{"go", "mod", "sum"}) {
+		t.Errorf("Expected Load to expand $go, got %q", got)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// builtinLanguages maps the language names accepted by Config.Languages to
+// the file extensions they are written in.
+var builtinLanguages = map[string][]string{
+	"c":          {"c", "h"},
+	"cpp":        {"cpp", "cc", "cxx", "c++", "hpp", "hh", "hxx", "h"},
+	"csharp":     {"cs", "csx"},
+	"css":        {"css", "scss", "sass", "less"},
+	"dart":       {"dart"},
+	"elixir":     {"ex", "exs"},
+	"erlang":     {"erl", "hrl"},
+	"go":         {"go"},
+	"haskell":    {"hs", "lhs"},
+	"html":       {"html", "htm"},
+	"java":       {"java"},
+	"javascript": {"js", "jsx", "mjs", "cjs"},
+	"json":       {"json", "jsonc"},
+	"kotlin":     {"kt", "kts"},
+	"lua":        {"lua"},
+	"markdown":   {"md", "mdx", "markdown"},
+	"php":        {"php"},
+	"protobuf":   {"proto"},
+	"python":     {"py", "pyi", "pyw"},
+	"r":          {"r", "rmd"},
+	"ruby":       {"rb", "rake", "gemspec"},
+	"rust":       {"rs"},
+	"scala":      {"scala", "sc"},
+	"shell":      {"sh", "bash", "zsh"},
+	"sql":        {"sql"},
+	"svelte":     {"svelte"},
+	"swift":      {"swift"},
+	"terraform":  {"tf", "tfvars"},
+	"toml":       {"toml"},
+	"typescript": {"ts", "tsx", "mts", "cts"},
+	"vue":        {"vue"},
+	"yaml":       {"yaml", "yml"},
+}
+
+// languageByExtension maps each extension of builtinLanguages to its
+// language. Extensions of several languages (h) go to the first by name.
+var languageByExtension = func() map[string]string {
+	names := make([]string, 0, len(builtinLanguages))
+	for name := range builtinLanguages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	byExt := make(map[string]string)
+	for _, name := range names {
+		for _, ext := range builtinLanguages[name] {
+			if _, ok := byExt[ext]; !ok {
+				byExt[ext] = name
+			}
+		}
+	}
+	return byExt
+}()
+
+// LanguageOf returns the language a file extension (without the dot) is
+// written in, or "" if it is none of the known languages.
+func LanguageOf(ext string) string {
+	return languageByExtension[strings.ToLower(ext)]
+}
+
+// LanguageExtensions returns the set of extensions, lowercase, of the
+// configured languages, or nil when none are set. Names are case-insensitive.
+func (c *Config) LanguageExtensions() (map[string]bool, error) {
+	if len(c.Languages) == 0 {
+		return nil, nil
+	}
+	exts := make(map[string]bool)
+	for _, name := range c.Languages {
+		members, ok := builtinLanguages[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown language %q (known: %s)", name, knownLanguages())
+		}
+		for _, ext := range members {
+			exts[ext] = true
+		}
+	}
+	return exts, nil
+}
+
+// knownLanguages lists the language names for error messages.
+func knownLanguages() string {
+	names := make([]string, 0, len(builtinLanguages))
+	for name := range builtinLanguages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+)
+
+// Placeholders accepted in output file names, such as
+// "snapshots/{repo}-{date}.txt". ExpandOutputNames replaces them at the start
+// of a run.
+const (
+	// PlaceholderRepo is the name of the project's root folder.
+	PlaceholderRepo = "{repo}"
+
+	// PlaceholderDate is the day of the run, as 2006-01-02.
+	PlaceholderDate = "{date}"
+
+	// PlaceholderTime is the time of the run, as 150405 (no colons, which
+	// Windows doesn't allow in file names).
+	PlaceholderTime = "{time}"
+
+	// PlaceholderBranch is the current git branch, with slashes turned into
+	// dashes, or the commit hash when HEAD is detached.
+	PlaceholderBranch = "{branch}"
+)
+
+// outputPlaceholders lists every placeholder, in the order they are
+// expanded.
+var outputPlaceholders = []string{PlaceholderRepo, PlaceholderDate, PlaceholderTime, PlaceholderBranch}
+
+// placeholderGlobs match what each placeholder can expand to.
+var placeholderGlobs = map[string]string{
+	PlaceholderRepo:   "*",
+	PlaceholderDate:   "[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]",
+	PlaceholderTime:   "[0-9][0-9][0-9][0-9][0-9][0-9]",
+	PlaceholderBranch: "*",
+}
+
+// HasPlaceholders reports whether an output file name holds any of the
+// placeholders.
+func HasPlaceholders(name string) bool {
+	for _, p := range outputPlaceholders {
+		if strings.Contains(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandOutputName replaces the placeholders in name with what value returns
+// for them. value is only called for the placeholders name holds. Anything
+// else in braces is kept as written.
+func ExpandOutputName(name string, value func(placeholder string) (string, error)) (string, error) {
+	for _, p := range outputPlaceholders {
+		if !strings.Contains(name, p) {
+			continue
+		}
+		v, err := value(p)
+		if err != nil {
+			return "", err
+		}
+		name = strings.ReplaceAll(name, p, strings.ReplaceAll(v, "/", "-"))
+	}
+	return name, nil
+}
+
+// ExpandOutputNames expands the placeholders of output_file, of the rules'
+// output_file, and of the outputs list (see ExpandOutputName). The names as
+// written are kept, so that the files they named on earlier runs are still
+// skipped; see EffectiveSystemExcludes.
+func (c *Config) ExpandOutputNames(value func(placeholder string) (string, error)) error {
+	expand := func(name *string) error {
+		if !HasPlaceholders(*name) {
+			return nil
+		}
+		expanded, err := ExpandOutputName(*name, value)
+		if err != nil {
+			return err
+		}
+		c.outputTemplates = append(c.outputTemplates, *name)
+		*name = expanded
+		return nil
+	}
+
+	if err := expand(&c.OutputFile); err != nil {
+		return err
+	}
+	for key, rule := range c.Dirs {
+		if err := expand(&rule.OutputFile); err != nil {
+			return err
+		}
+		c.Dirs[key] = rule
+	}
+	for i := range c.Outputs {
+		if err := expand(&c.Outputs[i].File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputTemplateExcludes returns the system excludes that skip every file
+// the templated output names could have written, their checksums, and their
+// keep_previous versions: each placeholder becomes a glob of what it expands
+// to (see placeholderGlobs), in a pattern anchored at the root. Names outside
+// the project need none.
+func (c *Config) outputTemplateExcludes() []string {
+	var excludes []string
+	for _, name := range append(c.OutputFiles(), c.outputTemplates...) {
+		if !HasPlaceholders(name) || filepath.IsAbs(name) {
+			continue
+		}
+		pattern := path.Clean(filepath.ToSlash(name))
+		if pattern == ".." || strings.HasPrefix(pattern, "../") {
+			continue
+		}
+		for _, p := range outputPlaceholders {
+			pattern = strings.ReplaceAll(pattern, p, placeholderGlobs[p])
+		}
+		excludes = append(excludes, "/"+pattern, "/"+pattern+".sha256")
+		for n := 1; n <= c.KeepPrevious; n++ {
+			excludes = append(excludes, "/"+fileutil.RotatedName(pattern, n))
+		}
+	}
+	return excludes
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/tokens"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaFile is the JSON Schema written next to textify.yaml for editors.
+const SchemaFile = "textify.schema.json"
+
+// schemaModeline points YAML-aware editors at the schema file.
+const schemaModeline = "# yaml-language-server: $schema=" + SchemaFile + "\n"
+
+// Schema is the subset of JSON Schema used to describe the config.
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+
+	// AdditionalProperties is false for structs, so unknown (e.g., misspelled)
+	// keys are reported, and the value schema for maps.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+}
+
+// schemaEnums lists the allowed values of string keys that take a fixed set.
+var schemaEnums = map[string][]string{
+	"format":         {FormatText, FormatMarkdownDoc, FormatJSON, FormatIndex, FormatHTML},
+	"group_by":       {GroupByLanguage, GroupByExtension},
+	"ignore_sources": {IgnoreGitignore, IgnoreDockerignore, IgnoreTextifyignore},
+	"order":          {OrderPath, OrderGitHot},
+	"token_families": tokens.FamilyNames(),
+	"tree_mode":      {TreeModeIncluded, TreeModeAll},
+	"tree_sort":      {TreeSortLexicographic, TreeSortNatural},
+}
+
+// ruleSchemaEnums is schemaEnums for the keys of a DirRule, which take fewer
+// values than the top-level keys of the same name.
+var ruleSchemaEnums = map[string][]string{
+	"format": {FormatText, FormatMarkdownDoc},
+}
+
+// outputDocs describe the keys of an outputs entry, which have no lines of
+// their own in keyDocs.
+var outputDocs = map[string]string{
+	"file":   "The file to write, relative to root; it is never scanned.",
+	"format": "The format of this file: 'text' (default), 'markdown-doc', 'json', 'index', or 'html', as for the top-level format.",
+}
+
+// GenerateSchema builds the JSON Schema for textify.yaml from the Config and
+// DirRule structs, with descriptions taken from keyDocs, so the
+// schema always matches what Load accepts.
+func GenerateSchema() *Schema {
+	topDocs, ruleDocs := headerDocs()
+	s := structSchema(reflect.TypeOf(Config{}), topDocs, ruleDocs)
+	s.Schema = "http://json-schema.org/draft-07/schema#"
+	s.Title = "Textify configuration"
+	return s
+}
+
+// WriteSchema writes the generated schema as indented JSON to path.
+func WriteSchema(path string) error {
+	data, err := json.MarshalIndent(GenerateSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// structSchema describes a struct type by its yaml-tagged fields.
+func structSchema(t reflect.Type, docs, ruleDocs map[string]string) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+	enums := schemaEnums
+	if t == reflect.TypeOf(DirRule{}) {
+		enums = ruleSchemaEnums
+	}
+	for i := 0; i < t.NumField(); i++ {
+		name := yamlName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		prop := typeSchema(t.Field(i).Type, ruleDocs)
+		prop.Description = docs[name]
+		if prop.Type == "array" {
+			prop.Items.Enum = enums[name]
+		} else {
+			prop.Enum = enums[name]
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+// typeSchema describes a field type. Nested structs use the rule
+// descriptions, which describe DirRules, except for outputs entries, which
+// use outputDocs.
+func typeSchema(t reflect.Type, ruleDocs map[string]string) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), ruleDocs)
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem(), ruleDocs)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(t.Elem(), ruleDocs)}
+	case reflect.Struct:
+		if t == reflect.TypeOf(OutputSpec{}) {
+			return structSchema(t, outputDocs, ruleDocs)
+		}
+		return structSchema(t, ruleDocs, ruleDocs)
+	}
+	panic(fmt.Sprintf("config: no schema for type %s", t))
+}
+
+// yamlName returns the YAML key of a struct field, or "" if it has none.
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// headerDocLine matches "# key: description" lines in keyDocs.
+var headerDocLine = regexp.MustCompile(`^#\s+([a-z0-9_]+):\s+(?:\((?:optional|[a-z\[\]]+)\)\s+)?(.+)$`)
+
+// headerDocs extracts the top-level and rule key descriptions from
+// keyDocs.
+func headerDocs() (top, rule map[string]string) {
+	top, rule = make(map[string]string), make(map[string]string)
+	docs := top
+	for _, line := range strings.Split(keyDocs, "\n") {
+		if strings.HasPrefix(line, "# Rule Options:") {
+			docs = rule
+			continue
+		}
+		if m := headerDocLine.FindStringSubmatch(line); m != nil {
+			docs[m[1]] = m[2]
+		}
+	}
+	return top, rule
+}
+
+// ValidateSchema checks raw textify.yaml content against the generated schema
+// and returns a description of every violation, in a stable order.
+func ValidateSchema(data []byte) ([]string, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var problems []string
+	validateValue(GenerateSchema(), doc, "", &problems)
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// validateValue appends the schema violations of value, found at path, to problems.
+func validateValue(s *Schema, value interface{}, path string, problems *[]string) {
+	// Empty values (e.g. "dirs:" with nothing under it) are the same as omitted
+	if value == nil {
+		return
+	}
+	where := path
+	if where == "" {
+		where = "(root)"
+	}
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, where+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch s.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("expected true or false, got %v", value)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("expected a string, got %v", value)
+			return
+		}
+		if len(s.Enum) > 0 && !containsString(s.Enum, str) {
+			fail("must be one of %s, got %q", strings.Join(s.Enum, ", "), str)
+		}
+	case "integer":
+		if _, ok := value.(int); !ok {
+			fail("expected an integer, got %v", value)
+		}
+	case "number":
+		switch value.(type) {
+		case int, float64:
+		default:
+			fail("expected a number, got %v", value)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("expected a list, got %v", value)
+			return
+		}
+		for i, item := range items {
+			validateValue(s.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "object":
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			fail("expected a mapping, got %v", value)
+			return
+		}
+		for key, v := range fields {
+			if prop, ok := s.Properties[key]; ok {
+				child := key
+				if path != "" {
+					child = path + "." + key
+				}
+				validateValue(prop, v, child, problems)
+				continue
+			}
+			// Map keys (directory paths) may contain dots, so they are quoted
+			if extra, ok := s.AdditionalProperties.(*Schema); ok {
+				validateValue(extra, v, fmt.Sprintf("%s[%q]", path, key), problems)
+				continue
+			}
+			fail("unknown key %q", key)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaDescribesEveryKey(t *testing.T) {
+	s := GenerateSchema()
+	rule := s.Properties["dirs"].AdditionalProperties.(*Schema)
+
+	// A key missing from keyDocs would ship without documentation
+	for name, prop := range s.Properties {
+		if prop.Description == "" {
+			t.Errorf("Top-level key %q has no description in keyDocs", name)
+		}
+	}
+	for name, prop := range rule.Properties {
+		if prop.Description == "" {
+			t.Errorf("Rule key %q has no description in keyDocs", name)
+		}
+	}
+
+	output := s.Properties["outputs"].Items
+	for name, prop := range output.Properties {
+		if prop.Description != outputDocs[name] || prop.Description == "" {
+			t.Errorf("outputs key %q has description %q, want its own", name, prop.Description)
+		}
+	}
+
+	if got := s.Properties["max_depth"].Type; got != "integer" {
+		t.Errorf("Expected max_depth to be an integer, got %q", got)
+	}
+	if got := rule.Properties["extensions"].Items.Type; got != "string" {
+		t.Errorf("Expected extensions to be a list of strings, got %q", got)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	data := []byte(`
+output_file: out.txt
+ordr: path
+order: hot
+encoded_data_fraction: 1
+dirs:
+  .:
+    enabled: true
+    extensions: go
+  src:
+    enabled: true
+    max_depth: deep
+    exclude: [a, 1]
+    format: json
+`)
+	problems, err := ValidateSchema(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`(root): unknown key "ordr"`,
+		`dirs["."].extensions: expected a list, got go`,
+		`dirs["src"].exclude[1]: expected a string, got 1`,
+		`dirs["src"].format: must be one of text, markdown-doc, got "json"`,
+		`dirs["src"].max_depth: expected an integer, got deep`,
+		`order: must be one of path, git-hot, got "hot"`,
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Unexpected problems.\nExpected: %q\nGot:      %q", expected, problems)
+	}
+
+	// A saved default config is always valid
+	cfg := DefaultConfig()
+	cfg.Dirs["."] = DirRule{Enabled: true, Extensions: []string{"go"}}
+	tempDir, err := os.MkdirTemp("", "schema_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "textify.yaml")
+	if err := cfg.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := ValidateSchema(saved); err != nil || len(problems) > 0 {
+		t.Errorf("Expected a saved config to validate, got %q (%v)", problems, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	negative := -1
+	cfg := &Config{
+		OutputFile:        "out.txt",
+		TreeSort:          "alpha",
+		Order:             "hot",
+		GroupBy:           "lang",
+		IgnoreSources:     []string{"gitignore", "npmignore", "gitignore"},
+		Languages:         []string{"go", "klingon"},
+		FileGap:           &negative,
+		MaxLineBytes:      -1,
+		KeepPrevious:      -1,
+		AlwaysIncludeDirs: []string{"docs", "../shared"},
+		TokenFamilies:     []string{"cl100k_base", "p50k_base", "cl100k_base"},
+		Costs:             map[string]string{"gpt-4o-input": "2.50per1M", "free": "", "cheap": "a lot"},
+		Dirs: map[string]DirRule{
+			"src": {Enabled: true, ContentIncludeRegex: "(", MaxDepth: &negative, Format: FormatJSON},
+		},
+	}
+	expected := []string{
+		`tree_sort: unknown tree sort "alpha"`,
+		`order: unknown order "hot"`,
+		`group_by: unknown grouping "lang"`,
+		`ignore_sources: unknown ignore source "npmignore"`,
+		"ignore_sources: gitignore is listed twice",
+		`languages: unknown language "klingon" (known: ` + knownLanguages() + `)`,
+		"file_gap: must not be negative",
+		"max_line_bytes: must not be negative",
+		"keep_previous: must not be negative",
+		`costs["cheap"]: invalid price "a lot": want an amount per a number of tokens, e.g. 2.50per1M`,
+		`token_families: unknown family "p50k_base" (known: cl100k_base, o200k_base, chars/4)`,
+		"token_families: cl100k_base is listed twice",
+		`always_include_dirs: "../shared" is not a directory inside the project`,
+		"dirs[\"src\"]: invalid content regex: error parsing regexp: missing closing ): `(`",
+		`dirs["src"].max_depth: must not be negative`,
+		`dirs["src"].format: must be "text" or "markdown-doc", not "json"`,
+	}
+	if problems := cfg.Check(); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Unexpected problems.\nExpected: %q\nGot:      %q", expected, problems)
+	}
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workspace is a monorepo workspace declared at the project root.
+type Workspace struct {
+	// File is the file that declares it: package.json, pnpm-workspace.yaml,
+	// or go.work.
+	File string
+
+	// Members are the member packages or modules, relative to the root with
+	// forward slashes, sorted.
+	Members []string
+}
+
+// workspaceSkipDirs are never searched for members, whatever the globs say.
+var workspaceSkipDirs = []string{".git", "node_modules"}
+
+// DetectWorkspaces returns the workspaces declared at root, with their
+// globs resolved to the member directories that exist: JS workspaces (the
+// workspaces of package.json, the packages of pnpm-workspace.yaml), whose
+// members must hold a package.json, and the modules a go.work uses.
+func DetectWorkspaces(root string) []Workspace {
+	var workspaces []Workspace
+	add := func(file string, members []string) {
+		if len(members) > 0 {
+			workspaces = append(workspaces, Workspace{File: file, Members: members})
+		}
+	}
+	if patterns := packageJSONWorkspaces(root); patterns != nil {
+		add("package.json", resolveWorkspaceGlobs(root, patterns, "package.json"))
+	}
+	if patterns := pnpmWorkspaces(root); patterns != nil {
+		add("pnpm-workspace.yaml", resolveWorkspaceGlobs(root, patterns, "package.json"))
+	}
+	if dirs := goWorkModules(root); dirs != nil {
+		add("go.work", resolveWorkspaceGlobs(root, dirs, ""))
+	}
+	return workspaces
+}
+
+// workspaceMembers returns the members of all the workspaces, sorted and
+// without duplicates.
+func workspaceMembers(workspaces []Workspace) []string {
+	set := make(map[string]bool)
+	for _, ws := range workspaces {
+		for _, member := range ws.Members {
+			set[member] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+// packageJSONWorkspaces returns the workspaces globs of the root
+// package.json: a list, or the packages of an object as Yarn allows.
+func packageJSONWorkspaces(root string) []string {
+	data, err := os.ReadFile(filepath.Join(root, "package.json"))
+	if err != nil {
+		return nil
+	}
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal(data, &manifest) != nil || manifest.Workspaces == nil {
+		return nil
+	}
+	var list []string
+	if json.Unmarshal(manifest.Workspaces, &list) == nil {
+		return list
+	}
+	var object struct {
+		Packages []string `json:"packages"`
+	}
+	if json.Unmarshal(manifest.Workspaces, &object) == nil {
+		return object.Packages
+	}
+	return nil
+}
+
+// pnpmWorkspaces returns the packages globs of pnpm-workspace.yaml.
+func pnpmWorkspaces(root string) []string {
+	data, err := os.ReadFile(filepath.Join(root, "pnpm-workspace.yaml"))
+	if err != nil {
+		return nil
+	}
+	var manifest struct {
+		Packages []string `yaml:"packages"`
+	}
+	if yaml.Unmarshal(data, &manifest) != nil {
+		return nil
+	}
+	return manifest.Packages
+}
+
+// goWorkModules returns the directories of the use directives of go.work,
+// in either form: "use ./api" or a parenthesized block.
+func goWorkModules(root string) []string {
+	file, err := os.Open(filepath.Join(root, "go.work"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var dirs []string
+	inUse := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inUse && fields[0] == ")":
+			inUse = false
+		case inUse:
+			dirs = append(dirs, strings.Trim(fields[0], `"`))
+		case fields[0] == "use" && len(fields) > 1 && fields[1] == "(":
+			inUse = true
+		case fields[0] == "use" && len(fields) > 1:
+			dirs = append(dirs, strings.Trim(fields[1], `"`))
+		}
+	}
+	return dirs
+}
+
+// resolveWorkspaceGlobs expands workspace globs to the directories they
+// match under root, relative with forward slashes and sorted. "**" matches
+// any number of directories, and globs starting with "!" take matches out
+// again. With marker set, only directories holding that file count. The
+// root itself and directories outside it are never members.
+func resolveWorkspaceGlobs(root string, patterns []string, marker string) []string {
+	set := make(map[string]bool)
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		p = path.Clean(filepath.ToSlash(strings.TrimPrefix(p, "!")))
+		if p == "." || p == ".." || strings.HasPrefix(p, "../") || path.IsAbs(p) {
+			continue
+		}
+		for _, dir := range globDirs(root, strings.Split(p, "/")) {
+			if marker != "" {
+				if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(dir), marker)); err != nil {
+					continue
+				}
+			}
+			if negated {
+				delete(set, dir)
+			} else {
+				set[dir] = true
+			}
+		}
+	}
+	return sortedKeys(set)
+}
+
+// globDirs returns the directories under root matching the glob segments.
+func globDirs(root string, segments []string) []string {
+	matches := []string{"."}
+	for _, segment := range segments {
+		var next []string
+		for _, dir := range matches {
+			if segment == "**" {
+				next = append(next, subdirsDeep(root, dir)...)
+				continue
+			}
+			for _, name := range subdirs(root, dir) {
+				if ok, _ := path.Match(segment, name); ok {
+					next = append(next, path.Join(dir, name))
+				}
+			}
+		}
+		matches = next
+	}
+	var dirs []string
+	for _, dir := range matches {
+		if dir != "." {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// subdirs returns the names of the directories in dir that members may be
+// in.
+func subdirs(root, dir string) []string {
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && !containsString(workspaceSkipDirs, entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// subdirsDeep returns dir and every directory below it that members may be
+// in, for "**".
+func subdirsDeep(root, dir string) []string {
+	dirs := []string{dir}
+	for _, name := range subdirs(root, dir) {
+		dirs = append(dirs, subdirsDeep(root, path.Join(dir, name))...)
+	}
+	return dirs
+}
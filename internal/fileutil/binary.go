@@ -1,44 +1,216 @@
 package fileutil
 
 import (
+	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"unicode/utf8"
 )
 
+// Kinds of content reported by Sniff.
+const (
+	// Text is valid UTF-8 without NUL bytes.
+	Text = iota
+
+	// Binary content has NUL bytes, as binary formats do.
+	Binary
+
+	// LegacyText has no NUL bytes but isn't valid UTF-8, like source files
+	// saved in Latin-1 or Windows-1252.
+	LegacyText
+)
+
 // IsBinary checks if a file is binary by reading its first 512 bytes.
 // It looks for NUL bytes or invalid UTF-8 sequences.
 func IsBinary(path string) (bool, error) {
+	kind, _, err := Sniff(path)
+	return kind != Text, err
+}
+
+// IsBinarySampled is IsBinary for files that may be text at the start and
+// binary further in; see SniffSampled.
+func IsBinarySampled(path string) (bool, error) {
+	kind, _, err := SniffSampled(path)
+	return kind != Text, err
+}
+
+// Sniff reads the first 512 bytes of a file once and reports both what kind
+// of content it holds and its content type (as ContentType).
+func Sniff(path string) (kind int, mime string, err error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return false, err
+		return Text, "", err
 	}
 	defer file.Close()
+	return SniffReader(file, path)
+}
 
+// SniffReader is Sniff for content read from r, of a file named name.
+func SniffReader(r io.Reader, name string) (kind int, mime string, err error) {
 	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
+	n, err := r.Read(buffer)
 	if err != nil && err != io.EOF {
-		return false, err
+		return Text, "", err
 	}
+	content := buffer[:n]
+	return contentKind(content, n == len(buffer)), ContentType(content, name), nil
+}
 
-	// Empty files are treated as text
-	if n == 0 {
-		return false, nil
+// sampleWindow is the size of each window SniffSampled reads.
+const sampleWindow = 512
+
+// SniffSampled is Sniff for files whose head may not speak for the rest,
+// such as text logs with binary records appended. Besides the first 512
+// bytes it reads windows at 25%, 50%, and 75% of the file and at its end, so
+// mixed files are caught without reading them whole. The file is Binary if
+// any window holds a NUL byte, otherwise LegacyText if any holds invalid
+// UTF-8. The content type comes from the head alone.
+func SniffSampled(path string) (kind int, mime string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Text, "", err
 	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return Text, "", err
+	}
+	return SniffSampledReader(file, info.Size(), path)
+}
 
-	content := buffer[:n]
+// SniffSampledReader is SniffSampled for the size bytes of content read from
+// file, of a file named name.
+func SniffSampledReader(file io.ReaderAt, size int64, name string) (kind int, mime string, err error) {
+	buffer := make([]byte, sampleWindow)
+	n, err := file.ReadAt(buffer, 0)
+	if err != nil && err != io.EOF {
+		return Text, "", err
+	}
+	kind = contentKind(buffer[:n], int64(n) < size)
+	mime = ContentType(buffer[:n], name)
+	if kind == Binary || size <= sampleWindow {
+		return kind, mime, nil
+	}
 
+	for _, offset := range []int64{size / 4, size / 2, size * 3 / 4, size - sampleWindow} {
+		n, err := file.ReadAt(buffer, offset)
+		if err != nil && err != io.EOF {
+			return Text, "", err
+		}
+		// A window may start in the middle of a character
+		switch contentKind(trimLeadingPartialRune(buffer[:n]), offset+int64(n) < size) {
+		case Binary:
+			return Binary, mime, nil
+		case LegacyText:
+			kind = LegacyText
+		}
+	}
+	return kind, mime, nil
+}
+
+// contentKind classifies the head of a file. truncated is set when the file
+// goes on past the head, so a character cut off at its end is not invalid.
+func contentKind(content []byte, truncated bool) int {
 	// Check for NUL bytes, common in binary formats
 	for _, b := range content {
 		if b == 0 {
-			return true, nil
+			return Binary
 		}
 	}
 
-	// Check for valid UTF-8
-	if !utf8.Valid(content) {
-		return true, nil
+	if truncated {
+		content = trimPartialRune(content)
 	}
+	// Empty files are treated as text
+	if utf8.Valid(content) {
+		return Text
+	}
+	return LegacyText
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence from the end of data.
+func trimPartialRune(data []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		b := data[len(data)-i]
+		if !utf8.RuneStart(b) {
+			continue
+		}
+		if !utf8.FullRune(data[len(data)-i:]) {
+			return data[:len(data)-i]
+		}
+		break
+	}
+	return data
+}
+
+// trimLeadingPartialRune drops the continuation bytes of a character cut off
+// at the start of data. More of them than a character can have are left, as
+// they are invalid anyway.
+func trimLeadingPartialRune(data []byte) []byte {
+	i := 0
+	for i < utf8.UTFMax-1 && i < len(data) && !utf8.RuneStart(data[i]) {
+		i++
+	}
+	return data[i:]
+}
+
+// textTypes refines plain text by extension for formats the content sniffer
+// can't tell apart from prose.
+var textTypes = map[string]string{
+	".csv":      "text/csv",
+	".tsv":      "text/tab-separated-values",
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".css":      "text/css",
+	".js":       "text/javascript",
+	".mjs":      "text/javascript",
+}
+
+// ContentType returns the media type of a file's head, without parameters
+// (e.g., "text/plain"). The type comes from the content; only content that
+// sniffs as plain text is refined by the file's extension, so a .csv holding
+// binary data is still reported as binary data.
+func ContentType(head []byte, name string) string {
+	mediaType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if mediaType == "text/plain" {
+		if refined, ok := textTypes[strings.ToLower(filepath.Ext(name))]; ok {
+			return refined
+		}
+	}
+	return mediaType
+}
+
+// DetectMIME sniffs a file's MIME type from its first 512 bytes.
+func DetectMIME(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return DetectMIMEReader(file)
+}
 
-	return false, nil
+// DetectMIMEReader is DetectMIME for content read from r.
+func DetectMIMEReader(r io.Reader) (string, error) {
+	buffer := make([]byte, 512)
+	n, err := r.Read(buffer)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buffer[:n]), nil
+}
+
+// FormatSize renders a byte count in a compact human readable form (e.g., 48KB).
+func FormatSize(bytes int64) string {
+	switch {
+	case bytes < 1024:
+		return fmt.Sprintf("%dB", bytes)
+	case bytes < 1024*1024:
+		return fmt.Sprintf("%dKB", bytes/1024)
+	default:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/(1024*1024))
+	}
 }
@@ -1,6 +1,7 @@
 package fileutil
 
 import (
+	"bytes"
 	"os"
 	"testing"
 )
@@ -42,3 +43,117 @@ func TestIsBinary(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{48 * 1024, "48KB"},
+		{3 * 1024 * 1024 / 2, "1.5MB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSize(tt.bytes); got != tt.expected {
+			t.Errorf("FormatSize(%d): expected %s, got %s", tt.bytes, tt.expected, got)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		head     string
+		name     string
+		expected string
+	}{
+		{"hello world", "notes.txt", "text/plain"},
+		{"a,b\n1,2\n", "data.csv", "text/csv"},
+		{"<!DOCTYPE html><html></html>", "page.csv", "text/html"},
+		{"#!/bin/sh\necho hi\n", "deploy", "text/plain"},
+		{"\x89PNG\r\n\x1a\n", "logo.txt", "image/png"},
+	}
+
+	for _, tt := range tests {
+		if got := ContentType([]byte(tt.head), tt.name); got != tt.expected {
+			t.Errorf("ContentType(%q, %s): expected %s, got %s", tt.head, tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestSniffKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		expected int
+	}{
+		{"UTF-8", []byte("café"), Text},
+		{"Latin-1", []byte("caf\xe9"), LegacyText},
+		{"NUL", []byte("caf\xe9\x00"), Binary},
+		// A character cut off by the 512-byte head is not invalid
+		{"Cut rune", append(bytes.Repeat([]byte("a"), 511), "é"...), Text},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpfile, err := os.CreateTemp("", "testfile")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(tmpfile.Name())
+			tmpfile.Write(tt.content)
+			tmpfile.Close()
+
+			kind, _, err := Sniff(tmpfile.Name())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if kind != tt.expected {
+				t.Errorf("expected kind %d, got %d", tt.expected, kind)
+			}
+		})
+	}
+}
+
+func TestIsBinarySampled(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		head     bool
+		expected bool
+	}{
+		// Text for 600 bytes, then a binary blob past the first 512 bytes
+		{"NUL deeper in", append(bytes.Repeat([]byte("a"), 600), make([]byte, 600)...), false, true},
+		// Windows starting inside a character don't make it invalid
+		{"Multibyte text", bytes.Repeat([]byte("日本語 "), 1000), false, false},
+		{"Short text", []byte("hello"), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpfile, err := os.CreateTemp("", "testfile")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(tmpfile.Name())
+			tmpfile.Write(tt.content)
+			tmpfile.Close()
+
+			isBin, err := IsBinary(tmpfile.Name())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if isBin != tt.head {
+				t.Errorf("IsBinary: expected %v, got %v", tt.head, isBin)
+			}
+			isBin, err = IsBinarySampled(tmpfile.Name())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if isBin != tt.expected {
+				t.Errorf("IsBinarySampled: expected %v, got %v", tt.expected, isBin)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSameContent(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "fileutil_test_compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	long := strings.Repeat("line\n", 20000)
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"Equal", long, long, true},
+		{"Empty", "", "", true},
+		{"Changed End", long + "a", long + "b", false},
+		{"Changed Start", "x" + long, "y" + long, false},
+		{"Longer", long, long + "more", false},
+	}
+	a, b := filepath.Join(tempDir, "a"), filepath.Join(tempDir, "b")
+	for _, tt := range tests {
+		os.WriteFile(a, []byte(tt.a), 0644)
+		os.WriteFile(b, []byte(tt.b), 0644)
+		if same, err := SameContent(a, b); err != nil || same != tt.same {
+			t.Errorf("%s: expected %v, got %v (%v)", tt.name, tt.same, same, err)
+		}
+	}
+
+	if same, err := SameContent(a, filepath.Join(tempDir, "missing")); err != nil || same {
+		t.Errorf("Expected a missing file to differ, got %v (%v)", same, err)
+	}
+}
@@ -0,0 +1,51 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RotatedName returns the name of the nth previous version of a file, with
+// the number before the extension: codebase.txt becomes codebase.1.txt, and
+// a name without an extension gets it at the end (notes.1).
+func RotatedName(name string, n int) string {
+	base := filepath.Base(name)
+	ext := filepath.Ext(base)
+	if ext == base {
+		ext = ""
+	}
+	return strings.TrimSuffix(name, ext) + "." + strconv.Itoa(n) + ext
+}
+
+// TempPattern is the os.CreateTemp pattern of the temporary file a new
+// version of name is written to, next to it, before it is renamed into
+// place. As a glob, it matches those files.
+func TempPattern(name string) string {
+	return "." + filepath.Base(name) + ".*.tmp"
+}
+
+// Rotate shifts the previous versions of path up by one, keeping at most
+// keep of them: the oldest is removed, each other moves to the next number,
+// and path itself becomes version 1. Missing versions are skipped, and
+// nothing happens if keep isn't positive. The caller then puts the new
+// version in place, so path is only missing for the time of one rename.
+func Rotate(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	if err := os.Remove(RotatedName(path, keep)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := keep - 1; n >= 0; n-- {
+		from := path
+		if n > 0 {
+			from = RotatedName(path, n)
+		}
+		if err := os.Rename(from, RotatedName(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestRotatedName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"codebase.txt", "codebase.2.txt"},
+		{"out/context.md", "out/context.2.md"},
+		{"notes", "notes.2"},
+		{".context", ".context.2"},
+	}
+	for _, tt := range tests {
+		if got := RotatedName(tt.name, 2); got != tt.want {
+			t.Errorf("RotatedName(%q, 2) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "fileutil_test_rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "codebase.txt")
+	// Four runs, keeping two previous versions
+	for run := 1; run <= 4; run++ {
+		if err := Rotate(path, 2); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(strconv.Itoa(run)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string]string{"codebase.txt": "4", "codebase.1.txt": "3", "codebase.2.txt": "2"} {
+		data, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("Expected %s to hold run %s, got %q (%v)", name, want, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "codebase.3.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected no third version, got %v", err)
+	}
+}
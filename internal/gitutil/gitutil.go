@@ -0,0 +1,183 @@
+package gitutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var (
+	cacheMu     sync.Mutex
+	changeCache = make(map[string]map[string]int)
+	blameCache  = make(map[string]map[string]FileInfo)
+)
+
+// FileInfo summarizes the git history of a single file.
+type FileInfo struct {
+	// Author is the author with the most commits touching the file.
+	Author string
+
+	// Modified is the date (YYYY-MM-DD) of the last commit touching the file.
+	Modified string
+}
+
+// ChangeCounts returns how many commits touched each file since the given git
+// date expression (e.g., "6.months"). Keys are slash-separated paths relative
+// to root. Results are cached per root for the lifetime of the process so
+// repeated scans do not re-run git log.
+func ChangeCounts(root, since string) (map[string]int, error) {
+	key := root + "\x00" + since
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if counts, ok := changeCache[key]; ok {
+		return counts, nil
+	}
+
+	out, err := run(root, "log", "--name-only", "--relative", "--pretty=format:", "--since="+since)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	lines := bufio.NewScanner(bytes.NewReader(out))
+	for lines.Scan() {
+		if path := strings.TrimSpace(lines.Text()); path != "" {
+			counts[path]++
+		}
+	}
+
+	changeCache[key] = counts
+	return counts, nil
+}
+
+// Authorship returns the primary author and last-modified date of every
+// tracked file under root, keyed by slash-separated path relative to root.
+// The whole history is read with a single git log and cached per root.
+func Authorship(root string) (map[string]FileInfo, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if info, ok := blameCache[root]; ok {
+		return info, nil
+	}
+
+	// Commit lines are prefixed with a NUL so they can't be confused with paths
+	out, err := run(root, "log", "--name-only", "--relative", "--date=short", "--pretty=format:%x00%an%x00%ad")
+	if err != nil {
+		return nil, err
+	}
+
+	info := make(map[string]FileInfo)
+	authorCounts := make(map[string]map[string]int)
+	var author, date string
+
+	lines := bufio.NewScanner(bytes.NewReader(out))
+	for lines.Scan() {
+		line := lines.Text()
+		if strings.HasPrefix(line, "\x00") {
+			parts := strings.SplitN(line[1:], "\x00", 2)
+			if len(parts) == 2 {
+				author, date = parts[0], parts[1]
+			}
+			continue
+		}
+		path := strings.TrimSpace(line)
+		if path == "" {
+			continue
+		}
+
+		// git log is newest first, so the first sighting is the last change
+		if _, seen := info[path]; !seen {
+			info[path] = FileInfo{Modified: date}
+			authorCounts[path] = make(map[string]int)
+		}
+		authorCounts[path][author]++
+	}
+
+	for path, counts := range authorCounts {
+		fi := info[path]
+		for name, n := range counts {
+			if n > counts[fi.Author] || (n == counts[fi.Author] && name < fi.Author) {
+				fi.Author = name
+			}
+		}
+		info[path] = fi
+	}
+
+	blameCache[root] = info
+	return info, nil
+}
+
+// ChangedFiles returns the files under root that differ from ref: files
+// changed on the current branch since it forked from ref, files with
+// uncommitted changes, and untracked files that aren't ignored. Deleted files
+// are left out. Keys are slash-separated paths relative to root.
+func ChangedFiles(root, ref string) (map[string]bool, error) {
+	if _, err := run(root, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("%s is not inside a git repository", root)
+	}
+	if _, err := run(root, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git ref %q", ref)
+	}
+
+	// Compare against the fork point so changes made on ref since then
+	// don't count; unrelated histories fall back to ref itself
+	base := ref
+	if out, err := run(root, "merge-base", ref, "HEAD"); err == nil {
+		base = strings.TrimSpace(string(out))
+	}
+
+	changed := make(map[string]bool)
+	for _, args := range [][]string{
+		{"diff", "--name-only", "--relative", "--diff-filter=d", base},
+		{"ls-files", "--others", "--exclude-standard"},
+	} {
+		out, err := run(root, args...)
+		if err != nil {
+			return nil, err
+		}
+		lines := bufio.NewScanner(bytes.NewReader(out))
+		for lines.Scan() {
+			if path := strings.TrimSpace(lines.Text()); path != "" {
+				changed[path] = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+// CurrentBranch returns the branch checked out in root's repository, or the
+// abbreviated commit hash when HEAD is detached.
+func CurrentBranch(root string) (string, error) {
+	out, err := run(root, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("%s is not inside a git repository with commits", root)
+	}
+	branch := strings.TrimSpace(string(out))
+	if branch != "HEAD" {
+		return branch, nil
+	}
+	out, err = run(root, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// run executes a git command inside dir and returns its standard output.
+func run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-c", "core.quotePath=false", "-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
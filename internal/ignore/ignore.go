@@ -0,0 +1,308 @@
+// Package ignore loads the ignore files a project's scan and discovery
+// leave entries out by: .gitignore, with those of the folders above the
+// project in its repository, .dockerignore, and .textifyignore.
+package ignore
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/monochromegane/go-gitignore"
+)
+
+// Ignore sources, as ignore_sources names them.
+const (
+	// Gitignore is the project's .gitignore (the default).
+	Gitignore = "gitignore"
+
+	// Dockerignore is the project's .dockerignore, read with Docker's
+	// syntax, which often describes the source of container-focused repos
+	// better.
+	Dockerignore = "dockerignore"
+
+	// Textifyignore is the project's .textifyignore, in .gitignore syntax,
+	// for what only textify should leave out.
+	Textifyignore = "textifyignore"
+)
+
+// fileNames maps each ignore source to its file at the project root.
+var fileNames = map[string]string{
+	Gitignore:     ".gitignore",
+	Dockerignore:  ".dockerignore",
+	Textifyignore: ".textifyignore",
+}
+
+// verdict is what one ignore file says about an entry.
+type verdict int
+
+const (
+	// noVerdict means no pattern of the file matches the entry.
+	noVerdict verdict = iota
+	ignoredVerdict
+	// keptVerdict means a "!" exception matches the entry.
+	keptVerdict
+)
+
+// ignoreFile is one loaded ignore file.
+type ignoreFile interface {
+	// verdict reports whether the file ignores relPath, keeps it with an
+	// exception, or doesn't match it at all.
+	verdict(relPath string, isDir bool) verdict
+
+	// exceptionsBelow reports whether an exception could keep entries
+	// inside relDir even though relDir itself is ignored.
+	exceptionsBelow(relDir string) bool
+}
+
+// ignoreChain applies several ignore files in order of precedence: the first
+// one that matches an entry decides whether it is ignored.
+type ignoreChain struct {
+	root  string
+	files []ignoreFile
+}
+
+// NewMatcher loads the ignore files of sources from root, skipping those
+// that don't exist. The gitignore source also brings in the .gitignore
+// files of the folders above root in its repository (see ParentGitignores),
+// after root's own, so the deepest file that matches decides, as in git.
+func NewMatcher(root string, sources []string) gitignore.IgnoreMatcher {
+	return newMatcher(os.DirFS(root), root, sources, ParentGitignores(root))
+}
+
+// NewMatcherFS is NewMatcher for a tree held in fsys, whose "." is root.
+// Nothing above it is read, so no parent .gitignore files apply.
+func NewMatcherFS(fsys fs.FS, root string, sources []string) gitignore.IgnoreMatcher {
+	return newMatcher(fsys, root, sources, nil)
+}
+
+// newMatcher chains the ignore files of sources in fsys, and after the
+// gitignore source's own, the parents on disk.
+func newMatcher(fsys fs.FS, root string, sources, parents []string) ignoreChain {
+	chain := ignoreChain{root: root}
+	for _, source := range sources {
+		if lines, err := readIgnoreLines(fsys, fileNames[source]); err == nil {
+			if source == Dockerignore {
+				chain.files = append(chain.files, newDockerignore(lines))
+			} else {
+				chain.files = append(chain.files, newGitignoreFile(root, "", lines))
+			}
+		}
+		if source != Gitignore {
+			continue
+		}
+		for _, file := range parents {
+			dir := filepath.Dir(file)
+			lines, err := readIgnoreLines(os.DirFS(dir), filepath.Base(file))
+			if err != nil {
+				continue
+			}
+			chain.files = append(chain.files, newGitignoreFile(dir, relSlash(dir, root), lines))
+		}
+	}
+	return chain
+}
+
+// ParentGitignores returns the .gitignore files of the folders above root
+// that apply to it, nearest first: those up to the root of the git
+// repository holding root, the closest folder with .git. Without a
+// repository above root, none apply.
+func ParentGitignores(root string) []string {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return nil
+	}
+	var files []string
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+		file := filepath.Join(dir, ".gitignore")
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return files
+		}
+	}
+}
+
+// relSlash returns target relative to root using forward slashes.
+func relSlash(root, target string) string {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Match implements gitignore.IgnoreMatcher. A directory an exception could
+// reach into is not ignored, so the walk enters it and asks again for each
+// entry inside.
+func (c ignoreChain) Match(fullPath string, isDir bool) bool {
+	relPath := relSlash(c.root, fullPath)
+	for i, f := range c.files {
+		switch f.verdict(relPath, isDir) {
+		case keptVerdict:
+			return false
+		case ignoredVerdict:
+			if isDir {
+				for _, g := range c.files[:i+1] {
+					if g.exceptionsBelow(relPath) {
+						return false
+					}
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// readIgnoreLines returns the patterns of the ignore file name in fsys,
+// without blank lines and comments.
+func readIgnoreLines(fsys fs.FS, name string) ([]string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// gitignoreFile is an ignore file in .gitignore syntax. As in git, an
+// exception can't keep an entry whose directory is ignored.
+type gitignoreFile struct {
+	// root is the folder of the file, which its patterns are relative to.
+	// prefix is the scan root relative to it, with forward slashes, or ""
+	// when they are the same; folders above the scan root are never
+	// checked, so scanning a folder the file ignores still works.
+	root   string
+	prefix string
+	ignore gitignore.IgnoreMatcher
+	accept gitignore.IgnoreMatcher
+}
+
+func newGitignoreFile(root, prefix string, lines []string) gitignoreFile {
+	var ignore, accept []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "!") {
+			accept = append(accept, strings.TrimPrefix(line, "!"))
+		} else {
+			ignore = append(ignore, line)
+		}
+	}
+	return gitignoreFile{
+		root:   root,
+		prefix: prefix,
+		ignore: gitignore.NewGitIgnoreFromReader(root, strings.NewReader(strings.Join(ignore, "\n"))),
+		accept: gitignore.NewGitIgnoreFromReader(root, strings.NewReader(strings.Join(accept, "\n"))),
+	}
+}
+
+func (g gitignoreFile) verdict(relPath string, isDir bool) verdict {
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if g.own(strings.Join(parts[:i], "/"), true) == ignoredVerdict {
+			return ignoredVerdict
+		}
+	}
+	return g.own(relPath, isDir)
+}
+
+// own is the verdict of the patterns matching relPath itself.
+func (g gitignoreFile) own(relPath string, isDir bool) verdict {
+	fullPath := filepath.Join(g.root, filepath.FromSlash(g.prefix), filepath.FromSlash(relPath))
+	if g.accept.Match(fullPath, isDir) {
+		return keptVerdict
+	}
+	if g.ignore.Match(fullPath, isDir) {
+		return ignoredVerdict
+	}
+	return noVerdict
+}
+
+func (g gitignoreFile) exceptionsBelow(relDir string) bool { return false }
+
+// dockerignore is an ignore file in .dockerignore syntax, which differs from
+// .gitignore's: every pattern is anchored at the root, so "*.log" only
+// matches root files; a pattern matching a directory matches everything
+// inside it; the last pattern matching an entry decides; and an exception
+// can keep a file inside an ignored directory.
+type dockerignore struct {
+	patterns []dockerPattern
+}
+
+type dockerPattern struct {
+	parts     []string
+	exception bool
+}
+
+func newDockerignore(lines []string) dockerignore {
+	var d dockerignore
+	for _, line := range lines {
+		exception := strings.HasPrefix(line, "!")
+		line = strings.TrimSpace(strings.TrimPrefix(line, "!"))
+		line = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(line)), "/")
+		if line == "" {
+			continue
+		}
+		d.patterns = append(d.patterns, dockerPattern{parts: strings.Split(line, "/"), exception: exception})
+	}
+	return d
+}
+
+func (d dockerignore) verdict(relPath string, isDir bool) verdict {
+	parts := strings.Split(relPath, "/")
+	v := noVerdict
+	for _, p := range d.patterns {
+		if !p.matches(parts) {
+			continue
+		}
+		if p.exception {
+			v = keptVerdict
+		} else {
+			v = ignoredVerdict
+		}
+	}
+	return v
+}
+
+// matches reports whether the pattern matches the path or one of the
+// directories it is in.
+func (p dockerPattern) matches(parts []string) bool {
+	for i := 1; i <= len(parts); i++ {
+		if MatchSegments(p.parts, parts[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d dockerignore) exceptionsBelow(relDir string) bool {
+	dirParts := strings.Split(relDir, "/")
+	for _, p := range d.patterns {
+		if p.exception && PrefixCouldMatch(p.parts, dirParts) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParentGitignores(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "ignore_test_parent_gitignores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialized two levels below the repository root; the .gitignore
+	// above the repository never applies
+	root := filepath.Join(tempDir, "repo", "services", "api")
+	os.MkdirAll(root, 0755)
+	os.MkdirAll(filepath.Join(tempDir, "repo", ".git"), 0755)
+	for _, dir := range []string{tempDir, filepath.Join(tempDir, "repo"), filepath.Join(tempDir, "repo", "services")} {
+		os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("coverage/\n"), 0644)
+	}
+
+	expected := []string{
+		filepath.Join(tempDir, "repo", "services", ".gitignore"),
+		filepath.Join(tempDir, "repo", ".gitignore"),
+	}
+	if files := ParentGitignores(root); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Unexpected parent .gitignore files.\nExpected: %q\nGot:      %q", expected, files)
+	}
+
+	// A repository's root has none
+	if files := ParentGitignores(filepath.Join(tempDir, "repo")); len(files) != 0 {
+		t.Errorf("Expected no parent .gitignore files at the repository root, got %q", files)
+	}
+}
@@ -0,0 +1,58 @@
+package ignore
+
+import "path"
+
+// PrefixCouldMatch reports whether the pattern segments can match dirParts
+// followed by at least one more segment.
+func PrefixCouldMatch(pattern, dirParts []string) bool {
+	for i, part := range dirParts {
+		if i >= len(pattern) {
+			return false
+		}
+		if pattern[i] == "**" {
+			return true
+		}
+		if !MatchSegment(pattern[i], part) {
+			return false
+		}
+	}
+	return len(pattern) > len(dirParts)
+}
+
+// MatchSegments matches pattern segments against path segments, where a "**"
+// segment matches zero or more path segments (at least one when it ends the
+// pattern, so "foo/**" matches what is inside foo but not foo itself).
+func MatchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if MatchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if !MatchSegment(pattern[0], parts[0]) {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// MatchSegment matches one pattern segment against one path segment, or
+// literally, for names holding glob characters.
+func MatchSegment(pattern, part string) bool {
+	if pattern == part {
+		return true
+	}
+	matched, _ := path.Match(pattern, part)
+	return matched
+}
@@ -0,0 +1,242 @@
+// Package linediff compares texts line by line, for diffs between outputs.
+package linediff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Kinds of Op.
+const (
+	Keep   = ' '
+	Delete = '-'
+	Insert = '+'
+)
+
+// Op is one line of an edit script: kept, deleted from the old text, or
+// inserted from the new one.
+type Op struct {
+	Kind byte
+	Line string
+}
+
+// maxEdits bounds the work of the diff, whose memory grows with the square
+// of the number of edits. Texts that differ by more are shown as replaced
+// whole.
+const maxEdits = 1000
+
+// Lines returns the shortest edit script turning a into b, or, past
+// maxEdits, one that deletes what differs in a and inserts what differs in
+// b. Lines common to the start and end are always kept.
+func Lines(a, b []string) []Op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	for _, line := range a[:prefix] {
+		ops = append(ops, Op{Keep, line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, Op{Keep, line})
+	}
+	return ops
+}
+
+// Split splits text into lines, without their newlines. Empty text has no
+// lines, and a final newline doesn't start one.
+func Split(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Counts returns how many lines ops insert and delete.
+func Counts(ops []Op) (inserted, deleted int) {
+	for _, op := range ops {
+		switch op.Kind {
+		case Insert:
+			inserted++
+		case Delete:
+			deleted++
+		}
+	}
+	return inserted, deleted
+}
+
+// Unified writes the hunks of ops in unified diff format, each change with
+// up to context unchanged lines around it. It writes nothing when nothing
+// changed; the caller writes the ---/+++ file lines.
+func Unified(w io.Writer, ops []Op, context int) error {
+	for start := 0; start < len(ops); {
+		// Find the next change and the end of the hunk it starts: changes
+		// closer than twice the context share a hunk
+		first := start
+		for first < len(ops) && ops[first].Kind == Keep {
+			first++
+		}
+		if first == len(ops) {
+			return nil
+		}
+		last := first
+		for i := first; i < len(ops) && i-last <= 2*context; i++ {
+			if ops[i].Kind != Keep {
+				last = i
+			}
+		}
+
+		from, to := first-context, last+context+1
+		if from < 0 {
+			from = 0
+		}
+		if to > len(ops) {
+			to = len(ops)
+		}
+		if err := writeHunk(w, ops, from, to); err != nil {
+			return err
+		}
+		start = to
+	}
+	return nil
+}
+
+// writeHunk writes ops[from:to] as one hunk, with line numbers counted from
+// the start of ops.
+func writeHunk(w io.Writer, ops []Op, from, to int) error {
+	oldStart, newStart := 1, 1
+	for _, op := range ops[:from] {
+		if op.Kind != Insert {
+			oldStart++
+		}
+		if op.Kind != Delete {
+			newStart++
+		}
+	}
+	oldLen, newLen := 0, 0
+	for _, op := range ops[from:to] {
+		if op.Kind != Insert {
+			oldLen++
+		}
+		if op.Kind != Delete {
+			newLen++
+		}
+	}
+	// An empty range names the line before it
+	if oldLen == 0 {
+		oldStart--
+	}
+	if newLen == 0 {
+		newStart--
+	}
+
+	if _, err := fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen); err != nil {
+		return err
+	}
+	for _, op := range ops[from:to] {
+		if _, err := fmt.Fprintf(w, "%c%s\n", op.Kind, op.Line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// myers finds the shortest edit script with Myers' O(ND) algorithm, keeping
+// the furthest-reaching paths of every step to trace the script back.
+func myers(a, b []string) []Op {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replace(a, b)
+	}
+
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds v[-d..d] as it was before step d
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		if d > maxEdits {
+			return replace(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return replace(a, b)
+}
+
+// backtrack follows the paths in trace from the end of both texts back to
+// their start and returns the script in order.
+func backtrack(trace [][]int, a, b []string) []Op {
+	var ops []Op
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, Op{Keep, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, Op{Insert, b[y-1]})
+			y--
+		} else {
+			ops = append(ops, Op{Delete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, Op{Keep, a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replace is the script that deletes all of a and inserts all of b.
+func replace(a, b []string) []Op {
+	ops := make([]Op, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, Op{Delete, line})
+	}
+	for _, line := range b {
+		ops = append(ops, Op{Insert, line})
+	}
+	return ops
+}
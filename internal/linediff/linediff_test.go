@@ -0,0 +1,78 @@
+package linediff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds both texts from an edit script.
+func apply(ops []Op) (a, b []string) {
+	for _, op := range ops {
+		if op.Kind != Insert {
+			a = append(a, op.Line)
+		}
+		if op.Kind != Delete {
+			b = append(b, op.Line)
+		}
+	}
+	return a, b
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		a, b              string
+		inserted, deleted int
+	}{
+		{"", "", 0, 0},
+		{"a\nb\nc\n", "a\nb\nc\n", 0, 0},
+		{"", "a\nb\n", 2, 0},
+		{"a\nb\n", "", 0, 2},
+		{"a\nb\nc\n", "a\nx\nc\n", 1, 1},
+		{"a\nb\nc\nd\ne\n", "b\nc\nx\ne\nf\n", 2, 2},
+		{"x\na\nb\nc\n", "a\nb\nc\ny\n", 1, 1},
+	}
+	for _, tt := range tests {
+		a, b := Split(tt.a), Split(tt.b)
+		ops := Lines(a, b)
+		gotA, gotB := apply(ops)
+		if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+			t.Errorf("Lines(%q, %q) doesn't rebuild the texts: %v", tt.a, tt.b, ops)
+		}
+		if inserted, deleted := Counts(ops); inserted != tt.inserted || deleted != tt.deleted {
+			t.Errorf("Lines(%q, %q): expected +%d -%d, got +%d -%d", tt.a, tt.b, tt.inserted, tt.deleted, inserted, deleted)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	var a, b []string
+	for i := 1; i <= 20; i++ {
+		line := strings.Repeat("x", i)
+		a = append(a, line)
+		if i == 3 {
+			b = append(b, "changed")
+			continue
+		}
+		b = append(b, line)
+		if i == 15 {
+			b = append(b, "added")
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Unified(&buf, Lines(a, b), 2); err != nil {
+		t.Fatal(err)
+	}
+	expected := "@@ -1,5 +1,5 @@\n x\n xx\n-xxx\n+changed\n xxxx\n xxxxx\n" +
+		"@@ -14,4 +14,5 @@\n " + strings.Repeat("x", 14) + "\n " + strings.Repeat("x", 15) + "\n+added\n " + strings.Repeat("x", 16) + "\n " + strings.Repeat("x", 17) + "\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected diff.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	Unified(&buf, Lines(a, a), 3)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no hunks for equal texts, got:\n%s", buf.String())
+	}
+}
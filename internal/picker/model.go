@@ -0,0 +1,272 @@
+// Package picker implements the interactive file selection used by
+// 'textify pick': a selection model that can be tested on its own, and a
+// minimal keyboard-driven terminal front end for it.
+package picker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+	"github.com/JohnEsleyer/textify/internal/tokens"
+)
+
+// Item is a file that can be picked.
+type Item struct {
+	// Path is relative to the project root, with forward slashes.
+	Path string
+	Size int64
+}
+
+// Tokens estimates the item's size in LLM tokens.
+func (i Item) Tokens() int64 {
+	return tokens.Estimate(i.Size)
+}
+
+// Row is one line of the tree: a directory or a file.
+type Row struct {
+	// Path is the directory or file path; Name is its last element.
+	Path  string
+	Name  string
+	Depth int
+	IsDir bool
+
+	// Item is the file for file rows.
+	Item Item
+}
+
+// Check states of a row's checkbox.
+const (
+	Unchecked = iota
+	Partial
+	Checked
+)
+
+// Model is the state of a pick session: the items, which are selected, the
+// filter, and the cursor over the visible rows.
+type Model struct {
+	items    []Item
+	selected map[string]bool
+	filter   string
+	rows     []Row
+	cursor   int
+}
+
+// NewModel returns a model for items, which must be in walk order (sorted,
+// depth-first) so directories group their files.
+func NewModel(items []Item) *Model {
+	m := &Model{items: items, selected: make(map[string]bool)}
+	m.refresh()
+	return m
+}
+
+// Rows returns the visible rows: the files matching the filter and the
+// directories containing them.
+func (m *Model) Rows() []Row { return m.rows }
+
+// Cursor returns the index of the highlighted row.
+func (m *Model) Cursor() int { return m.cursor }
+
+// Filter returns the current filter text.
+func (m *Model) Filter() string { return m.filter }
+
+// SetFilter changes the fuzzy filter and resets the cursor.
+func (m *Model) SetFilter(filter string) {
+	m.filter = filter
+	m.cursor = 0
+	m.refresh()
+}
+
+// Up and Down move the cursor, stopping at either end.
+func (m *Model) Up() {
+	if m.cursor > 0 {
+		m.cursor--
+	}
+}
+
+func (m *Model) Down() {
+	if m.cursor < len(m.rows)-1 {
+		m.cursor++
+	}
+}
+
+// Toggle flips the highlighted row. For a directory, every visible file under
+// it is selected, unless all of them already are, in which case they are
+// deselected.
+func (m *Model) Toggle() {
+	if len(m.rows) == 0 {
+		return
+	}
+	row := m.rows[m.cursor]
+	if !row.IsDir {
+		m.selected[row.Path] = !m.selected[row.Path]
+		return
+	}
+	m.setAll(m.filesUnder(row.Path))
+}
+
+// ToggleAll flips every visible file, like toggling the root directory.
+func (m *Model) ToggleAll() {
+	m.setAll(m.filesUnder(""))
+}
+
+// setAll selects all of paths, or deselects them if they are all selected.
+func (m *Model) setAll(paths []string) {
+	all := true
+	for _, p := range paths {
+		all = all && m.selected[p]
+	}
+	for _, p := range paths {
+		m.selected[p] = !all
+	}
+}
+
+// State returns the checkbox state of a row.
+func (m *Model) State(row Row) int {
+	if !row.IsDir {
+		if m.selected[row.Path] {
+			return Checked
+		}
+		return Unchecked
+	}
+	files := m.filesUnder(row.Path)
+	n := 0
+	for _, p := range files {
+		if m.selected[p] {
+			n++
+		}
+	}
+	switch {
+	case n == 0:
+		return Unchecked
+	case n == len(files):
+		return Checked
+	default:
+		return Partial
+	}
+}
+
+// Selected returns the selected items in walk order, including any hidden by
+// the current filter.
+func (m *Model) Selected() []Item {
+	var items []Item
+	for _, item := range m.items {
+		if m.selected[item.Path] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Total returns the combined size and estimated tokens of the selection.
+func (m *Model) Total() (size, tokens int64) {
+	for _, item := range m.Selected() {
+		size += item.Size
+		tokens += item.Tokens()
+	}
+	return size, tokens
+}
+
+// filesUnder returns the visible files under dir ("" for all of them).
+func (m *Model) filesUnder(dir string) []string {
+	var paths []string
+	for _, row := range m.rows {
+		if !row.IsDir && (dir == "" || strings.HasPrefix(row.Path, dir+"/")) {
+			paths = append(paths, row.Path)
+		}
+	}
+	return paths
+}
+
+// refresh rebuilds the visible rows from the items and the filter.
+func (m *Model) refresh() {
+	m.rows = m.rows[:0]
+	var prev []string
+	for _, item := range m.items {
+		if !fuzzyMatch(m.filter, item.Path) {
+			continue
+		}
+		parts := strings.Split(item.Path, "/")
+
+		// Add the directory rows not already shown for the previous file
+		common := 0
+		for common < len(parts)-1 && common < len(prev)-1 && parts[common] == prev[common] {
+			common++
+		}
+		for depth := common; depth < len(parts)-1; depth++ {
+			m.rows = append(m.rows, Row{
+				Path:  strings.Join(parts[:depth+1], "/"),
+				Name:  parts[depth],
+				Depth: depth,
+				IsDir: true,
+			})
+		}
+		m.rows = append(m.rows, Row{Path: item.Path, Name: parts[len(parts)-1], Depth: len(parts) - 1, Item: item})
+		prev = parts
+	}
+	if m.cursor >= len(m.rows) {
+		m.cursor = len(m.rows) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in s in order,
+// ignoring case. An empty pattern matches everything.
+func fuzzyMatch(pattern, s string) bool {
+	pattern, s = strings.ToLower(pattern), strings.ToLower(s)
+	for _, r := range pattern {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+// View renders the model as lines fitting in height rows, scrolling so the
+// cursor stays visible.
+func (m *Model) View(height int) []string {
+	size, tokens := m.Total()
+	lines := []string{
+		fmt.Sprintf("Filter: %s", m.filter),
+		fmt.Sprintf("Selected %d files, %s, ~%d tokens", len(m.Selected()), fileutil.FormatSize(size), tokens),
+		"",
+	}
+	footer := "↑/↓ move · space toggle · ctrl+a toggle all · type to filter · enter confirm · esc cancel"
+
+	listHeight := height - len(lines) - 2
+	if listHeight < 1 {
+		listHeight = 1
+	}
+	start := 0
+	if m.cursor >= listHeight {
+		start = m.cursor - listHeight + 1
+	}
+	end := start + listHeight
+	if end > len(m.rows) {
+		end = len(m.rows)
+	}
+
+	for i := start; i < end; i++ {
+		row := m.rows[i]
+		pointer := "  "
+		if i == m.cursor {
+			pointer = "> "
+		}
+		box := [...]string{"[ ]", "[-]", "[x]"}[m.State(row)]
+		line := pointer + strings.Repeat("  ", row.Depth) + box + " " + row.Name
+		if row.IsDir {
+			line += "/"
+		} else {
+			line += fmt.Sprintf("  (%s, ~%d tokens)", fileutil.FormatSize(row.Item.Size), row.Item.Tokens())
+		}
+		lines = append(lines, line)
+	}
+	if len(m.rows) == 0 {
+		lines = append(lines, "  (no files match)")
+	}
+	return append(lines, "", footer)
+}
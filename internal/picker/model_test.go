@@ -0,0 +1,130 @@
+package picker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testItems() []Item {
+	return []Item{
+		{Path: "README.md", Size: 400},
+		{Path: "cmd/app/main.go", Size: 1000},
+		{Path: "internal/auth/login.go", Size: 2000},
+		{Path: "internal/auth/token.go", Size: 800},
+		{Path: "internal/db/db.go", Size: 1200},
+	}
+}
+
+func rowPaths(m *Model) []string {
+	var paths []string
+	for _, row := range m.Rows() {
+		paths = append(paths, row.Path)
+	}
+	return paths
+}
+
+func selectedPaths(m *Model) []string {
+	var paths []string
+	for _, item := range m.Selected() {
+		paths = append(paths, item.Path)
+	}
+	return paths
+}
+
+func TestModelRowsFormATree(t *testing.T) {
+	m := NewModel(testItems())
+	expected := []string{
+		"README.md",
+		"cmd", "cmd/app", "cmd/app/main.go",
+		"internal", "internal/auth", "internal/auth/login.go", "internal/auth/token.go",
+		"internal/db", "internal/db/db.go",
+	}
+	if got := rowPaths(m); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected rows.\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+func TestModelFuzzyFilter(t *testing.T) {
+	m := NewModel(testItems())
+	m.SetFilter("iauthtok")
+	expected := []string{"internal", "internal/auth", "internal/auth/token.go"}
+	if got := rowPaths(m); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected rows.\nExpected: %q\nGot:      %q", expected, got)
+	}
+
+	m.SetFilter("nothing-matches")
+	if len(m.Rows()) != 0 || m.Cursor() != 0 {
+		t.Errorf("Expected no rows and cursor 0, got %q at %d", rowPaths(m), m.Cursor())
+	}
+}
+
+func TestModelSelection(t *testing.T) {
+	m := NewModel(testItems())
+
+	// Toggling a directory selects every file under it
+	for m.Rows()[m.Cursor()].Path != "internal/auth" {
+		m.Down()
+	}
+	m.Toggle()
+	if got := selectedPaths(m); !reflect.DeepEqual(got, []string{"internal/auth/login.go", "internal/auth/token.go"}) {
+		t.Errorf("Unexpected selection after toggling a folder: %q", got)
+	}
+	internal := m.Rows()[4]
+	if m.State(internal) != Partial || m.State(m.Rows()[m.Cursor()]) != Checked {
+		t.Errorf("Expected internal/ partial and internal/auth/ checked")
+	}
+
+	// A selection survives filtering, and folder toggles only touch visible files
+	m.SetFilter("db")
+	m.ToggleAll()
+	m.SetFilter("")
+	expected := []string{"internal/auth/login.go", "internal/auth/token.go", "internal/db/db.go"}
+	if got := selectedPaths(m); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected selection.\nExpected: %q\nGot:      %q", expected, got)
+	}
+
+	size, tokens := m.Total()
+	if size != 4000 || tokens != 1000 {
+		t.Errorf("Expected 4000 bytes and 1000 tokens, got %d and %d", size, tokens)
+	}
+
+	// Toggling a fully selected folder clears it
+	m.SetFilter("auth")
+	m.ToggleAll()
+	m.SetFilter("")
+	if got := selectedPaths(m); !reflect.DeepEqual(got, []string{"internal/db/db.go"}) {
+		t.Errorf("Unexpected selection after clearing auth: %q", got)
+	}
+}
+
+func TestHandleInput(t *testing.T) {
+	m := NewModel(testItems())
+
+	steps := []struct {
+		input string
+		want  int
+	}{
+		{"\x1b[B", inputContinue}, // down to cmd/
+		{"\x1b[A", inputContinue}, // back up to README.md
+		{" ", inputContinue},      // select it
+		{"log", inputContinue},    // filter
+		{"x", inputContinue},      // no match
+		{"\x7f", inputContinue},   // backspace
+		{"\x1b[C", inputContinue}, // ignored escape sequence
+		{"\r", inputConfirm},
+	}
+	for _, step := range steps {
+		if got := handleInput(m, []byte(step.input)); got != step.want {
+			t.Errorf("handleInput(%q) = %d, want %d", step.input, got, step.want)
+		}
+	}
+	if m.Filter() != "log" {
+		t.Errorf("Expected filter %q, got %q", "log", m.Filter())
+	}
+	if got := selectedPaths(m); !reflect.DeepEqual(got, []string{"README.md"}) {
+		t.Errorf("Unexpected selection: %q", got)
+	}
+	if handleInput(m, []byte("\x1b")) != inputCancel || handleInput(m, []byte("\x03")) != inputCancel {
+		t.Error("Expected esc and ctrl+c to cancel")
+	}
+}
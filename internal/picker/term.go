@@ -0,0 +1,142 @@
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNotTerminal is returned by Run when stdin or stdout is not a terminal.
+var ErrNotTerminal = errors.New("textify pick needs an interactive terminal (stdin and stdout must be a TTY)")
+
+// ErrCancelled is returned by Run when the user quits without confirming.
+var ErrCancelled = errors.New("selection cancelled")
+
+// Run shows the picker on the terminal and returns the confirmed selection.
+// The terminal is switched to raw mode with stty for the duration.
+func Run(items []Item) ([]Item, error) {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return nil, ErrNotTerminal
+	}
+
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("cannot control the terminal: %v", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("cannot control the terminal: %v", err)
+	}
+	// Use the alternate screen and hide the cursor while picking
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+		stty(strings.TrimSpace(saved))
+	}()
+
+	m := NewModel(items)
+	buf := make([]byte, 64)
+	for {
+		draw(os.Stdout, m, terminalHeight())
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		switch handleInput(m, buf[:n]) {
+		case inputConfirm:
+			return m.Selected(), nil
+		case inputCancel:
+			return nil, ErrCancelled
+		}
+	}
+}
+
+// Outcomes of handling a chunk of input.
+const (
+	inputContinue = iota
+	inputConfirm
+	inputCancel
+)
+
+// handleInput applies a chunk of raw terminal input to the model.
+func handleInput(m *Model, input []byte) int {
+	switch string(input) {
+	case "\x1b[A", "\x1bOA":
+		m.Up()
+		return inputContinue
+	case "\x1b[B", "\x1bOB":
+		m.Down()
+		return inputContinue
+	case "\r", "\n":
+		return inputConfirm
+	case "\x1b", "\x03":
+		return inputCancel
+	case " ":
+		m.Toggle()
+		return inputContinue
+	case "\x01":
+		m.ToggleAll()
+		return inputContinue
+	case "\x7f", "\x08":
+		if f := m.Filter(); f != "" {
+			_, size := utf8.DecodeLastRuneInString(f)
+			m.SetFilter(f[:len(f)-size])
+		}
+		return inputContinue
+	}
+
+	// Other escape sequences (e.g., left/right arrows) are ignored
+	if input[0] == 0x1b {
+		return inputContinue
+	}
+
+	// Anything printable extends the filter, including pasted text
+	var typed strings.Builder
+	for _, r := range string(input) {
+		if r >= ' ' && r != 0x7f {
+			typed.WriteRune(r)
+		}
+	}
+	if typed.Len() > 0 {
+		m.SetFilter(m.Filter() + typed.String())
+	}
+	return inputContinue
+}
+
+// draw clears the screen and renders the model.
+func draw(w io.Writer, m *Model, height int) {
+	// Raw mode needs explicit carriage returns
+	fmt.Fprint(w, "\x1b[H\x1b[2J"+strings.Join(m.View(height), "\r\n"))
+}
+
+// isTerminal reports whether f is a character device, i.e. a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stty runs stty against the terminal on stdin and returns its output.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// terminalHeight returns the terminal's number of rows, or 24 if unknown.
+func terminalHeight() int {
+	out, err := stty("size")
+	if err == nil {
+		if fields := strings.Fields(out); len(fields) == 2 {
+			if rows, err := strconv.Atoi(fields[0]); err == nil && rows > 0 {
+				return rows
+			}
+		}
+	}
+	return 24
+}
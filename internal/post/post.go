@@ -0,0 +1,101 @@
+// Package post streams an output to an HTTP endpoint as it is written.
+package post
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// Headers of the request. The hash is only known once the whole output is
+// written, so it comes as a trailer, after the body.
+const (
+	ProjectHeader = "X-Textify-Project"
+	HashTrailer   = "X-Textify-Sha256"
+)
+
+// Stream is a chunked POST whose body is what is written to it. Writes go
+// straight to the connection, so the output is never held in memory; they
+// block while the server is slow to read. Writes never fail: once the
+// request does, the rest of the output is dropped, and Finish reports why.
+type Stream struct {
+	pw   *io.PipeWriter
+	req  *http.Request
+	hash hash.Hash
+	err  error
+	done chan response
+}
+
+// response is how the request ended.
+type response struct {
+	status string
+	code   int
+	err    error
+}
+
+// Start begins POSTing to url, with the project's name in ProjectHeader and
+// the given content type. The request stops if ctx is done.
+func Start(ctx context.Context, url, project, contentType string) (*Stream, error) {
+	pr, pw := io.Pipe()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(ProjectHeader, project)
+	req.Trailer = http.Header{HashTrailer: nil}
+
+	s := &Stream{pw: pw, req: req, hash: sha256.New(), done: make(chan response, 1)}
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			// Unblock and stop the writes
+			pr.CloseWithError(err)
+			s.done <- response{err: err}
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		pr.CloseWithError(fmt.Errorf("the server answered %s", resp.Status))
+		s.done <- response{status: resp.Status, code: resp.StatusCode}
+	}()
+	return s, nil
+}
+
+// Write sends p, and adds it to the hash.
+func (s *Stream) Write(p []byte) (int, error) {
+	s.hash.Write(p)
+	if s.err == nil {
+		if _, err := s.pw.Write(p); err != nil {
+			s.err = err
+		}
+	}
+	return len(p), nil
+}
+
+// Finish ends the body, sending the hash of everything written, and waits for
+// the response. It returns the response status, and an error if the request
+// failed or the status isn't 2xx.
+func (s *Stream) Finish() (string, error) {
+	s.req.Trailer.Set(HashTrailer, hex.EncodeToString(s.hash.Sum(nil)))
+	s.pw.Close()
+	r := <-s.done
+	if r.err != nil {
+		return "", r.err
+	}
+	if r.code < 200 || r.code > 299 {
+		return r.status, fmt.Errorf("the server answered %s", r.status)
+	}
+	return r.status, nil
+}
+
+// Abort ends the body with err, so the server sees the request fail rather
+// than a truncated output, and waits for the request to end.
+func (s *Stream) Abort(err error) {
+	s.pw.CloseWithError(err)
+	<-s.done
+}
@@ -0,0 +1,96 @@
+package post
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	type received struct {
+		body, project, contentType, hash string
+		chunked                          bool
+	}
+	got := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{
+			body:        string(body),
+			project:     r.Header.Get(ProjectHeader),
+			contentType: r.Header.Get("Content-Type"),
+			hash:        r.Trailer.Get(HashTrailer),
+			chunked:     len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked",
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	s, err := Start(context.Background(), server.URL, "demo", "text/plain; charset=utf-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := strings.Repeat("FILE: main.go\npackage main\n", 10000)
+	for i := 0; i < len(output); i += 1000 {
+		s.Write([]byte(output[i : i+1000]))
+	}
+	status, err := s.Finish()
+	if err != nil || status != "201 Created" {
+		t.Fatalf("Expected 201 Created, got %q (%v)", status, err)
+	}
+
+	r := <-got
+	sum := sha256.Sum256([]byte(output))
+	if r.body != output || !r.chunked {
+		t.Errorf("Expected the output as a chunked body, got %d bytes (chunked: %v)", len(r.body), r.chunked)
+	}
+	if r.project != "demo" || r.contentType != "text/plain; charset=utf-8" || r.hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected headers: %+v", r)
+	}
+}
+
+func TestStreamRejected(t *testing.T) {
+	// The server answers before reading the whole body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no space left", http.StatusInsufficientStorage)
+	}))
+	defer server.Close()
+
+	s, err := Start(context.Background(), server.URL, "demo", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := []byte(strings.Repeat("x", 64<<10))
+	for i := 0; i < 100; i++ {
+		if n, err := s.Write(chunk); n != len(chunk) || err != nil {
+			t.Fatalf("Expected writes to go on, got %d, %v", n, err)
+		}
+	}
+	if status, err := s.Finish(); err == nil || status != "507 Insufficient Storage" {
+		t.Errorf("Expected the rejection to be reported, got %q (%v)", status, err)
+	}
+}
+
+func TestStreamAbort(t *testing.T) {
+	failed := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		failed <- err != nil
+	}))
+	defer server.Close()
+
+	s, err := Start(context.Background(), server.URL, "demo", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("FILE: partial\n"))
+	s.Abort(errors.New("scan failed"))
+	if !<-failed {
+		t.Error("Expected the server to see the body fail")
+	}
+}
@@ -0,0 +1,274 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// emitter frames the files of one output in its format. The scanner reads
+// every file once and hands it to all the emitters of an output at the same
+// time, through a fanOut.
+type emitter interface {
+	// start begins the output. tree holds the tree's paths, or is nil when
+	// the output has no tree.
+	start(tree []string) error
+
+	// section starts a group of files, such as the documentation written
+	// first by docs_first: label names it in text, title in documents.
+	section(label, title string)
+
+	// beginFile starts a file and returns the writer for its content, which
+	// endFile completes. format is the file's rule's format, which decides
+	// how the content is written in place of the output's own; "" keeps it.
+	beginFile(relPath string, notes []string, format string) io.Writer
+	endFile() error
+
+	// listFile records a file without content, such as a listed binary,
+	// size bytes large on disk.
+	listFile(relPath string, notes []string, size int64)
+
+	// wantsContent reports whether the emitter writes files' content, or
+	// counts it. When none of an output's emitters do, files are listed
+	// instead of read.
+	wantsContent() bool
+
+	// finish writes whatever the format holds back until all files are known
+	// and flushes the output.
+	finish(title, summary string) error
+}
+
+// newEmitter returns the emitter for a format, writing to w. summary tells
+// it that finish will be given a summary to put in front, gap is the
+// file_gap of text output, and sizesOnly leaves out the lines of index
+// output.
+func newEmitter(format string, w io.Writer, summary bool, gap int, sizesOnly bool) emitter {
+	switch format {
+	case config.FormatMarkdownDoc:
+		return newMarkdownDoc(w)
+	case config.FormatJSON:
+		return newJSONDoc(w)
+	case config.FormatIndex:
+		return newIndexDoc(w, !sizesOnly)
+	case config.FormatHTML:
+		return newHTMLDoc(w)
+	default:
+		return newTextDoc(w, summary, gap)
+	}
+}
+
+// fanOut sends every part of the output to each of its emitters.
+type fanOut []emitter
+
+func (f fanOut) start(tree []string) error {
+	for _, e := range f {
+		if err := e.start(tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f fanOut) section(label, title string) {
+	for _, e := range f {
+		e.section(label, title)
+	}
+}
+
+// beginFile starts the file in every emitter and returns a writer that
+// copies its content to all of them.
+func (f fanOut) beginFile(relPath string, notes []string, format string) io.Writer {
+	writers := make([]io.Writer, len(f))
+	for i, e := range f {
+		writers[i] = e.beginFile(relPath, notes, format)
+	}
+	return io.MultiWriter(writers...)
+}
+
+func (f fanOut) endFile() error {
+	for _, e := range f {
+		if err := e.endFile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f fanOut) listFile(relPath string, notes []string, size int64) {
+	for _, e := range f {
+		e.listFile(relPath, notes, size)
+	}
+}
+
+func (f fanOut) wantsContent() bool {
+	for _, e := range f {
+		if e.wantsContent() {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanOut) finish(title, summary string) error {
+	for _, e := range f {
+		if err := e.finish(title, summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// textDoc writes each file under a plain-text header. It streams to the
+// output, unless a summary goes in front, which needs the whole run's counts;
+// then everything is held back until finish, in a temporary file so large
+// outputs don't stay in memory.
+type textDoc struct {
+	out *bufio.Writer
+
+	// w is where the tree and files go: out, or held. heldFile is held when
+	// it is a temporary file, and not a buffer.
+	w        *bufio.Writer
+	held     io.ReadWriter
+	heldFile *os.File
+	holding  bool
+
+	// flushed counts what w has flushed, for offset. prefix is the length
+	// of the summary finish put in front of the held files.
+	flushed *countingWriter
+	prefix  int64
+
+	// content sanitizes the content of the file being written. Content in
+	// the markdown-doc format is held in fenced until it can be fenced.
+	content *sanitizer
+	relPath string
+	fenced  *bytes.Buffer
+
+	// gap is the number of newlines after each file's content.
+	gap int
+}
+
+func newTextDoc(w io.Writer, summary bool, gap int) *textDoc {
+	d := &textDoc{holding: summary, gap: gap, flushed: &countingWriter{w: w}}
+	d.out = bufio.NewWriter(d.flushed)
+	d.w = d.out
+	if summary {
+		d.held = &bytes.Buffer{}
+		if f, err := os.CreateTemp("", "textify-body-*"); err == nil {
+			// Unlinked at once where the system allows it, so nothing is
+			// left behind by a run that never finishes
+			os.Remove(f.Name())
+			d.held, d.heldFile = f, f
+		}
+		d.flushed = &countingWriter{w: d.held}
+		d.w = bufio.NewWriter(d.flushed)
+	}
+	return d
+}
+
+// offset is how much was written to the output so far, not counting the
+// summary finish puts in front (prefix).
+func (d *textDoc) offset() int64 {
+	return d.flushed.n + int64(d.w.Buffered())
+}
+
+// copySection writes a file's section of an earlier output again, as it was.
+func (d *textDoc) copySection(r io.Reader) error {
+	_, err := io.Copy(d.w, r)
+	return err
+}
+
+func (d *textDoc) start(tree []string) error {
+	if tree == nil {
+		return nil
+	}
+	return writeTree(d.w, tree)
+}
+
+func (d *textDoc) section(label, title string) {
+	fmt.Fprintf(d.w, "%s:\n\n", label)
+}
+
+func (d *textDoc) beginFile(relPath string, notes []string, format string) io.Writer {
+	d.listFile(relPath, notes, 0)
+	d.relPath, d.fenced = relPath, nil
+	if format == config.FormatMarkdownDoc {
+		d.fenced = &bytes.Buffer{}
+		d.content = newSanitizer(d.fenced)
+		return d.content
+	}
+	d.content = newSanitizer(d.w)
+	return d.content
+}
+
+func (d *textDoc) endFile() error {
+	if err := d.content.Flush(); err != nil {
+		return err
+	}
+	lineStart := d.content.lineStart
+	if d.fenced != nil {
+		writeFenced(d.w, d.relPath, d.fenced.Bytes())
+		lineStart = true
+	}
+	// The next header must start on a line of its own
+	gap := d.gap
+	if gap == 0 && !lineStart {
+		gap = 1
+	}
+	_, err := io.WriteString(d.w, strings.Repeat("\n", gap))
+	return err
+}
+
+// listFile writes the separator block that introduces a file.
+func (d *textDoc) listFile(relPath string, notes []string, size int64) {
+	fmt.Fprintf(d.w, "%s\n", separator)
+	fmt.Fprintf(d.w, "%s\n", fileHeader(relPath, notes))
+	fmt.Fprintf(d.w, "%s\n\n", separator)
+}
+
+func (d *textDoc) wantsContent() bool { return true }
+
+func (d *textDoc) finish(title, summary string) error {
+	if d.holding {
+		if err := d.w.Flush(); err != nil {
+			return err
+		}
+		if d.heldFile != nil {
+			defer os.Remove(d.heldFile.Name())
+			defer d.heldFile.Close()
+			if _, err := d.heldFile.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		n, _ := fmt.Fprintf(d.out, "%s\n\n", summary)
+		d.prefix = int64(n)
+		if _, err := io.Copy(d.out, d.held); err != nil {
+			return err
+		}
+	}
+	return d.out.Flush()
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// fileHeader builds the FILE line for a file, including any annotations.
+func fileHeader(relPath string, notes []string) string {
+	if len(notes) == 0 {
+		return "FILE: " + relPath
+	}
+	return fmt.Sprintf("FILE: %s (%s)", relPath, strings.Join(notes, ", "))
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+)
+
+// defaultEncodedRunLength is the shortest base64 run counted as encoded data.
+const defaultEncodedRunLength = 200
+
+// isBase64Byte reports whether b can appear in standard base64.
+func isBase64Byte(b byte) bool {
+	return b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' ||
+		b == '+' || b == '/' || b == '='
+}
+
+// encodedRuns returns the [start, end) offsets of base64-looking runs of at
+// least minRun bytes. Runs may span line breaks, so wrapped payloads such as
+// PEM bundles count as one run, but never start or end on one.
+func encodedRuns(data []byte, minRun int) [][2]int {
+	var runs [][2]int
+	start := -1
+	// end is one past the last base64 byte of the current run
+	end := 0
+	flush := func() {
+		if start >= 0 && end-start >= minRun {
+			runs = append(runs, [2]int{start, end})
+		}
+		start = -1
+	}
+
+	for i, b := range data {
+		switch {
+		case isBase64Byte(b):
+			if start < 0 {
+				start = i
+			}
+			end = i + 1
+		case (b == '\n' || b == '\r') && start >= 0:
+			// Line breaks continue a run but are not part of it yet
+		default:
+			flush()
+		}
+	}
+	flush()
+	return runs
+}
+
+// encodedShare returns the fraction of data made up of base64 runs.
+func encodedShare(data []byte, minRun int) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	encoded := 0
+	for _, r := range encodedRuns(data, minRun) {
+		encoded += r[1] - r[0]
+	}
+	return float64(encoded) / float64(len(data))
+}
+
+// collapseEncoded replaces each base64 run with a short placeholder giving
+// its size, e.g. "[base64 data, 14KB]".
+func collapseEncoded(data []byte, minRun int) []byte {
+	var out []byte
+	last := 0
+	for _, r := range encodedRuns(data, minRun) {
+		out = append(out, data[last:r[0]]...)
+		out = append(out, fmt.Sprintf("[base64 data, %s]", fileutil.FormatSize(int64(r[1]-r[0])))...)
+		last = r[1]
+	}
+	return append(out, data[last:]...)
+}
@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestCollapseEncodedData(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_encoded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	payload := strings.Repeat("QUJDRA==", 256) // 2KB of base64
+	svg := `<svg><image href="data:image/png;base64,` + payload + `"/></svg>` + "\n"
+	pem := "-----BEGIN CERTIFICATE-----\n" + strings.Repeat(strings.Repeat("A", 64)+"\n", 8) + "-----END CERTIFICATE-----\n"
+	createFile(t, tempDir, "icon.svg", svg)
+	createFile(t, tempDir, "forced.svg", svg)
+	createFile(t, tempDir, "cert.pem", pem)
+	createFile(t, tempDir, "main.go", "package main\n\nconst token = \""+strings.Repeat("x", 300)+"\"\n\n"+strings.Repeat("func f() { println(\"hello, world\") }\n", 20))
+
+	cfg := &config.Config{
+		OutputFile:          "codebase.txt",
+		EncodedDataFraction: 0.5,
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Include: []string{"forced.svg"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	// Mostly-encoded files keep their structure with the payload collapsed
+	assertContains(t, output, `<svg><image href="data:image/png;base64,[base64 data, 2KB]"/></svg>`)
+	assertContains(t, output, "-----BEGIN CERTIFICATE-----\n[base64 data, 519B]\n-----END CERTIFICATE-----")
+
+	// Force-included files are written in full
+	assertContains(t, output, "FILE: forced.svg")
+	if strings.Count(output, payload) != 1 {
+		t.Errorf("Expected the force-included file to keep its payload")
+	}
+
+	// A single long run in an otherwise normal file stays untouched
+	assertContains(t, output, strings.Repeat("x", 300))
+}
@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maskedValue replaces the value of every masked dotenv key.
+const maskedValue = "****"
+
+// isEnvFile reports whether a file name is a dotenv file (.env, .env.local, ...).
+func isEnvFile(name string) bool {
+	return name == ".env" || strings.HasPrefix(name, ".env.")
+}
+
+// maskEnv copies a dotenv file from src to dst, replacing each KEY=value
+// assignment's value with maskedValue unless KEY is in keep. Comments, blank
+// lines, and line endings are preserved, so line counts are unchanged.
+func maskEnv(dst io.Writer, src io.Reader, keep map[string]bool) error {
+	r := bufio.NewReader(src)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			if _, werr := io.WriteString(dst, maskEnvLine(line, keep)); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// maskEnvLine masks the value of a single dotenv line.
+func maskEnvLine(line string, keep map[string]bool) string {
+	body := strings.TrimRight(line, "\r\n")
+	ending := line[len(body):]
+
+	trimmed := strings.TrimSpace(body)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return line
+	}
+
+	eq := strings.Index(body, "=")
+	if eq < 0 {
+		return line
+	}
+	key := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(body[:eq]), "export "))
+	if keep[key] {
+		return line
+	}
+	return body[:eq+1] + maskedValue + ending
+}
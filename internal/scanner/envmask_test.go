@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestMaskEnvKeepsAllowlistedKeys(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_envmask")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, ".env", strings.Join([]string{
+		"# service settings",
+		"NODE_ENV=production",
+		"export LOG_LEVEL = debug",
+		"DATABASE_URL=postgres://user:hunter2@db/app",
+		"",
+		"API_KEY=sk-secret",
+		"NODE_ENV_EXTRA=also-secret",
+	}, "\n"))
+	createFile(t, tempDir, "config.txt", "API_KEY=not-an-env-file")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		MaskEnv:     true,
+		EnvKeepKeys: []string{"NODE_ENV", "LOG_LEVEL"},
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	want := strings.Join([]string{
+		"# service settings",
+		"NODE_ENV=production",
+		"export LOG_LEVEL = debug",
+		"DATABASE_URL=****",
+		"",
+		"API_KEY=****",
+		"NODE_ENV_EXTRA=****",
+	}, "\n")
+	assertContains(t, output, want)
+	assertNotContains(t, output, "hunter2")
+	assertNotContains(t, output, "sk-secret")
+
+	// Only dotenv files are masked
+	assertContains(t, output, "API_KEY=not-an-env-file")
+}
@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/walker"
+)
+
+// Export writes the files Scan would include to destDir as a directory tree
+// instead of one output, each at its path relative to rootPath. The same
+// rules, binary check, and content settings apply, so each file holds
+// exactly what its section of the output would. Rule outputs are ignored:
+// every file goes to destDir. destDir must not exist yet or be empty, and
+// must not hold the project.
+func Export(rootPath string, cfg *config.Config, destDir string) (*Result, error) {
+	absRoot, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	absDest, err := filepath.Abs(destDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExportDest(absRoot, absDest); err != nil {
+		return nil, err
+	}
+
+	exportCfg := *cfg
+	exportCfg.Dirs = make(map[string]config.DirRule, len(cfg.Dirs))
+	for dir, rule := range cfg.Dirs {
+		rule.OutputFile = ""
+		exportCfg.Dirs[dir] = rule
+	}
+	exportCfg.ListBinaries = false
+	// Every exported file is a real copy
+	exportCfg.KeepLinkedDuplicates = true
+
+	w, err := newWalker(nil, rootPath, &exportCfg)
+	if err != nil {
+		return nil, err
+	}
+	// The destination is never part of what is exported
+	if rel := walker.RelSlash(absRoot, absDest); rel != ".." && !strings.HasPrefix(rel, "../") && !filepath.IsAbs(rel) {
+		w.SkipPaths[rel] = true
+	}
+	s, err := newScanner(rootPath, &exportCfg, Hooks{})
+	if err != nil {
+		return nil, err
+	}
+	defer s.saveCache()
+
+	stats := &statsVisitor{results: make(map[string]*Result)}
+	files := &fileCollector{}
+	if err := w.Walk(stats, files); err != nil {
+		return stats.result(""), err
+	}
+	s.files = files.files
+	s.probeFiles()
+
+	dest := &dirExport{rootPath: rootPath, destDir: destDir}
+	s.out = fanOut{dest}
+	s.result = stats.result("")
+	defer dest.abandon()
+	for _, f := range s.files {
+		// Unreadable files are skipped, but failing to write stops the export
+		s.appendFileContent(f)
+		if dest.err != nil {
+			return s.result, dest.err
+		}
+		if err := s.checkOutputSize(); err != nil {
+			return s.result, err
+		}
+	}
+	return s.result, nil
+}
+
+// checkExportDest fails unless the absolute destDir is new or an empty
+// directory, and doesn't hold rootPath, whose files it could overwrite.
+func checkExportDest(rootPath, destDir string) error {
+	if rel := walker.RelSlash(destDir, rootPath); rel == "." || (rel != ".." && !strings.HasPrefix(rel, "../") && !filepath.IsAbs(rel)) {
+		return fmt.Errorf("%s holds the project; export to a new folder", destDir)
+	}
+
+	entries, err := os.ReadDir(destDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%s is not empty; export to a new or empty folder", destDir)
+	}
+	return nil
+}
+
+// dirExport is the emitter of Export: each file's content goes to its own
+// file under destDir, with the permissions of the original. Headers, the
+// tree, and sections have no place in it.
+type dirExport struct {
+	rootPath string
+	destDir  string
+
+	file *os.File
+	w    *bufio.Writer
+
+	// err is the first write error, which stops the export.
+	err error
+}
+
+func (d *dirExport) start(tree []string) error { return nil }
+
+func (d *dirExport) section(label, title string) {}
+
+func (d *dirExport) beginFile(relPath string, notes []string, format string) io.Writer {
+	d.abandon()
+	if d.err != nil {
+		return io.Discard
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filepath.Join(d.rootPath, filepath.FromSlash(relPath))); err == nil {
+		mode = info.Mode().Perm()
+	}
+	dst := filepath.Join(d.destDir, filepath.FromSlash(relPath))
+	if d.err = os.MkdirAll(filepath.Dir(dst), 0755); d.err != nil {
+		return io.Discard
+	}
+	if d.file, d.err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode); d.err != nil {
+		return io.Discard
+	}
+	d.w = bufio.NewWriter(d.file)
+	return d
+}
+
+// Write passes content to the current file, remembering the first error.
+func (d *dirExport) Write(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	var n int
+	n, d.err = d.w.Write(p)
+	return n, d.err
+}
+
+func (d *dirExport) endFile() error {
+	if d.file == nil {
+		return d.err
+	}
+	if err := d.w.Flush(); err != nil && d.err == nil {
+		d.err = err
+	}
+	if err := d.file.Close(); err != nil && d.err == nil {
+		d.err = err
+	}
+	d.file, d.w = nil, nil
+	return d.err
+}
+
+// abandon closes a file whose read failed, so it was never ended.
+func (d *dirExport) abandon() {
+	if d.file != nil {
+		d.file.Close()
+		d.file, d.w = nil, nil
+	}
+}
+
+func (d *dirExport) listFile(relPath string, notes []string, size int64) {}
+
+func (d *dirExport) wantsContent() bool { return true }
+
+func (d *dirExport) finish(title, summary string) error { return nil }
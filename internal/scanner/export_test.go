@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/walker"
+)
+
+func TestExport(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"src/util", "logs", "docs"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	createFile(t, tempDir, "main.go", "package main\n")
+	createFile(t, tempDir, "src/util/strings.go", "package util\n")
+	createFile(t, tempDir, "src/logo.png", "\x89PNG\x00\x00")
+	createFile(t, tempDir, "logs/run.log", "ignored\n")
+	createFile(t, tempDir, ".gitignore", "logs/\n")
+	createFile(t, tempDir, "docs/guide.md", "# Guide\n")
+	createFile(t, tempDir, ".env", "TOKEN=secret\n")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		MaskEnv:    true,
+		Dirs: map[string]config.DirRule{
+			".":    {Enabled: true, Exclude: []string{".gitignore"}},
+			"docs": {Enabled: true, OutputFile: "docs.txt"},
+		},
+	}
+
+	// The destination may be inside the project, empty
+	dest := filepath.Join(tempDir, "export")
+	os.Mkdir(dest, 0755)
+	result, err := Export(tempDir, cfg, dest)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var exported []string
+	filepath.Walk(dest, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			exported = append(exported, walker.RelSlash(dest, path))
+		}
+		return nil
+	})
+	sort.Strings(exported)
+	expected := []string{".env", "docs/guide.md", "main.go", "src/util/strings.go"}
+	if !reflect.DeepEqual(exported, expected) {
+		t.Errorf("Expected exactly %q, got %q", expected, exported)
+	}
+	if result.Included != 4 || result.Skipped[ReasonBinary] != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	// Files hold what the output would, transformed but without headers
+	data, _ := os.ReadFile(filepath.Join(dest, "src/util/strings.go"))
+	if string(data) != "package util\n" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(dest, ".env"))
+	if string(data) == "TOKEN=secret\n" {
+		t.Errorf("Expected .env to be masked, got %q", data)
+	}
+
+	// Exporting again needs a new folder
+	if _, err := Export(tempDir, cfg, dest); err == nil {
+		t.Error("Expected an error exporting to a non-empty folder")
+	}
+	if _, err := Export(tempDir, cfg, filepath.Dir(tempDir)); err == nil {
+		t.Error("Expected an error exporting to a folder holding the project")
+	}
+}
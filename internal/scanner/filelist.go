@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/walker"
+)
+
+// ReasonNotFound is the skip reason of listed paths that aren't files in the
+// project.
+const ReasonNotFound = "not found"
+
+// ScanFiles writes exactly the listed files, in the order given, instead of
+// walking the project: the path rules, gitignore, and rule outputs don't
+// apply, but the binary check, the content settings, the tree, and the output
+// formats do. Paths are resolved against rootPath; paths that aren't files
+// inside it, and the outputs and config themselves, are skipped. It suits
+// lists produced by other tools, such as 'git diff --name-only'.
+func ScanFiles(rootPath string, cfg *config.Config, paths []string, writer io.Writer) (*Result, error) {
+	return ScanFilesContext(context.Background(), rootPath, cfg, paths, writer)
+}
+
+// ScanFilesContext is ScanFiles, stopping once ctx is done, as ScanContext
+// does.
+func ScanFilesContext(ctx context.Context, rootPath string, cfg *config.Config, paths []string, writer io.Writer) (*Result, error) {
+	s, err := newScanner(rootPath, cfg, Hooks{})
+	if err != nil {
+		return nil, err
+	}
+	s.ctx = ctx
+	defer s.saveCache()
+
+	stats := &statsVisitor{results: make(map[string]*Result)}
+	skipped := stats.result("")
+	skip := func(reason string) {
+		skipped.Skipped[reason]++
+		skipped.SkippedFiles++
+	}
+
+	never := skipPaths(rootPath, cfg)
+	seen := make(map[string]bool)
+	var tree []string
+	for _, p := range paths {
+		relPath, ok := listedPath(rootPath, p)
+		if !ok {
+			fmt.Printf("Warning: %s is outside the project; skipping\n", p)
+			skip(ReasonNotFound)
+			continue
+		}
+		if seen[relPath] {
+			continue
+		}
+		seen[relPath] = true
+		if never[relPath] {
+			skip(ReasonExcluded)
+			continue
+		}
+
+		absPath := filepath.Join(rootPath, filepath.FromSlash(relPath))
+		if info, err := os.Stat(absPath); err != nil || !info.Mode().IsRegular() {
+			fmt.Printf("Warning: %s is not a file; skipping\n", p)
+			skip(ReasonNotFound)
+			continue
+		}
+		s.files = append(s.files, fileEntry{absPath: absPath, relPath: relPath})
+		tree = append(tree, relPath)
+	}
+	natural := cfg.TreeSort == config.TreeSortNatural
+	sort.Slice(tree, func(i, j int) bool { return walker.PathLess(tree[i], tree[j], natural) })
+
+	// The rules don't apply, and neither do the outputs they set
+	listCfg := *cfg
+	listCfg.Dirs = nil
+	return s.writeOutputs(&listCfg, writer, map[string][]string{"": tree}, stats)
+}
+
+// listedPath returns the slash-separated path of a listed file relative to
+// rootPath, and false if it is outside rootPath.
+func listedPath(rootPath, p string) (string, bool) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(rootPath, p)
+	}
+	relPath := walker.RelSlash(rootPath, p)
+	if relPath == ".." || strings.HasPrefix(relPath, "../") || filepath.IsAbs(relPath) {
+		return "", false
+	}
+	return relPath, true
+}
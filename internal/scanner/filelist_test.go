@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestScanFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_filelist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "sub"), 0755)
+	createFile(t, tempDir, "a.go", "package a\n")
+	createFile(t, tempDir, "b.go", "package b\n")
+	createFile(t, tempDir, "sub/c.go", "package sub\n")
+	createFile(t, tempDir, "codebase.txt", "old output\n")
+
+	// The rules would leave out sub/, but a listed file is always considered
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IncludeTree: true,
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}, "sub": {Enabled: false}},
+	}
+	list := []string{"sub/c.go", "./a.go", "a.go", "deleted.go", "../outside.go", "sub", "codebase.txt"}
+
+	var buf bytes.Buffer
+	result, err := ScanFiles(tempDir, cfg, list, &buf)
+	if err != nil {
+		t.Fatalf("ScanFiles failed: %v", err)
+	}
+	output := buf.String()
+	if !strings.HasPrefix(output, "PROJECT STRUCTURE:\n.\n├── a.go\n└── sub\n    └── c.go\n") {
+		t.Errorf("Unexpected tree:\n%s", output)
+	}
+	// Files are written in the order listed
+	first, second := strings.Index(output, "FILE: sub/c.go"), strings.Index(output, "FILE: a.go")
+	if first < 0 || second < first {
+		t.Errorf("Expected sub/c.go, then a.go:\n%s", output)
+	}
+	assertNotContains(t, output, "FILE: b.go")
+	assertNotContains(t, output, "old output")
+
+	if result.Included != 2 || result.Skipped[ReasonNotFound] != 3 || result.Skipped[ReasonExcluded] != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
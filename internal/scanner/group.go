@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// otherGroup holds the files group_by can't place: those in none of the
+// known languages, or without an extension.
+const otherGroup = ""
+
+// fileGroup is a section of the output for group_by, starting at the file
+// at index start.
+type fileGroup struct {
+	start int
+	key   string
+}
+
+// label and title are the group's section names, as docs_first's are
+// "DOCUMENTATION" and "Documentation".
+func (g fileGroup) label() string {
+	if g.key == otherGroup {
+		return "OTHER"
+	}
+	return strings.ToUpper(strings.TrimPrefix(g.key, "."))
+}
+
+func (g fileGroup) title() string {
+	if g.key == otherGroup {
+		return "Other"
+	}
+	if strings.HasPrefix(g.key, ".") {
+		return g.key
+	}
+	return strings.ToUpper(g.key[:1]) + g.key[1:]
+}
+
+// groupKey returns the language ("go") or the extension (".go") a file is
+// grouped by.
+func groupKey(relPath, by string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(relPath), "."))
+	if ext == "" {
+		return otherGroup
+	}
+	if by == config.GroupByLanguage {
+		return config.LanguageOf(ext)
+	}
+	return "." + ext
+}
+
+// groupFiles sorts s.files into groups by language or extension, in name
+// order with the other files last, and returns where each group starts.
+// Files keep their order within a group. Only the list of paths is
+// reordered; no content is read or held.
+func (s *scanner) groupFiles(by string) []fileGroup {
+	keys := make([]string, len(s.files))
+	for i, f := range s.files {
+		keys[i] = groupKey(f.relPath, by)
+	}
+	order := make([]int, len(s.files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := keys[order[i]], keys[order[j]]
+		if (a == otherGroup) != (b == otherGroup) {
+			return b == otherGroup
+		}
+		return a < b
+	})
+
+	files := make([]fileEntry, len(s.files))
+	var groups []fileGroup
+	for i, j := range order {
+		files[i] = s.files[j]
+		if len(groups) == 0 || groups[len(groups)-1].key != keys[j] {
+			groups = append(groups, fileGroup{start: i, key: keys[j]})
+		}
+	}
+	s.files = files
+	return groups
+}
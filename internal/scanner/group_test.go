@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestGroupBy(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "cmd"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "web"), 0755)
+	files := map[string]string{
+		"Makefile":    "build:\n\tgo build\n",
+		"README.md":   "# Demo\n",
+		"cmd/tool.go": "package main\n",
+		"main.go":     "package main\n",
+		"web/app.ts":  "export {}\n",
+		"web/app.tsx": "export {}\n",
+	}
+	for name, content := range files {
+		createFile(t, tempDir, name, content)
+	}
+
+	tests := []struct {
+		by     string
+		labels []string
+		order  []string
+	}{
+		{config.GroupByLanguage,
+			[]string{"GO:", "MARKDOWN:", "TYPESCRIPT:", "OTHER:"},
+			[]string{"cmd/tool.go", "main.go", "README.md", "web/app.ts", "web/app.tsx", "Makefile"}},
+		{config.GroupByExtension,
+			[]string{"GO:", "MD:", "TS:", "TSX:", "OTHER:"},
+			[]string{"cmd/tool.go", "main.go", "README.md", "web/app.ts", "web/app.tsx", "Makefile"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			cfg := &config.Config{
+				OutputFile: "codebase.txt",
+				GroupBy:    tt.by,
+				DocsFirst:  true, // replaced by the groups
+				Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+			}
+			var buf bytes.Buffer
+			if _, err := Scan(tempDir, cfg, &buf); err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+			output := buf.String()
+
+			var labels []string
+			for _, line := range strings.Split(output, "\n") {
+				if strings.HasSuffix(line, ":") && strings.ToUpper(line) == line && !strings.HasPrefix(line, "FILE") {
+					labels = append(labels, line)
+				}
+			}
+			if !reflect.DeepEqual(labels, tt.labels) {
+				t.Errorf("Expected sections %q, got %q:\n%s", tt.labels, labels, output)
+			}
+
+			sections, err := ParseOutput(buf.Bytes())
+			if err != nil {
+				t.Fatalf("ParseOutput failed: %v", err)
+			}
+			var order []string
+			for _, s := range sections {
+				order = append(order, s.Path)
+				if s.Content != files[s.Path] {
+					t.Errorf("Expected %s to parse back as %q, got %q", s.Path, files[s.Path], s.Content)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("Expected files in order %q, got %q", tt.order, order)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"os"
+
+	"github.com/JohnEsleyer/textify/internal/walker"
+)
+
+// ReasonHook is the skip reason of files vetoed by Hooks.OnFileStart.
+const ReasonHook = "hook"
+
+// Hooks are optional callbacks that let a caller follow a scan file by file,
+// e.g. to stream progress or compute its own metrics, and veto files. Paths
+// are slash-separated and relative to the root. Every hook is called
+// synchronously on the goroutine running the scan, during the walk for
+// path-based skips and while files are written for the rest; should file
+// reading ever move to worker goroutines, hooks will be called from those, so
+// they should be safe for concurrent use.
+type Hooks struct {
+	// OnFileStart is called for each file once every built-in rule has let
+	// it through, just before it is written. Returning true skips the file,
+	// with ReasonHook.
+	OnFileStart func(relPath string, info os.FileInfo) (skip bool)
+
+	// OnFileWritten is called after a file's content was written, with the
+	// bytes and lines written.
+	OnFileWritten func(relPath string, bytes int64, lines int)
+
+	// OnSkip is called for every entry left out, with the reason. Skipped
+	// directories are reported once; their contents aren't walked.
+	OnSkip func(relPath, reason string)
+}
+
+// skipHookVisitor reports the walker's skips to Hooks.OnSkip.
+type skipHookVisitor struct {
+	onSkip func(relPath, reason string)
+}
+
+func (v *skipHookVisitor) OnDir(d walker.Decision) {
+	if !d.Include {
+		v.onSkip(d.RelPath, d.Reason)
+	}
+}
+
+func (v *skipHookVisitor) OnFile(d walker.Decision) {
+	if !d.Include {
+		v.onSkip(d.RelPath, d.Reason)
+	}
+}
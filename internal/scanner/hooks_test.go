@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestHooks(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "vendor"), 0755)
+	createFile(t, tempDir, "a.go", "package a\n\nfunc A() {}\n")
+	createFile(t, tempDir, "secret.go", "package a\n")
+	createFile(t, tempDir, "debug.log", "log\n")
+	createFile(t, tempDir, "vendor/lib.go", "package lib\n")
+	createFile(t, tempDir, "blob.go", "\x00\x01\x02")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".":      {Enabled: true, Extensions: []string{"go"}},
+			"vendor": {Enabled: false},
+		},
+	}
+
+	var events []string
+	hooks := Hooks{
+		OnFileStart: func(relPath string, info os.FileInfo) bool {
+			events = append(events, fmt.Sprintf("start %s (%d bytes)", relPath, info.Size()))
+			return relPath == "secret.go"
+		},
+		OnFileWritten: func(relPath string, bytes int64, lines int) {
+			events = append(events, fmt.Sprintf("written %s (%d bytes, %d lines)", relPath, bytes, lines))
+		},
+		OnSkip: func(relPath, reason string) {
+			events = append(events, fmt.Sprintf("skip %s: %s", relPath, reason))
+		},
+	}
+
+	var buf bytes.Buffer
+	result, err := ScanWithHooks(tempDir, cfg, &buf, hooks)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	// Path skips come from the walk, before any file is written
+	expected := []string{
+		"skip debug.log: extension not allowed",
+		"skip vendor: disabled",
+		"start a.go (23 bytes)",
+		"written a.go (23 bytes, 3 lines)",
+		"skip blob.go: binary",
+		"start secret.go (10 bytes)",
+		"skip secret.go: hook",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Unexpected events.\nExpected: %q\nGot:      %q", expected, events)
+	}
+	assertNotContains(t, buf.String(), "secret.go")
+	if result.Included != 1 || result.Skipped[ReasonHook] != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestScanContextCancel(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for i := 0; i < 5; i++ {
+		createFile(t, tempDir, fmt.Sprintf("f%d.go", i), "package f\n")
+	}
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	// Cancel once the first file is under way; it still finishes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hooks := Hooks{
+		OnFileStart: func(relPath string, info os.FileInfo) bool {
+			cancel()
+			return false
+		},
+	}
+	var buf bytes.Buffer
+	result, err := ScanContext(ctx, tempDir, cfg, &buf, hooks)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if result == nil || result.Included != 1 {
+		t.Errorf("Expected the scan to stop after one file, got %+v", result)
+	}
+	assertNotContains(t, buf.String(), "f1.go")
+
+	// A context done before the scan stops the walk
+	if _, err := ScanContext(ctx, tempDir, cfg, &buf, Hooks{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled from a cancelled walk, got %v", err)
+	}
+}
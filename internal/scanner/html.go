@@ -0,0 +1,211 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// htmlHead starts every html output; outputStart recognizes it.
+const htmlHead = "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<meta name=\"generator\" content=\"textify\">\n"
+
+// Limits that keep a large html output usable: files are collapsed <details>
+// elements, which browsers don't lay out until opened, and only files up to
+// htmlOpenFileBytes start open, until htmlOpenBytes of content are open in
+// all. Folders of the sidebar start open up to htmlOpenTreePaths paths.
+const (
+	htmlOpenFileBytes = 64 << 10
+	htmlOpenBytes     = 1 << 20
+	htmlOpenTreePaths = 300
+)
+
+// htmlStyle is the inline style sheet of html output.
+const htmlStyle = `<style>
+body { margin: 0; font: 14px/1.5 system-ui, sans-serif; color: #1f2328; display: flex; }
+nav { position: sticky; top: 0; height: 100vh; overflow: auto; width: 280px; flex: none; padding: 12px; box-sizing: border-box; background: #f6f8fa; border-right: 1px solid #d0d7de; font-size: 13px; }
+nav ul { list-style: none; margin: 0; padding-left: 14px; }
+nav > ul { padding-left: 0; }
+nav a { color: #0969da; text-decoration: none; }
+nav summary { cursor: pointer; }
+main { flex: 1; min-width: 0; padding: 0 24px 48px; }
+h1 { border-bottom: 1px solid #d0d7de; padding-bottom: 8px; }
+.summary, .notes { color: #59636e; }
+.file { margin: 12px 0; border: 1px solid #d0d7de; border-radius: 6px; }
+.file > summary { padding: 6px 12px; background: #f6f8fa; cursor: pointer; font-family: ui-monospace, monospace; }
+.file .notes { margin: 6px 12px; }
+pre { margin: 0; padding: 12px; overflow: auto; font: 12px/1.45 ui-monospace, monospace; }
+</style>
+`
+
+// htmlDoc collects an html output: a single self-contained page with the
+// tree as a sidebar and a collapsible section per file. Sections are written
+// to body as files are added; the page around them is written once the files
+// actually written are known.
+type htmlDoc struct {
+	out      io.Writer
+	tree     []string
+	body     bytes.Buffer
+	sections []mdSection
+	anchors  map[string]bool
+
+	// open is how many bytes of content start open so far.
+	open int64
+
+	// relPath and content hold the file being written, and notes its notes.
+	relPath string
+	notes   []string
+	content bytes.Buffer
+}
+
+func newHTMLDoc(w io.Writer) *htmlDoc {
+	return &htmlDoc{out: w, anchors: make(map[string]bool)}
+}
+
+// start keeps the tree, which becomes the sidebar.
+func (d *htmlDoc) start(tree []string) error {
+	if tree != nil {
+		d.tree = append([]string{}, tree...)
+	}
+	return nil
+}
+
+func (d *htmlDoc) section(label, title string) {
+	fmt.Fprintf(&d.body, "<h2>%s</h2>\n", html.EscapeString(title))
+}
+
+// beginFile ignores format: content is always escaped in a <pre> block.
+func (d *htmlDoc) beginFile(relPath string, notes []string, format string) io.Writer {
+	d.relPath, d.notes = relPath, notes
+	d.content.Reset()
+	return &d.content
+}
+
+func (d *htmlDoc) endFile() error {
+	content := d.content.Bytes()
+	open := len(content) <= htmlOpenFileBytes && d.open+int64(len(content)) <= htmlOpenBytes
+	if open {
+		d.open += int64(len(content))
+	}
+	d.fileSection(d.relPath, d.notes, open, func() {
+		lang := fenceLanguage(d.relPath)
+		if lang != "" {
+			fmt.Fprintf(&d.body, "<pre><code class=\"language-%s\">", html.EscapeString(lang))
+		} else {
+			d.body.WriteString("<pre><code>")
+		}
+		d.body.WriteString(html.EscapeString(string(content)))
+		d.body.WriteString("</code></pre>\n")
+	})
+	return nil
+}
+
+func (d *htmlDoc) listFile(relPath string, notes []string, size int64) {
+	d.fileSection(relPath, notes, false, nil)
+}
+
+func (d *htmlDoc) wantsContent() bool { return true }
+
+// fileSection writes a file's section, with content writing what goes
+// inside it, if anything.
+func (d *htmlDoc) fileSection(relPath string, notes []string, open bool, content func()) {
+	anchor := uniqueAnchor(d.anchors, relPath)
+	d.sections = append(d.sections, mdSection{relPath: relPath, anchor: anchor})
+
+	attr := ""
+	if open {
+		attr = " open"
+	}
+	fmt.Fprintf(&d.body, "<details class=\"file\" id=\"%s\"%s>\n<summary>%s</summary>\n", anchor, attr, html.EscapeString(relPath))
+	if len(notes) > 0 {
+		fmt.Fprintf(&d.body, "<p class=\"notes\">%s</p>\n", html.EscapeString(strings.Join(notes, ", ")))
+	}
+	if content != nil {
+		content()
+	}
+	d.body.WriteString("</details>\n")
+}
+
+// finish writes the whole page: the head, the sidebar, the title and
+// summary, and the file sections.
+func (d *htmlDoc) finish(title, summary string) error {
+	var head bytes.Buffer
+	head.WriteString(htmlHead)
+	fmt.Fprintf(&head, "<title>%s</title>\n", html.EscapeString(title))
+	head.WriteString(htmlStyle)
+	head.WriteString("</head>\n<body>\n<nav>\n")
+	d.writeSidebar(&head)
+	head.WriteString("</nav>\n<main>\n")
+	fmt.Fprintf(&head, "<h1>%s</h1>\n", html.EscapeString(title))
+	if summary != "" {
+		fmt.Fprintf(&head, "<p class=\"summary\">%s</p>\n", html.EscapeString(summary))
+	}
+	if len(d.sections) == 0 {
+		head.WriteString("<p><em>No files were included.</em></p>\n")
+	}
+
+	if _, err := d.out.Write(head.Bytes()); err != nil {
+		return err
+	}
+	if _, err := d.out.Write(d.body.Bytes()); err != nil {
+		return err
+	}
+	_, err := io.WriteString(d.out, "</main>\n</body>\n</html>\n")
+	return err
+}
+
+// writeSidebar writes the tree, or the files written without one, as nested
+// lists, each folder a collapsible <details>, and each file written linked
+// to its section.
+func (d *htmlDoc) writeSidebar(w *bytes.Buffer) {
+	anchors := make(map[string]string, len(d.sections))
+	paths := d.tree
+	for _, sec := range d.sections {
+		anchors[sec.relPath] = sec.anchor
+		if d.tree == nil {
+			paths = append(paths, sec.relPath)
+		}
+	}
+	// Files may be written in another order, but the folders of a tree
+	// must each come in one piece
+	if d.tree == nil {
+		sort.Strings(paths)
+	}
+	folderAttr := ""
+	if len(paths) <= htmlOpenTreePaths {
+		folderAttr = " open"
+	}
+
+	w.WriteString("<ul>\n")
+	var prev []string
+	for _, p := range paths {
+		parts := strings.Split(p, "/")
+		dirs := parts[:len(parts)-1]
+
+		// Close the folders the previous path was in that this one isn't
+		common := 0
+		for common < len(dirs) && common < len(prev) && dirs[common] == prev[common] {
+			common++
+		}
+		for i := len(prev); i > common; i-- {
+			w.WriteString("</ul></details></li>\n")
+		}
+		for _, dir := range dirs[common:] {
+			fmt.Fprintf(w, "<li><details%s><summary>%s/</summary><ul>\n", folderAttr, html.EscapeString(dir))
+		}
+		prev = dirs
+
+		name := html.EscapeString(parts[len(parts)-1])
+		if anchor, ok := anchors[p]; ok {
+			fmt.Fprintf(w, "<li><a href=\"#%s\">%s</a></li>\n", anchor, name)
+		} else {
+			fmt.Fprintf(w, "<li>%s</li>\n", name)
+		}
+	}
+	for range prev {
+		w.WriteString("</ul></details></li>\n")
+	}
+	w.WriteString("</ul>\n")
+}
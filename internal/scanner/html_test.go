@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestHTMLDoc(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "a", "deep"), 0755)
+	createFile(t, tempDir, "a/util.go", "package a\n\nfunc Less(x, y int) bool { return x < y && y > 0 }\n")
+	createFile(t, tempDir, "a/deep/page.html", "<script>alert(\"hi\")</script>\n")
+	createFile(t, tempDir, "a/big.txt", strings.Repeat("x", htmlOpenFileBytes+1))
+	createFile(t, tempDir, "Makefile", "all:\n")
+
+	cfg := &config.Config{
+		OutputFile:    "codebase.html",
+		Format:        config.FormatHTML,
+		IncludeTree:   true,
+		HeaderSummary: true,
+		Dirs:          map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	if !strings.HasPrefix(output, htmlHead) || !strings.HasSuffix(output, "</html>\n") {
+		t.Errorf("Expected a whole page, got:\n%s", output)
+	}
+	assertContains(t, output, "<title>"+filepath.Base(tempDir)+"</title>\n<style>")
+	assertContains(t, output, "<p class=\"summary\">Included 4 files (")
+
+	// The sidebar nests the tree and links each file to its section
+	assertContains(t, output, "<ul>\n<li><a href=\"#makefile\">Makefile</a></li>\n<li><details open><summary>a/</summary><ul>\n"+
+		"<li><a href=\"#a-big-txt\">big.txt</a></li>\n<li><details open><summary>deep/</summary><ul>\n"+
+		"<li><a href=\"#a-deep-page-html\">page.html</a></li>\n</ul></details></li>\n"+
+		"<li><a href=\"#a-util-go\">util.go</a></li>\n</ul></details></li>\n</ul>\n</nav>")
+
+	// Content is escaped and tagged with its language
+	assertContains(t, output, "<details class=\"file\" id=\"a-util-go\" open>\n<summary>a/util.go</summary>\n"+
+		"<pre><code class=\"language-go\">package a\n\nfunc Less(x, y int) bool { return x &lt; y &amp;&amp; y &gt; 0 }\n</code></pre>\n</details>\n")
+	assertContains(t, output, "&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt;")
+	assertNotContains(t, output, "<script>")
+	assertContains(t, output, "<details class=\"file\" id=\"makefile\" open>\n<summary>Makefile</summary>\n<pre><code>all:\n")
+
+	// Large files start closed
+	assertContains(t, output, "<details class=\"file\" id=\"a-big-txt\">\n")
+
+	// The page is recognized as textify output
+	if !outputStart.MatchString(output) {
+		t.Error("Expected html output to look generated")
+	}
+}
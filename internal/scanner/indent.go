@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+)
+
+// indenter is a writer that normalizes the leading whitespace of every line
+// for indent_style. The indentation is measured in columns, with tab stops
+// every width columns, and rewritten as spaces, or as tabs followed by the
+// spaces that don't fill a whole tab, so alignment is kept. Nothing after the
+// first other character of a line is touched.
+type indenter struct {
+	w     io.Writer
+	tabs  bool
+	width int
+
+	// atStart is true while the current line has only had whitespace, whose
+	// width in columns is col.
+	atStart bool
+	col     int
+
+	out []byte
+}
+
+func newIndenter(w io.Writer, tabs bool, width int) *indenter {
+	return &indenter{w: w, tabs: tabs, width: width, atStart: true}
+}
+
+func (d *indenter) Write(p []byte) (int, error) {
+	d.out = d.out[:0]
+	for _, b := range p {
+		if d.atStart {
+			switch b {
+			case ' ':
+				d.col++
+				continue
+			case '\t':
+				d.col += d.width - d.col%d.width
+				continue
+			}
+			d.indent()
+		}
+		d.out = append(d.out, b)
+		if b == '\n' {
+			d.atStart = true
+		}
+	}
+	if _, err := d.w.Write(d.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the indentation of a final line that has nothing else.
+func (d *indenter) Flush() error {
+	if !d.atStart || d.col == 0 {
+		return nil
+	}
+	d.out = d.out[:0]
+	d.indent()
+	_, err := d.w.Write(d.out)
+	return err
+}
+
+// indent appends the pending indentation in the configured style and ends
+// the line's leading whitespace.
+func (d *indenter) indent() {
+	spaces := d.col
+	if d.tabs {
+		d.out = append(d.out, bytes.Repeat([]byte{'\t'}, d.col/d.width)...)
+		spaces = d.col % d.width
+	}
+	d.out = append(d.out, bytes.Repeat([]byte{' '}, spaces)...)
+	d.atStart, d.col = false, 0
+}
+
+// keepsIndent reports whether tabs are part of a file's syntax or data, as
+// in Makefiles and TSV, so its indentation must not be rewritten.
+func keepsIndent(relPath string) bool {
+	name := path.Base(relPath)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".mk", ".tsv":
+		return true
+	}
+	return name == "Makefile" || name == "makefile" || name == "GNUmakefile"
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestIndenter(t *testing.T) {
+	cases := []struct {
+		name  string
+		tabs  bool
+		width int
+		in    string
+		want  string
+	}{
+		{"tabs to spaces", false, 4, "func f() {\n\tif x {\n\t\treturn\n\t}\n}\n", "func f() {\n    if x {\n        return\n    }\n}\n"},
+		{"tab stops keep alignment", false, 4, "  \tx\n", "    x\n"},
+		{"spaces to tabs", true, 2, "a:\n  b:\n    c: 1\n", "a:\n\tb:\n\t\tc: 1\n"},
+		{"leftover spaces stay", true, 4, "      x\n", "\t  x\n"},
+		{"inside lines untouched", false, 2, "\ts := \"a\tb\"\n", "  s := \"a\tb\"\n"},
+		{"blank line and no final newline", true, 4, "    \n    x", "\t\n\tx"},
+		{"whitespace at the end", false, 4, "x\n\t", "x\n    "},
+	}
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		d := newIndenter(&buf, tc.tabs, tc.width)
+		// Byte-at-a-time writes must not lose the state between them
+		for i := 0; i < len(tc.in); i++ {
+			if _, err := d.Write([]byte{tc.in[i]}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := d.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestIndentStyle(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_indent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "main.go", "func main() {\n\tprintln(1)\n}\n")
+	createFile(t, tempDir, "Makefile", "build:\n\tgo build\n")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IndentStyle: "spaces:2",
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}},
+	}
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+	assertContains(t, output, "func main() {\n  println(1)\n}\n")
+	// Makefile recipes must start with a tab
+	assertContains(t, output, "build:\n\tgo build\n")
+
+	cfg.IndentStyle = "spaces"
+	if _, err := Scan(tempDir, cfg, &buf); err == nil {
+		t.Error("Expected an error for spaces without a width")
+	}
+}
@@ -0,0 +1,84 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// indexHeader starts the rows of every index output, naming the columns;
+// outputStart recognizes it.
+const indexHeader = "FILE INDEX: path  lines  bytes  language\n"
+
+// indexDoc writes an index output: a line per file with its path, lines,
+// bytes, and language, separated by two spaces, under indexHeader. The
+// lines are held back until finish, which puts the summary in front.
+type indexDoc struct {
+	out  io.Writer
+	rows bytes.Buffer
+
+	// countLines counts the lines of each file's content; without it the
+	// column is "-" and the content isn't needed.
+	countLines bool
+
+	relPath string
+	content lineCounter
+}
+
+func newIndexDoc(w io.Writer, countLines bool) *indexDoc {
+	return &indexDoc{out: w, countLines: countLines}
+}
+
+// start ignores the tree: the index already names every file.
+func (d *indexDoc) start(tree []string) error { return nil }
+
+func (d *indexDoc) section(label, title string) {}
+
+// beginFile counts the content, whatever the format.
+func (d *indexDoc) beginFile(relPath string, notes []string, format string) io.Writer {
+	d.relPath = relPath
+	d.content = lineCounter{}
+	return &d.content
+}
+
+func (d *indexDoc) endFile() error {
+	lines := "-"
+	if d.countLines {
+		lines = strconv.Itoa(d.content.count())
+	}
+	d.row(d.relPath, lines, d.content.bytes)
+	return nil
+}
+
+func (d *indexDoc) listFile(relPath string, notes []string, size int64) {
+	d.row(relPath, "-", size)
+}
+
+func (d *indexDoc) wantsContent() bool { return d.countLines }
+
+// row writes a file's line. Files in none of the known languages show "-".
+func (d *indexDoc) row(relPath, lines string, size int64) {
+	lang := config.LanguageOf(strings.TrimPrefix(path.Ext(relPath), "."))
+	if lang == "" {
+		lang = "-"
+	}
+	fmt.Fprintf(&d.rows, "%s  %s  %d  %s\n", relPath, lines, size, lang)
+}
+
+func (d *indexDoc) finish(title, summary string) error {
+	if summary != "" {
+		if _, err := fmt.Fprintf(d.out, "%s\n\n", summary); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(d.out, indexHeader); err != nil {
+		return err
+	}
+	_, err := d.rows.WriteTo(d.out)
+	return err
+}
@@ -0,0 +1,81 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestIndexFormat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "src"), 0755)
+	createFile(t, tempDir, "README.md", "# Demo\n\nRead me.\n")
+	createFile(t, tempDir, "src/main.go", "package main\n\nfunc main() {}")
+	createFile(t, tempDir, "notes.txt", "")
+	createFile(t, tempDir, "logo.png", "\x89PNG\x00\x00")
+
+	cfg := &config.Config{
+		OutputFile:    "index.txt",
+		Format:        config.FormatIndex,
+		IncludeTree:   true,
+		HeaderSummary: true,
+		ListBinaries:  true,
+		Dirs:          map[string]config.DirRule{".": {Enabled: true}},
+	}
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	expected := result.summary() + "\n\n" +
+		indexHeader +
+		"README.md  3  17  markdown\n" +
+		"logo.png  -  6  -\n" +
+		"notes.txt  0  0  -\n" +
+		"src/main.go  3  28  go\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected index.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	// Without lines, files are listed by their size alone
+	cfg.IndexSizesOnly = true
+	cfg.HeaderSummary = false
+	buf.Reset()
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	expected = indexHeader +
+		"README.md  -  17  markdown\n" +
+		"logo.png  -  6  -\n" +
+		"notes.txt  -  0  -\n" +
+		"src/main.go  -  28  go\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected index without lines.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	// An earlier index is recognized: it can be written over, and is
+	// skipped as a dump under another name
+	createFile(t, tempDir, "index.txt", buf.String())
+	createFile(t, tempDir, "old-index.txt", buf.String())
+	if generated, err := LooksGenerated(filepath.Join(tempDir, "index.txt")); err != nil || !generated {
+		t.Errorf("Expected the index to look generated, got %v (%v)", generated, err)
+	}
+	cfg.SkipTextifyDumps = true
+	buf.Reset()
+	result, err = Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if n := result.Skipped[ReasonTextifyDump]; n != 1 || strings.Contains(buf.String(), "old-index.txt") {
+		t.Errorf("Expected old-index.txt skipped as a dump, got %d dumps:\n%s", n, buf.String())
+	}
+}
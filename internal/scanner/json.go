@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// jsonDoc collects a json output, which is written whole once the files are
+// known.
+type jsonDoc struct {
+	out io.Writer
+	doc jsonOutput
+
+	// group is the group of the files being written, if any.
+	group string
+
+	// content holds the content of the file being written.
+	content bytes.Buffer
+}
+
+// jsonOutput is the top-level object of a json output.
+type jsonOutput struct {
+	Title   string     `json:"title"`
+	Summary string     `json:"summary,omitempty"`
+	Tree    []string   `json:"tree,omitempty"`
+	Files   []jsonFile `json:"files"`
+}
+
+// jsonFile is a file in a json output. Content is nil for files listed
+// without it, such as binaries.
+type jsonFile struct {
+	Path    string   `json:"path"`
+	Group   string   `json:"group,omitempty"`
+	Notes   []string `json:"notes,omitempty"`
+	Content *string  `json:"content,omitempty"`
+}
+
+func newJSONDoc(w io.Writer) *jsonDoc {
+	return &jsonDoc{out: w, doc: jsonOutput{Files: []jsonFile{}}}
+}
+
+func (d *jsonDoc) start(tree []string) error {
+	d.doc.Tree = tree
+	return nil
+}
+
+// section sets the group of the files that follow, e.g. "documentation".
+func (d *jsonDoc) section(label, title string) {
+	d.group = strings.ToLower(title)
+}
+
+// beginFile ignores format: content is always a JSON string.
+func (d *jsonDoc) beginFile(relPath string, notes []string, format string) io.Writer {
+	d.listFile(relPath, notes, 0)
+	d.content.Reset()
+	return &d.content
+}
+
+func (d *jsonDoc) endFile() error {
+	content := d.content.String()
+	d.doc.Files[len(d.doc.Files)-1].Content = &content
+	return nil
+}
+
+func (d *jsonDoc) listFile(relPath string, notes []string, size int64) {
+	d.doc.Files = append(d.doc.Files, jsonFile{Path: relPath, Group: d.group, Notes: notes})
+}
+
+func (d *jsonDoc) wantsContent() bool { return true }
+
+// finish writes the object as indented JSON. Code is full of <, >, and &,
+// so they are left unescaped.
+func (d *jsonDoc) finish(title, summary string) error {
+	d.doc.Title = title
+	d.doc.Summary = summary
+	enc := json.NewEncoder(d.out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d.doc)
+}
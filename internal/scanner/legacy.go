@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"unicode/utf8"
+)
+
+// Encodings guessed for text that isn't valid UTF-8.
+const (
+	// encodingBrokenUTF8 is mostly UTF-8 with a few invalid bytes, which
+	// are replaced with U+FFFD.
+	encodingBrokenUTF8 = "utf-8 with invalid bytes replaced"
+
+	// encodingWindows1252 is the usual encoding of legacy Western source
+	// files; it is a superset of the printable Latin-1 range.
+	encodingWindows1252 = "windows-1252"
+)
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// Latin-1. Zero marks the five undefined bytes.
+var windows1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// guessEncoding guesses the encoding of text that isn't valid UTF-8. Text
+// that already contains valid multi-byte sequences is taken to be UTF-8 with
+// some damage; otherwise it is taken to be Windows-1252.
+func guessEncoding(data []byte) string {
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r != utf8.RuneError && size > 1 {
+			return encodingBrokenUTF8
+		}
+		data = data[size:]
+	}
+	return encodingWindows1252
+}
+
+// decodeLegacy converts text in the given encoding (from guessEncoding) to
+// valid UTF-8.
+func decodeLegacy(data []byte, encoding string) []byte {
+	out := make([]byte, 0, len(data)+len(data)/8)
+	if encoding == encodingBrokenUTF8 {
+		for len(data) > 0 {
+			r, size := utf8.DecodeRune(data)
+			out = utf8.AppendRune(out, r)
+			data = data[size:]
+		}
+		return out
+	}
+
+	for _, b := range data {
+		r := rune(b)
+		if b >= 0x80 && b <= 0x9f {
+			if r = windows1252[b-0x80]; r == 0 {
+				r = utf8.RuneError
+			}
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+	"github.com/JohnEsleyer/textify/internal/tokens"
+)
+
+// ErrOutputTooLarge is returned, wrapped with the folders that contributed
+// most, when a run passes max_output_size. Nothing it wrote is kept.
+var ErrOutputTooLarge = errors.New("the output passed max_output_size")
+
+// topDirsShown is how many folders the size warning and error name.
+const topDirsShown = 5
+
+// track adds a written file's content to the running total of the run and
+// to the top-level folder it is in (or "." for root files), both for the
+// run and in the output's Result.Dirs.
+func (s *scanner) track(relPath string, n int64) {
+	s.written += n
+	dir := "."
+	if i := strings.Index(relPath, "/"); i >= 0 {
+		dir = relPath[:i]
+	}
+	s.dirBytes[dir] += n
+
+	if s.result.Dirs == nil {
+		s.result.Dirs = make(map[string]DirTotal)
+	}
+	total := s.result.Dirs[dir]
+	total.Files++
+	total.Bytes += n
+	s.result.Dirs[dir] = total
+}
+
+// checkOutputSize warns, once, when the run's output passes output_warn_size,
+// and fails with ErrOutputTooLarge when it passes max_output_size. It is
+// checked after every file, so the warning comes while the run goes on.
+func (s *scanner) checkOutputSize() error {
+	if s.maxSize > 0 && s.written > s.maxSize {
+		return fmt.Errorf("%w (%s); largest folders: %s", ErrOutputTooLarge, fileutil.FormatSize(s.maxSize), strings.Join(s.topDirs(), ", "))
+	}
+	if s.warnSize > 0 && !s.warned && s.written > s.warnSize {
+		s.warned = true
+		fmt.Printf("\n⚠ Warning: the output has passed %s (~%d tokens so far). Is a virtualenv, dependency, or build folder not excluded?\n",
+			fileutil.FormatSize(s.warnSize), tokens.Estimate(s.written))
+		fmt.Printf("  Largest folders so far: %s\n", strings.Join(s.topDirs(), ", "))
+		fmt.Printf("  Set output_warn_size to change this threshold, or max_output_size to stop runs that pass it.\n\n")
+	}
+	return nil
+}
+
+// topDirs lists the top-level folders that contributed most to the output
+// so far, largest first, with their sizes.
+func (s *scanner) topDirs() []string {
+	dirs := make([]string, 0, len(s.dirBytes))
+	for dir := range s.dirBytes {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if s.dirBytes[dirs[i]] != s.dirBytes[dirs[j]] {
+			return s.dirBytes[dirs[i]] > s.dirBytes[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	if len(dirs) > topDirsShown {
+		dirs = dirs[:topDirsShown]
+	}
+	for i, dir := range dirs {
+		dirs[i] = fmt.Sprintf("%s (%s)", dir, fileutil.FormatSize(s.dirBytes[dir]))
+	}
+	return dirs
+}
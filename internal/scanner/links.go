@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// linkedFile is a file written to the output, remembered so that other
+// paths to the same file on disk can point to it.
+type linkedFile struct {
+	absPath string
+	relPath string
+	info    os.FileInfo
+}
+
+// linkedOriginal returns the file already written that is the same file on
+// disk as info, such as one hardlinked to it or a symlink's target. Only
+// files of the same size are compared.
+func (s *scanner) linkedOriginal(info os.FileInfo) (linkedFile, bool) {
+	for _, f := range s.linked[info.Size()] {
+		if os.SameFile(f.info, info) {
+			return f, true
+		}
+	}
+	return linkedFile{}, false
+}
+
+// rememberWritten records a file written in full for linkedOriginal.
+func (s *scanner) rememberWritten(absPath, relPath string, info os.FileInfo) {
+	if s.linked == nil {
+		s.linked = make(map[int64][]linkedFile)
+	}
+	s.linked[info.Size()] = append(s.linked[info.Size()], linkedFile{absPath, relPath, info})
+}
+
+// writeLinkedDuplicate writes a file's header and, instead of its content,
+// a line naming the path it was already written under.
+func (s *scanner) writeLinkedDuplicate(absPath, relPath string, info os.FileInfo, original linkedFile) error {
+	kind := "hardlink"
+	if s.throughSymlink(absPath) || s.throughSymlink(original.absPath) {
+		kind = "symlink"
+	}
+	s.skip(relPath, ReasonLinkedDuplicate)
+	content := s.out.beginFile(relPath, s.fileNotes(relPath, s.fileMeta(absPath, info)...), "")
+	if _, err := fmt.Fprintf(content, "[identical to %s — %s]\n", original.relPath, kind); err != nil {
+		return err
+	}
+	if err := s.out.endFile(); err != nil {
+		return err
+	}
+	fmt.Fprintf(Progress, "Listed: %s (identical to %s)\n", relPath, original.relPath)
+	return nil
+}
+
+// throughSymlink reports whether absPath, or a directory on its way from
+// the root, is a symlink.
+func (s *scanner) throughSymlink(absPath string) bool {
+	if s.fsys != nil {
+		return false
+	}
+	for p := absPath; p != s.rootPath && p != filepath.Dir(p); p = filepath.Dir(p) {
+		if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// preferDirectPaths fills viaLinks with follow_symlinks: a file reached
+// through a symlink, whether the file itself or a directory on its way, is
+// only a note when its target is also in the output under its own path, so
+// the content always shows under the real path. A link to a file outside
+// the output, such as one shared from outside the project, keeps the
+// target's content under the link's path.
+func (s *scanner) preferDirectPaths() {
+	if !s.followSymlinks || s.keepLinkedDuplicates {
+		return
+	}
+	realRoot, err := filepath.EvalSymlinks(s.rootPath)
+	if err != nil {
+		return
+	}
+	direct := make(map[string]linkedFile)
+	targets := make(map[string]string)
+	for _, f := range s.files {
+		target, err := filepath.EvalSymlinks(f.absPath)
+		if err != nil {
+			continue
+		}
+		if target == filepath.Join(realRoot, filepath.FromSlash(f.relPath)) {
+			direct[target] = linkedFile{absPath: f.absPath, relPath: f.relPath}
+		} else {
+			targets[f.relPath] = target
+		}
+	}
+	for relPath, target := range targets {
+		if f, ok := direct[target]; ok {
+			if s.viaLinks == nil {
+				s.viaLinks = make(map[string]linkedFile)
+			}
+			s.viaLinks[relPath] = f
+		}
+	}
+}
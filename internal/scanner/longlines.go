@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+)
+
+// lineLimiter is a reader that cuts lines longer than max bytes, as found in
+// minified files, so the line-oriented stages after it (env masking,
+// indentation, repetition) never hold more than max bytes of a line. The
+// rest of a long line is replaced by a note of how many bytes were left
+// out; its newline is kept, so line counts are unchanged. A cut never splits
+// a UTF-8 character.
+type lineLimiter struct {
+	r   io.Reader
+	max int
+
+	// col is the length of the current line so far, and cut how many of
+	// its bytes were left out. cr is set when the last byte left out was a
+	// carriage return, which is kept with the newline after it.
+	col int
+	cut int
+	cr  bool
+
+	// truncated counts the lines cut.
+	truncated int
+
+	in  []byte
+	out []byte
+	pos int
+	err error
+}
+
+func newLineLimiter(r io.Reader, max int) *lineLimiter {
+	return &lineLimiter{r: r, max: max, in: make([]byte, 32*1024)}
+}
+
+func (l *lineLimiter) Read(p []byte) (int, error) {
+	for l.pos == len(l.out) {
+		if l.err != nil {
+			// A long final line without a newline still gets its note
+			if l.cut > 0 {
+				l.out, l.pos = l.endCut(l.out[:0]), 0
+				continue
+			}
+			return 0, l.err
+		}
+		n, err := l.r.Read(l.in)
+		l.err = err
+		l.out, l.pos = l.out[:0], 0
+		for _, b := range l.in[:n] {
+			switch {
+			case b == '\n':
+				if l.cr {
+					l.cut--
+				}
+				if l.cut > 0 {
+					l.out = l.endCut(l.out)
+				}
+				if l.cr {
+					l.out = append(l.out, '\r')
+				}
+				l.out = append(l.out, b)
+				l.col, l.cut, l.cr = 0, 0, false
+			// The rest of a character started before the limit is kept
+			case l.cut == 0 && (l.col < l.max || b&0xC0 == 0x80):
+				l.out = append(l.out, b)
+				l.col++
+			default:
+				l.cut++
+				l.cr = b == '\r'
+			}
+		}
+	}
+	n := copy(p, l.out[l.pos:])
+	l.pos += n
+	return n, nil
+}
+
+// endCut appends the note for the line being cut.
+func (l *lineLimiter) endCut(out []byte) []byte {
+	out = append(out, fmt.Sprintf(" ... (line truncated, %d bytes omitted)", l.cut)...)
+	l.cut = 0
+	l.truncated++
+	return out
+}
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestLongLines(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_longlines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// A minified bundle: 2MB on a single line
+	bundle := "!function(){" + strings.Repeat("var a=1;", 2<<20/8) + "}();\n"
+	createFile(t, tempDir, "lib.js", bundle)
+	createFile(t, tempDir, "main.go", "package main\n")
+
+	cfg := &config.Config{
+		OutputFile:         "codebase.txt",
+		CollapseRepetition: true,
+		IndentStyle:        "spaces:4",
+		Dirs:               map[string]config.DirRule{".": {Enabled: true}},
+	}
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+	omitted := len(bundle) - 1 - config.DefaultMaxLineBytes
+	assertContains(t, output, "FILE: lib.js\n"+separator+"\n\n!function(){var a=1;")
+	assertContains(t, output, " ... (line truncated, "+strconv.Itoa(omitted)+" bytes omitted)\n")
+	assertContains(t, output, "FILE: main.go\n")
+	if len(output) > config.DefaultMaxLineBytes+1000 {
+		t.Errorf("Expected the long line to be cut, got %d bytes of output", len(output))
+	}
+	if result.TruncatedLines != 1 || result.Lines != 2 {
+		t.Errorf("Unexpected result: %d truncated lines, %d lines", result.TruncatedLines, result.Lines)
+	}
+}
+
+func TestLineLimiter(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"short\n", "short\n"},
+		{"exactly8\n", "exactly8\n"},
+		{"123456789\nok\n", "12345678 ... (line truncated, 1 bytes omitted)\nok\n"},
+		// The carriage return of a cut line stays with its newline
+		{"123456789\r\n", "12345678 ... (line truncated, 1 bytes omitted)\r\n"},
+		{"exactly8\r\n", "exactly8\r\n"},
+		// A character isn't split at the limit
+		{"1234567é90\n", "1234567é ... (line truncated, 2 bytes omitted)\n"},
+		{"no newline at the end", "no newli ... (line truncated, 13 bytes omitted)"},
+	}
+	for _, tt := range tests {
+		got, err := io.ReadAll(iotest.OneByteReader(newLineLimiter(iotest.OneByteReader(strings.NewReader(tt.in)), 8)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("lineLimiter(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
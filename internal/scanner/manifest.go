@@ -0,0 +1,251 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// ManifestSuffix is appended to the output path to name its manifest
+// sidecar, which start --update writes.
+const ManifestSuffix = ".manifest.json"
+
+// manifestVersion is bumped whenever the manifest or the sections it points
+// to change shape; older manifests are stale.
+const manifestVersion = 1
+
+// Manifest records where each file's section lies in an output, and what the
+// file held when it was written, so an update (see UpdateContext) only reads
+// and writes again the files that changed.
+type Manifest struct {
+	Version int `json:"version"`
+
+	// Config is the hash of the settings the output was written with, and
+	// Output the hash of the output itself (Result.Hash).
+	Config string `json:"config"`
+	Output string `json:"output"`
+
+	// Files are the sections of the files written in full, in output order.
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile is the section of an output one file wrote, from its header
+// to the gap after it, with what the file added to the run's counts.
+type ManifestFile struct {
+	Path string `json:"path"`
+
+	// Hash is the SHA-256 of the file on disk, and Size and ModTime are
+	// what it was checked against first.
+	Hash    string `json:"hash"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+
+	// Start and End are the section's byte offsets in the output.
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+
+	Lines          int   `json:"lines"`
+	Bytes          int64 `json:"bytes"`
+	TruncatedLines int   `json:"truncated_lines,omitempty"`
+	Truncated      bool  `json:"truncated,omitempty"`
+	CollapsedBytes int64 `json:"collapsed_bytes,omitempty"`
+	Masked         bool  `json:"masked,omitempty"`
+	Legacy         bool  `json:"legacy,omitempty"`
+}
+
+// Previous is an output written before, with its manifest, which an update
+// copies the sections of unchanged files from.
+type Previous struct {
+	file     *os.File
+	sections map[string]ManifestFile
+}
+
+// OpenPrevious opens the output at outPath for an update with cfg. It fails,
+// saying why, when the output can't be updated: cfg writes it in another
+// format than text, or alongside the outputs list, or with notes that
+// change without the files doing so, or its manifest is missing or stale.
+func OpenPrevious(outPath string, cfg *config.Config) (*Previous, error) {
+	name := filepath.Base(outPath)
+	if err := updatable(cfg); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(outPath); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("there is no %s to update yet", name)
+	}
+	data, err := os.ReadFile(outPath + ManifestSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s has no manifest yet", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil || m.Version != manifestVersion {
+		return nil, fmt.Errorf("the manifest of %s is from another version of textify", name)
+	}
+	if m.Config != configHash(cfg) {
+		return nil, fmt.Errorf("%s was written with other settings", name)
+	}
+
+	f, err := os.Open(outPath)
+	if err != nil {
+		return nil, err
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != m.Output {
+		f.Close()
+		return nil, fmt.Errorf("%s changed since its manifest was written", name)
+	}
+
+	p := &Previous{file: f, sections: make(map[string]ManifestFile, len(m.Files))}
+	for _, sec := range m.Files {
+		p.sections[sec.Path] = sec
+	}
+	return p, nil
+}
+
+// Close closes the previous output.
+func (p *Previous) Close() error {
+	return p.file.Close()
+}
+
+// WriteManifest writes the manifest of the output at outPath to its sidecar
+// (outPath + ManifestSuffix).
+func WriteManifest(outPath string, m *Manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath+ManifestSuffix, data, 0644)
+}
+
+// UpdateContext is ScanContext for an output written before: the sections
+// of the files that didn't change since, by size and modification time or
+// else by hash, are copied from previous instead of read and written again.
+// The output is the same as ScanContext's.
+func UpdateContext(ctx context.Context, rootPath string, cfg *config.Config, writer io.Writer, previous *Previous) (*Result, error) {
+	return scan(ctx, nil, rootPath, cfg, writer, Hooks{}, previous)
+}
+
+// updatable reports why an output written with cfg can't be updated, if it
+// can't: its sections are only known in text written alone, and only
+// depend on the files when no header note comes from git or file modes.
+func updatable(cfg *config.Config) error {
+	switch {
+	case cfg.Format != "" && cfg.Format != config.FormatText:
+		return fmt.Errorf("only text output can be updated")
+	case len(cfg.Outputs) > 0:
+		return fmt.Errorf("an output with an outputs list can't be updated")
+	case cfg.Order == config.OrderGitHot || cfg.IncludeGitBlame:
+		return fmt.Errorf("an output with git notes in its headers can't be updated")
+	case cfg.IncludeFileMeta:
+		return fmt.Errorf("an output with include_file_meta can't be updated")
+	}
+	return nil
+}
+
+// configHash is the hash of the settings an output is written with.
+func configHash(cfg *config.Config) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// unchanged returns the section f wrote to the previous output, if there is
+// one to copy: f held the same content then, and isn't preprocessed.
+func (s *scanner) unchanged(f fileEntry, info os.FileInfo) (ManifestFile, bool) {
+	if s.manifest == nil || s.previous == nil || s.preprocessorFor(f.relPath) != nil {
+		return ManifestFile{}, false
+	}
+	sec, ok := s.previous.sections[f.relPath]
+	if !ok || sec.Size != info.Size() {
+		return ManifestFile{}, false
+	}
+	if s.previous.unmodified(f, info) && !s.paranoid {
+		return sec, true
+	}
+	file, err := s.open(f)
+	if err != nil {
+		return ManifestFile{}, false
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return ManifestFile{}, false
+	}
+	return sec, hex.EncodeToString(hasher.Sum(nil)) == sec.Hash
+}
+
+// copySection writes the section f wrote to the previous output again, and
+// counts it as if the file had been read.
+func (s *scanner) copySection(f fileEntry, info os.FileInfo, sec ManifestFile) error {
+	start := s.offset()
+	if err := s.doc.copySection(io.NewSectionReader(s.previous.file, sec.Start, sec.End-sec.Start)); err != nil {
+		return err
+	}
+	s.result.TruncatedLines += sec.TruncatedLines
+	if sec.Truncated {
+		s.result.TruncatedFiles++
+	}
+	s.result.CollapsedBytes += sec.CollapsedBytes
+	if sec.Masked {
+		s.result.MaskedFiles++
+	}
+	s.dirLines[f.ruleDir] += sec.Lines
+	s.result.Lines += sec.Lines
+	s.result.Bytes += sec.Bytes
+	s.track(f.relPath, sec.Bytes)
+
+	if s.cache != nil {
+		s.recordHash(f.relPath, info, sec.Legacy, sec.Hash)
+	}
+	if !s.keepLinkedDuplicates {
+		s.rememberWritten(f.absPath, f.relPath, info)
+	}
+	s.result.Included++
+	s.result.Reused++
+	sec.ModTime = info.ModTime().UnixNano()
+	s.addSection(sec, start)
+	fmt.Fprintf(Progress, "Kept: %s\n", f.relPath)
+	return nil
+}
+
+// unmodified reports whether f is unchanged since previous by its size and
+// modification time alone, without reading it.
+func (p *Previous) unmodified(f fileEntry, info os.FileInfo) bool {
+	sec, ok := p.sections[f.relPath]
+	return ok && sec.Size == info.Size() && sec.ModTime == info.ModTime().UnixNano()
+}
+
+// offset is where the output being written is, if it has a manifest.
+func (s *scanner) offset() int64 {
+	if s.manifest == nil {
+		return 0
+	}
+	return s.doc.offset()
+}
+
+// addSection records a section written from start to the current offset in
+// the manifest of the output being written, if it has one.
+func (s *scanner) addSection(sec ManifestFile, start int64) {
+	if s.manifest == nil {
+		return
+	}
+	sec.Start, sec.End = start, s.offset()
+	s.manifest.Files = append(s.manifest.Files, sec)
+}
@@ -0,0 +1,205 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// writeOutput writes a full scan of root to outPath, with its manifest.
+func writeOutput(t *testing.T, root string, cfg *config.Config, outPath string) *Manifest {
+	var buf bytes.Buffer
+	result, err := Scan(root, cfg, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Manifest == nil {
+		t.Fatal("Expected a text output to have a manifest")
+	}
+	if err := os.WriteFile(outPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteManifest(outPath, result.Manifest); err != nil {
+		t.Fatal(err)
+	}
+	return result.Manifest
+}
+
+// updateOutput updates the output at outPath and its manifest, and returns
+// the update's result.
+func updateOutput(t *testing.T, root string, cfg *config.Config, outPath string) *Result {
+	previous, err := OpenPrevious(outPath, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	result, err := UpdateContext(context.Background(), root, cfg, &buf, previous)
+	previous.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(outPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteManifest(outPath, result.Manifest); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestUpdateRewritesOnlyChangedSections(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "b"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "m"), 0755)
+	createFile(t, tempDir, "a.go", "package a\n")
+	createFile(t, tempDir, "b/b.go", "package b\n")
+	createFile(t, tempDir, "m/mid.go", "package mid\n")
+	createFile(t, tempDir, "z.go", "package z\n")
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IncludeTree: true,
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}},
+	}
+	outPath := filepath.Join(tempDir, "codebase.txt")
+	manifest := writeOutput(t, tempDir, cfg, outPath)
+	before, _ := os.ReadFile(outPath)
+
+	var mid ManifestFile
+	for _, f := range manifest.Files {
+		if f.Path == "m/mid.go" {
+			mid = f
+		}
+	}
+	if mid.Path == "" || mid.Start == 0 || mid.End <= mid.Start || mid.End >= int64(len(before)) {
+		t.Fatalf("Expected the middle file's section inside the output, got %+v", mid)
+	}
+
+	createFile(t, tempDir, "m/mid.go", "package mid\n\nfunc Mid() {}\n")
+	result := updateOutput(t, tempDir, cfg, outPath)
+	if result.Included != 4 || result.Reused != 3 {
+		t.Errorf("Expected 3 of 4 files copied and only the edited one read, got %d of %d", result.Reused, result.Included)
+	}
+
+	after, _ := os.ReadFile(outPath)
+	tail := int64(len(before)) - mid.End
+	if !bytes.Equal(after[:mid.Start], before[:mid.Start]) {
+		t.Error("Expected everything before the edited file's section to be unchanged")
+	}
+	if !bytes.Equal(after[int64(len(after))-tail:], before[mid.End:]) {
+		t.Error("Expected everything after the edited file's section to be unchanged")
+	}
+	section := string(after[mid.Start : int64(len(after))-tail])
+	assertContains(t, section, "FILE: m/mid.go")
+	assertContains(t, section, "func Mid() {}")
+
+	var full bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &full); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, full.Bytes()) {
+		t.Errorf("Updated output differs from a full regeneration.\nExpected:\n%s\nGot:\n%s", full.String(), after)
+	}
+}
+
+func TestUpdateMatchesRegeneration(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_update_steps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "a.go", "package a\n")
+	createFile(t, tempDir, "b.go", "package b\n")
+	createFile(t, tempDir, "c.go", "package c\n")
+	cfg := &config.Config{
+		OutputFile:    "codebase.txt",
+		IncludeTree:   true,
+		HeaderSummary: true,
+		Dirs:          map[string]config.DirRule{".": {Enabled: true}},
+	}
+	outPath := filepath.Join(tempDir, "codebase.txt")
+	writeOutput(t, tempDir, cfg, outPath)
+
+	// A changed, a touched, an added, and a removed file
+	later := time.Now().Add(time.Hour)
+	steps := []struct {
+		name   string
+		step   func()
+		reused int
+	}{
+		{"Changed", func() { createFile(t, tempDir, "c.go", "package c\n\nfunc C() {}\n") }, 2},
+		{"Touched", func() { os.Chtimes(filepath.Join(tempDir, "b.go"), later, later) }, 3},
+		{"Added", func() { createFile(t, tempDir, "d.go", "package d\n") }, 3},
+		{"Removed", func() { os.Remove(filepath.Join(tempDir, "a.go")) }, 3},
+	}
+	for _, tt := range steps {
+		tt.step()
+		result := updateOutput(t, tempDir, cfg, outPath)
+		if result.Reused != tt.reused {
+			t.Errorf("%s: expected %d files copied, got %d", tt.name, tt.reused, result.Reused)
+		}
+
+		var full bytes.Buffer
+		if _, err := Scan(tempDir, cfg, &full); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := os.ReadFile(outPath); !bytes.Equal(got, full.Bytes()) {
+			t.Errorf("%s: updated output differs from a full regeneration.\nExpected:\n%s\nGot:\n%s", tt.name, full.String(), got)
+		}
+	}
+}
+
+func TestOpenPreviousStale(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_update_stale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "a.go", "package a\n")
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+	outPath := filepath.Join(tempDir, "codebase.txt")
+	if _, err := OpenPrevious(outPath, cfg); err == nil {
+		t.Error("Expected a missing output to have nothing to update")
+	}
+	writeOutput(t, tempDir, cfg, outPath)
+
+	other := *cfg
+	other.IncludeChecksums = true
+	if _, err := OpenPrevious(outPath, &other); err == nil {
+		t.Error("Expected an output written with other settings to be stale")
+	}
+	json := other
+	json.Format = config.FormatJSON
+	if _, err := OpenPrevious(outPath, &json); err == nil {
+		t.Error("Expected json output not to be updatable")
+	}
+
+	f, err := os.OpenFile(outPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("edited by hand\n")
+	f.Close()
+	if _, err := OpenPrevious(outPath, cfg); err == nil {
+		t.Error("Expected an output edited since its manifest to be stale")
+	}
+
+	os.Remove(outPath + ManifestSuffix)
+	if _, err := OpenPrevious(outPath, cfg); err == nil {
+		t.Error("Expected an output without a manifest to be stale")
+	}
+}
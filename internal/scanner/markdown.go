@@ -0,0 +1,241 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// markdownDoc collects the file sections of a markdown-doc output. Sections
+// are written to body as files are added; the title, table of contents, and
+// tree go in front of them once the files actually written are known.
+type markdownDoc struct {
+	out      io.Writer
+	tree     []string
+	body     bytes.Buffer
+	sections []mdSection
+	anchors  map[string]bool
+
+	// grouped is set once a heading starts a group of files; files before
+	// any group get a "Files" heading.
+	grouped bool
+
+	// relPath and content hold the file being written, which is fenced
+	// once complete, since the fence depends on the content; it is written
+	// through sanitized, as in text output. raw is set for files in the
+	// text format, which aren't fenced.
+	relPath   string
+	content   bytes.Buffer
+	sanitized *sanitizer
+	raw       bool
+}
+
+// mdSection is a file's entry in the table of contents.
+type mdSection struct {
+	relPath string
+	anchor  string
+}
+
+func newMarkdownDoc(w io.Writer) *markdownDoc {
+	return &markdownDoc{out: w, anchors: make(map[string]bool)}
+}
+
+// start keeps the tree, which goes after the table of contents.
+func (d *markdownDoc) start(tree []string) error {
+	if tree != nil {
+		d.tree = append([]string{}, tree...)
+	}
+	return nil
+}
+
+// section starts a group of files with a heading.
+func (d *markdownDoc) section(label, title string) {
+	d.heading(title)
+}
+
+func (d *markdownDoc) beginFile(relPath string, notes []string, format string) io.Writer {
+	d.fileHeading(relPath, notes)
+	d.relPath = relPath
+	d.raw = format == config.FormatText
+	d.content.Reset()
+	d.sanitized = newControlSanitizer(&d.content)
+	return d.sanitized
+}
+
+func (d *markdownDoc) endFile() error {
+	if err := d.sanitized.Flush(); err != nil {
+		return err
+	}
+	if d.raw {
+		content := d.content.Bytes()
+		d.body.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			d.body.WriteByte('\n')
+		}
+		d.body.WriteByte('\n')
+		return nil
+	}
+	d.fileContent(d.relPath, d.content.Bytes())
+	return nil
+}
+
+func (d *markdownDoc) listFile(relPath string, notes []string, size int64) {
+	d.fileHeading(relPath, notes)
+}
+
+func (d *markdownDoc) wantsContent() bool { return true }
+
+// finish writes the whole document.
+func (d *markdownDoc) finish(title, summary string) error {
+	return d.write(d.out, title, summary, d.tree)
+}
+
+// heading starts a group of files.
+func (d *markdownDoc) heading(title string) {
+	fmt.Fprintf(&d.body, "## %s\n\n", title)
+	d.grouped = true
+}
+
+// fileHeading starts the section for a file and adds it to the table of
+// contents.
+func (d *markdownDoc) fileHeading(relPath string, notes []string) {
+	if !d.grouped {
+		d.heading("Files")
+	}
+	anchor := d.anchor(relPath)
+	d.sections = append(d.sections, mdSection{relPath: relPath, anchor: anchor})
+
+	fmt.Fprintf(&d.body, "<a id=\"%s\"></a>\n\n### `%s`\n\n", anchor, relPath)
+	if len(notes) > 0 {
+		fmt.Fprintf(&d.body, "_%s_\n\n", strings.Join(notes, ", "))
+	}
+}
+
+// fileContent writes a file's content as a fenced code block.
+func (d *markdownDoc) fileContent(relPath string, content []byte) {
+	writeFenced(&d.body, relPath, content)
+	d.body.WriteByte('\n')
+}
+
+// writeFenced writes content as a fenced code block tagged with the file's
+// language, using a fence longer than any backtick run in the content so it
+// can't be closed early.
+func writeFenced(w io.Writer, relPath string, content []byte) {
+	fence := strings.Repeat("`", longestRun(content, '`')+1)
+	if len(fence) < 3 {
+		fence = "```"
+	}
+	fmt.Fprintf(w, "%s%s\n", fence, fenceLanguage(relPath))
+	w.Write(content)
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		io.WriteString(w, "\n")
+	}
+	fmt.Fprintf(w, "%s\n", fence)
+}
+
+// anchor returns a unique anchor for a path; see uniqueAnchor.
+func (d *markdownDoc) anchor(relPath string) string {
+	return uniqueAnchor(d.anchors, relPath)
+}
+
+// uniqueAnchor returns an anchor for a path not in taken, and adds it.
+// Anchors are built from the whole path, and a numeric suffix separates
+// paths that still slugify alike (such as "a-b.go" and "a/b.go").
+func uniqueAnchor(taken map[string]bool, relPath string) string {
+	base := slugify(relPath)
+	anchor := base
+	for n := 2; taken[anchor]; n++ {
+		anchor = fmt.Sprintf("%s-%d", base, n)
+	}
+	taken[anchor] = true
+	return anchor
+}
+
+// write renders the whole document to w: the title, the summary (if not
+// empty), the table of contents, the tree (if paths is not nil), and the file
+// sections.
+func (d *markdownDoc) write(w io.Writer, title, summary string, tree []string) error {
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "# %s\n\n", title)
+	if summary != "" {
+		fmt.Fprintf(&head, "%s\n\n", summary)
+	}
+	head.WriteString("## Contents\n\n")
+	for _, sec := range d.sections {
+		fmt.Fprintf(&head, "- [%s](#%s)\n", sec.relPath, sec.anchor)
+	}
+	if len(d.sections) == 0 {
+		head.WriteString("_No files were included._\n")
+	}
+	head.WriteString("\n")
+
+	if tree != nil {
+		head.WriteString("## Project structure\n\n```text\n.\n")
+		if err := writeTreeLines(&head, tree); err != nil {
+			return err
+		}
+		head.WriteString("```\n\n")
+	}
+
+	if _, err := w.Write(head.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(d.body.Bytes())
+	return err
+}
+
+// documentTitle names the document after the project's folder.
+func documentTitle(rootPath string) string {
+	if abs, err := filepath.Abs(rootPath); err == nil {
+		rootPath = abs
+	}
+	return filepath.Base(rootPath)
+}
+
+// slugify lowercases s and replaces every run of characters other than
+// letters and digits with a single dash.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "file"
+	}
+	return slug
+}
+
+// fenceLanguage returns the info string for a file's code fence: its
+// extension, which GitHub and most renderers accept as a language name.
+func fenceLanguage(relPath string) string {
+	return strings.ToLower(strings.TrimPrefix(path.Ext(relPath), "."))
+}
+
+// longestRun returns the length of the longest run of c in data.
+func longestRun(data []byte, c byte) int {
+	longest, run := 0, 0
+	for _, b := range data {
+		if b != c {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestMarkdownDoc(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "a"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "b"), 0755)
+	createFile(t, tempDir, "a/util.go", "package a")
+	createFile(t, tempDir, "b/util.go", "package b")
+	createFile(t, tempDir, "build.log", "\x1b[31mFAIL\x1b[0m\r\n"+separator+"\n")
+	createFile(t, tempDir, "README.md", "Use it like this:\n\n```sh\ntextify start\n```")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.md",
+		Format:      config.FormatMarkdownDoc,
+		IncludeTree: true,
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	// The contents link to every file, and same-named files get distinct anchors
+	assertContains(t, output, "# "+filepath.Base(tempDir)+"\n")
+	assertContains(t, output, "- [README.md](#readme-md)\n- [a/util.go](#a-util-go)\n- [b/util.go](#b-util-go)\n")
+	assertContains(t, output, "<a id=\"a-util-go\"></a>\n\n### `a/util.go`\n\n```go\npackage a\n```\n")
+	assertContains(t, output, "<a id=\"b-util-go\"></a>")
+	assertContains(t, output, "## Project structure\n\n```text\n.\n├── README.md\n")
+	assertNotContains(t, output, "FILE: ")
+
+	// Control characters are escaped as in text output; separators needn't be
+	assertContains(t, output, "```log\n\\x1b[31mFAIL\\x1b[0m\r\n"+separator+"\n```\n")
+
+	// A fence in the content needs a longer fence around it
+	assertContains(t, output, "````md\nUse it like this:")
+
+	order := []string{"## Contents", "## Project structure", "## Files", "### `README.md`", "### `a/util.go`"}
+	last := -1
+	for _, marker := range order {
+		idx := strings.Index(output, marker)
+		if idx < 0 || idx < last {
+			t.Fatalf("Expected %q after the previous marker in:\n%s", marker, output)
+		}
+		last = idx
+	}
+}
+
+func TestMarkdownAnchorsAreUnique(t *testing.T) {
+	d := newMarkdownDoc(nil)
+	got := []string{d.anchor("a/b.go"), d.anchor("a-b.go"), d.anchor("A/B.go"), d.anchor("__")}
+	want := []string{"a-b-go", "a-b-go-2", "a-b-go-3", "file"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("anchor %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+)
+
+// filesFor returns the files that go to an output, keyed by the rule's
+// output_file ("" for the top-level output), keeping their order.
+func filesFor(files []fileEntry, output string) []fileEntry {
+	var out []fileEntry
+	for _, f := range files {
+		if f.rule.OutputFile == output {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// pendingOutputs are output files being written to temporary files next to
+// them, so that a failed scan leaves every previous output untouched.
+type pendingOutputs struct {
+	rootPath string
+
+	// keep is how many previous versions of each output are rotated out
+	// of the way before it is replaced (keep_previous).
+	keep int
+
+	names []string
+	files map[string]*os.File
+
+	// unchanged holds the outputs whose new version was identical to the
+	// file in place, which commit left untouched.
+	unchanged map[string]bool
+}
+
+// create starts the temporary file for the output file name, as configured.
+func (p *pendingOutputs) create(name string) (*os.File, error) {
+	outPath := resolvePath(p.rootPath, name)
+	f, err := os.CreateTemp(filepath.Dir(outPath), fileutil.TempPattern(outPath))
+	if err != nil {
+		return nil, err
+	}
+	if p.files == nil {
+		p.files = make(map[string]*os.File)
+	}
+	p.names = append(p.names, name)
+	p.files[name] = f
+	if err := f.Chmod(0644); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// commit closes the temporary files and renames them all into place (ok) or
+// removes them (!ok). An output identical to the file in place isn't
+// renamed, so the file keeps its modification time, and isn't rotated.
+func (p *pendingOutputs) commit(ok bool) error {
+	var firstErr error
+	for _, name := range p.names {
+		f := p.files[name]
+		if err := f.Close(); err != nil && ok {
+			ok = false
+			firstErr = fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	for _, name := range p.names {
+		tmp := p.files[name].Name()
+		if !ok {
+			os.Remove(tmp)
+			continue
+		}
+		outPath := resolvePath(p.rootPath, name)
+		if same, err := fileutil.SameContent(tmp, outPath); err == nil && same {
+			os.Remove(tmp)
+			if p.unchanged == nil {
+				p.unchanged = make(map[string]bool)
+			}
+			p.unchanged[name] = true
+			continue
+		}
+		if err := fileutil.Rotate(outPath, p.keep); err != nil {
+			os.Remove(tmp)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if err := os.Rename(tmp, outPath); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// writeRuleOutputs writes the output of every rule with an output_file, each
+// with its own tree and summary, to a pending file.
+func (s *scanner) writeRuleOutputs(cfg *config.Config, files []fileEntry, treePaths map[string][]string, stats *statsVisitor, pending *pendingOutputs) (map[string]*Result, error) {
+	outputs := cfg.RuleOutputs()
+	if len(outputs) == 0 {
+		return nil, nil
+	}
+
+	results := make(map[string]*Result, len(outputs))
+	for _, out := range outputs {
+		f, err := pending.create(out)
+		if err != nil {
+			return nil, err
+		}
+
+		title := fmt.Sprintf("%s (%s)", documentTitle(s.rootPath), out)
+		rendered, err := s.writeOutput([]target{{w: f, format: cfg.Format}}, cfg, filesFor(files, out), treePaths[out], stats.result(out), title)
+		if err != nil {
+			return nil, fmt.Errorf("writing %s: %w", out, err)
+		}
+		results[out] = rendered[0]
+	}
+	return results, nil
+}
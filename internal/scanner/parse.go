@@ -0,0 +1,183 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// Section is a file's part of a generated output.
+type Section struct {
+	Path string
+
+	// Content is the file's content as written to the output. Listed files,
+	// such as binaries, have none.
+	Content string
+}
+
+// ErrNotOutput is returned by ParseOutput for data that doesn't start the
+// way textify output does.
+var ErrNotOutput = errors.New("not textify output")
+
+// ParseOutput splits a generated output, in any of the formats, into its
+// file sections, in order. Summaries, trees, and section labels are left
+// out. Separator lines escaped in text output are restored. Text output is
+// read as written with the default file_gap.
+func ParseOutput(data []byte) ([]Section, error) {
+	if len(data) > 0 && !outputStart.Match(data) {
+		return nil, ErrNotOutput
+	}
+	text := string(data)
+	switch {
+	case strings.HasPrefix(text, "{"):
+		return parseJSONOutput(data)
+	case strings.HasPrefix(text, "# "):
+		return parseMarkdownOutput(text), nil
+	default:
+		return parseTextOutput(text), nil
+	}
+}
+
+// parseTextOutput finds the files by their separator-framed FILE headers.
+// Each file's content is followed by a blank line, and possibly by the
+// label of the next group.
+func parseTextOutput(text string) []Section {
+	lines := strings.SplitAfter(text, "\n")
+	var sections []Section
+	var current *Section
+	var body strings.Builder
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Content = strings.TrimSuffix(trimGroupLabel(body.String()), "\n\n")
+		sections = append(sections, *current)
+		current = nil
+	}
+
+	boundary := strings.Repeat("=", len(separator)) + "\n"
+	for i := 0; i < len(lines); i++ {
+		switch {
+		case lines[i] == separator+"\n" && i+2 < len(lines) && strings.HasPrefix(lines[i+1], "FILE: ") && lines[i+2] == separator+"\n":
+			flush()
+			relPath, _ := splitFileHeader(strings.TrimSuffix(strings.TrimPrefix(lines[i+1], "FILE: "), "\n"))
+			current = &Section{Path: relPath}
+			body.Reset()
+			i += 2
+			if i+1 < len(lines) && lines[i+1] == "\n" {
+				i++
+			}
+		case lines[i] == boundary && i+2 < len(lines) && strings.HasPrefix(lines[i+1], "APPENDED SCAN: "):
+			flush()
+			i += 2
+		case current != nil:
+			if lines[i] == "\\"+separator+"\n" || lines[i] == "\\"+separator {
+				body.WriteString(lines[i][1:])
+				continue
+			}
+			body.WriteString(lines[i])
+		}
+	}
+	flush()
+	return sections
+}
+
+// trimGroupLabel removes the label of the group that follows a file's
+// content, such as "SOURCE:" or group_by's "GO:", on a line of its own
+// after a blank line.
+func trimGroupLabel(body string) string {
+	if !strings.HasSuffix(body, ":\n\n") {
+		return body
+	}
+	rest := strings.TrimSuffix(body, ":\n\n")
+	i := strings.LastIndex(rest, "\n")
+	label := rest[i+1:]
+	if label == "" || (i >= 0 && !strings.HasSuffix(rest[:i+1], "\n\n")) {
+		return body
+	}
+	for _, r := range label {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("+#._-", r)) {
+			return body
+		}
+	}
+	return rest[:i+1]
+}
+
+// splitFileHeader splits what follows "FILE: " into the path and its notes.
+func splitFileHeader(header string) (relPath, notes string) {
+	if strings.HasSuffix(header, ")") {
+		if i := strings.LastIndex(header, " ("); i >= 0 {
+			return header[:i], header[i+2 : len(header)-1]
+		}
+	}
+	return header, ""
+}
+
+// parseMarkdownOutput finds the files by their anchored headings. Each
+// file's content is the fenced block that follows its heading and notes;
+// listed files have no block.
+func parseMarkdownOutput(text string) []Section {
+	lines := strings.Split(text, "\n")
+	var sections []Section
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], `<a id="`) || i+2 >= len(lines) {
+			continue
+		}
+		heading := lines[i+2]
+		if !strings.HasPrefix(heading, "### `") || !strings.HasSuffix(heading, "`") {
+			continue
+		}
+		section := Section{Path: strings.TrimSuffix(strings.TrimPrefix(heading, "### `"), "`")}
+		i += 3
+
+		// Skip the blank lines and notes before the fence
+		j := i
+		for j < len(lines) && (lines[j] == "" || (len(lines[j]) > 1 && strings.HasPrefix(lines[j], "_") && strings.HasSuffix(lines[j], "_"))) {
+			j++
+		}
+		if j < len(lines) && strings.HasPrefix(lines[j], "```") {
+			fence := lines[j][:len(lines[j])-len(strings.TrimLeft(lines[j], "`"))]
+			var content []string
+			for j++; j < len(lines) && lines[j] != fence; j++ {
+				content = append(content, lines[j]+"\n")
+			}
+			section.Content = strings.Join(content, "")
+			i = j
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// parseJSONOutput reads the files of a json output.
+func parseJSONOutput(data []byte) ([]Section, error) {
+	var doc jsonOutput
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	sections := make([]Section, len(doc.Files))
+	for i, f := range doc.Files {
+		sections[i].Path = f.Path
+		if f.Content != nil {
+			sections[i].Content = *f.Content
+		}
+	}
+	return sections, nil
+}
+
+// WriteSection writes s as a section of text output: the file's header, then
+// its content.
+func WriteSection(w io.Writer, s Section) error {
+	d := newTextDoc(w, false, config.DefaultFileGap)
+	content := d.beginFile(s.Path, nil, "")
+	if _, err := io.WriteString(content, s.Content); err != nil {
+		return err
+	}
+	if err := d.endFile(); err != nil {
+		return err
+	}
+	return d.out.Flush()
+}
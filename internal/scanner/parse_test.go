@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestParseOutput(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_parse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "README.md", "# Demo\n")
+	createFile(t, tempDir, "main.go", "package main\n\n"+separator+"\n```go\n```\n")
+	createFile(t, tempDir, "notes.txt", "no newline")
+	createFile(t, tempDir, "logo.png", "\x89PNG\x00\x00")
+
+	expected := []Section{
+		{Path: "README.md", Content: "# Demo\n"},
+		{Path: "logo.png"},
+		{Path: "main.go", Content: "package main\n\n" + separator + "\n```go\n```\n"},
+		{Path: "notes.txt", Content: "no newline"},
+	}
+	for _, format := range []string{config.FormatText, config.FormatMarkdownDoc, config.FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			cfg := &config.Config{
+				OutputFile:    "codebase.txt",
+				Format:        format,
+				IncludeTree:   true,
+				HeaderSummary: true,
+				DocsFirst:     true,
+				ListBinaries:  true,
+				Dirs:          map[string]config.DirRule{".": {Enabled: true}},
+			}
+			var buf bytes.Buffer
+			if _, err := Scan(tempDir, cfg, &buf); err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+
+			sections, err := ParseOutput(buf.Bytes())
+			if err != nil {
+				t.Fatalf("ParseOutput failed: %v", err)
+			}
+			// Markdown always ends a fenced block with a newline
+			want := append([]Section{}, expected...)
+			if format == config.FormatMarkdownDoc {
+				want[3].Content += "\n"
+			}
+			if !reflect.DeepEqual(sections, want) {
+				t.Errorf("Unexpected sections.\nExpected: %q\nGot:      %q\n%s", want, sections, buf.String())
+			}
+		})
+	}
+
+	if _, err := ParseOutput([]byte("just some notes\n")); err != ErrNotOutput {
+		t.Errorf("Expected ErrNotOutput, got %v", err)
+	}
+}
+
+func TestWriteSection(t *testing.T) {
+	s := Section{Path: "src/main.go", Content: "package main\n\n" + separator + "\n"}
+	var buf bytes.Buffer
+	if err := WriteSection(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	sections, err := ParseOutput(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseOutput failed: %v", err)
+	}
+	if !reflect.DeepEqual(sections, []Section{s}) {
+		t.Errorf("Expected the section back, got %q from:\n%s", sections, buf.String())
+	}
+}
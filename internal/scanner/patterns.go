@@ -0,0 +1,80 @@
+package scanner
+
+import (
+	"sort"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/walker"
+)
+
+// Kinds of rule patterns counted in PatternCount.
+const (
+	PatternInclude = "include"
+	PatternExclude = "exclude"
+)
+
+// PatternCount is how many entries, files or directories, one of a rule's
+// include or exclude patterns matched during a scan. A pattern that matched
+// nothing is probably stale or misspelled.
+type PatternCount struct {
+	// Dir is the key of the rule in dirs, and Kind is PatternInclude or
+	// PatternExclude.
+	Dir     string
+	Kind    string
+	Pattern string
+
+	Matches int
+}
+
+// patternKey identifies a pattern in patternCounter.
+type patternKey struct {
+	dir, kind, pattern string
+}
+
+// patternCounter counts the entries each rule pattern matched during the
+// walk.
+type patternCounter struct {
+	counts map[patternKey]int
+}
+
+func (v *patternCounter) OnDir(d walker.Decision)  { v.count(d) }
+func (v *patternCounter) OnFile(d walker.Decision) { v.count(d) }
+
+func (v *patternCounter) count(d walker.Decision) {
+	kind := PatternInclude
+	if d.Reason == ReasonExcluded {
+		kind = PatternExclude
+	}
+	for _, p := range d.Matched {
+		v.counts[patternKey{d.RuleDir, kind, p}]++
+	}
+}
+
+// patternCounts returns the counts of every include and exclude pattern of
+// the rules the walk could reach, by rule key, with each rule's include
+// patterns before its exclude patterns in config order. Rules that are
+// disabled, or inside a disabled folder, are left out: their patterns are
+// never tried.
+func (v *patternCounter) patternCounts(dirs map[string]config.DirRule) []PatternCount {
+	keys := make([]string, 0, len(dirs))
+	for key, rule := range dirs {
+		if rule.Enabled && walker.Resolve(dirs, key).DisabledAt == "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var counts []PatternCount
+	for _, key := range keys {
+		for _, kind := range []string{PatternInclude, PatternExclude} {
+			patterns := dirs[key].Include
+			if kind == PatternExclude {
+				patterns = dirs[key].Exclude
+			}
+			for _, p := range patterns {
+				counts = append(counts, PatternCount{Dir: key, Kind: kind, Pattern: p, Matches: v.counts[patternKey{key, kind, p}]})
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestPatternCounts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":       {Data: []byte("package main\n")},
+		"main_test.go":  {Data: []byte("package main\n")},
+		"notes.log":     {Data: []byte("log\n")},
+		"gen/a.pb.go":   {Data: []byte("package gen\n")},
+		"gen/b.pb.go":   {Data: []byte("package gen\n")},
+		"src/x.go":      {Data: []byte("package src\n")},
+		"old/legacy.go": {Data: []byte("package old\n")},
+	}
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Include: []string{"gen/*.pb.go", "*.md"}, Exclude: []string{"*_test.go", "*.log", "*.tmp"}},
+			// Both patterns match x.go, and both are credited
+			"src": {Enabled: true, Exclude: []string{"*.go", "x.go"}},
+			// Patterns of rules the walk never reaches aren't reported
+			"old":     {Enabled: false, Include: []string{"*.go"}},
+			"old/new": {Enabled: true, Exclude: []string{"*"}},
+		},
+	}
+	result, err := ScanFS(fsys, "project", cfg, io.Discard, Hooks{})
+	if err != nil {
+		t.Fatalf("ScanFS failed: %v", err)
+	}
+
+	expected := []PatternCount{
+		{Dir: ".", Kind: PatternInclude, Pattern: "gen/*.pb.go", Matches: 2},
+		{Dir: ".", Kind: PatternInclude, Pattern: "*.md", Matches: 0},
+		{Dir: ".", Kind: PatternExclude, Pattern: "*_test.go", Matches: 1},
+		{Dir: ".", Kind: PatternExclude, Pattern: "*.log", Matches: 1},
+		{Dir: ".", Kind: PatternExclude, Pattern: "*.tmp", Matches: 0},
+		{Dir: "src", Kind: PatternExclude, Pattern: "*.go", Matches: 1},
+		{Dir: "src", Kind: PatternExclude, Pattern: "x.go", Matches: 1},
+	}
+	if !reflect.DeepEqual(result.Patterns, expected) {
+		t.Errorf("Unexpected pattern counts.\nExpected: %+v\nGot:      %+v", expected, result.Patterns)
+	}
+}
@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReasonPreprocessFailed is the skip reason of files whose preprocessor
+// failed.
+const ReasonPreprocessFailed = "preprocessor failed"
+
+// pathPlaceholder is replaced in preprocessor arguments by the file's path.
+const pathPlaceholder = "{path}"
+
+// Limits on a preprocessor run. A command that runs longer is killed, and
+// one that prints more fails, so a broken converter can't stall or flood the
+// output.
+const (
+	preprocessTimeout   = 30 * time.Second
+	preprocessMaxOutput = 10 << 20
+
+	// preprocessMaxStderr is how much of a command's stderr is kept, for
+	// the first line of the error.
+	preprocessMaxStderr = 64 << 10
+)
+
+// errOutputTooLarge is returned when a preprocessor prints more than
+// preprocessMaxOutput bytes.
+var errOutputTooLarge = fmt.Errorf("output larger than %d bytes", preprocessMaxOutput)
+
+// normalizePreprocessors keys the configured commands by lowercase extension
+// without the dot, dropping empty commands.
+func normalizePreprocessors(configured map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(configured))
+	for ext, argv := range configured {
+		if len(argv) > 0 {
+			out[strings.ToLower(strings.TrimPrefix(ext, "."))] = argv
+		}
+	}
+	return out
+}
+
+// preprocessorFor returns the command that converts a file, or nil.
+func (s *scanner) preprocessorFor(relPath string) []string {
+	if s.preprocessors == nil {
+		return nil
+	}
+	return s.preprocessors[strings.ToLower(strings.TrimPrefix(path.Ext(relPath), "."))]
+}
+
+// preprocess runs argv for a file, from the project root, and returns what it
+// prints. The file's path relative to the root, starting with "./" so a name
+// like "-o.drawio" isn't taken for a flag, replaces every {path} in the
+// arguments; without one, the file is piped to the command's stdin instead.
+// Files that resolve outside the root, through a symlink, are refused.
+func (s *scanner) preprocess(argv []string, absPath, relPath string) ([]byte, error) {
+	if !insideRoot(s.rootPath, absPath) {
+		return nil, errors.New("file is outside the project")
+	}
+
+	args := make([]string, len(argv))
+	usesPath := false
+	for i, arg := range argv {
+		usesPath = usesPath || strings.Contains(arg, pathPlaceholder)
+		args[i] = strings.ReplaceAll(arg, pathPlaceholder, "."+string(filepath.Separator)+filepath.FromSlash(relPath))
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, preprocessTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = s.rootPath
+	if !usesPath {
+		file, err := os.Open(absPath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		cmd.Stdin = file
+	}
+	stdout := &cappedBuffer{limit: preprocessMaxOutput}
+	stderr := &cappedBuffer{limit: preprocessMaxStderr, drop: true}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	switch {
+	case stdout.overflow:
+		return nil, errOutputTooLarge
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("timed out after %s", preprocessTimeout)
+	case err != nil:
+		if msg := firstLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// insideRoot reports whether absPath, with symlinks resolved, is inside
+// rootPath.
+func insideRoot(rootPath, absPath string) bool {
+	root, err := filepath.EvalSymlinks(rootPath)
+	if err != nil {
+		return false
+	}
+	target, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// cappedBuffer is a buffer that fails writes beyond limit bytes, or with
+// drop, keeps the first limit bytes and silently drops the rest.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit    int
+	drop     bool
+	overflow bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		b.overflow = true
+		if b.drop {
+			b.Buffer.Write(p[:b.limit-b.Len()])
+			return len(p), nil
+		}
+		return 0, errOutputTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// firstLine returns the first non-empty line of s, trimmed.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestPreprocessors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("preprocessor commands in this test need a POSIX shell")
+	}
+	if _, err := exec.LookPath("tr"); err != nil {
+		t.Skip("tr not available")
+	}
+	tempDir, err := os.MkdirTemp("", "scanner_test_preprocess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	outside, err := os.MkdirTemp("", "scanner_test_preprocess_outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	createFile(t, tempDir, "diagram.drawio", "\x00\x01compressed")
+	createFile(t, tempDir, "-o.drawio", "\x00\x01compressed")
+	createFile(t, tempDir, "notes.up", "shout this\n")
+	createFile(t, tempDir, "broken.bad", "x\n")
+	createFile(t, outside, "secret.up", "outside\n")
+	if err := os.Symlink(filepath.Join(outside, "secret.up"), filepath.Join(tempDir, "link.up")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	cfg := &config.Config{
+		OutputFile:         "codebase.txt",
+		AllowPreprocessors: true,
+		Preprocessors: map[string][]string{
+			"drawio": {"sh", "-c", "echo \"diagram at $1\"", "sh", "{path}"},
+			".UP":    {"tr", "a-z", "A-Z"},
+			"bad":    {"sh", "-c", "echo boom >&2; exit 3"},
+		},
+		Dirs: map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	// The config alone doesn't run anything
+	var buf bytes.Buffer
+	os.Unsetenv(config.AllowPreprocessorsEnv)
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertNotContains(t, buf.String(), "converted by")
+
+	os.Setenv(config.AllowPreprocessorsEnv, "1")
+	defer os.Unsetenv(config.AllowPreprocessorsEnv)
+	buf.Reset()
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+	// The binary check doesn't apply to converted files
+	assertContains(t, output, "FILE: diagram.drawio (converted by sh)")
+	assertContains(t, output, "diagram at ./diagram.drawio\n")
+	// A path that looks like a flag is passed as a path
+	assertContains(t, output, "diagram at ./-o.drawio\n")
+	assertContains(t, output, "FILE: notes.up (converted by tr)")
+	assertContains(t, output, "SHOUT THIS\n")
+	assertNotContains(t, output, "broken.bad")
+	assertNotContains(t, output, "OUTSIDE")
+	if result.Skipped[ReasonPreprocessFailed] != 2 {
+		t.Errorf("Expected the failing and the outside files to be skipped, got %v", result.Skipped)
+	}
+
+	// Without the opt-in nothing runs
+	cfg.AllowPreprocessors = false
+	buf.Reset()
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertContains(t, buf.String(), "FILE: notes.up\n")
+	assertContains(t, buf.String(), "shout this\n")
+	assertNotContains(t, buf.String(), "diagram.drawio")
+}
+
+func TestCappedBufferDrop(t *testing.T) {
+	b := &cappedBuffer{limit: 8, drop: true}
+	for _, part := range []string{"first ", "line\n", "more noise\n"} {
+		if n, err := b.Write([]byte(part)); err != nil || n != len(part) {
+			t.Fatalf("Write(%q) = %d, %v; want the whole part taken", part, n, err)
+		}
+	}
+	if got := b.String(); got != "first li" {
+		t.Errorf("Expected the first 8 bytes kept, got %q", got)
+	}
+}
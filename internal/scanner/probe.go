@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"os"
+	"runtime"
+	"sync"
+)
+
+// maxProbeWorkers bounds the goroutines used by the binary pre-pass. The
+// work is mostly waiting on the filesystem, so more workers than CPUs help,
+// but not without limit.
+const maxProbeWorkers = 16
+
+// probe is what the pre-pass learned about a file before the write phase.
+type probe struct {
+	info os.FileInfo
+	err  error
+
+	// detected is set when kind holds a fresh verdict. Files whose cached
+	// verdict can be trusted are not read again.
+	detected bool
+	kind     int
+	mime     string
+}
+
+// probeFiles stats and binary-checks every collected file concurrently, so
+// the sequential write phase doesn't wait on those small reads one file at a
+// time. The cache is only read here; it is updated in the write phase.
+func (s *scanner) probeFiles() {
+	workers := runtime.NumCPU() * 2
+	if workers > maxProbeWorkers {
+		workers = maxProbeWorkers
+	}
+	if workers > len(s.files) {
+		workers = len(s.files)
+	}
+
+	probes := make([]probe, len(s.files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				probes[j] = s.probeFile(s.files[j])
+			}
+		}()
+	}
+	// Once the scan is stopped, the write phase won't need the rest
+	for j := range s.files {
+		if s.ctx.Err() != nil {
+			break
+		}
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	s.probes = make(map[string]probe, len(s.files))
+	for j, f := range s.files {
+		s.probes[f.relPath] = probes[j]
+	}
+}
+
+// probeFile stats a file and sniffs whether it is binary and its content
+// type, unless the cache already answers the binary question.
+func (s *scanner) probeFile(f fileEntry) probe {
+	info, err := s.statFile(f)
+	if err != nil {
+		return probe{err: err}
+	}
+	// An update copies unchanged files without looking at them
+	if s.previous != nil && !s.paranoid && s.previous.unmodified(f, info) {
+		return probe{info: info}
+	}
+	if s.cache != nil && !s.paranoid {
+		if entry, ok := s.cache.Lookup(f.relPath, info); ok && s.trustCached(entry) {
+			return probe{info: info}
+		}
+	}
+	kind, mime, err := s.sniff(f)
+	if err != nil {
+		return probe{info: info, err: err}
+	}
+	return probe{info: info, detected: true, kind: kind, mime: mime}
+}
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/fileutil"
+)
+
+func TestProbeFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "main.go", "package main")
+	createFile(t, tempDir, "image.bin", "\x00\x01\x02")
+
+	s := &scanner{ctx: context.Background(), rootPath: tempDir}
+	for _, name := range []string{"main.go", "image.bin", "gone.txt"} {
+		s.files = append(s.files, fileEntry{absPath: filepath.Join(tempDir, name), relPath: name})
+	}
+	s.probeFiles()
+
+	if p := s.probes["main.go"]; !p.detected || p.kind != fileutil.Text || p.info == nil {
+		t.Errorf("Expected main.go to be probed as text, got %+v", p)
+	}
+	if p := s.probes["image.bin"]; !p.detected || p.kind != fileutil.Binary {
+		t.Errorf("Expected image.bin to be probed as binary, got %+v", p)
+	}
+	if p := s.probes["gone.txt"]; p.err == nil {
+		t.Error("Expected a missing file to record its stat error")
+	}
+}
+
+// BenchmarkScan measures a scan of a project with many small files, where
+// the per-file binary check dominates.
+func BenchmarkScan(b *testing.B) {
+	tempDir, err := os.MkdirTemp("", "scanner_bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for d := 0; d < 50; d++ {
+		dir := filepath.Join(tempDir, fmt.Sprintf("pkg%02d", d))
+		os.MkdirAll(dir, 0755)
+		for f := 0; f < 40; f++ {
+			content := fmt.Sprintf("package pkg%02d\n\nfunc F%d() int { return %d }\n", d, f, f)
+			os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%02d.go", f)), []byte(content), 0644)
+		}
+	}
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+	stdout := os.Stdout
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	defer func() { os.Stdout = stdout }()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Scan(tempDir, cfg, io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
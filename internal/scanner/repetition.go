@@ -0,0 +1,175 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Defaults for collapse_repetition.
+const (
+	defaultRepetitionSimilarity = 0.9
+	defaultRepetitionMinRun     = 8
+
+	// repetitionSample is how many lines of a collapsed run are kept.
+	repetitionSample = 2
+
+	// repetitionMaxFuzzyLen bounds the lines compared by edit distance;
+	// longer lines only count as similar when identical.
+	repetitionMaxFuzzyLen = 256
+)
+
+// repetitionCollapser is a writer that collapses runs of near-identical
+// consecutive lines, as found in generated code, into the first few lines of
+// the run followed by "... (N similar lines omitted)". A line belongs to a
+// run when its similarity to the run's first line is at least similarity.
+type repetitionCollapser struct {
+	w          io.Writer
+	similarity float64
+	minRun     int
+
+	// partial holds an incomplete line between writes.
+	partial []byte
+
+	// run holds the lines of the current run, up to minRun; beyond that only
+	// the count and size of the extra lines are kept.
+	run        [][]byte
+	extra      int
+	extraBytes int
+
+	// saved is the number of bytes left out so far.
+	saved int
+}
+
+func newRepetitionCollapser(w io.Writer, similarity float64, minRun int) *repetitionCollapser {
+	return &repetitionCollapser{w: w, similarity: similarity, minRun: minRun}
+}
+
+func (c *repetitionCollapser) Write(p []byte) (int, error) {
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			c.partial = append(c.partial, data...)
+			break
+		}
+		line := append(c.partial, data[:i+1]...)
+		c.partial = nil
+		if err := c.addLine(line); err != nil {
+			return 0, err
+		}
+		data = data[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes the pending run and any final line without a newline.
+func (c *repetitionCollapser) Flush() error {
+	if len(c.partial) > 0 {
+		if err := c.addLine(c.partial); err != nil {
+			return err
+		}
+		c.partial = nil
+	}
+	return c.endRun()
+}
+
+// addLine extends the current run with line, or ends it and starts a new one.
+func (c *repetitionCollapser) addLine(line []byte) error {
+	if len(c.run) > 0 && c.similar(c.run[0], line) {
+		if len(c.run) < c.minRun {
+			c.run = append(c.run, line)
+		} else {
+			c.extra++
+			c.extraBytes += len(line)
+		}
+		return nil
+	}
+	if err := c.endRun(); err != nil {
+		return err
+	}
+	// Blank lines never start a run
+	if len(bytes.TrimSpace(line)) == 0 {
+		_, err := c.w.Write(line)
+		return err
+	}
+	c.run = append(c.run, line)
+	return nil
+}
+
+// endRun writes the current run, collapsed if it is long enough.
+func (c *repetitionCollapser) endRun() error {
+	defer func() {
+		c.run, c.extra, c.extraBytes = c.run[:0], 0, 0
+	}()
+
+	if len(c.run) < c.minRun {
+		for _, line := range c.run {
+			if _, err := c.w.Write(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, line := range c.run[:repetitionSample] {
+		if _, err := c.w.Write(line); err != nil {
+			return err
+		}
+	}
+	omitted := len(c.run) - repetitionSample + c.extra
+	omittedBytes := c.extraBytes
+	for _, line := range c.run[repetitionSample:] {
+		omittedBytes += len(line)
+	}
+
+	first := c.run[0]
+	indent := first[:len(first)-len(bytes.TrimLeft(first, " \t"))]
+	marker := fmt.Sprintf("%s... (%d similar lines omitted)\n", indent, omitted)
+	c.saved += omittedBytes - len(marker)
+	_, err := io.WriteString(c.w, marker)
+	return err
+}
+
+// similar reports whether two lines are near-identical.
+func (c *repetitionCollapser) similar(a, b []byte) bool {
+	a, b = bytes.TrimRight(a, "\r\n"), bytes.TrimRight(b, "\r\n")
+	if bytes.Equal(a, b) {
+		return true
+	}
+	if len(a) > repetitionMaxFuzzyLen || len(b) > repetitionMaxFuzzyLen {
+		return false
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	return 1-float64(editDistance(a, b))/float64(longest) >= c.similarity
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b []byte) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
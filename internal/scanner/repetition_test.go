@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestCollapseRepetition(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_repetition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var gen strings.Builder
+	gen.WriteString("var table = map[int]string{\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&gen, "\t%d: \"value_%d\",\n", i, i)
+	}
+	gen.WriteString("}\n")
+	createFile(t, tempDir, "table.go", gen.String())
+	createFile(t, tempDir, "main.go", "package main\n\nfunc main() {\n\tprintln(1)\n\tprintln(2)\n}\n")
+
+	cfg := &config.Config{
+		OutputFile:           "codebase.txt",
+		CollapseRepetition:   true,
+		RepetitionSimilarity: 0.6,
+		Dirs:                 map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "var table = map[int]string{\n\t0: \"value_0\",\n\t1: \"value_1\",\n\t... (98 similar lines omitted)\n}\n")
+	assertContains(t, output, "func main() {\n\tprintln(1)\n\tprintln(2)\n}\n")
+	if result.CollapsedBytes <= 0 {
+		t.Errorf("Expected collapsed bytes to be reported, got %d", result.CollapsedBytes)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"\t1: \"value_1\",", "\t12: \"value_12\",", 2},
+	}
+	for _, c := range cases {
+		if got := editDistance([]byte(c.a), []byte(c.b)); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/tokens"
+)
+
+// ReportVersion is the version of the report schema, in every report. It is
+// bumped when a field is renamed, removed, or changes meaning; new fields
+// don't bump it.
+const ReportVersion = 1
+
+// Kinds of report warnings.
+const (
+	WarningMaskedSecrets = "masked_secrets"
+	WarningOutputSize    = "output_warn_size"
+	WarningTruncated     = "truncated_lines"
+	WarningFileTokens    = "max_file_tokens"
+	WarningLineCap       = "max_dir_lines"
+	WarningStalePattern  = "stale_pattern"
+	WarningUnreadable    = "unreadable"
+)
+
+// Report is the machine-readable account of a run (start --report), for CI.
+// It is built from the Result the console summary prints, so the two always
+// agree.
+type Report struct {
+	Version  int             `json:"report_version"`
+	Inputs   ReportInputs    `json:"inputs"`
+	Totals   ReportTotals    `json:"totals"`
+	Dirs     []ReportDir     `json:"dirs"`
+	Outputs  []ReportOutput  `json:"outputs"`
+	Warnings []ReportWarning `json:"warnings"`
+
+	// TokenFamilies are the token counts of the output file for each of
+	// token_families, if any.
+	TokenFamilies []ReportTokens `json:"token_families,omitempty"`
+
+	// DurationMS is how long the run took, in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// ReportInputs describes what a run started from.
+type ReportInputs struct {
+	Root           string `json:"root"`
+	Config         string `json:"config"`
+	ConfigSHA256   string `json:"config_sha256"`
+	TextifyVersion string `json:"textify_version"`
+}
+
+// ReportTotals are the counts of the top-level output. Bytes is the file
+// content written, and OutputBytes the whole output, headers and tree
+// included; Tokens is estimated from OutputBytes.
+type ReportTotals struct {
+	FilesIncluded   int            `json:"files_included"`
+	FilesSkipped    int            `json:"files_skipped"`
+	SkippedByReason map[string]int `json:"skipped_by_reason"`
+	Lines           int            `json:"lines"`
+	Bytes           int64          `json:"bytes"`
+	OutputBytes     int64          `json:"output_bytes"`
+	Words           int64          `json:"words"`
+	Tokens          int64          `json:"tokens"`
+}
+
+// ReportDir is what one top-level folder, or "." for the root files, added
+// to the top-level output. Tokens is estimated from Bytes.
+type ReportDir struct {
+	Dir    string `json:"dir"`
+	Files  int    `json:"files"`
+	Bytes  int64  `json:"bytes"`
+	Tokens int64  `json:"tokens"`
+}
+
+// ReportOutput is one of the files a run wrote: the output file, then those
+// of the rules and of the outputs list.
+type ReportOutput struct {
+	File      string `json:"file"`
+	Format    string `json:"format"`
+	Files     int    `json:"files"`
+	Bytes     int64  `json:"bytes"`
+	Words     int64  `json:"words"`
+	Tokens    int64  `json:"tokens"`
+	Unchanged bool   `json:"unchanged"`
+}
+
+// ReportTokens is the token count of the output file for one tokenizer
+// family. Estimated is set when it is the chars/4 estimate instead, and Note
+// then tells why.
+type ReportTokens struct {
+	Family    string `json:"family"`
+	Tokens    int64  `json:"tokens"`
+	Estimated bool   `json:"estimated"`
+	Note      string `json:"note,omitempty"`
+}
+
+// ReportWarning is something about a run worth a look, with Kind one of the
+// Warning constants.
+type ReportWarning struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// NewReport builds the report of a run of cfg that returned result and took
+// duration. changed tells whether the output file itself changed.
+func NewReport(result *Result, cfg *config.Config, inputs ReportInputs, changed bool, duration time.Duration) *Report {
+	skipped := make(map[string]int, len(result.Skipped))
+	for reason, n := range result.Skipped {
+		skipped[reason] = n
+	}
+	r := &Report{
+		Version: ReportVersion,
+		Inputs:  inputs,
+		Totals: ReportTotals{
+			FilesIncluded:   result.Included,
+			FilesSkipped:    result.SkippedFiles,
+			SkippedByReason: skipped,
+			Lines:           result.Lines,
+			Bytes:           result.Bytes,
+			OutputBytes:     result.Size,
+			Words:           result.Words,
+			Tokens:          tokens.Estimate(result.Size),
+		},
+		Dirs:       []ReportDir{},
+		Warnings:   []ReportWarning{},
+		DurationMS: duration.Milliseconds(),
+	}
+
+	dirs := make([]string, 0, len(result.Dirs))
+	for dir := range result.Dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		total := result.Dirs[dir]
+		r.Dirs = append(r.Dirs, ReportDir{Dir: dir, Files: total.Files, Bytes: total.Bytes, Tokens: tokens.Estimate(total.Bytes)})
+	}
+
+	r.addOutput(cfg.OutputFile, cfg.Format, result, !changed)
+	for _, name := range cfg.RuleOutputs() {
+		if o := result.Outputs[name]; o != nil {
+			r.addOutput(name, cfg.Format, o, o.Unchanged)
+		}
+	}
+	for _, o := range cfg.Outputs {
+		if res := result.Outputs[o.File]; res != nil {
+			r.addOutput(o.File, o.Format, res, res.Unchanged)
+		}
+	}
+
+	for _, p := range result.Unreadable {
+		r.warn(WarningUnreadable, "could not read %s", p)
+	}
+	if result.MaskedFiles > 0 {
+		r.warn(WarningMaskedSecrets, "masked the values of %d dotenv file(s)", result.MaskedFiles)
+	}
+	if result.PassedWarnSize {
+		warnSize, _, _ := cfg.OutputLimits()
+		r.warn(WarningOutputSize, "the output passed output_warn_size (%d bytes)", warnSize)
+	}
+	if result.TruncatedLines > 0 {
+		r.warn(WarningTruncated, "cut %d line(s) longer than %d bytes (max_line_bytes)", result.TruncatedLines, cfg.EffectiveMaxLineBytes())
+	}
+	if result.TruncatedFiles > 0 {
+		r.warn(WarningFileTokens, "cut %d file(s) at max_file_tokens", result.TruncatedFiles)
+	}
+	for _, dir := range result.CappedDirNames() {
+		r.warn(WarningLineCap, "line cap reached in %s (max_dir_lines: %d); %d files left out", dir, cfg.Dirs[dir].MaxDirLines, result.CappedDirs[dir])
+	}
+	for _, p := range result.Patterns {
+		if p.Matches == 0 {
+			r.warn(WarningStalePattern, "dirs[%q].%s pattern %q matched nothing", p.Dir, p.Kind, p.Pattern)
+		}
+	}
+	return r
+}
+
+func (r *Report) addOutput(file, format string, result *Result, unchanged bool) {
+	if format == "" {
+		format = config.FormatText
+	}
+	r.Outputs = append(r.Outputs, ReportOutput{
+		File:      file,
+		Format:    format,
+		Files:     result.Included,
+		Bytes:     result.Size,
+		Words:     result.Words,
+		Tokens:    tokens.Estimate(result.Size),
+		Unchanged: unchanged,
+	})
+}
+
+// AddTokenCounts adds the counts of the output file per token family, which
+// are only known once it is written.
+func (r *Report) AddTokenCounts(counts []tokens.FamilyCount) {
+	for _, c := range counts {
+		r.TokenFamilies = append(r.TokenFamilies, ReportTokens{Family: c.Family, Tokens: c.Tokens, Estimated: c.Estimated, Note: c.Note})
+	}
+}
+
+func (r *Report) warn(kind, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, ReportWarning{Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// WriteReport writes the report as indented JSON to path.
+func WriteReport(path string, r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
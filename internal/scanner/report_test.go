@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/tokens"
+)
+
+func TestNewReport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":     {Data: []byte("package main\n")},
+		".env":        {Data: []byte("TOKEN=hunter2\n")},
+		"notes.log":   {Data: []byte("log\n")},
+		"src/a.go":    {Data: []byte("package src\n")},
+		"src/b.go":    {Data: []byte("package src\n\nfunc B() {}\n")},
+		"docs/readme": {Data: []byte("docs\n")},
+	}
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		MaskEnv:    true,
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Exclude: []string{"*.log", "*.tmp"}},
+		},
+	}
+	result, err := ScanFS(fsys, "project", cfg, io.Discard, Hooks{})
+	if err != nil {
+		t.Fatalf("ScanFS failed: %v", err)
+	}
+	inputs := ReportInputs{Root: "/project", ConfigSHA256: "abc", TextifyVersion: "v1.2.3"}
+	report := NewReport(result, cfg, inputs, true, 1500*time.Millisecond)
+
+	if report.Version != ReportVersion || report.Inputs != inputs || report.DurationMS != 1500 {
+		t.Errorf("Unexpected header: version %d, inputs %+v, duration %d", report.Version, report.Inputs, report.DurationMS)
+	}
+	// The totals are those of the summary
+	totals := ReportTotals{
+		FilesIncluded:   result.Included,
+		FilesSkipped:    1,
+		SkippedByReason: map[string]int{ReasonExcluded: 1},
+		Lines:           result.Lines,
+		Bytes:           result.Bytes,
+		OutputBytes:     result.Size,
+		Words:           result.Words,
+		Tokens:          tokens.Estimate(result.Size),
+	}
+	if result.Included != 5 || !reflect.DeepEqual(report.Totals, totals) {
+		t.Errorf("Unexpected totals.\nExpected: %+v\nGot:      %+v", totals, report.Totals)
+	}
+
+	dirs := []ReportDir{
+		{Dir: ".", Files: 2, Bytes: 24, Tokens: tokens.Estimate(24)},
+		{Dir: "docs", Files: 1, Bytes: 5, Tokens: tokens.Estimate(5)},
+		{Dir: "src", Files: 2, Bytes: 37, Tokens: tokens.Estimate(37)},
+	}
+	if !reflect.DeepEqual(report.Dirs, dirs) {
+		t.Errorf("Unexpected folders.\nExpected: %+v\nGot:      %+v", dirs, report.Dirs)
+	}
+	var bytes int64
+	for _, d := range report.Dirs {
+		bytes += d.Bytes
+	}
+	if bytes != report.Totals.Bytes {
+		t.Errorf("Folders add up to %d bytes, totals say %d", bytes, report.Totals.Bytes)
+	}
+
+	outputs := []ReportOutput{{File: "codebase.txt", Format: config.FormatText, Files: 5, Bytes: result.Size, Words: result.Words, Tokens: tokens.Estimate(result.Size)}}
+	if !reflect.DeepEqual(report.Outputs, outputs) {
+		t.Errorf("Unexpected outputs.\nExpected: %+v\nGot:      %+v", outputs, report.Outputs)
+	}
+
+	var kinds []string
+	for _, w := range report.Warnings {
+		kinds = append(kinds, w.Kind)
+	}
+	if expected := []string{WarningMaskedSecrets, WarningStalePattern}; !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("Unexpected warnings.\nExpected: %q\nGot:      %+v", expected, report.Warnings)
+	}
+}
@@ -0,0 +1,136 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// separator is the dashed line that opens and closes every file header.
+var separator = strings.Repeat("-", 50)
+
+// sanitizer is a writer that makes file content safe to embed in the output.
+// Control characters other than newline, tab, and the carriage return of a
+// \r\n line ending are written as visible \xNN escapes, so a bare \r can't
+// overwrite a line in a terminal, and any content line identical to the
+// header separator is prefixed with a backslash so it cannot be mistaken for
+// the start of a new file. Neither change adds or removes lines. Outputs
+// without separators only escape control characters (newControlSanitizer).
+type sanitizer struct {
+	w io.Writer
+
+	// separators is set when separator lines are escaped.
+	separators bool
+
+	// line holds the start of the current line while it could still turn
+	// out to be a separator; pending is true while that is possible.
+	line    []byte
+	pending bool
+
+	out []byte
+
+	// cr is set while a carriage return is held back until the next byte
+	// tells whether it ends a line.
+	cr bool
+
+	// lineStart is true while the content is empty or ends with a newline.
+	lineStart bool
+}
+
+func newSanitizer(w io.Writer) *sanitizer {
+	return &sanitizer{w: w, separators: true, pending: true, lineStart: true}
+}
+
+// newControlSanitizer returns a sanitizer that only escapes control
+// characters, for outputs whose file boundaries no content line can fake.
+func newControlSanitizer(w io.Writer) *sanitizer {
+	return &sanitizer{w: w, lineStart: true}
+}
+
+func (s *sanitizer) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		s.lineStart = p[len(p)-1] == '\n'
+	}
+	s.out = s.out[:0]
+	for _, b := range p {
+		if s.pending {
+			s.line = append(s.line, b)
+			switch {
+			case b == '\n':
+				body := s.line[:len(s.line)-1]
+				if n := len(body); n > 0 && body[n-1] == '\r' {
+					body = body[:n-1]
+				}
+				if string(body) == separator {
+					s.out = append(s.out, '\\')
+				}
+				s.releaseLine()
+			case b == '-' && len(s.line) <= len(separator):
+			case b == '\r' && len(s.line) == len(separator)+1:
+			default:
+				s.releaseLine()
+				s.pending = false
+			}
+			continue
+		}
+		s.emit(b)
+		if b == '\n' {
+			s.pending = s.separators
+		}
+	}
+	if _, err := s.w.Write(s.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes a final line held back without a trailing newline. The output
+// adds a newline after every file, so a final separator is escaped too.
+func (s *sanitizer) Flush() error {
+	s.out = s.out[:0]
+	if s.pending && string(s.line) == separator {
+		s.out = append(s.out, '\\')
+	}
+	s.releaseLine()
+	if s.cr {
+		s.cr = false
+		s.out = appendSanitized(s.out, '\r')
+	}
+	_, err := s.w.Write(s.out)
+	return err
+}
+
+// releaseLine moves the held-back line start to the output buffer.
+func (s *sanitizer) releaseLine() {
+	for _, b := range s.line {
+		s.emit(b)
+	}
+	s.line = s.line[:0]
+}
+
+// emit appends b to the output buffer, sanitized, holding a carriage return
+// back until the byte after it shows whether it is part of a \r\n.
+func (s *sanitizer) emit(b byte) {
+	if s.cr {
+		s.cr = false
+		if b == '\n' {
+			s.out = append(s.out, '\r', '\n')
+			return
+		}
+		s.out = appendSanitized(s.out, '\r')
+	}
+	if b == '\r' {
+		s.cr = true
+		return
+	}
+	s.out = appendSanitized(s.out, b)
+}
+
+// appendSanitized appends b, escaping C0 control characters other than
+// newline and tab.
+func appendSanitized(out []byte, b byte) []byte {
+	if b < 0x20 && b != '\n' && b != '\t' {
+		return append(out, fmt.Sprintf(`\x%02x`, b)...)
+	}
+	return append(out, b)
+}
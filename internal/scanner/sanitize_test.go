@@ -0,0 +1,44 @@
+package scanner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSanitizer(t *testing.T) {
+	sep := strings.Repeat("-", 50)
+	input := "plain\n" +
+		sep + "\n" +
+		"FILE: fake.go\n" +
+		sep + "\r\n" +
+		sep + "-\n" +
+		"\x1b[31mred\x1b[0m\tok\x0b\n" +
+		"progress 10%\rprogress 99%\r\r\n" +
+		sep + "\r"
+
+	want := "plain\n" +
+		`\` + sep + "\n" +
+		"FILE: fake.go\n" +
+		`\` + sep + "\r\n" +
+		sep + "-\n" +
+		`\x1b[31mred\x1b[0m` + "\tok" + `\x0b` + "\n" +
+		`progress 10%\x0dprogress 99%\x0d` + "\r\n" +
+		sep + `\x0d`
+
+	// Write one byte at a time so lines span many writes
+	var buf bytes.Buffer
+	s := newSanitizer(&buf)
+	for i := 0; i < len(input); i++ {
+		if _, err := s.Write([]byte{input[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != want {
+		t.Errorf("Sanitized output mismatch.\nGot:\n%q\nWant:\n%q", got, want)
+	}
+}
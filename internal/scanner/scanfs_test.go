@@ -0,0 +1,145 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// TestRulePrecedence checks how the path rules rank against each other. Each
+// case is a folder holding f.go, with the case's rule and, if gitignored, a
+// .gitignore line for the file. want is the skip reason, or "" when f.go is
+// included.
+func TestRulePrecedence(t *testing.T) {
+	enabled := func(rule config.DirRule) config.DirRule {
+		rule.Enabled = true
+		return rule
+	}
+	forced := []string{"f.go"}
+	tests := []struct {
+		name       string
+		rule       config.DirRule
+		gitignored bool
+		want       string
+	}{
+		{"plain", enabled(config.DirRule{}), false, ""},
+		{"gitignored", enabled(config.DirRule{}), true, ReasonGitignored},
+		{"forced over gitignore", enabled(config.DirRule{Include: forced}), true, ""},
+		{"allowed extension", enabled(config.DirRule{Extensions: []string{"go"}}), false, ""},
+		{"extension not allowed", enabled(config.DirRule{Extensions: []string{"py"}}), false, ReasonExtNotAllowed},
+		{"forced over allowlist", enabled(config.DirRule{Extensions: []string{"py"}, Include: forced}), false, ""},
+		{"blocked extension", enabled(config.DirRule{ExcludeExtensions: []string{"go"}}), false, ReasonExtExcluded},
+		{"forced over blocklist", enabled(config.DirRule{ExcludeExtensions: []string{"go"}, Include: forced}), false, ""},
+		{"blocklist over allowlist", enabled(config.DirRule{Extensions: []string{"go"}, ExcludeExtensions: []string{"go"}}), false, ReasonExtExcluded},
+		{"gitignore over blocklist", enabled(config.DirRule{ExcludeExtensions: []string{"go"}}), true, ReasonGitignored},
+		{"gitignore over allowlist", enabled(config.DirRule{Extensions: []string{"py"}}), true, ReasonGitignored},
+		{"ignore_git", enabled(config.DirRule{IgnoreGit: true}), true, ""},
+		{"ignore_git keeps extensions", enabled(config.DirRule{IgnoreGit: true, Extensions: []string{"py"}}), true, ReasonExtNotAllowed},
+		{"excluded", enabled(config.DirRule{Exclude: forced}), false, ReasonExcluded},
+		{"exclude over include", enabled(config.DirRule{Exclude: forced, Include: forced}), false, ReasonExcluded},
+		{"exclude over gitignore", enabled(config.DirRule{Exclude: forced}), true, ReasonExcluded},
+		{"disabled", config.DirRule{}, false, ReasonDisabled},
+		{"disabled over include", config.DirRule{Include: forced}, false, ReasonDisabled},
+		{"disabled over gitignore", config.DirRule{}, true, ReasonDisabled},
+		{"disabled over ignore_git", config.DirRule{IgnoreGit: true}, false, ReasonDisabled},
+	}
+
+	fsys := fstest.MapFS{}
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+	var gitignore strings.Builder
+	wantSkipped := make(map[string]int)
+	for i, tt := range tests {
+		dir := fmt.Sprintf("case%02d", i)
+		fsys[dir+"/f.go"] = &fstest.MapFile{Data: []byte("package " + dir + "\n")}
+		cfg.Dirs[dir] = tt.rule
+		if tt.gitignored {
+			fmt.Fprintf(&gitignore, "/%s/f.go\n", dir)
+		}
+		if tt.want != "" {
+			wantSkipped[tt.want]++
+		}
+	}
+	fsys[".gitignore"] = &fstest.MapFile{Data: []byte(gitignore.String())}
+
+	// Disabled folders are skipped whole, the rest file by file
+	reasons := make(map[string]string)
+	hooks := Hooks{OnSkip: func(relPath, reason string) { reasons[relPath] = reason }}
+	var buf bytes.Buffer
+	result, err := ScanFS(fsys, "project", cfg, &buf, hooks)
+	if err != nil {
+		t.Fatalf("ScanFS failed: %v", err)
+	}
+	output := buf.String()
+	for i, tt := range tests {
+		dir := fmt.Sprintf("case%02d", i)
+		included := strings.Contains(output, "FILE: "+dir+"/f.go\n")
+		if included != (tt.want == "") {
+			t.Errorf("%s: expected included=%v, got %v", tt.name, tt.want == "", included)
+		}
+		reason, ok := reasons[dir+"/f.go"]
+		if !ok {
+			reason = reasons[dir]
+		}
+		if reason != tt.want {
+			t.Errorf("%s: expected reason %q, got %q", tt.name, tt.want, reason)
+		}
+	}
+	if !reflect.DeepEqual(result.Skipped, wantSkipped) {
+		t.Errorf("Unexpected skip reasons.\nExpected: %v\nGot:      %v", wantSkipped, result.Skipped)
+	}
+}
+
+// TestScanFSMatchesScan checks that a project read through an fs.FS comes
+// out as it does from the disk.
+func TestScanFSMatchesScan(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "textify_test_scanfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "cmd", "app"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "node_modules", "left-pad"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "logs"), 0755)
+	createFile(t, tempDir, "README.md", "# Demo\n")
+	createFile(t, tempDir, "cmd/app/main.go", "package main\n\nfunc main() {}\n")
+	createFile(t, tempDir, "logs/debug.log", "noise\n")
+	createFile(t, tempDir, "node_modules/left-pad/index.js", "module.exports = 1\n")
+	createFile(t, tempDir, "image.bin", "\x00\x01\x02")
+	createFile(t, tempDir, ".gitignore", "logs/\n")
+	createFile(t, tempDir, ".gitattributes", "README.md export-ignore\n")
+
+	cfg := &config.Config{
+		OutputFile:      "codebase.txt",
+		IncludeTree:     true,
+		HeaderSummary:   true,
+		ListBinaries:    true,
+		UseExportIgnore: true,
+		Dirs:            map[string]config.DirRule{".": {Enabled: true}},
+	}
+	var fromDisk, fromFS bytes.Buffer
+	diskResult, err := Scan(tempDir, cfg, &fromDisk)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	fsResult, err := ScanFS(os.DirFS(tempDir), filepath.Base(tempDir), cfg, &fromFS, Hooks{})
+	if err != nil {
+		t.Fatalf("ScanFS failed: %v", err)
+	}
+	if fromFS.String() != fromDisk.String() {
+		t.Errorf("Expected the same output from the disk and from an fs.FS.\nDisk:\n%s\nFS:\n%s", fromDisk.String(), fromFS.String())
+	}
+	if !reflect.DeepEqual(fsResult.Skipped, diskResult.Skipped) || fsResult.VendoredFiles != diskResult.VendoredFiles {
+		t.Errorf("Expected the same skips, got %v (%d vendored) and %v (%d vendored)", diskResult.Skipped, diskResult.VendoredFiles, fsResult.Skipped, fsResult.VendoredFiles)
+	}
+}
@@ -1,212 +1,1561 @@
 package scanner
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/JohnEsleyer/textify/internal/cache"
 	"github.com/JohnEsleyer/textify/internal/config"
 	"github.com/JohnEsleyer/textify/internal/fileutil"
+	"github.com/JohnEsleyer/textify/internal/gitutil"
+	"github.com/JohnEsleyer/textify/internal/tokens"
+	"github.com/JohnEsleyer/textify/internal/walker"
+)
 
-	"github.com/monochromegane/go-gitignore"
+// Skip reasons recorded in Result.Skipped. Path-based reasons come from the
+// walker; the rest are decided while reading file contents.
+const (
+	ReasonDisabled      = walker.ReasonDisabled
+	ReasonExcluded      = walker.ReasonExcluded
+	ReasonGitignored    = walker.ReasonGitignored
+	ReasonExtExcluded   = walker.ReasonExtExcluded
+	ReasonExtNotAllowed = walker.ReasonExtNotAllowed
+	ReasonTooOld        = walker.ReasonTooOld
+	ReasonMaxDepth      = walker.ReasonMaxDepth
+	ReasonArtifact      = walker.ReasonArtifact
+	ReasonVendored      = walker.ReasonVendored
+	ReasonNotSelected   = walker.ReasonNotSelected
+	ReasonDirExcluded   = walker.ReasonDirExcluded
+	ReasonUnchanged     = walker.ReasonUnchanged
+	ReasonOtherLanguage = walker.ReasonOtherLanguage
+	ReasonExportIgnore  = walker.ReasonExportIgnore
+	ReasonSymlinkedDir  = walker.ReasonSymlinkedDir
+	ReasonSymlinkLoop   = walker.ReasonSymlinkLoop
+	ReasonBinary        = "binary"
+	ReasonContentFilter = "content filter"
+	ReasonMIMEFilter    = "mime filter"
+	ReasonDirLineCap    = "directory line cap"
+	ReasonTextifyDump   = "textify output"
+
+	// ReasonLinkedDuplicate files are the same file on disk as one already
+	// written; only their header and a note naming it are written.
+	ReasonLinkedDuplicate = "linked duplicate"
 )
 
+// Progress receives a line for every file added to the output. Commands
+// that only measure the output can silence it.
+var Progress io.Writer = os.Stdout
+
+// gitHotWindow is how far back git history is inspected for git-hot ordering.
+const gitHotWindow = "6.months"
+
+// ChecksumSuffix is appended to the output path to name its checksum sidecar.
+const ChecksumSuffix = ".sha256"
+
+// contentFilterLimit caps how many bytes of a file the content regexes inspect,
+// bounding the cost of matching against very large files.
+const contentFilterLimit = 1 << 20
+
+// Result summarizes what a scan wrote and what it left out.
+type Result struct {
+	// Included is the number of files written to the output.
+	Included int
+
+	// Lines and Bytes measure the file contents written, without headers.
+	Lines int
+	Bytes int64
+
+	// Skipped counts skipped entries (files or whole directories) by reason.
+	Skipped map[string]int
+
+	// SkippedFiles is the number of files left out, for any reason. Files
+	// inside skipped directories are never seen, so they aren't counted.
+	SkippedFiles int
+
+	// SkippedExtensions counts the files left out because no rule allowed
+	// their extension (ReasonExtNotAllowed), by extension, lowercase and
+	// without its dot.
+	SkippedExtensions map[string]int
+
+	// CappedDirs maps each rule directory that reached max_dir_lines to the
+	// number of files it left out.
+	CappedDirs map[string]int
+
+	// VendoredDirs lists the vendored folders pruned, in walk order, and
+	// VendoredFiles counts the files they held, with those left out of
+	// vendored folders that include patterns reach into.
+	VendoredDirs  []string
+	VendoredFiles int
+
+	// CollapsedBytes is how many bytes collapse_repetition left out.
+	CollapsedBytes int64
+
+	// TruncatedLines is how many lines were cut at max_line_bytes.
+	TruncatedLines int
+
+	// TruncatedFiles is how many files were cut at max_file_tokens.
+	TruncatedFiles int
+
+	// Unreadable lists the files that could not be read, in output order.
+	// They are left out, or cut short if reading failed midway.
+	Unreadable []string
+
+	// MaskedFiles is how many dotenv files had their values masked
+	// (mask_env).
+	MaskedFiles int
+
+	// Dirs splits the files written and their content by top-level folder,
+	// with "." for the files at the root.
+	Dirs map[string]DirTotal
+
+	// PassedWarnSize is set on the result of a run whose output passed
+	// output_warn_size, across every output.
+	PassedWarnSize bool
+
+	// Unchanged is set on the results of rule and extra outputs whose new
+	// version was identical to the file in place, which was left untouched.
+	Unchanged bool
+
+	// Patterns counts the entries each include and exclude pattern of the
+	// rules matched (see PatternCount). It is only set on the result of a
+	// walk, not on those of the rule and extra outputs.
+	Patterns []PatternCount
+
+	// Hash is the hex-encoded SHA-256 of everything written to the output.
+	// It only changes when the output does.
+	Hash string
+
+	// Size is the number of bytes written to the output.
+	Size int64
+
+	// Words is the number of whitespace-separated words written to the
+	// output, headers and tree included.
+	Words int64
+
+	// Reused is how many files an update copied from the previous output
+	// instead of reading them again (see UpdateContext).
+	Reused int
+
+	// Manifest maps the sections of the output, for the next update. It is
+	// only set when the output can be updated (see OpenPrevious).
+	Manifest *Manifest
+
+	// Outputs holds the results of the other output files, keyed by file as
+	// configured: those set by rules and those in the outputs list. It is
+	// nil when there are none.
+	Outputs map[string]*Result
+}
+
+// DirTotal is what the files of one top-level folder added to an output.
+type DirTotal struct {
+	Files int
+	Bytes int64
+}
+
+// fileEntry is a file selected by the path rules, waiting to be written.
+type fileEntry struct {
+	absPath string
+	relPath string
+	rule    config.DirRule
+	ruleDir string
+
+	// forced files matched an include pattern and are never abridged.
+	forced bool
+}
+
+// scanner holds the state shared across a single scan.
+type scanner struct {
+	// ctx stops the scan once done.
+	ctx context.Context
+
+	rootPath string
+	regexps  map[string]*regexp.Regexp
+	result   *Result
+
+	// fsys, if set, holds the project instead of the directory at rootPath,
+	// which then only names it (see ScanFS).
+	fsys fs.FS
+
+	// out frames the output being written, in each of its formats.
+	out fanOut
+
+	// files are the walk's included files, in output order.
+	files []fileEntry
+
+	// changeCounts holds per-file commit counts when ordering by git-hot.
+	changeCounts map[string]int
+
+	// authorship holds per-file git authorship when IncludeGitBlame is on.
+	authorship map[string]gitutil.FileInfo
+
+	// cache remembers binary verdicts and content hashes between runs.
+	cache    *cache.Cache
+	paranoid bool
+
+	// forceText and forceBinary override the binary check by extension.
+	forceText   map[string]bool
+	forceBinary map[string]bool
+
+	// deepBinaryCheck samples windows across large files in the binary
+	// check, not just their head.
+	deepBinaryCheck bool
+
+	// sanitizeUTF8 outputs text that isn't valid UTF-8, converted from a
+	// guessed encoding, instead of skipping it as binary.
+	sanitizeUTF8 bool
+
+	// listBinaries writes a placeholder header for binaries instead of
+	// dropping them silently.
+	listBinaries bool
+
+	// dirLines tracks the lines written per rule directory for max_dir_lines.
+	dirLines map[string]int
+
+	// maskEnv masks dotenv values, except for the keys in envKeepKeys.
+	maskEnv     bool
+	envKeepKeys map[string]bool
+
+	// includeFileMeta adds permissions and symlink targets to headers.
+	includeFileMeta bool
+
+	// includeChecksums adds each file's SHA-256 and size to its header.
+	includeChecksums bool
+
+	// skipDumps skips files that start like textify output or with one of
+	// dumpMarkers.
+	skipDumps   bool
+	dumpMarkers [][]byte
+
+	// linked holds the files written in full by size, unless
+	// keepLinkedDuplicates writes every path to a file in full.
+	keepLinkedDuplicates bool
+
+	// maxLineBytes is the longest line written in full.
+	maxLineBytes int
+
+	// followSymlinks is set with follow_symlinks. viaLinks then maps files
+	// reached through a symlink to the same file reached without one, which
+	// gets the content wherever it comes in the output (see
+	// preferDirectPaths). Both it and linked are for the output being
+	// written.
+	followSymlinks bool
+	viaLinks       map[string]linkedFile
+	linked         map[int64][]linkedFile
+
+	// warnSize and maxSize are output_warn_size and max_output_size in
+	// bytes. written is the content written by the run so far, across every
+	// output, and dirBytes splits it by top-level folder.
+	warnSize, maxSize int64
+	written           int64
+	dirBytes          map[string]int64
+	warned            bool
+
+	// encodedFraction and encodedRunLength configure collapsing of files
+	// that are mostly base64; a zero fraction turns it off.
+	encodedFraction  float64
+	encodedRunLength int
+
+	// collapseRepetition shortens runs of near-identical lines.
+	collapseRepetition   bool
+	repetitionSimilarity float64
+	repetitionMinRun     int
+
+	// indentWidth, if set, normalizes leading whitespace to tabs of that
+	// width (indentTabs) or to spaces.
+	indentTabs  bool
+	indentWidth int
+
+	// probes holds the pre-pass stat and binary results, by relative path.
+	probes map[string]probe
+
+	// hooks are the caller's per-file callbacks.
+	hooks Hooks
+
+	// preprocessors convert files to text by extension, when allowed.
+	preprocessors map[string][]string
+
+	// previous is the output being updated, if any. manifest is filled with
+	// the sections written to doc while the top-level output is, when it
+	// can be updated.
+	previous *Previous
+	manifest *Manifest
+	doc      *textDoc
+}
+
 // Scan initiates the directory walk based on the provided configuration.
-func Scan(rootPath string, cfg *config.Config, writer io.Writer) error {
-	matcher := getIgnoreMatcher(rootPath)
-	bufWriter := bufio.NewWriter(writer)
-	defer bufWriter.Flush()
+// Files go to writer, except those governed by a rule with its own
+// output_file, which Scan writes to that file; see writeRuleOutputs.
+func Scan(rootPath string, cfg *config.Config, writer io.Writer) (*Result, error) {
+	return ScanWithHooks(rootPath, cfg, writer, Hooks{})
+}
 
-	// Initial rule (Root ".")
-	rootRule, ok := cfg.Dirs["."]
-	if !ok {
-		// If root is missing from config, default to enabled but no extensions
-		rootRule = config.DirRule{Enabled: true, Extensions: []string{}}
-	}
-
-	return walk(rootPath, rootPath, cfg.Dirs, rootRule, matcher, bufWriter)
-}
-
-func walk(
-	fullPath string,
-	rootPath string,
-	dirRules map[string]config.DirRule,
-	currentRule config.DirRule,
-	matcher gitignore.IgnoreMatcher,
-	writer *bufio.Writer,
-) error {
-    
-    // Check if the directory we are currently IN has a specific rule
-	relDir, _ := filepath.Rel(rootPath, fullPath)
-	if relDir == "." {
-		relDir = "."
-	} else {
-		relDir = filepath.ToSlash(relDir)
+// ScanWithHooks is Scan, calling hooks as files are processed.
+func ScanWithHooks(rootPath string, cfg *config.Config, writer io.Writer, hooks Hooks) (*Result, error) {
+	return ScanContext(context.Background(), rootPath, cfg, writer, hooks)
+}
+
+// ScanContext is ScanWithHooks, stopping once ctx is done: the walk before
+// its next entry, the write phase before its next file. It then returns
+// ctx's error and the counts so far. Nothing is left of the rule and extra
+// outputs, and the cache keeps what was learned; what reached writer is
+// for the caller to discard.
+func ScanContext(ctx context.Context, rootPath string, cfg *config.Config, writer io.Writer, hooks Hooks) (*Result, error) {
+	return scan(ctx, nil, rootPath, cfg, writer, hooks, nil)
+}
+
+// ScanFS is ScanWithHooks for a project held in fsys, such as an
+// fstest.MapFS or an embed.FS, whose "." is the project root; name names
+// it, as the output's title. The project is neither read from the disk nor
+// written to, so the settings that need it or git history don't apply:
+// cache_file, changed_since, include_git_blame, order: git-hot,
+// follow_symlinks, preprocessors, outputs, and the rules' output_file,
+// whose files go to writer with the rest.
+func ScanFS(fsys fs.FS, name string, cfg *config.Config, writer io.Writer, hooks Hooks) (*Result, error) {
+	copied := *cfg
+	copied.CacheFile = ""
+	copied.ChangedSince = ""
+	copied.IncludeGitBlame = false
+	copied.FollowSymlinks = false
+	copied.Preprocessors = nil
+	copied.Outputs = nil
+	if copied.Order == config.OrderGitHot {
+		copied.Order = config.OrderPath
+	}
+	copied.Dirs = make(map[string]config.DirRule, len(cfg.Dirs))
+	for dir, rule := range cfg.Dirs {
+		rule.OutputFile = ""
+		copied.Dirs[dir] = rule
 	}
+	return scan(context.Background(), fsys, name, &copied, writer, hooks, nil)
+}
 
-	if specificRule, exists := dirRules[relDir]; exists {
-		currentRule = specificRule
+// scan is ScanContext, reading the project from fsys if set, and updating
+// previous if set.
+func scan(ctx context.Context, fsys fs.FS, rootPath string, cfg *config.Config, writer io.Writer, hooks Hooks, previous *Previous) (*Result, error) {
+	w, err := newWalker(fsys, rootPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newScanner(rootPath, cfg, hooks)
+	if err != nil {
+		return nil, err
+	}
+	s.ctx = ctx
+	s.fsys = fsys
+	s.previous = previous
+	defer s.saveCache()
+
+	// A single walk feeds every consumer of the rule decisions
+	stats := &statsVisitor{results: make(map[string]*Result), fsys: fsys}
+	files := &fileCollector{}
+	tree := &treeVisitor{paths: make(map[string][]string), all: cfg.TreeMode == config.TreeModeAll}
+	patterns := &patternCounter{counts: make(map[patternKey]int)}
+	visitors := []walker.Visitor{stats, files, tree, patterns}
+	if hooks.OnSkip != nil {
+		visitors = append(visitors, &skipHookVisitor{onSkip: hooks.OnSkip})
+	}
+	if err := w.WalkContext(ctx, visitors...); err != nil {
+		return stats.result(""), err
 	}
+	s.files = files.files
 
-    // 1. CHECK ENABLED STATUS
-    // If the directory is explicitly disabled in config, stop everything here.
-    if !currentRule.Enabled {
-        return nil // Skip this directory and its children
-    }
+	if err := s.orderFiles(cfg.Order); err != nil {
+		return stats.result(""), err
+	}
+	result, err := s.writeOutputs(cfg, writer, tree.paths, stats)
+	if result != nil {
+		result.Patterns = patterns.patternCounts(cfg.Dirs)
+	}
+	return result, err
+}
 
-	entries, err := os.ReadDir(fullPath)
+// newScanner sets up a scan of rootPath with the content settings of cfg.
+// The caller saves the cache when done.
+func newScanner(rootPath string, cfg *config.Config, hooks Hooks) (*scanner, error) {
+	regexps, err := compileContentFilters(cfg.Dirs)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	indentTabs, indentWidth, err := config.ParseIndentStyle(cfg.IndentStyle)
+	if err != nil {
+		return nil, err
+	}
+	warnSize, maxSize, err := cfg.OutputLimits()
+	if err != nil {
+		return nil, err
 	}
 
-	for _, entry := range entries {
-		entryPath := filepath.Join(fullPath, entry.Name())
-		relEntryPath, _ := filepath.Rel(rootPath, entryPath)
-		relEntryPath = filepath.ToSlash(relEntryPath)
-		ext := strings.TrimPrefix(filepath.Ext(entry.Name()), ".")
+	s := &scanner{
+		ctx:          context.Background(),
+		rootPath:     rootPath,
+		regexps:      regexps,
+		paranoid:     cfg.Paranoid,
+		listBinaries: cfg.ListBinaries,
+		sanitizeUTF8: cfg.SanitizeInvalidUTF8,
 
-		// -----------------------------
-		// 1. SYSTEM EXCLUDES (Hardcoded)
-		// -----------------------------
-		if shouldAlwaysExclude(entry.Name()) {
-			continue
+		deepBinaryCheck: cfg.DeepBinaryCheck,
+		dirLines:        make(map[string]int),
+		maskEnv:         cfg.MaskEnv,
+		envKeepKeys:     make(map[string]bool),
+		forceText:       make(map[string]bool),
+		forceBinary:     make(map[string]bool),
+
+		includeFileMeta:  cfg.IncludeFileMeta,
+		includeChecksums: cfg.IncludeChecksums,
+		skipDumps:        cfg.SkipTextifyDumps,
+
+		keepLinkedDuplicates: cfg.KeepLinkedDuplicates,
+		followSymlinks:       cfg.FollowSymlinks,
+		maxLineBytes:         cfg.EffectiveMaxLineBytes(),
+		encodedFraction:      cfg.EncodedDataFraction,
+		encodedRunLength:     cfg.EncodedRunLength,
+		indentTabs:           indentTabs,
+		indentWidth:          indentWidth,
+		warnSize:             warnSize,
+		maxSize:              maxSize,
+		dirBytes:             make(map[string]int64),
+		hooks:                hooks,
+	}
+	if s.encodedRunLength <= 0 {
+		s.encodedRunLength = defaultEncodedRunLength
+	}
+	if cfg.CollapseRepetition {
+		s.collapseRepetition = true
+		s.repetitionSimilarity = cfg.RepetitionSimilarity
+		if s.repetitionSimilarity <= 0 {
+			s.repetitionSimilarity = defaultRepetitionSimilarity
 		}
+		s.repetitionMinRun = cfg.RepetitionMinRun
+		if s.repetitionMinRun <= repetitionSample {
+			s.repetitionMinRun = defaultRepetitionMinRun
+		}
+	}
+	for _, marker := range cfg.DumpMarkers {
+		if marker != "" {
+			s.dumpMarkers = append(s.dumpMarkers, []byte(marker))
+		}
+	}
+	for _, key := range cfg.EnvKeepKeys {
+		s.envKeepKeys[key] = true
+	}
+	for _, ext := range cfg.ForceText {
+		s.forceText[ext] = true
+	}
+	for _, ext := range cfg.ForceBinary {
+		s.forceBinary[ext] = true
+	}
 
-		// -----------------------------
-		// 2. USER EXCLUDES (Specific Files/Patterns)
-		// Priority: High. If excluded here, it is skipped regardless of include rules.
-		// -----------------------------
-		if checkPatternMatch(entry.Name(), relEntryPath, currentRule.Exclude) {
-			continue
+	if len(cfg.Preprocessors) > 0 {
+		if cfg.PreprocessorsAllowed() {
+			s.preprocessors = normalizePreprocessors(cfg.Preprocessors)
+		} else {
+			fmt.Printf("Warning: preprocessors are ignored unless allow_preprocessors is true and %s=1 is set\n", config.AllowPreprocessorsEnv)
 		}
+	}
+	if cfg.CacheFile != "" {
+		s.cache = cache.Load(resolvePath(rootPath, cfg.CacheFile))
+	}
+	return s, nil
+}
 
-		// -----------------------------
-		// 3. FORCE INCLUDE (Specific Files/Patterns)
-		// Priority: Overrides .gitignore and extension rules
-		// -----------------------------
-		isForced := checkPatternMatch(entry.Name(), relEntryPath, currentRule.Include)
+// saveCache saves the cache, if there is one.
+func (s *scanner) saveCache() {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Save(); err != nil {
+		fmt.Printf("Warning: could not save cache: %v\n", err)
+	}
+}
 
-		if entry.IsDir() {
-            // Check if this specific SUBDIRECTORY has a rule that disables it
-            if subRule, ok := dirRules[relEntryPath]; ok {
-                if !subRule.Enabled {
-                    continue 
-                }
-            }
+// writeOutputs writes the selected files, s.files, to every output: the
+// top-level one to writer, in each of its formats, and the rule outputs.
+// treePaths and stats hold each output's tree and skips, keyed by the rule's
+// output_file ("" for the top-level output).
+func (s *scanner) writeOutputs(cfg *config.Config, writer io.Writer, treePaths map[string][]string, stats *statsVisitor) (*Result, error) {
+	if cfg.IncludeGitBlame {
+		authorship, err := gitutil.Authorship(s.rootPath)
+		if err != nil {
+			fmt.Printf("Warning: include_git_blame needs git history (%v); skipping authorship\n", err)
+		}
+		s.authorship = authorship
+	}
 
-			// If not forced, respect gitignore for directories
-			if !isForced && matcher.Match(entryPath, true) {
-				continue
-			}
-			
-			if err := walk(entryPath, rootPath, dirRules, currentRule, matcher, writer); err != nil {
-				return err
+	s.probeFiles()
+	all := s.files
+
+	// Rule and extra outputs are written to temporary files first and only
+	// renamed into place once the top-level output succeeded too
+	pending := &pendingOutputs{rootPath: s.rootPath, keep: cfg.KeepPrevious}
+	outputs, err := s.writeRuleOutputs(cfg, all, treePaths, stats, pending)
+	if err != nil {
+		pending.commit(false)
+		return stats.result(""), err
+	}
+	targets := []target{{w: writer, format: cfg.Format}}
+	if updatable(cfg) == nil {
+		targets[0].manifest = &Manifest{Version: manifestVersion, Config: configHash(cfg)}
+	}
+	for _, out := range cfg.Outputs {
+		f, err := pending.create(out.File)
+		if err != nil {
+			pending.commit(false)
+			return stats.result(""), err
+		}
+		targets = append(targets, target{w: f, format: out.Format})
+	}
+
+	results, err := s.writeOutput(targets, cfg, filesFor(all, ""), treePaths[""], stats.result(""), documentTitle(s.rootPath))
+	if err != nil {
+		pending.commit(false)
+		return stats.result(""), err
+	}
+	for i, out := range cfg.Outputs {
+		if outputs == nil {
+			outputs = make(map[string]*Result)
+		}
+		outputs[out.File] = results[i+1]
+	}
+	if err := pending.commit(true); err != nil {
+		return results[0], err
+	}
+	for name, r := range outputs {
+		r.Unchanged = pending.unchanged[name]
+	}
+	results[0].Outputs = outputs
+	results[0].PassedWarnSize = s.warned
+	return results[0], nil
+}
+
+// target is a file an output is written to, in one format. manifest, if
+// set, is filled with the sections of the files written, which only text
+// written alone has.
+type target struct {
+	w        io.Writer
+	format   string
+	manifest *Manifest
+}
+
+// writeOutput writes one output, to every target at once: the given files,
+// in order, with the tree of treePaths and the configured summary. result
+// already holds the walk's skips for these files and is completed with the
+// rest. The returned results, one per target, differ in Hash and Size.
+func (s *scanner) writeOutput(targets []target, cfg *config.Config, files []fileEntry, treePaths []string, result *Result, title string) ([]*Result, error) {
+	// Hash everything written so consumers can tell whether the output changed
+	hashes := make([]hash.Hash, len(targets))
+	sizes := make([]*lineCounter, len(targets))
+	words := make([]*wordCounter, len(targets))
+	s.out = make(fanOut, len(targets))
+	for i, t := range targets {
+		hashes[i], sizes[i], words[i] = sha256.New(), &lineCounter{}, &wordCounter{}
+		s.out[i] = newEmitter(t.format, io.MultiWriter(t.w, hashes[i], sizes[i], words[i]), cfg.HeaderSummary, cfg.EffectiveFileGap(), cfg.IndexSizesOnly)
+	}
+	s.result = result
+	s.files = files
+	s.manifest = targets[0].manifest
+	if s.manifest != nil {
+		s.doc = s.out[0].(*textDoc)
+		defer func() { s.manifest, s.doc = nil, nil }()
+	}
+
+	// A file only points to another written to the same output
+	s.linked, s.viaLinks = nil, nil
+	s.preferDirectPaths()
+
+	// Binaries are only known after the walk, once the pre-pass read them
+	var tree []string
+	if cfg.IncludeTree {
+		tree = append([]string{}, treePaths...)
+		if cfg.TreeMode == config.TreeModeAll {
+			tree = s.markBinaries(tree)
+		}
+	}
+	if err := s.out.start(tree); err != nil {
+		return nil, err
+	}
+
+	// Grouping replaces the documentation and source sections
+	docs := 0
+	var groups []fileGroup
+	if cfg.GroupBy != "" {
+		groups = s.groupFiles(cfg.GroupBy)
+	} else if cfg.DocsFirst {
+		docs = s.moveDocsFirst()
+	}
+
+	for i, f := range s.files {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Announce where the documentation ends and the code begins
+		if docs > 0 && i == 0 {
+			s.out.section("DOCUMENTATION", "Documentation")
+		}
+		if docs > 0 && i == docs {
+			s.out.section("SOURCE", "Source")
+		}
+		if len(groups) > 0 && groups[0].start == i {
+			s.out.section(groups[0].label(), groups[0].title())
+			groups = groups[1:]
+		}
+		// Unreadable files are skipped rather than aborting the whole scan
+		if err := s.appendFileContent(f); err != nil {
+			fmt.Printf("Warning: could not read %s (%v); skipping\n", f.relPath, err)
+			s.result.Unreadable = append(s.result.Unreadable, f.relPath)
+		}
+		if err := s.checkOutputSize(); err != nil {
+			return nil, err
+		}
+	}
+
+	var summary string
+	if cfg.HeaderSummary {
+		summary = s.result.summary()
+	}
+	if err := s.out.finish(title, summary); err != nil {
+		return nil, err
+	}
+	// Sections were counted in the body, which the summary went in front of
+	if m := s.manifest; m != nil {
+		for i := range m.Files {
+			m.Files[i].Start += s.doc.prefix
+			m.Files[i].End += s.doc.prefix
+		}
+		m.Output = hex.EncodeToString(hashes[0].Sum(nil))
+	}
+
+	results := make([]*Result, len(targets))
+	for i := range targets {
+		r := *s.result
+		r.Hash = hex.EncodeToString(hashes[i].Sum(nil))
+		r.Size = sizes[i].bytes
+		r.Words = words[i].words
+		results[i] = &r
+	}
+	results[0].Manifest = s.manifest
+	return results, nil
+}
+
+// summary describes the scope of the output in one line, for the top of it.
+func (r *Result) summary() string {
+	return fmt.Sprintf("Included %d files (%d lines, ~%d tokens); excluded %d files", r.Included, r.Lines, tokens.Estimate(r.Bytes), r.SkippedFiles)
+}
+
+// WriteChecksum writes the output's hash to a sidecar file next to it
+// (outPath + ChecksumSuffix) in the format of sha256sum, so tools can poll the
+// sidecar and only re-ingest the output when the hash changes.
+func WriteChecksum(outPath, hash string) error {
+	line := fmt.Sprintf("%s  %s\n", hash, filepath.Base(outPath))
+	// An unchanged checksum is left untouched, like an unchanged output
+	if old, err := os.ReadFile(outPath + ChecksumSuffix); err == nil && string(old) == line {
+		return nil
+	}
+	return os.WriteFile(outPath+ChecksumSuffix, []byte(line), 0644)
+}
+
+// outputStart matches the beginning of every output textify writes: the
+// summary, the tree, a section label (a group_by label only with the file
+// header after it), or a file header in text, the title of a markdown
+// document followed by its summary or table of contents, the title of a
+// json output, the head of an html page, or the header of an index.
+var outputStart = regexp.MustCompile(`\A(?:Included \d+ files \(|PROJECT STRUCTURE:\n|DOCUMENTATION:\n|SOURCE:\n|(?:[A-Z0-9+#._-]+:\n\n)?` + separator + `\nFILE: |# [^\n]*\n\n(?:Included \d+ files \(|## Contents\n)|\{\n  "title": |` + regexp.QuoteMeta(htmlHead) + `|` + regexp.QuoteMeta(indexHeader) + `)`)
+
+// AppendBoundary returns the text that separates a scan of rootPath
+// appended to an existing output from what came before it, in the given
+// output format.
+func AppendBoundary(rootPath, format string) string {
+	if abs, err := filepath.Abs(rootPath); err == nil {
+		rootPath = abs
+	}
+	if format == config.FormatMarkdownDoc {
+		return fmt.Sprintf("\n---\n\n_Appended scan of `%s`_\n\n", rootPath)
+	}
+	rule := strings.Repeat("=", len(separator))
+	return fmt.Sprintf("%s\nAPPENDED SCAN: %s\n%s\n\n", rule, rootPath, rule)
+}
+
+// looksLikeDump reports whether the head of a file is the start of textify
+// output, or of a configured dump marker.
+func (s *scanner) looksLikeDump(head []byte) bool {
+	for _, marker := range s.dumpMarkers {
+		if bytes.HasPrefix(head, marker) {
+			return true
+		}
+	}
+	return outputStart.Match(head)
+}
+
+// LooksGenerated reports whether writing over path loses nothing hand-made:
+// the file doesn't exist, is empty, or starts the way textify output does.
+func LooksGenerated(path string) (bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 256)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return n == 0 || outputStart.Match(head[:n]), nil
+}
+
+// List walks the project and returns the files the path rules select, in walk
+// order, without reading any file contents. It is a dry run of Scan, across
+// every output.
+func List(rootPath string, cfg *config.Config) ([]string, *Result, error) {
+	w, err := newWalker(nil, rootPath, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats := &statsVisitor{results: make(map[string]*Result)}
+	files := &fileCollector{}
+	if err := w.Walk(stats, files); err != nil {
+		return nil, stats.total(), err
+	}
+
+	paths := make([]string, len(files.files))
+	for i, f := range files.files {
+		paths[i] = f.relPath
+	}
+	return paths, stats.total(), nil
+}
+
+// newWalker configures a walker with the run-level settings from cfg, for
+// the project in fsys if set.
+func newWalker(fsys fs.FS, rootPath string, cfg *config.Config) (*walker.Walker, error) {
+	modifiedSince, err := ParseModifiedSince(cfg.ModifiedSince, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var w *walker.Walker
+	if fsys != nil {
+		w = walker.NewFS(fsys, rootPath, cfg)
+	} else {
+		w = walker.New(rootPath, cfg)
+	}
+	w.ModifiedSince = modifiedSince
+	if cfg.ChangedSince != "" {
+		if w.Changed, err = gitutil.ChangedFiles(rootPath, cfg.ChangedSince); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.MaxDepth != nil {
+		w.MaxDepth = *cfg.MaxDepth
+	}
+
+	w.SkipPaths = skipPaths(rootPath, cfg)
+	// New versions of the outputs are written to temporary files next to
+	// them, some before the walk
+	for _, out := range cfg.OutputFiles() {
+		rel := walker.RelSlash(rootPath, resolvePath(rootPath, out))
+		w.SystemExcludes = append(w.SystemExcludes, "/"+path.Join(path.Dir(rel), fileutil.TempPattern(rel)))
+	}
+	return w, nil
+}
+
+// skipPaths returns the relative paths of the config, the outputs, their
+// checksums and previous versions, and the cache, which are never part of
+// the output, whatever the system excludes say. The config is skipped both
+// under its usual name and where it was actually loaded from, and so is its
+// overlay.
+func skipPaths(rootPath string, cfg *config.Config) map[string]bool {
+	paths := map[string]bool{config.FileName: true}
+	if absRoot, err := filepath.Abs(rootPath); err == nil {
+		for _, p := range []string{cfg.Path, cfg.OverlayPath} {
+			if p != "" {
+				paths[walker.RelSlash(absRoot, p)] = true
 			}
+		}
+	}
+	for _, out := range cfg.OutputFiles() {
+		if out == "" {
 			continue
 		}
+		out = resolvePath(rootPath, out)
+		paths[walker.RelSlash(rootPath, out)] = true
+		paths[walker.RelSlash(rootPath, out+ChecksumSuffix)] = true
+		paths[walker.RelSlash(rootPath, out+ManifestSuffix)] = true
+		for n := 1; n <= cfg.KeepPrevious; n++ {
+			paths[walker.RelSlash(rootPath, fileutil.RotatedName(out, n))] = true
+		}
+	}
+	if cfg.CacheFile != "" {
+		paths[walker.RelSlash(rootPath, resolvePath(rootPath, cfg.CacheFile))] = true
+	}
+	return paths
+}
 
-		// -----------------------------
-		// FILE PROCESSING LOGIC
-		// -----------------------------
+// resolvePath resolves a configured path against the project root.
+func resolvePath(rootPath, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(rootPath, p)
+}
 
-		// 4. GITIGNORE CHECK
-		// If not forced, check if ignored by git
-		if !isForced && matcher.Match(entryPath, false) {
-			continue
+// statsVisitor counts the entries the walker skipped, by reason, for each
+// output, keyed by the rule's output_file ("" for the top-level output).
+type statsVisitor struct {
+	results map[string]*Result
+
+	// fsys, if set, holds the project (see ScanFS).
+	fsys fs.FS
+}
+
+// result returns the counts for an output, creating them if needed.
+func (v *statsVisitor) result(output string) *Result {
+	r, ok := v.results[output]
+	if !ok {
+		r = &Result{Skipped: make(map[string]int), CappedDirs: make(map[string]int)}
+		v.results[output] = r
+	}
+	return r
+}
+
+func (v *statsVisitor) OnDir(d walker.Decision) {
+	if !d.Include {
+		r := v.result(d.Rule.OutputFile)
+		r.Skipped[d.Reason]++
+		if d.Reason == ReasonVendored {
+			r.VendoredDirs = append(r.VendoredDirs, d.RelPath)
+			r.VendoredFiles += v.countFiles(d)
 		}
+	}
+}
 
-		// 5. EXTENSION EXCLUDES (Blocklist)
-		if !isForced && len(currentRule.ExcludeExtensions) > 0 {
-			if contains(currentRule.ExcludeExtensions, ext) {
-				continue
+// countFiles returns how many files are in the directory d and below,
+// without reading or even stating them.
+func (v *statsVisitor) countFiles(d walker.Decision) int {
+	n := 0
+	count := func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			n++
+		}
+		return nil
+	}
+	if v.fsys != nil {
+		fs.WalkDir(v.fsys, d.RelPath, count)
+	} else {
+		filepath.WalkDir(d.Path, count)
+	}
+	return n
+}
+
+func (v *statsVisitor) OnFile(d walker.Decision) {
+	if !d.Include {
+		r := v.result(d.Rule.OutputFile)
+		r.Skipped[d.Reason]++
+		r.SkippedFiles++
+		if d.Reason == ReasonVendored {
+			r.VendoredFiles++
+		}
+		if d.Reason == ReasonExtNotAllowed {
+			if r.SkippedExtensions == nil {
+				r.SkippedExtensions = make(map[string]int)
 			}
+			r.SkippedExtensions[strings.ToLower(strings.TrimPrefix(path.Ext(d.RelPath), "."))]++
 		}
+	}
+}
 
-		// 6. EXTENSION INCLUDES (Allowlist)
-		// If Extensions list is provided, file MUST match one of them (unless forced)
-		if !isForced && len(currentRule.Extensions) > 0 {
-			if !contains(currentRule.Extensions, ext) {
-				continue
+// total adds up the counts of every output.
+func (v *statsVisitor) total() *Result {
+	total := &Result{Skipped: make(map[string]int), CappedDirs: make(map[string]int)}
+	for _, r := range v.results {
+		for reason, n := range r.Skipped {
+			total.Skipped[reason] += n
+		}
+		total.SkippedFiles += r.SkippedFiles
+		for ext, n := range r.SkippedExtensions {
+			if total.SkippedExtensions == nil {
+				total.SkippedExtensions = make(map[string]int)
 			}
+			total.SkippedExtensions[ext] += n
 		}
+	}
+	return total
+}
 
-		// Write content
-		if err := appendFileContent(entryPath, relEntryPath, writer); err != nil {
-			continue
+// fileCollector queues included files for output once the walk completes.
+type fileCollector struct {
+	files []fileEntry
+}
+
+func (v *fileCollector) OnDir(d walker.Decision) {}
+
+func (v *fileCollector) OnFile(d walker.Decision) {
+	if d.Include {
+		v.files = append(v.files, fileEntry{absPath: d.Path, relPath: d.RelPath, rule: d.Rule, ruleDir: d.RuleDir, forced: d.Forced})
+	}
+}
+
+// orderFiles sorts the collected files according to the configured order.
+func (s *scanner) orderFiles(order string) error {
+	switch order {
+	case "", config.OrderPath:
+		// The walk already yields files in path order
+		return nil
+	case config.OrderGitHot:
+		counts, err := gitutil.ChangeCounts(s.rootPath, gitHotWindow)
+		if err != nil {
+			fmt.Printf("Warning: order %q needs git history (%v); using path order\n", order, err)
+			return nil
 		}
+		s.changeCounts = counts
+		// Stable sort keeps path order as the tiebreak
+		sort.SliceStable(s.files, func(i, j int) bool {
+			return counts[s.files[i].relPath] > counts[s.files[j].relPath]
+		})
+		return nil
+	default:
+		return fmt.Errorf("unknown order %q", order)
 	}
-	return nil
 }
 
-// getIgnoreMatcher attempts to load .gitignore from the root path.
-func getIgnoreMatcher(root string) gitignore.IgnoreMatcher {
-	gitignorePath := filepath.Join(root, ".gitignore")
-	matcher, err := gitignore.NewGitIgnore(gitignorePath)
-	if err != nil {
-		return gitignore.NewGitIgnoreFromReader(root, strings.NewReader(""))
+// moveDocsFirst moves documentation files ahead of everything else, keeping
+// the configured order within each group, and returns how many there are.
+func (s *scanner) moveDocsFirst() int {
+	sort.SliceStable(s.files, func(i, j int) bool {
+		return isDocFile(s.files[i].relPath) && !isDocFile(s.files[j].relPath)
+	})
+	docs := 0
+	for docs < len(s.files) && isDocFile(s.files[docs].relPath) {
+		docs++
 	}
-	return matcher
+	return docs
 }
 
-// shouldAlwaysExclude handles hardcoded exclusions for tool integrity.
-func shouldAlwaysExclude(name string) bool {
-	return name == ".git" || name == "textify.yaml" || name == "codebase.txt"
+// isDocFile reports whether a relative path is documentation: a README
+// anywhere, anything under a top-level docs/ folder, or Markdown at the root.
+func isDocFile(relPath string) bool {
+	name := path.Base(relPath)
+	if strings.HasPrefix(strings.ToUpper(name), "README") {
+		return true
+	}
+	if strings.HasPrefix(relPath, "docs/") {
+		return true
+	}
+	return !strings.Contains(relPath, "/") && strings.EqualFold(path.Ext(name), ".md")
 }
 
-// checkPatternMatch checks if the file matches any of the glob patterns.
-func checkPatternMatch(name, relPath string, patterns []string) bool {
-	for _, p := range patterns {
-		// Match against filename
-		if matched, _ := filepath.Match(p, name); matched {
-			return true
-		}
-		// Match against relative path
-		if matched, _ := filepath.Match(p, relPath); matched {
-			return true
+// ParseModifiedSince converts a modified_since value into a cutoff time.
+// It accepts a duration relative to now (e.g., "48h") or a date ("2024-05-01").
+// An empty value returns the zero time, meaning no cutoff.
+func ParseModifiedSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid modified_since %q: use a duration like 48h or a date like 2024-05-01", value)
+}
+
+// skip records a file left out of the output.
+func (s *scanner) skip(relPath, reason string) {
+	s.result.Skipped[reason]++
+	s.result.SkippedFiles++
+	if s.hooks.OnSkip != nil {
+		s.hooks.OnSkip(relPath, reason)
+	}
+}
+
+// lineCounter is a writer that counts the lines passing through it. A final
+// line without a trailing newline still counts.
+type lineCounter struct {
+	newlines int
+	last     byte
+	bytes    int64
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.bytes += int64(len(p))
+	c.newlines += bytes.Count(p, []byte{'\n'})
+	if len(p) > 0 {
+		c.last = p[len(p)-1]
+	}
+	return len(p), nil
+}
+
+func (c *lineCounter) count() int {
+	if c.last != 0 && c.last != '\n' {
+		return c.newlines + 1
+	}
+	return c.newlines
+}
+
+// wordCounter is a writer that counts the whitespace-separated words passing
+// through it, as the output is written, so it never has to be read back.
+// Only ASCII whitespace separates words.
+type wordCounter struct {
+	words  int64
+	inWord bool
+}
+
+func (c *wordCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		switch b {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+			c.inWord = false
+		default:
+			if !c.inWord {
+				c.words++
+				c.inWord = true
+			}
 		}
-		// Direct folder/file path match
-		if p == relPath {
-			return true
+	}
+	return len(p), nil
+}
+
+// compileContentFilters compiles every content regex used by the rules once,
+// keyed by the pattern source so identical patterns share a compiled value.
+func compileContentFilters(dirRules map[string]config.DirRule) (map[string]*regexp.Regexp, error) {
+	regexps := make(map[string]*regexp.Regexp)
+	for dir, rule := range dirRules {
+		for _, pattern := range []string{rule.ContentIncludeRegex, rule.ContentExcludeRegex} {
+			if pattern == "" || regexps[pattern] != nil {
+				continue
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid content regex for %q: %w", dir, err)
+			}
+			regexps[pattern] = re
 		}
 	}
-	return false
+	return regexps, nil
+}
+
+// passesContentFilter reports whether the head of a file satisfies the rule's
+// content include/exclude regexes.
+func (s *scanner) passesContentFilter(head []byte, rule config.DirRule) bool {
+	if rule.ContentIncludeRegex != "" && !s.regexps[rule.ContentIncludeRegex].Match(head) {
+		return false
+	}
+	if rule.ContentExcludeRegex != "" && s.regexps[rule.ContentExcludeRegex].Match(head) {
+		return false
+	}
+	return true
 }
 
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
+// passesMIMEFilter reports whether a content type satisfies the rule's
+// mime_include/mime_exclude patterns.
+func passesMIMEFilter(mime string, rule config.DirRule) bool {
+	for _, pattern := range rule.MimeExclude {
+		if ok, _ := path.Match(pattern, mime); ok {
+			return false
+		}
+	}
+	if len(rule.MimeInclude) == 0 {
+		return true
+	}
+	for _, pattern := range rule.MimeInclude {
+		if ok, _ := path.Match(pattern, mime); ok {
 			return true
 		}
 	}
 	return false
 }
 
+// contentType returns a file's sniffed content type. The pre-pass has it
+// unless the cache answered the binary check, in which case the file is
+// sniffed now.
+func (s *scanner) contentType(f fileEntry) (string, error) {
+	if p := s.probes[f.relPath]; p.detected {
+		return p.mime, nil
+	}
+	_, mime, err := s.sniff(f)
+	return mime, err
+}
+
 // appendFileContent writes the file header and content to the buffer.
-func appendFileContent(absPath, relPath string, writer *bufio.Writer) error {
-	// Check for binary content
-	isBin, err := fileutil.IsBinary(absPath)
-	if err != nil || isBin {
-		return nil // Skip binaries silently
+func (s *scanner) appendFileContent(f fileEntry) error {
+	absPath, relPath, rule := f.absPath, f.relPath, f.rule
+
+	// Directories that already reached their line cap take no more files
+	if rule.MaxDirLines > 0 && s.dirLines[f.ruleDir] >= rule.MaxDirLines {
+		s.skip(relPath, ReasonDirLineCap)
+		s.result.CappedDirs[f.ruleDir]++
+		return nil
 	}
 
-	file, err := os.Open(absPath)
+	info, err := s.stat(f)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	if !s.keepLinkedDuplicates {
+		if direct, ok := s.viaLinks[relPath]; ok {
+			return s.writeLinkedDuplicate(absPath, relPath, info, direct)
+		}
+		if original, ok := s.linkedOriginal(info); ok {
+			return s.writeLinkedDuplicate(absPath, relPath, info, original)
+		}
+	}
+	if sec, ok := s.unchanged(f, info); ok {
+		return s.copySection(f, info, sec)
+	}
+
+	// A preprocessed file's content is whatever its command prints, so the
+	// checks on the file itself don't apply
+	var input io.Reader
+	var notes []string
+	var hasher hash.Hash
+	raw := &lineCounter{}
+	var legacy bool
+	if argv := s.preprocessorFor(relPath); argv != nil {
+		converted, err := s.preprocess(argv, absPath, relPath)
+		if err != nil {
+			fmt.Printf("Warning: could not preprocess %s (%v); skipping\n", relPath, err)
+			s.skip(relPath, ReasonPreprocessFailed)
+			return nil
+		}
+		input = bytes.NewReader(converted)
+		notes = append(notes, "converted by "+filepath.Base(argv[0]))
+	} else {
+		// Check for binary content
+		isBin, isLegacy, err := s.isBinary(f, info)
+		if err != nil {
+			return err
+		}
+		if isBin {
+			s.skip(relPath, ReasonBinary)
+			if s.listBinaries {
+				return s.writeBinaryPlaceholder(f, info)
+			}
+			return nil // Skip binaries silently
+		}
+
+		// MIME rules narrow what the extension rules let through
+		if !f.forced && (len(rule.MimeInclude) > 0 || len(rule.MimeExclude) > 0) {
+			mime, err := s.contentType(f)
+			if err != nil {
+				return err
+			}
+			if !passesMIMEFilter(mime, rule) {
+				s.skip(relPath, ReasonMIMEFilter)
+				return nil
+			}
+		}
+
+		file, err := s.open(f)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		// Hash while streaming so no extra read is needed. The hash is
+		// always of the file on disk, before any decoding or masking.
+		hasher = sha256.New()
+		input = io.TeeReader(file, io.MultiWriter(hasher, raw))
+		legacy = isLegacy
 
-	separator := strings.Repeat("-", 50)
-	fmt.Fprintf(writer, "%s\n", separator)
-	fmt.Fprintf(writer, "FILE: %s\n", relPath)
-	fmt.Fprintf(writer, "%s\n\n", separator)
+		// Text in a legacy encoding is converted to UTF-8 before anything
+		// looks at it, so it is read whole
+		if legacy {
+			raw, err := io.ReadAll(input)
+			if err != nil {
+				return err
+			}
+			encoding := guessEncoding(raw)
+			input = bytes.NewReader(decodeLegacy(raw, encoding))
+			notes = append(notes, "decoded as "+encoding)
+		}
+	}
+
+	// Content filters, the encoded-data check, and the dump check inspect a
+	// bounded head of the file. The same bytes are reused for the output so
+	// the file is only read once.
+	checkEncoded := s.encodedFraction > 0 && !f.forced
+	var head []byte
+	if rule.ContentIncludeRegex != "" || rule.ContentExcludeRegex != "" || checkEncoded || s.skipDumps {
+		head, err = io.ReadAll(io.LimitReader(input, contentFilterLimit))
+		if err != nil {
+			return err
+		}
+		if s.skipDumps && s.looksLikeDump(head) {
+			s.skip(relPath, ReasonTextifyDump)
+			return nil
+		}
+		if !s.passesContentFilter(head, rule) {
+			s.skip(relPath, ReasonContentFilter)
+			return nil
+		}
+	}
+
+	// The caller has the last word, once every built-in rule agreed
+	if s.hooks.OnFileStart != nil && s.hooks.OnFileStart(relPath, info) {
+		s.skip(relPath, ReasonHook)
+		return nil
+	}
+
+	// An index without lines needs nothing more of the content
+	if !s.out.wantsContent() {
+		return s.listContentless(absPath, relPath, info, notes)
+	}
 
-	if _, err = io.Copy(writer, file); err != nil {
+	lines := &lineCounter{}
+	src := io.MultiReader(bytes.NewReader(head), input)
+	// The header comes before the content, so the file is read whole to
+	// know its hash in time. Preprocessed files have no file content to
+	// check against.
+	if s.includeChecksums && hasher != nil {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+		src = bytes.NewReader(data)
+		notes = append(notes, fmt.Sprintf("SHA256: %s  SIZE: %d", hex.EncodeToString(hasher.Sum(nil)), raw.bytes))
+	}
+	if checkEncoded && encodedShare(head, s.encodedRunLength) > s.encodedFraction {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+		src = bytes.NewReader(collapseEncoded(data, s.encodedRunLength))
+	}
+	// Everything after this point works line by line
+	limiter := newLineLimiter(src, s.maxLineBytes)
+	src = limiter
+	var capper *tokenLimiter
+	if rule.MaxFileTokens > 0 && !f.forced {
+		capper = newTokenLimiter(src, rule.MaxFileTokens)
+		src = capper
+	}
+	start := s.offset()
+	content := s.out.beginFile(relPath, s.fileNotes(relPath, append(notes, s.fileMeta(absPath, info)...)...), rule.Format)
+	var dst io.Writer = io.MultiWriter(content, lines)
+	var collapser *repetitionCollapser
+	if s.collapseRepetition {
+		collapser = newRepetitionCollapser(dst, s.repetitionSimilarity, s.repetitionMinRun)
+		dst = collapser
+	}
+	// Indentation is normalized before repetition is judged
+	var indented *indenter
+	if s.indentWidth > 0 && !keepsIndent(relPath) {
+		indented = newIndenter(dst, s.indentTabs, s.indentWidth)
+		dst = indented
+	}
+	masked := false
+	if s.maskEnv && isEnvFile(filepath.Base(absPath)) {
+		if err = maskEnv(dst, src, s.envKeepKeys); err == nil {
+			s.result.MaskedFiles++
+			masked = true
+		}
+	} else {
+		_, err = io.Copy(dst, src)
+	}
+	if err != nil {
+		return err
+	}
+	if indented != nil {
+		if err := indented.Flush(); err != nil {
+			return err
+		}
+	}
+	var collapsed int64
+	if collapser != nil {
+		if err := collapser.Flush(); err != nil {
+			return err
+		}
+		collapsed = int64(collapser.saved)
+		s.result.CollapsedBytes += collapsed
+	}
+	s.result.TruncatedLines += limiter.truncated
+	truncated := capper != nil && capper.cut
+	if truncated {
+		s.result.TruncatedFiles++
+	}
+	if err := s.out.endFile(); err != nil {
 		return err
 	}
-	fmt.Fprintf(writer, "\n\n")
+	s.dirLines[f.ruleDir] += lines.count()
+	s.result.Lines += lines.count()
+	s.result.Bytes += lines.bytes
+	s.track(relPath, lines.bytes)
+
+	if s.cache != nil && hasher != nil {
+		s.recordHash(relPath, info, legacy, hex.EncodeToString(hasher.Sum(nil)))
+	}
+	// Only a file hashed whole can be told unchanged by its hash
+	if hasher != nil && raw.bytes == info.Size() {
+		s.addSection(ManifestFile{
+			Path: relPath, Hash: hex.EncodeToString(hasher.Sum(nil)),
+			Size: info.Size(), ModTime: info.ModTime().UnixNano(),
+			Lines: lines.count(), Bytes: lines.bytes,
+			TruncatedLines: limiter.truncated, Truncated: truncated,
+			CollapsedBytes: collapsed, Masked: masked, Legacy: legacy,
+		}, start)
+	}
+
+	if !s.keepLinkedDuplicates {
+		s.rememberWritten(absPath, relPath, info)
+	}
+	s.result.Included++
+	if s.hooks.OnFileWritten != nil {
+		s.hooks.OnFileWritten(relPath, lines.bytes, lines.count())
+	}
+	fmt.Fprintf(Progress, "Added: %s\n", relPath)
+	return nil
+}
+
+// listContentless records an included file by its size alone, for outputs
+// that don't write content.
+func (s *scanner) listContentless(absPath, relPath string, info os.FileInfo, notes []string) error {
+	s.out.listFile(relPath, s.fileNotes(relPath, append(notes, s.fileMeta(absPath, info)...)...), info.Size())
+	s.result.Bytes += info.Size()
+	s.track(relPath, info.Size())
+	if !s.keepLinkedDuplicates {
+		s.rememberWritten(absPath, relPath, info)
+	}
+	s.result.Included++
+	if s.hooks.OnFileWritten != nil {
+		s.hooks.OnFileWritten(relPath, info.Size(), 0)
+	}
+	fmt.Fprintf(Progress, "Added: %s\n", relPath)
+	return nil
+}
+
+// stat returns a file's info, from the pre-pass if it ran.
+func (s *scanner) stat(f fileEntry) (os.FileInfo, error) {
+	if p, ok := s.probes[f.relPath]; ok {
+		return p.info, p.err
+	}
+	return s.statFile(f)
+}
+
+// statFile stats a file, following symlinks, from fsys if set.
+func (s *scanner) statFile(f fileEntry) (os.FileInfo, error) {
+	if s.fsys != nil {
+		return fs.Stat(s.fsys, f.relPath)
+	}
+	return os.Stat(f.absPath)
+}
+
+// open opens a file for reading, from fsys if set.
+func (s *scanner) open(f fileEntry) (fs.File, error) {
+	if s.fsys != nil {
+		return s.fsys.Open(f.relPath)
+	}
+	return os.Open(f.absPath)
+}
+
+// isBinary reports whether a file is binary. The force_text and force_binary
+// extensions decide first, then the cache is consulted. In paranoid mode a
+// cached verdict is only trusted if the file's content hash still matches,
+// since size and mtime can be preserved across edits. legacy is set for text
+// that isn't valid UTF-8 but will be output anyway, because sanitizeUTF8 is on.
+func (s *scanner) isBinary(f fileEntry, info os.FileInfo) (binary, legacy bool, err error) {
+	absPath, relPath := f.absPath, f.relPath
+	// Per-extension overrides have the final say
+	ext := strings.TrimPrefix(path.Ext(relPath), ".")
+	if s.forceBinary[ext] {
+		return true, false, nil
+	}
+	if s.forceText[ext] {
+		return false, false, nil
+	}
+
+	if s.cache != nil {
+		if entry, ok := s.cache.Lookup(relPath, info); ok && s.trustCached(entry) {
+			if !s.paranoid {
+				return entry.Binary, false, nil
+			}
+			if entry.Hash != "" {
+				if hash, err := hashFile(absPath); err == nil && hash == entry.Hash {
+					return entry.Binary, false, nil
+				}
+			}
+		}
+	}
+
+	// The pre-pass usually has the answer already
+	p := s.probes[relPath]
+	kind := p.kind
+	if !p.detected {
+		kind, _, err = s.sniff(f)
+		if err != nil {
+			return false, false, err
+		}
+	}
+	if s.cache != nil {
+		s.cache.Store(relPath, info, kind != fileutil.Text, "")
+	}
+	if kind == fileutil.LegacyText && s.sanitizeUTF8 {
+		return false, true, nil
+	}
+	return kind != fileutil.Text, false, nil
+}
+
+// trustCached reports whether a cached verdict answers the binary check
+// with the current settings. The cache only knows binary or not, so when
+// sanitizing, a binary verdict is checked again in case the file is legacy
+// text; and with the deep check, a text verdict may come from a check of the
+// head alone.
+func (s *scanner) trustCached(entry cache.Entry) bool {
+	if entry.Binary {
+		return !s.sanitizeUTF8
+	}
+	return !s.deepBinaryCheck
+}
+
+// sniff classifies a file's content for the binary check, sampling the whole
+// file with the deep check on.
+func (s *scanner) sniff(f fileEntry) (kind int, mime string, err error) {
+	if s.fsys == nil {
+		if s.deepBinaryCheck {
+			return fileutil.SniffSampled(f.absPath)
+		}
+		return fileutil.Sniff(f.absPath)
+	}
+	file, err := s.fsys.Open(f.relPath)
+	if err != nil {
+		return fileutil.Text, "", err
+	}
+	defer file.Close()
+	// Files that can't be read at offsets are judged by their head
+	if at, ok := file.(io.ReaderAt); ok && s.deepBinaryCheck {
+		info, err := file.Stat()
+		if err != nil {
+			return fileutil.Text, "", err
+		}
+		return fileutil.SniffSampledReader(at, info.Size(), f.relPath)
+	}
+	return fileutil.SniffReader(file, f.relPath)
+}
+
+// markBinaries marks the included files in a tree that will be skipped as
+// binary, as far as the pre-pass and the cache already know.
+func (s *scanner) markBinaries(paths []string) []string {
+	marked := make([]string, len(paths))
+	for i, p := range paths {
+		marked[i] = p
+		if s.knownBinary(p) {
+			marked[i] += binaryMark
+		}
+	}
+	return marked
+}
+
+// knownBinary reports whether an included file will be skipped as binary,
+// without reading it: from the extension overrides, the pre-pass, or the
+// cache, in the order isBinary consults them.
+func (s *scanner) knownBinary(relPath string) bool {
+	p, ok := s.probes[relPath]
+	if !ok || p.err != nil || s.preprocessorFor(relPath) != nil {
+		return false
+	}
+	ext := strings.TrimPrefix(path.Ext(relPath), ".")
+	if s.forceBinary[ext] || s.forceText[ext] {
+		return s.forceBinary[ext]
+	}
+	if p.detected {
+		return p.kind == fileutil.Binary || (p.kind == fileutil.LegacyText && !s.sanitizeUTF8)
+	}
+	if s.cache == nil {
+		return false
+	}
+	entry, cached := s.cache.Lookup(relPath, p.info)
+	return cached && entry.Binary
+}
+
+// recordHash stores the content hash of a text file in the cache. If the
+// cached hash disagrees, the content changed without its size or mtime
+// changing, so the entry is dropped and the file is re-checked next run.
+// Legacy text stays cached as binary, as it is without sanitizing.
+func (s *scanner) recordHash(relPath string, info os.FileInfo, legacy bool, hash string) {
+	if entry, ok := s.cache.Lookup(relPath, info); ok && entry.Hash != "" && entry.Hash != hash {
+		s.cache.Forget(relPath)
+		return
+	}
+	s.cache.Store(relPath, info, legacy, hash)
+}
 
-	fmt.Printf("Added: %s\n", relPath)
+// hashFile returns the hex-encoded SHA-256 of a file's content.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// writeBinaryPlaceholder records a binary file's existence, size, and type
+// without dumping its bytes.
+func (s *scanner) writeBinaryPlaceholder(f fileEntry, info os.FileInfo) error {
+	absPath, relPath := f.absPath, f.relPath
+	file, err := s.open(f)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	mime, err := fileutil.DetectMIMEReader(file)
+	if err != nil {
+		return err
+	}
+	notes := append([]string{"binary", fileutil.FormatSize(info.Size()), mime}, s.fileMeta(absPath, info)...)
+	s.out.listFile(relPath, s.fileNotes(relPath, notes...), info.Size())
+	fmt.Fprintf(Progress, "Listed: %s (binary)\n", relPath)
 	return nil
-}
\ No newline at end of file
+}
+
+// fileMeta returns the header notes for a file's permissions and, if it is a
+// symlink, its target. info describes the file after following symlinks.
+func (s *scanner) fileMeta(absPath string, info os.FileInfo) []string {
+	if !s.includeFileMeta {
+		return nil
+	}
+	notes := []string{"mode: " + info.Mode().String()}
+	if s.fsys != nil {
+		return notes
+	}
+	if linfo, err := os.Lstat(absPath); err == nil && linfo.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(absPath); err == nil {
+			notes = append(notes, "-> "+filepath.ToSlash(target))
+		}
+	}
+	return notes
+}
+
+// fileNotes returns the annotations for a file's header. Extra notes are
+// listed before the scanner's own annotations.
+func (s *scanner) fileNotes(relPath string, extra ...string) []string {
+	notes := append([]string{}, extra...)
+	if s.changeCounts != nil {
+		notes = append(notes, fmt.Sprintf("changes: %d", s.changeCounts[relPath]))
+	}
+	// Untracked files have no history and get no authorship note
+	if info, ok := s.authorship[relPath]; ok {
+		notes = append(notes, fmt.Sprintf("author: %s, modified: %s", info.Author, info.Modified))
+	}
+	return notes
+}
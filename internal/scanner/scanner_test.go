@@ -2,10 +2,20 @@ package scanner
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
+
 	"github.com/JohnEsleyer/textify/internal/config"
 )
 
@@ -46,8 +56,8 @@ func TestScanWithGranularRules(t *testing.T) {
 		Dirs: map[string]config.DirRule{
 			".": {
 				Enabled:           true,
-				Extensions:        []string{"go", "json"}, // Allow list
-				ExcludeExtensions: []string{"log", "tmp"}, // Block list
+				Extensions:        []string{"go", "json"},  // Allow list
+				ExcludeExtensions: []string{"log", "tmp"},  // Block list
 				Include:           []string{"secrets.env"}, // Force Include
 				Exclude:           []string{"annoying.go"}, // Force Exclude
 			},
@@ -56,7 +66,7 @@ func TestScanWithGranularRules(t *testing.T) {
 
 	// 3. Run Scan
 	var buf bytes.Buffer
-	err = Scan(tempDir, cfg, &buf)
+	_, err = Scan(tempDir, cfg, &buf)
 	if err != nil {
 		t.Fatalf("Scan failed: %v", err)
 	}
@@ -102,7 +112,7 @@ func TestMixedDirectoryRules(t *testing.T) {
 				Extensions: []string{"go"}, // Only Go
 			},
 			"frontend": {
-				Enabled: true,
+				Enabled:    true,
 				Extensions: []string{"js", "css"},
 				Exclude:    []string{"style.css"}, // Exclude specific file despite matching extension
 			},
@@ -119,25 +129,1611 @@ func TestMixedDirectoryRules(t *testing.T) {
 	assertNotContains(t, output, "FILE: frontend/style.css")
 }
 
-func createFile(t *testing.T, dir, name, content string) {
-	path := filepath.Join(dir, name)
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+func TestContentFilters(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_content")
+	if err != nil {
 		t.Fatal(err)
 	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "billing.go", "type BillingService struct{}")
+	createFile(t, tempDir, "billing_mock.go", "// generated mock\ntype BillingService struct{}")
+	createFile(t, tempDir, "users.go", "type UserService struct{}")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".": {
+				Enabled:             true,
+				ContentIncludeRegex: `BillingService`,
+				ContentExcludeRegex: `(?m)^// generated`,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "FILE: billing.go")
+	assertContains(t, output, "type BillingService struct{}")
+	assertNotContains(t, output, "FILE: billing_mock.go")
+	assertNotContains(t, output, "FILE: users.go")
+
+	if got := result.Skipped[ReasonContentFilter]; got != 2 {
+		t.Errorf("Expected 2 files skipped by content filter, got %d", got)
+	}
+
+	// Invalid regexes are reported before anything is written
+	cfg.Dirs["."] = config.DirRule{Enabled: true, ContentIncludeRegex: "("}
+	if _, err := Scan(tempDir, cfg, &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an invalid content regex")
+	}
 }
 
-func assertContains(t *testing.T, output, substr string) {
-	output = filepath.ToSlash(output)
-	substr = filepath.ToSlash(substr)
-	if !strings.Contains(output, substr) {
-		t.Errorf("Expected output to contain '%s', but it didn't.", substr)
+func TestGitHotOrder(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tempDir, err := os.MkdirTemp("", "scanner_test_githot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = tempDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	git("init", "-q")
+	createFile(t, tempDir, "a.go", "a")
+	createFile(t, tempDir, "b.go", "b")
+	createFile(t, tempDir, "c.go", "c")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	for i := 0; i < 2; i++ {
+		createFile(t, tempDir, "c.go", strings.Repeat("c", i+2))
+		git("commit", "-q", "-am", "touch c")
+	}
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Order:      config.OrderGitHot,
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "FILE: c.go (changes: 3)")
+	assertContains(t, output, "FILE: a.go (changes: 1)")
+	assertNotContains(t, output, "author:")
+
+	// Most touched first, then path order for ties
+	c := strings.Index(output, "FILE: c.go")
+	a := strings.Index(output, "FILE: a.go")
+	b := strings.Index(output, "FILE: b.go")
+	if !(c < a && a < b) {
+		t.Errorf("Expected order c.go, a.go, b.go; got offsets %d, %d, %d", c, a, b)
 	}
 }
 
-func assertNotContains(t *testing.T, output, substr string) {
-	output = filepath.ToSlash(output)
-	substr = filepath.ToSlash(substr)
-	if strings.Contains(output, substr) {
-		t.Errorf("Expected output NOT to contain '%s', but it did.", substr)
+func TestChangedSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tempDir, err := os.MkdirTemp("", "scanner_test_changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = tempDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	git("init", "-q")
+	createFile(t, tempDir, "a.go", "a")
+	createFile(t, tempDir, "b.go", "b")
+	createFile(t, tempDir, "c.go", "c")
+	createFile(t, tempDir, "notes.txt", "notes")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	git("tag", "base")
+	createFile(t, tempDir, "a.go", "aa")
+	git("commit", "-q", "-am", "change a")
+	createFile(t, tempDir, "b.go", "bb")
+	createFile(t, tempDir, "new.go", "new")
+	createFile(t, tempDir, "notes.txt", "more notes")
+
+	cfg := &config.Config{
+		OutputFile:   "codebase.txt",
+		ChangedSince: "base",
+		Dirs:         map[string]config.DirRule{".": {Enabled: true, Extensions: []string{"go"}}},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	// Committed, modified, and untracked changes count; rules still apply
+	assertContains(t, output, "FILE: a.go")
+	assertContains(t, output, "FILE: b.go")
+	assertContains(t, output, "FILE: new.go")
+	assertNotContains(t, output, "FILE: c.go")
+	assertNotContains(t, output, "FILE: notes.txt")
+	if result.Skipped[ReasonUnchanged] != 1 {
+		t.Errorf("Expected 1 unchanged file, got %d", result.Skipped[ReasonUnchanged])
+	}
+
+	cfg.ChangedSince = "no-such-ref"
+	if _, err := Scan(tempDir, cfg, &buf); err == nil || !strings.Contains(err.Error(), "no-such-ref") {
+		t.Errorf("Expected an unknown ref error, got %v", err)
+	}
+}
+
+func TestIncludeGitBlame(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tempDir, err := os.MkdirTemp("", "scanner_test_blame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	git := func(name string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=" + name, "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = tempDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	git("alice", "init", "-q")
+	createFile(t, tempDir, "main.go", "v1")
+	git("alice", "add", ".")
+	git("alice", "commit", "-q", "-m", "initial")
+	createFile(t, tempDir, "main.go", "v2")
+	git("alice", "commit", "-q", "-am", "update")
+	createFile(t, tempDir, "main.go", "v3")
+	git("bob", "commit", "-q", "-am", "tweak")
+	createFile(t, tempDir, "untracked.go", "new")
+
+	cfg := &config.Config{
+		OutputFile:      "codebase.txt",
+		IncludeGitBlame: true,
+		Dirs:            map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "FILE: main.go (author: alice, modified: ")
+	assertContains(t, output, "FILE: untracked.go\n")
+}
+
+func TestModifiedSince(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_modified")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.Mkdir(filepath.Join(tempDir, "old"), 0755)
+	createFile(t, tempDir, "fresh.go", "fresh")
+	createFile(t, tempDir, "fresh.md", "fresh but wrong extension")
+	createFile(t, tempDir, "stale.go", "stale")
+	createFile(t, tempDir, "old/fresh_in_old_dir.go", "fresh")
+
+	// Age the stale file and the directory; directories must still be traversed
+	past := time.Now().Add(-72 * time.Hour)
+	os.Chtimes(filepath.Join(tempDir, "stale.go"), past, past)
+	os.Chtimes(filepath.Join(tempDir, "old"), past, past)
+
+	cfg := &config.Config{
+		OutputFile:    "codebase.txt",
+		ModifiedSince: "48h",
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Extensions: []string{"go"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "FILE: fresh.go")
+	assertContains(t, output, "FILE: old/fresh_in_old_dir.go")
+	assertNotContains(t, output, "FILE: stale.go")
+	assertNotContains(t, output, "FILE: fresh.md")
+
+	if result.Skipped[ReasonTooOld] != 1 {
+		t.Errorf("Expected 1 file skipped as too old, got %d", result.Skipped[ReasonTooOld])
+	}
+
+	cfg.ModifiedSince = "last tuesday"
+	if _, err := Scan(tempDir, cfg, &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an invalid modified_since value")
+	}
+}
+
+func TestParseModifiedSince(t *testing.T) {
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.Local)
+
+	got, err := ParseModifiedSince("48h", now)
+	if err != nil || !got.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("Expected 48h before now, got %v (err: %v)", got, err)
+	}
+
+	got, err = ParseModifiedSince("2024-05-01", now)
+	if err != nil || !got.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Expected midnight on 2024-05-01, got %v (err: %v)", got, err)
+	}
+
+	if got, _ := ParseModifiedSince("", now); !got.IsZero() {
+		t.Errorf("Expected zero time for an empty value, got %v", got)
+	}
+}
+
+func TestDeepDirectoryTree(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_deep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Deep enough to be pathological while staying under PATH_MAX
+	const depth = 1500
+	deepRel := strings.TrimSuffix(strings.Repeat("d/", depth), "/")
+	if err := os.MkdirAll(filepath.Join(tempDir, deepRel), 0755); err != nil {
+		t.Fatal(err)
+	}
+	createFile(t, tempDir, deepRel+"/bottom.txt", "deep")
+	createFile(t, tempDir, "top.txt", "shallow")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	assertContains(t, buf.String(), "FILE: "+deepRel+"/bottom.txt")
+	if result.Included != 2 {
+		t.Errorf("Expected 2 included files, got %d", result.Included)
+	}
+
+	// Depth-first order is preserved: d/... is visited before top.txt
+	if strings.Index(buf.String(), "bottom.txt") > strings.Index(buf.String(), "top.txt") {
+		t.Error("Expected the deep file before top.txt")
+	}
+}
+
+func TestCacheParanoidMode(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	target := filepath.Join(tempDir, "data.txt")
+	createFile(t, tempDir, "data.txt", "hello world")
+	info, _ := os.Stat(target)
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		CacheFile:  ".textify-cache.json",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertContains(t, buf.String(), "FILE: data.txt")
+	assertNotContains(t, buf.String(), ".textify-cache.json")
+
+	// Same size and mtime, but the content is now binary
+	createFile(t, tempDir, "data.txt", "hello\x00world")
+	os.Chtimes(target, info.ModTime(), info.ModTime())
+
+	// Paranoid mode notices the hash changed and re-checks the file
+	cfg.Paranoid = true
+	buf.Reset()
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertNotContains(t, buf.String(), "FILE: data.txt")
+	if result.Skipped[ReasonBinary] != 1 {
+		t.Errorf("Expected the file to be skipped as binary, got %v", result.Skipped)
+	}
+}
+
+func TestPatternsUseForwardSlashes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_slashes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "internal", "scanner"), 0755)
+	createFile(t, tempDir, filepath.Join("internal", "scanner", "scanner.go"), "package scanner")
+	createFile(t, tempDir, filepath.Join("internal", "scanner", "scanner_test.go"), "package scanner")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Exclude: []string{"internal/scanner/*_test.go"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	// Headers always use forward slashes, whatever the OS
+	if !strings.Contains(output, "FILE: internal/scanner/scanner.go") {
+		t.Error("Expected a forward-slash FILE header")
+	}
+	if strings.Contains(output, "scanner_test.go") {
+		t.Error("Expected the slash-written exclude glob to match")
+	}
+}
+
+func TestListBinaries(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_binaries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 2048)...)
+	os.WriteFile(filepath.Join(tempDir, "diagram.png"), png, 0644)
+	createFile(t, tempDir, "main.go", "package main")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	Scan(tempDir, cfg, &buf)
+	assertNotContains(t, buf.String(), "diagram.png")
+
+	cfg.ListBinaries = true
+	buf.Reset()
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertContains(t, buf.String(), "FILE: diagram.png (binary, 2KB, image/png)\n")
+	assertNotContains(t, buf.String(), "PNG")
+	if result.Included != 1 {
+		t.Errorf("Expected placeholders not to count as included, got %d", result.Included)
+	}
+}
+
+func TestSanitizeInvalidUTF8(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_latin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// "café" in Latin-1, where é is the single byte 0xE9
+	createFile(t, tempDir, "menu.txt", "caf\xe9\n")
+	createFile(t, tempDir, "blob.bin", "caf\x00\xe9\n")
+	createFile(t, tempDir, "mixed.txt", "na\xc3\xafve caf\xe9\n")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertNotContains(t, buf.String(), "menu.txt")
+	if result.Skipped[ReasonBinary] != 3 {
+		t.Errorf("Expected 3 binary skips without sanitizing, got %d", result.Skipped[ReasonBinary])
+	}
+
+	cfg.SanitizeInvalidUTF8 = true
+	buf.Reset()
+	result, err = Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+	assertContains(t, output, "FILE: menu.txt (decoded as windows-1252)\n"+separator+"\n\ncafé\n")
+	assertContains(t, output, "FILE: mixed.txt (decoded as utf-8 with invalid bytes replaced)\n"+separator+"\n\nnaïve caf\uFFFD\n")
+	assertNotContains(t, output, "blob.bin")
+	if result.Skipped[ReasonBinary] != 1 {
+		t.Errorf("Expected the NUL file to stay binary, got %d binary skips", result.Skipped[ReasonBinary])
+	}
+}
+
+func TestScanGoldenOutput(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.Mkdir(filepath.Join(tempDir, "pkg"), 0755)
+	createFile(t, tempDir, "main.go", "package main\n")
+	createFile(t, tempDir, "pkg/util.go", "package pkg\n")
+	createFile(t, tempDir, "pkg/util.txt", "ignored by extension")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IncludeTree: true,
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Extensions: []string{"go"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	sep := strings.Repeat("-", 50)
+	golden := "PROJECT STRUCTURE:\n.\n├── main.go\n└── pkg\n    └── util.go\n\n" +
+		sep + "\nFILE: main.go\n" + sep + "\n\npackage main\n\n\n" +
+		sep + "\nFILE: pkg/util.go\n" + sep + "\n\npackage pkg\n\n\n"
+	if buf.String() != golden {
+		t.Errorf("Output does not match golden.\nExpected:\n%q\nGot:\n%q", golden, buf.String())
 	}
-}
\ No newline at end of file
+}
+
+func TestHeaderSummary(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "main.go", "package main\n")
+	createFile(t, tempDir, "util.go", "package main\n\nfunc util() {}\n")
+	createFile(t, tempDir, "notes.txt", "ignored by extension")
+	createFile(t, tempDir, "blob.go", "\x00\x01")
+
+	cfg := &config.Config{
+		OutputFile:    "codebase.txt",
+		IncludeTree:   true,
+		HeaderSummary: true,
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Extensions: []string{"go"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	// 42 bytes of content over 4 lines; the extension and binary skips count
+	summary := "Included 2 files (4 lines, ~11 tokens); excluded 2 files\n\nPROJECT STRUCTURE:\n"
+	if !strings.HasPrefix(buf.String(), summary) {
+		t.Errorf("Expected the output to start with %q, got:\n%s", summary, buf.String())
+	}
+	if result.Lines != 4 || result.Bytes != 42 || result.SkippedFiles != 2 {
+		t.Errorf("Unexpected counts: %d lines, %d bytes, %d skipped files", result.Lines, result.Bytes, result.SkippedFiles)
+	}
+
+	cfg.Format = config.FormatMarkdownDoc
+	buf.Reset()
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertContains(t, buf.String(), "\n\nIncluded 2 files (4 lines, ~11 tokens); excluded 2 files\n\n## Contents\n")
+}
+
+func TestRuleOutputFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "backend", "db"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "frontend"), 0755)
+	createFile(t, tempDir, "README.md", "# Monorepo\n")
+	createFile(t, tempDir, "backend/main.go", "package main\n")
+	createFile(t, tempDir, "backend/db/db.go", "package db\n")
+	createFile(t, tempDir, "frontend/app.js", "app()\n")
+
+	cfg := &config.Config{
+		OutputFile:    "codebase.txt",
+		IncludeTree:   true,
+		HeaderSummary: true,
+		Dirs: map[string]config.DirRule{
+			".":        {Enabled: true},
+			"backend":  {Enabled: true, OutputFile: "backend-context.txt"},
+			"frontend": {Enabled: true, OutputFile: "frontend-context.txt"},
+		},
+	}
+
+	// The second run must not pick up the outputs of the first
+	for run := 0; run < 2; run++ {
+		var buf bytes.Buffer
+		result, err := Scan(tempDir, cfg, &buf)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		main := buf.String()
+		if !strings.HasPrefix(main, "Included 1 files (1 lines, ~3 tokens); excluded 0 files\n\nPROJECT STRUCTURE:\n.\n└── README.md\n") {
+			t.Errorf("Unexpected top-level output:\n%s", main)
+		}
+		assertNotContains(t, main, "main.go")
+
+		data, err := os.ReadFile(filepath.Join(tempDir, "backend-context.txt"))
+		if err != nil {
+			t.Fatalf("Expected the backend output to be written: %v", err)
+		}
+		backend := string(data)
+		tree := "PROJECT STRUCTURE:\n.\n└── backend\n    ├── db\n    │   └── db.go\n    └── main.go\n"
+		if !strings.HasPrefix(backend, "Included 2 files (2 lines, ~6 tokens); excluded 0 files\n\n"+tree) {
+			t.Errorf("Unexpected backend output:\n%s", backend)
+		}
+		assertContains(t, backend, "FILE: backend/db/db.go")
+		assertNotContains(t, backend, "app.js")
+
+		data, _ = os.ReadFile(filepath.Join(tempDir, "frontend-context.txt"))
+		assertContains(t, string(data), "FILE: frontend/app.js")
+
+		if result.Included != 1 || len(result.Outputs) != 2 || result.Outputs["backend-context.txt"].Included != 2 {
+			t.Errorf("Unexpected results: %d included, outputs %+v", result.Included, result.Outputs)
+		}
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(tempDir)
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("Unexpected leftover %s", e.Name())
+		}
+	}
+}
+
+func TestKeepPrevious(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "backend"), 0755)
+	createFile(t, tempDir, "README.md", "# Monorepo\n")
+	createFile(t, tempDir, "backend/main.go", "package main\n")
+	// The previous top-level output, rotated by textify start
+	createFile(t, tempDir, "codebase.1.txt", "FILE: old\n")
+
+	cfg := &config.Config{
+		OutputFile:   "codebase.txt",
+		KeepPrevious: 1,
+		Dirs: map[string]config.DirRule{
+			".":       {Enabled: true},
+			"backend": {Enabled: true, OutputFile: "backend.txt"},
+		},
+	}
+	for run := 1; run <= 3; run++ {
+		createFile(t, tempDir, "backend/main.go", fmt.Sprintf("package main // run %d\n", run))
+		var buf bytes.Buffer
+		result, err := Scan(tempDir, cfg, &buf)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if result.Included != 1 {
+			t.Errorf("Expected the previous versions to be skipped, got %d files:\n%s", result.Included, buf.String())
+		}
+	}
+
+	for name, want := range map[string]string{"backend.txt": "run 3", "backend.1.txt": "run 2"} {
+		data, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Expected %s to be written: %v", name, err)
+		}
+		assertContains(t, string(data), want)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "backend.2.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected only one previous version, got %v", err)
+	}
+
+	// An unchanged output is left in place, and nothing is rotated
+	before, _ := os.Stat(filepath.Join(tempDir, "backend.txt"))
+	result, err := Scan(tempDir, cfg, io.Discard)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	after, _ := os.Stat(filepath.Join(tempDir, "backend.txt"))
+	if !result.Outputs["backend.txt"].Unchanged || !os.SameFile(before, after) {
+		t.Errorf("Expected backend.txt to be left untouched, got %+v", result.Outputs["backend.txt"])
+	}
+	data, _ := os.ReadFile(filepath.Join(tempDir, "backend.1.txt"))
+	assertContains(t, string(data), "run 2")
+}
+
+func TestExtraOutputFormats(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_formats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "README.md", "# Demo\n")
+	createFile(t, tempDir, "main.go", "package main\n")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IncludeTree: true,
+		DocsFirst:   true,
+		Outputs: []config.OutputSpec{
+			{File: "codebase.md", Format: config.FormatMarkdownDoc},
+			{File: "codebase.json", Format: config.FormatJSON},
+		},
+		Dirs: map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	// The second run must not pick up the outputs of the first
+	for run := 0; run < 2; run++ {
+		var buf bytes.Buffer
+		result, err := Scan(tempDir, cfg, &buf)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		assertContains(t, buf.String(), "FILE: main.go")
+		assertNotContains(t, buf.String(), "codebase.json")
+
+		md, err := os.ReadFile(filepath.Join(tempDir, "codebase.md"))
+		if err != nil {
+			t.Fatalf("Expected the markdown output to be written: %v", err)
+		}
+		assertContains(t, string(md), "## Documentation\n\n<a id=\"readme-md\"></a>")
+		assertContains(t, string(md), "```go\npackage main\n```")
+
+		data, err := os.ReadFile(filepath.Join(tempDir, "codebase.json"))
+		if err != nil {
+			t.Fatalf("Expected the json output to be written: %v", err)
+		}
+		var doc jsonOutput
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("Invalid json output: %v\n%s", err, data)
+		}
+		if len(doc.Files) != 2 || doc.Files[0].Group != "documentation" || doc.Files[1].Path != "main.go" ||
+			doc.Files[1].Content == nil || *doc.Files[1].Content != "package main\n" || len(doc.Tree) != 2 {
+			t.Errorf("Unexpected json output:\n%s", data)
+		}
+
+		// Every format gets its own stats
+		jsonResult := result.Outputs["codebase.json"]
+		if jsonResult == nil || jsonResult.Included != 2 || jsonResult.Size != int64(len(data)) || jsonResult.Hash == result.Hash {
+			t.Errorf("Unexpected results: %+v, outputs %+v", result, result.Outputs)
+		}
+		if result.Size != int64(buf.Len()) {
+			t.Errorf("Expected size %d, got %d", buf.Len(), result.Size)
+		}
+		if words := int64(len(strings.Fields(buf.String()))); result.Words != words {
+			t.Errorf("Expected %d words, got %d", words, result.Words)
+		}
+	}
+}
+
+func TestMaxDirLines(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_dirlines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "gen", "sub"), 0755)
+	createFile(t, tempDir, "gen/a.go", "1\n2\n3\n")
+	createFile(t, tempDir, "gen/b.go", "1\n2")
+	createFile(t, tempDir, "gen/sub/c.go", "1\n")
+	createFile(t, tempDir, "main.go", "1\n2\n3\n4\n5\n6\n")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".":   {Enabled: true},
+			"gen": {Enabled: true, MaxDirLines: 5},
+		},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	// a.go (3 lines) + b.go (2 lines) reach the cap; the inheriting subfolder is capped too
+	assertContains(t, output, "FILE: gen/a.go")
+	assertContains(t, output, "FILE: gen/b.go")
+	assertNotContains(t, output, "FILE: gen/sub/c.go")
+	assertContains(t, output, "FILE: main.go")
+
+	if result.CappedDirs["gen"] != 1 || len(result.CappedDirs) != 1 {
+		t.Errorf("Expected gen to be capped with 1 file left out, got %v", result.CappedDirs)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_maxdepth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "pkg", "a", "b"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "lib", "x"), 0755)
+	createFile(t, tempDir, "main.go", "main")
+	createFile(t, tempDir, "pkg/p.go", "p")
+	createFile(t, tempDir, "pkg/a/a.go", "a")
+	createFile(t, tempDir, "pkg/a/b/b.go", "b")
+	createFile(t, tempDir, "lib/l.go", "l")
+	createFile(t, tempDir, "lib/x/x.go", "x")
+
+	newConfig := func() *config.Config {
+		lib := 0
+		return &config.Config{
+			OutputFile:  "codebase.txt",
+			IncludeTree: true,
+			Dirs: map[string]config.DirRule{
+				".":   {Enabled: true},
+				"lib": {Enabled: true, MaxDepth: &lib},
+			},
+		}
+	}
+
+	// Depth 0 means root files only; pruned folders are collapsed in the tree
+	cfg := newConfig()
+	zero := 0
+	cfg.MaxDepth = &zero
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+	assertContains(t, output, "FILE: main.go")
+	assertNotContains(t, output, "FILE: pkg/p.go")
+	assertContains(t, output, "├── lib\n│   └── ...\n")
+	if result.Skipped[ReasonMaxDepth] != 2 {
+		t.Errorf("Expected 2 pruned folders, got %d", result.Skipped[ReasonMaxDepth])
+	}
+
+	// With a global depth of 2, the stricter per-rule limit still prunes lib/x
+	cfg = newConfig()
+	two := 2
+	cfg.MaxDepth = &two
+	paths, _, err := List(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	got := strings.Join(paths, ",")
+	want := "lib/l.go,main.go,pkg/a/a.go,pkg/p.go"
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestIncludeFileMeta(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes and symlinks differ on Windows")
+	}
+
+	tempDir, err := os.MkdirTemp("", "scanner_test_filemeta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "scripts"), 0755)
+	createFile(t, tempDir, "scripts/run.sh", "#!/bin/sh\necho hi\n")
+	if err := os.Chmod(filepath.Join(tempDir, "scripts/run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	createFile(t, tempDir, "notes.txt", "notes")
+	if err := os.Chmod(filepath.Join(tempDir, "notes.txt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("scripts/run.sh", filepath.Join(tempDir, "run")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OutputFile:      "codebase.txt",
+		IncludeFileMeta: true,
+		Dirs:            map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "FILE: scripts/run.sh (mode: -rwxr-xr-x)")
+	assertContains(t, output, "FILE: notes.txt (mode: -rw-r--r--)")
+	assertContains(t, output, "FILE: run (mode: -rwxr-xr-x, -> scripts/run.sh)")
+}
+
+func TestIncludeChecksums(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_checksums")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Enough content to need more than one read
+	content := strings.Repeat("func main() {}\n", 1000)
+	createFile(t, tempDir, "main.go", content)
+
+	cfg := &config.Config{
+		OutputFile:       "codebase.txt",
+		IncludeChecksums: true,
+		Dirs:             map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	assertContains(t, buf.String(), fmt.Sprintf("FILE: main.go (SHA256: %s  SIZE: %d)", hex.EncodeToString(sum[:]), len(data)))
+	assertContains(t, buf.String(), content)
+}
+
+func TestDocsFirst(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_docsfirst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "docs"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "src", "docs"), 0755)
+	createFile(t, tempDir, "CHANGELOG.md", "changes")
+	createFile(t, tempDir, "README.md", "readme")
+	createFile(t, tempDir, "docs/guide.txt", "guide")
+	createFile(t, tempDir, "main.go", "package main")
+	createFile(t, tempDir, "src/app.go", "package src")
+	createFile(t, tempDir, "src/notes.md", "notes")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		DocsFirst:  true,
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	order := []string{
+		"DOCUMENTATION:",
+		"FILE: CHANGELOG.md",
+		"FILE: README.md",
+		"FILE: docs/guide.txt",
+		"SOURCE:",
+		"FILE: main.go",
+		"FILE: src/app.go",
+		"FILE: src/notes.md",
+	}
+	last := -1
+	for _, marker := range order {
+		idx := strings.Index(output, marker)
+		if idx < 0 || idx < last {
+			t.Fatalf("Expected %q after the previous marker in:\n%s", marker, output)
+		}
+		last = idx
+	}
+}
+
+func TestRuntimeExcludes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_runtime_excludes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "src", "auth"), 0755)
+	createFile(t, tempDir, "main.go", "package main")
+	createFile(t, tempDir, "main_test.go", "package main")
+	createFile(t, tempDir, "src/auth/login.go", "package auth")
+	createFile(t, tempDir, "src/auth/login_test.go", "package auth")
+	createFile(t, tempDir, "src/util.go", "package src")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".":   {Enabled: true},
+			"src": {Enabled: true, Include: []string{"*_test.go"}},
+		},
+	}
+	cfg.AddExcludes([]string{"*_test.go", "src/util.go"})
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	// Runtime excludes reach folders with their own rules and beat includes
+	assertContains(t, output, "FILE: main.go")
+	assertContains(t, output, "FILE: src/auth/login.go")
+	assertNotContains(t, output, "FILE: main_test.go")
+	assertNotContains(t, output, "FILE: src/auth/login_test.go")
+	assertNotContains(t, output, "FILE: src/util.go")
+}
+
+func TestLoadedConfigNeverOutput(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_config_path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "settings"), 0755)
+	createFile(t, tempDir, "settings/app.yaml", "port: 8080\n")
+	createFile(t, tempDir, "settings/team-textify.yaml", "output_file: codebase.txt\napi_token: hunter2\ndirs:\n  .:\n    enabled: true\n")
+
+	cfg, err := config.Load(filepath.Join(tempDir, "settings", "team-textify.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertContains(t, buf.String(), "FILE: settings/app.yaml")
+	assertNotContains(t, buf.String(), "hunter2")
+}
+
+func TestOutputHash(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "main.go", "package main")
+	createFile(t, tempDir, "util.go", "package main\n\nfunc util() {}")
+
+	cfg := &config.Config{
+		OutputFile:  "out.txt",
+		IncludeTree: true,
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}},
+	}
+	scan := func() (string, string) {
+		var buf bytes.Buffer
+		result, err := Scan(tempDir, cfg, &buf)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		return result.Hash, buf.String()
+	}
+
+	first, output := scan()
+	if len(first) != 64 {
+		t.Fatalf("Expected a hex SHA-256, got %q", first)
+	}
+
+	// Neither the output nor its checksum sidecar feed back into the next run
+	createFile(t, tempDir, "out.txt", output)
+	if err := WriteChecksum(filepath.Join(tempDir, "out.txt"), first); err != nil {
+		t.Fatal(err)
+	}
+	sidecar, _ := os.ReadFile(filepath.Join(tempDir, "out.txt.sha256"))
+	if string(sidecar) != first+"  out.txt\n" {
+		t.Errorf("Unexpected sidecar content %q", sidecar)
+	}
+	if second, _ := scan(); second != first {
+		t.Errorf("Expected a stable hash on unchanged input, got %s then %s", first, second)
+	}
+
+	createFile(t, tempDir, "util.go", "package main\n\nfunc util() { println() }")
+	if third, _ := scan(); third == first {
+		t.Error("Expected the hash to change when a file changes")
+	}
+}
+
+func TestLooksGenerated(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "main.go", "package main\n")
+	outputs := map[string]*config.Config{
+		"tree.txt":  {IncludeTree: true},
+		"plain.txt": {},
+		"docs.txt":  {DocsFirst: true},
+		"doc.md":    {Format: config.FormatMarkdownDoc},
+		"sum.txt":   {HeaderSummary: true},
+		"sum.md":    {HeaderSummary: true, Format: config.FormatMarkdownDoc},
+		"out.json":  {Format: config.FormatJSON},
+		"index.txt": {Format: config.FormatIndex},
+	}
+	for name, cfg := range outputs {
+		cfg.OutputFile = name
+		cfg.Dirs = map[string]config.DirRule{".": {Enabled: true, Extensions: []string{"go"}}}
+		var buf bytes.Buffer
+		if _, err := Scan(tempDir, cfg, &buf); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		createFile(t, tempDir, name, buf.String())
+	}
+	createFile(t, tempDir, "empty.txt", "")
+	createFile(t, tempDir, "notes.txt", "Hand-written context\n")
+
+	for name, want := range map[string]bool{
+		"tree.txt":    true,
+		"plain.txt":   true,
+		"docs.txt":    true,
+		"doc.md":      true,
+		"sum.txt":     true,
+		"sum.md":      true,
+		"out.json":    true,
+		"index.txt":   true,
+		"empty.txt":   true,
+		"missing.txt": true,
+		"notes.txt":   false,
+	} {
+		got, err := LooksGenerated(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("LooksGenerated(%s) failed: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("LooksGenerated(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMIMEFilters(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_mime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "src"), 0755)
+	createFile(t, tempDir, "deploy", "#!/bin/sh\necho deploy\n")
+	createFile(t, tempDir, "data.csv", "a,b\n1,2\n")
+	createFile(t, tempDir, "keep.csv", "c,d\n3,4\n")
+	createFile(t, tempDir, "page.txt", "<!DOCTYPE html><html></html>")
+	createFile(t, tempDir, "src/main.go", "package main")
+	createFile(t, tempDir, "src/run", "#!/bin/sh\necho run\n")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".": {
+				Enabled:     true,
+				Include:     []string{"keep.csv"},
+				MimeInclude: []string{"text/*"},
+				MimeExclude: []string{"text/csv", "text/html"},
+			},
+			// Extension rules come first: MIME rules can't bring back a file
+			// they reject
+			"src": {Enabled: true, Extensions: []string{"go"}, MimeInclude: []string{"text/*"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "FILE: deploy")
+	assertContains(t, output, "FILE: src/main.go")
+	// Include patterns bypass MIME rules
+	assertContains(t, output, "FILE: keep.csv")
+	assertNotContains(t, output, "FILE: data.csv")
+	// The content decides, not the .txt extension
+	assertNotContains(t, output, "FILE: page.txt")
+	assertNotContains(t, output, "FILE: src/run")
+
+	if n := result.Skipped[ReasonMIMEFilter]; n != 2 {
+		t.Errorf("Expected 2 files skipped by MIME type, got %d", n)
+	}
+	if n := result.Skipped[ReasonExtNotAllowed]; n != 1 {
+		t.Errorf("Expected src/run to be skipped by its extension, got %d", n)
+	}
+}
+
+func TestForceTextAndBinary(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_force")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "table.dat", "id\x00name\n1\x00alice\n")
+	createFile(t, tempDir, "logo.svg", "<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>")
+	createFile(t, tempDir, "other.dat", "\x00\x01\x02")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		ForceText:   []string{"dat"},
+		ForceBinary: []string{"svg"},
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	// NUL bytes would make table.dat binary; forced text is written, escaped
+	assertContains(t, output, "FILE: table.dat")
+	assertContains(t, output, "id\\x00name")
+	assertContains(t, output, "FILE: other.dat")
+	assertNotContains(t, output, "FILE: logo.svg")
+	if n := result.Skipped[ReasonBinary]; n != 1 {
+		t.Errorf("Expected logo.svg to be skipped as binary, got %d binaries", n)
+	}
+}
+
+func TestSkipTextifyDumps(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_dumps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "notes"), 0755)
+	createFile(t, tempDir, "main.go", "package main\n")
+	createFile(t, tempDir, "notes/context-old.txt", "PROJECT STRUCTURE:\n├── main.go\n\n")
+	createFile(t, tempDir, "notes/wrapped.txt", "# CONTEXT DUMP\nmain.go\n")
+
+	cfg := &config.Config{
+		OutputFile:       "codebase.txt",
+		IncludeTree:      true,
+		SkipTextifyDumps: true,
+		DumpMarkers:      []string{"# CONTEXT DUMP"},
+		Dirs:             map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "FILE: main.go")
+	assertNotContains(t, output, "FILE: notes/context-old.txt")
+	assertNotContains(t, output, "FILE: notes/wrapped.txt")
+	if n := result.Skipped[ReasonTextifyDump]; n != 2 {
+		t.Errorf("Expected 2 dumps skipped, got %d", n)
+	}
+
+	// The dump written by this scan is skipped under any name
+	createFile(t, tempDir, "copy.txt", output)
+	buf.Reset()
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertNotContains(t, buf.String(), "FILE: copy.txt")
+}
+
+func TestMaxOutputSize(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_maxsize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "venv"), 0755)
+	createFile(t, tempDir, "main.go", "package main\n")
+	for _, name := range []string{"a.py", "b.py", "c.py"} {
+		createFile(t, tempDir, "venv/"+name, strings.Repeat("x = 1\n", 100))
+	}
+
+	cfg := &config.Config{
+		OutputFile:     "codebase.txt",
+		OutputWarnSize: "0",
+		MaxOutputSize:  "1KB",
+		Outputs:        []config.OutputSpec{{File: "codebase.json", Format: config.FormatJSON}},
+		Dirs:           map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	_, err = Scan(tempDir, cfg, &buf)
+	if !errors.Is(err, ErrOutputTooLarge) {
+		t.Fatalf("Expected ErrOutputTooLarge, got %v", err)
+	}
+	assertContains(t, err.Error(), "largest folders: venv (1KB), . (13B)")
+	// The scan stopped at the file that passed the limit
+	assertNotContains(t, buf.String(), "FILE: venv/c.py")
+	if _, err := os.Stat(filepath.Join(tempDir, "codebase.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected the extra output not to be written, got %v", err)
+	}
+
+	cfg.MaxOutputSize = "10KB"
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Errorf("Expected the scan to fit 10KB, got %v", err)
+	}
+}
+
+func TestExcludeDirsInTree(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_exclude_dirs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "web", "node_modules", "react"), 0755)
+	createFile(t, tempDir, "web/app.js", "app")
+	createFile(t, tempDir, "web/node_modules/react/index.js", "react")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IncludeTree: true,
+		ExcludeDirs: []string{"node_modules"},
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+
+	assertContains(t, output, "└── web\n    ├── app.js\n    └── node_modules\n        └── ...\n")
+	assertNotContains(t, output, "FILE: web/node_modules/react/index.js")
+	if n := result.Skipped[ReasonDirExcluded]; n != 1 {
+		t.Errorf("Expected 1 excluded directory, got %d", n)
+	}
+}
+
+func createFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertContains(t *testing.T, output, substr string) {
+	output = filepath.ToSlash(output)
+	substr = filepath.ToSlash(substr)
+	if !strings.Contains(output, substr) {
+		t.Errorf("Expected output to contain '%s', but it didn't.", substr)
+	}
+}
+
+func assertNotContains(t *testing.T, output, substr string) {
+	output = filepath.ToSlash(output)
+	substr = filepath.ToSlash(substr)
+	if strings.Contains(output, substr) {
+		t.Errorf("Expected output NOT to contain '%s', but it did.", substr)
+	}
+}
+
+func TestDeepBinaryCheck(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_deep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "mixed.log", strings.Repeat("line\n", 200)+strings.Repeat("\x00", 1000))
+	createFile(t, tempDir, "plain.txt", strings.Repeat("line\n", 500))
+
+	for _, deep := range []bool{false, true} {
+		cfg := &config.Config{
+			OutputFile:      "codebase.txt",
+			DeepBinaryCheck: deep,
+			Dirs:            map[string]config.DirRule{".": {Enabled: true}},
+		}
+		var buf bytes.Buffer
+		result, err := Scan(tempDir, cfg, &buf)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		output := buf.String()
+
+		assertContains(t, output, "FILE: plain.txt")
+		if deep {
+			assertNotContains(t, output, "FILE: mixed.log")
+			if n := result.Skipped[ReasonBinary]; n != 1 {
+				t.Errorf("Expected mixed.log to be skipped as binary, got %d binaries", n)
+			}
+		} else {
+			assertContains(t, output, "FILE: mixed.log")
+		}
+	}
+}
+
+func TestFileGap(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_gap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, tempDir, "a.go", "package a\n")
+	createFile(t, tempDir, "b.go", "package b")
+	createFile(t, tempDir, "c.go", "package c\n")
+
+	header := func(name string) string {
+		return separator + "\nFILE: " + name + "\n" + separator + "\n\n"
+	}
+	tests := []struct {
+		gap      int
+		expected string
+	}{
+		// A file without a final newline still gets one
+		{0, header("a.go") + "package a\n" + header("b.go") + "package b\n" + header("c.go") + "package c\n"},
+		{1, header("a.go") + "package a\n\n" + header("b.go") + "package b\n" + header("c.go") + "package c\n\n"},
+		{2, header("a.go") + "package a\n\n\n" + header("b.go") + "package b\n\n" + header("c.go") + "package c\n\n\n"},
+	}
+	for _, tt := range tests {
+		gap := tt.gap
+		cfg := &config.Config{
+			OutputFile: "codebase.txt",
+			FileGap:    &gap,
+			Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+		}
+		var buf bytes.Buffer
+		if _, err := Scan(tempDir, cfg, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("file_gap %d: unexpected output.\nExpected: %q\nGot:      %q", tt.gap, tt.expected, buf.String())
+		}
+	}
+}
+
+func TestLinkedDuplicates(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on Windows")
+	}
+
+	tempDir, err := os.MkdirTemp("", "scanner_test_links")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	content := "package a\n\nfunc A() {}\n"
+	createFile(t, tempDir, "a.go", content)
+	// Same content, but a file of its own
+	createFile(t, tempDir, "copy.go", content)
+	if err := os.Link(filepath.Join(tempDir, "a.go"), filepath.Join(tempDir, "hard.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.go", filepath.Join(tempDir, "soft.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs:       map[string]config.DirRule{".": {Enabled: true}},
+	}
+	var buf bytes.Buffer
+	result, err := Scan(tempDir, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+	if n := strings.Count(output, "func A()"); n != 2 {
+		t.Errorf("Expected the content twice (a.go and copy.go), got %d:\n%s", n, output)
+	}
+	assertContains(t, output, "FILE: hard.go\n"+separator+"\n\n[identical to a.go — hardlink]\n")
+	assertContains(t, output, "FILE: soft.go\n"+separator+"\n\n[identical to a.go — symlink]\n")
+	if result.Included != 2 || result.Skipped[ReasonLinkedDuplicate] != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	cfg.KeepLinkedDuplicates = true
+	buf.Reset()
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if n := strings.Count(buf.String(), "func A()"); n != 4 {
+		t.Errorf("Expected every path in full with keep_linked_duplicates, got %d copies", n)
+	}
+}
+
+func TestLinkedDuplicatesAcrossOutputs(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_links_outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.Mkdir(filepath.Join(tempDir, "sub"), 0755)
+	createFile(t, tempDir, "sub/a.go", "package a\n\nfunc A() {}\n")
+	if err := os.Link(filepath.Join(tempDir, "sub", "a.go"), filepath.Join(tempDir, "b.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".":   {Enabled: true},
+			"sub": {Enabled: true, OutputFile: "sub.txt"},
+		},
+	}
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	sub, err := os.ReadFile(filepath.Join(tempDir, "sub.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each output has the content, since neither shows the other's files
+	for name, output := range map[string]string{"codebase.txt": buf.String(), "sub.txt": string(sub)} {
+		if !strings.Contains(output, "func A()") || strings.Contains(output, "identical to") {
+			t.Errorf("Expected %s to hold the content itself, got:\n%s", name, output)
+		}
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on Windows")
+	}
+
+	tempDir, err := os.MkdirTemp("", "scanner_test_follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// The project shares a file and a folder from outside it, links to its
+	// own internal folder, and has a link back to itself
+	root := filepath.Join(tempDir, "project")
+	for _, dir := range []string{"common/shared", "project/internal"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createFile(t, tempDir, "common/types.go", "package common\n\ntype ID string\n")
+	createFile(t, tempDir, "common/shared/log.go", "package shared\n\nfunc Log() {}\n")
+	createFile(t, root, "main.go", "package main\n")
+	createFile(t, root, "internal/real.go", "package internal\n\nfunc Real() {}\n")
+	links := map[string]string{
+		"types.go": "../common/types.go",
+		"shared":   "../common/shared",
+		"alias":    "internal",
+		"loop":     ".",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IncludeTree: true,
+		Dirs:        map[string]config.DirRule{".": {Enabled: true}},
+	}
+	var buf bytes.Buffer
+	result, err := Scan(root, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	// Linked files are read through; linked folders are skipped
+	assertContains(t, buf.String(), "FILE: types.go\n"+separator+"\n\npackage common\n\ntype ID string\n")
+	assertNotContains(t, buf.String(), "func Log()")
+	if result.Skipped[ReasonSymlinkedDir] != 3 {
+		t.Errorf("Expected three symlinked directories skipped, got %+v", result.Skipped)
+	}
+
+	cfg.FollowSymlinks = true
+	buf.Reset()
+	result, err = Scan(root, cfg, &buf)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	output := buf.String()
+	assertContains(t, output, "FILE: shared/log.go\n"+separator+"\n\npackage shared\n\nfunc Log() {}\n")
+	assertContains(t, output, "FILE: types.go\n"+separator+"\n\npackage common\n")
+	assertContains(t, output, "├── shared\n│   └── log.go\n")
+	// alias/real.go comes first, but the content goes to the real path
+	assertContains(t, output, "FILE: alias/real.go\n"+separator+"\n\n[identical to internal/real.go — symlink]\n")
+	assertContains(t, output, "FILE: internal/real.go\n"+separator+"\n\npackage internal\n")
+	if n := strings.Count(output, "func Real()"); n != 1 {
+		t.Errorf("Expected the linked file's content once, got %d:\n%s", n, output)
+	}
+	if result.Skipped[ReasonSymlinkLoop] != 1 || result.Skipped[ReasonLinkedDuplicate] != 1 {
+		t.Errorf("Unexpected skips: %+v", result.Skipped)
+	}
+}
+
+func TestDirFormat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_dirformat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "src"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "data"), 0755)
+	createFile(t, tempDir, "src/main.go", "package main")
+	createFile(t, tempDir, "data/rows.csv", "a,b\n1,2\n")
+
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".":    {Enabled: true},
+			"src":  {Enabled: true, Format: config.FormatMarkdownDoc},
+			"data": {Enabled: true, Format: config.FormatText},
+		},
+	}
+
+	// In text output, the markdown directory's files are fenced
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertContains(t, buf.String(), "FILE: src/main.go\n"+separator+"\n\n```go\npackage main\n```\n\n\n")
+	assertContains(t, buf.String(), "FILE: data/rows.csv\n"+separator+"\n\na,b\n1,2\n\n\n")
+
+	// In markdown output, the text directory's files are not
+	cfg.Format = config.FormatMarkdownDoc
+	buf.Reset()
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertContains(t, buf.String(), "### `src/main.go`\n\n```go\npackage main\n```\n\n")
+	assertContains(t, buf.String(), "### `data/rows.csv`\n\na,b\n1,2\n\n")
+}
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ExtensionCount is how many files with one extension were left out.
+type ExtensionCount struct {
+	// Ext is the extension, lowercase and without its dot; "" for files
+	// without one.
+	Ext   string
+	Files int
+}
+
+// SkippedFolders is the number of folders left out as a whole, whose files
+// were never looked at.
+func (r *Result) SkippedFolders() int {
+	n := -r.SkippedFiles
+	for _, count := range r.Skipped {
+		n += count
+	}
+	return n
+}
+
+// CappedDirNames returns the rule directories that reached max_dir_lines,
+// sorted, so summaries list them in the same order every run.
+func (r *Result) CappedDirNames() []string {
+	dirs := make([]string, 0, len(r.CappedDirs))
+	for dir := range r.CappedDirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// SkipBreakdown lists the skip reasons with their counts, largest first,
+// as in "1100 gitignored, 80 extension not allowed, 20 binary". Counts
+// include skipped folders.
+func (r *Result) SkipBreakdown() string {
+	reasons := make([]string, 0, len(r.Skipped))
+	for reason, n := range r.Skipped {
+		if n > 0 {
+			reasons = append(reasons, reason)
+		}
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if r.Skipped[reasons[i]] != r.Skipped[reasons[j]] {
+			return r.Skipped[reasons[i]] > r.Skipped[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%d %s", r.Skipped[reason], reason)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// TopSkippedExtensions returns the extensions of the files left out because
+// no rule allowed them, most files first, at most max of them.
+func (r *Result) TopSkippedExtensions(max int) []ExtensionCount {
+	counts := make([]ExtensionCount, 0, len(r.SkippedExtensions))
+	for ext, n := range r.SkippedExtensions {
+		counts = append(counts, ExtensionCount{Ext: ext, Files: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Files != counts[j].Files {
+			return counts[i].Files > counts[j].Files
+		}
+		return counts[i].Ext < counts[j].Ext
+	})
+	if len(counts) > max {
+		counts = counts[:max]
+	}
+	return counts
+}
@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestSkipSummary(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":          {Data: []byte("package main\n")},
+		"db/schema.sql":    {Data: []byte("create table t (id int);\n")},
+		"db/seed.SQL":      {Data: []byte("insert into t values (1);\n")},
+		"proto/api.proto":  {Data: []byte("syntax = \"proto3\";\n")},
+		"Jenkinsfile":      {Data: []byte("pipeline {}\n")},
+		"build.log":        {Data: []byte("ok\n")},
+		"old/legacy.go":    {Data: []byte("package old\n")},
+		"old/legacy_2.go":  {Data: []byte("package old\n")},
+		"vendor/x/dep.go":  {Data: []byte("package x\n")},
+		"vendor/modules.t": {Data: []byte("x\n")},
+	}
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".":   {Enabled: true, Extensions: []string{"go"}, Exclude: []string{"*.log"}},
+			"old": {Enabled: false},
+		},
+	}
+	result, err := ScanFS(fsys, "project", cfg, io.Discard, Hooks{})
+	if err != nil {
+		t.Fatalf("ScanFS failed: %v", err)
+	}
+
+	if result.SkippedFiles != 5 || result.SkippedFolders() != 2 {
+		t.Errorf("Expected 5 files and 2 folders skipped, got %d and %d", result.SkippedFiles, result.SkippedFolders())
+	}
+	if got, expected := result.SkipBreakdown(), "4 extension not allowed, 1 disabled, 1 excluded, 1 vendored"; got != expected {
+		t.Errorf("Unexpected breakdown.\nExpected: %q\nGot:      %q", expected, got)
+	}
+	if result.VendoredFiles != 2 {
+		t.Errorf("Expected the 2 files of vendor/ counted, got %d", result.VendoredFiles)
+	}
+	expected := []ExtensionCount{{Ext: "sql", Files: 2}, {Ext: "", Files: 1}}
+	if got := result.TopSkippedExtensions(2); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected extensions.\nExpected: %+v\nGot:      %+v", expected, got)
+	}
+}
+
+func TestCappedDirNames(t *testing.T) {
+	r := &Result{CappedDirs: map[string]int{"web": 2, "api": 1, "docs": 4}}
+	if got, expected := r.CappedDirNames(), []string{"api", "docs", "web"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/JohnEsleyer/textify/internal/tokens"
+)
+
+// tokenLimiter is a reader that ends a file's content once it reaches max
+// tokens, as the tokens package estimates them, for max_file_tokens. What
+// follows is replaced by a note of where the file was cut, and never read.
+// A cut never splits a UTF-8 character.
+type tokenLimiter struct {
+	r   io.Reader
+	max int
+
+	// left is how many bytes may still pass, and last is the last one that
+	// did, so the note starts on a line of its own.
+	left int64
+	last byte
+
+	// cut is set once the file was cut; note is what is left of its note.
+	cut  bool
+	note []byte
+	err  error
+}
+
+func newTokenLimiter(r io.Reader, max int) *tokenLimiter {
+	return &tokenLimiter{r: r, max: max, left: int64(max) * tokens.BytesPerToken}
+}
+
+func (l *tokenLimiter) Read(p []byte) (int, error) {
+	if len(l.note) > 0 {
+		n := copy(p, l.note)
+		l.note = l.note[n:]
+		return n, nil
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.left == 0 {
+		// A byte past the cap tells whether anything was left out
+		var b [1]byte
+		if n, err := io.ReadFull(l.r, b[:]); n == 0 {
+			l.err = err
+			return 0, err
+		}
+		l.endCut()
+		return l.Read(p)
+	}
+
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	// A character the cap runs through is left out whole
+	kept := n
+	if l.left == 0 {
+		kept = fullRunes(p[:n])
+	}
+	if kept > 0 {
+		l.last = p[kept-1]
+	}
+	if kept < n {
+		l.endCut()
+		return kept, nil
+	}
+	return n, err
+}
+
+// endCut queues the note for the cut and ends the content after it.
+func (l *tokenLimiter) endCut() {
+	note := fmt.Sprintf("... (truncated at %d tokens)\n", l.max)
+	if l.last != '\n' && l.last != 0 {
+		note = "\n" + note
+	}
+	l.cut, l.note, l.err = true, []byte(note), io.EOF
+}
+
+// fullRunes returns the length of p without the incomplete UTF-8 character
+// it may end with.
+func fullRunes(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if utf8.FullRune(p[i:]) {
+				return len(p)
+			}
+			return i
+		}
+	}
+	return len(p)
+}
@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestTokenLimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		max      int
+		expected string
+		cut      bool
+	}{
+		{"under the cap", "abcd\n", 2, "abcd\n", false},
+		{"exactly the cap", "abcdefgh", 2, "abcdefgh", false},
+		{"cut mid-line", "abcdefghij\n", 2, "abcdefgh\n... (truncated at 2 tokens)\n", true},
+		{"cut after a newline", "abc\nefgh\n", 1, "abc\n... (truncated at 1 tokens)\n", true},
+		{"character kept whole", "abcdefé", 2, "abcdefé", false},
+		{"character across the cap", "abcdefgé", 2, "abcdefg\n... (truncated at 2 tokens)\n", true},
+	}
+	for _, tt := range tests {
+		// One byte at a time, the cap is met at every possible read
+		for _, r := range []io.Reader{strings.NewReader(tt.input), iotest.OneByteReader(strings.NewReader(tt.input))} {
+			l := newTokenLimiter(r, tt.max)
+			out, err := io.ReadAll(l)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if string(out) != tt.expected || l.cut != tt.cut {
+				t.Errorf("%s: expected %q (cut %v), got %q (cut %v)", tt.name, tt.expected, tt.cut, out, l.cut)
+			}
+		}
+	}
+}
+
+func TestMaxFileTokens(t *testing.T) {
+	long := strings.Repeat("0123456789\n", 20)
+	fsys := fstest.MapFS{
+		"big.txt":    {Data: []byte(long)},
+		"forced.txt": {Data: []byte(long)},
+		"small.txt":  {Data: []byte("short\n")},
+	}
+	cfg := &config.Config{
+		OutputFile: "codebase.txt",
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, MaxFileTokens: 10, Include: []string{"forced.txt"}},
+		},
+	}
+	var buf bytes.Buffer
+	result, err := ScanFS(fsys, "project", cfg, &buf, Hooks{})
+	if err != nil {
+		t.Fatalf("ScanFS failed: %v", err)
+	}
+	output := buf.String()
+
+	// Ten tokens are 40 bytes: three full lines and 7 bytes of the fourth
+	cut := strings.Repeat("0123456789\n", 3) + "0123456\n... (truncated at 10 tokens)\n"
+	if !strings.Contains(output, "FILE: big.txt\n"+separator+"\n\n"+cut+"\n") {
+		t.Errorf("Expected big.txt cut at 10 tokens, got:\n%s", output)
+	}
+	if !strings.Contains(output, long) || !strings.Contains(output, "short\n") {
+		t.Errorf("Expected forced.txt and small.txt in full, got:\n%s", output)
+	}
+	if result.TruncatedFiles != 1 {
+		t.Errorf("Expected 1 truncated file, got %d", result.TruncatedFiles)
+	}
+}
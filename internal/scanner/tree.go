@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/walker"
+)
+
+// collapsedEntry stands in for the contents of a directory pruned by max depth
+// or exclude_dirs.
+const collapsedEntry = "..."
+
+// Marks for entries the "all" tree mode shows although their contents are
+// not in the output.
+const (
+	excludedMark = " [excluded]"
+	binaryMark   = " [binary]"
+)
+
+// treeVisitor collects the included files, in walk order, for the project
+// tree of each output, keyed by the rule's output_file ("" for the top-level
+// output). Directories pruned by max depth or exclude_dirs appear collapsed,
+// with a single "..." child. With all set, skipped files are listed too,
+// marked as excluded, and so are other skipped directories, collapsed.
+type treeVisitor struct {
+	paths map[string][]string
+	all   bool
+}
+
+func (v *treeVisitor) OnDir(d walker.Decision) {
+	out := d.Rule.OutputFile
+	switch {
+	case d.Reason == walker.ReasonMaxDepth || d.Reason == walker.ReasonDirExcluded:
+		v.paths[out] = append(v.paths[out], d.RelPath+"/"+collapsedEntry)
+	case v.all && !d.Include:
+		v.paths[out] = append(v.paths[out], d.RelPath+"/"+collapsedEntry+excludedMark)
+	}
+}
+
+func (v *treeVisitor) OnFile(d walker.Decision) {
+	out := d.Rule.OutputFile
+	switch {
+	case d.Include:
+		v.paths[out] = append(v.paths[out], d.RelPath)
+	case v.all:
+		v.paths[out] = append(v.paths[out], d.RelPath+excludedMark)
+	}
+}
+
+// writeTree streams the project structure section for the given
+// slash-separated file paths to w.
+func writeTree(w io.Writer, paths []string) error {
+	if _, err := fmt.Fprint(w, "PROJECT STRUCTURE:\n.\n"); err != nil {
+		return err
+	}
+	if err := writeTreeLines(w, paths); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(w, "\n")
+	return err
+}
+
+// writeTreeLines streams the tree lines below the root for the given paths.
+// Paths must be in walk order (depth-first, sorted by name), which is how the
+// walk collects them, so the tree reuses the walk's decisions rather than
+// walking the filesystem again. Lines are written as they are produced; the
+// only state kept is the last child of each directory and the connectors of
+// the current path.
+func writeTreeLines(w io.Writer, paths []string) error {
+	lastChild := lastChildren(paths)
+
+	var prev []string
+	// isLast[d] records whether the component at depth d of the current path
+	// is the last entry in its directory, which decides the indent below it.
+	var isLast []bool
+
+	for _, p := range paths {
+		parts := strings.Split(p, "/")
+
+		// Skip the directory lines already printed for the previous path
+		common := 0
+		for common < len(parts)-1 && common < len(prev)-1 && parts[common] == prev[common] {
+			common++
+		}
+		isLast = isLast[:common]
+
+		for depth := common; depth < len(parts); depth++ {
+			parent := strings.Join(parts[:depth], "/")
+			last := lastChild[parent] == parts[depth]
+
+			var line strings.Builder
+			for _, ancestorLast := range isLast {
+				if ancestorLast {
+					line.WriteString("    ")
+				} else {
+					line.WriteString("│   ")
+				}
+			}
+			if last {
+				line.WriteString("└── ")
+			} else {
+				line.WriteString("├── ")
+			}
+			line.WriteString(parts[depth])
+			line.WriteString("\n")
+
+			if _, err := io.WriteString(w, line.String()); err != nil {
+				return err
+			}
+			isLast = append(isLast, last)
+		}
+		prev = parts
+	}
+	return nil
+}
+
+// lastChildren maps each directory (slash-separated, "" for the root) to the
+// name of its last child among the given paths.
+func lastChildren(paths []string) map[string]string {
+	last := make(map[string]string)
+	// Walking backwards, the first child seen for a directory is its last
+	for i := len(paths) - 1; i >= 0; i-- {
+		parts := strings.Split(paths[i], "/")
+		for depth := range parts {
+			parent := strings.Join(parts[:depth], "/")
+			if _, ok := last[parent]; !ok {
+				last[parent] = parts[depth]
+			}
+		}
+	}
+	return last
+}
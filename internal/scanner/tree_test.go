@@ -0,0 +1,135 @@
+package scanner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestWriteTreeGolden(t *testing.T) {
+	paths := []string{
+		"README.md",
+		"cmd/textify/main.go",
+		"internal/config/config.go",
+		"internal/config/discovery.go",
+		"internal/scanner/scanner.go",
+		"main.go",
+	}
+
+	golden := `PROJECT STRUCTURE:
+.
+├── README.md
+├── cmd
+│   └── textify
+│       └── main.go
+├── internal
+│   ├── config
+│   │   ├── config.go
+│   │   └── discovery.go
+│   └── scanner
+│       └── scanner.go
+└── main.go
+
+`
+
+	var buf bytes.Buffer
+	if err := writeTree(&buf, paths); err != nil {
+		t.Fatalf("writeTree failed: %v", err)
+	}
+	if buf.String() != golden {
+		t.Errorf("Tree does not match golden output.\nExpected:\n%s\nGot:\n%s", golden, buf.String())
+	}
+}
+
+func TestWriteTreeEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	writeTree(&buf, nil)
+	if buf.String() != "PROJECT STRUCTURE:\n.\n\n" {
+		t.Errorf("Unexpected empty tree: %q", buf.String())
+	}
+}
+
+func TestScanWritesTreeFromWalk(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.Mkdir(filepath.Join(tempDir, "src"), 0755)
+	createFile(t, tempDir, "src/app.go", "package src")
+	createFile(t, tempDir, "src/notes.txt", "skip me")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IncludeTree: true,
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Extensions: []string{"go"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	expected := "PROJECT STRUCTURE:\n.\n└── src\n    └── app.go\n\n" + strings.Repeat("-", 50) + "\nFILE: src/app.go\n"
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("Expected the tree to precede the contents and honor the rules, got:\n%s", buf.String())
+	}
+}
+
+func TestTreeModeAll(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "scanner_test_tree_all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "src"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, "vendor", "lib"), 0755)
+	createFile(t, tempDir, "src/app.go", "package src")
+	createFile(t, tempDir, "src/logo.go", "\x89PNG\x00\x00")
+	createFile(t, tempDir, "src/notes.txt", "skip me")
+	createFile(t, tempDir, "vendor/lib/lib.go", "package lib")
+
+	cfg := &config.Config{
+		OutputFile:  "codebase.txt",
+		IncludeTree: true,
+		TreeMode:    config.TreeModeAll,
+		Dirs: map[string]config.DirRule{
+			".":      {Enabled: true, Extensions: []string{"go"}},
+			"vendor": {Enabled: false},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	expected := "PROJECT STRUCTURE:\n.\n" +
+		"├── src\n" +
+		"│   ├── app.go\n" +
+		"│   ├── logo.go [binary]\n" +
+		"│   └── notes.txt [excluded]\n" +
+		"└── vendor\n" +
+		"    └── ... [excluded]\n\n"
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("Expected left-out entries to be marked, got:\n%s", buf.String())
+	}
+	assertNotContains(t, buf.String(), "skip me")
+
+	// The default mode shows only what is included
+	cfg.TreeMode = ""
+	buf.Reset()
+	if _, err := Scan(tempDir, cfg, &buf); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	assertNotContains(t, buf.String(), "[excluded]")
+	assertNotContains(t, buf.String(), "[binary]")
+}
@@ -0,0 +1,176 @@
+package tokens
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// whitespace is the Unicode White_Space class, which \s means to the
+// tokenizers; Go's \s is ASCII only.
+const whitespace = `\t\n\v\f\r \x{85}\p{Z}`
+
+// Pre-tokenizer patterns of the encodings, in Go syntax: \s is spelled out,
+// and the trailing \s+(?!\S) alternative, a lookahead Go can't express, is
+// left to splitter.next.
+var (
+	cl100kPattern = strings.NewReplacer(`\s`, whitespace).Replace(
+		`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|[\s]*[\r\n]+|[\s]+`)
+	o200kPattern = strings.NewReplacer(`\s`, whitespace).Replace(
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+			`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+			`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|[\s]*[\r\n]+|[\s]+`)
+)
+
+// noRank is the rank of byte sequences that aren't tokens.
+const noRank = math.MaxInt32
+
+// Encoding is a byte-pair encoding tokenizer, as the tiktoken library
+// defines them, that counts the tokens of text.
+type Encoding struct {
+	Name  string
+	ranks map[string]int
+	split *regexp.Regexp
+}
+
+// LoadEncoding reads the ranks of an encoding from r, in the .tiktoken
+// format: a base64-encoded token and its rank per line. pattern is the
+// encoding's pre-tokenizer.
+func LoadEncoding(name, pattern string, r io.Reader) (*Encoding, error) {
+	split, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	e := &Encoding{Name: name, ranks: make(map[string]int), split: split}
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("%s line %d: expected a token and its rank", name, line)
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", name, line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", name, line, err)
+		}
+		e.ranks[string(b)] = n
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	// Every byte is a token of its own, so any text can be encoded
+	for b := 0; b < 256; b++ {
+		if _, ok := e.ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("%s: byte %#x has no rank", name, b)
+		}
+	}
+	return e, nil
+}
+
+// Count returns how many tokens text encodes to.
+func (e *Encoding) Count(text []byte) int64 {
+	var n int64
+	s := splitter{re: e.split, text: text}
+	for piece := s.next(); piece != nil; piece = s.next() {
+		n += int64(e.pieceTokens(piece))
+	}
+	return n
+}
+
+// pieceTokens returns how many tokens a piece of pre-tokenized text merges
+// into: starting from its bytes, the adjacent pair of parts with the lowest
+// rank is merged until no pair is a token.
+func (e *Encoding) pieceTokens(piece []byte) int {
+	if _, ok := e.ranks[string(piece)]; ok {
+		return 1
+	}
+	// parts[i].rank is the rank of parts i and i+1 merged
+	type part struct{ start, rank int }
+	parts := make([]part, len(piece)+1)
+	rank := func(i int) int {
+		if i+2 >= len(parts) {
+			return noRank
+		}
+		if r, ok := e.ranks[string(piece[parts[i].start:parts[i+2].start])]; ok {
+			return r
+		}
+		return noRank
+	}
+	for i := range parts {
+		parts[i].start = i
+	}
+	for i := range parts {
+		parts[i].rank = rank(i)
+	}
+	for {
+		min, at := noRank, -1
+		for i := 0; i < len(parts)-1; i++ {
+			if parts[i].rank < min {
+				min, at = parts[i].rank, i
+			}
+		}
+		if at < 0 {
+			return len(parts) - 1
+		}
+		parts = append(parts[:at+1], parts[at+2:]...)
+		parts[at].rank = rank(at)
+		if at > 0 {
+			parts[at-1].rank = rank(at - 1)
+		}
+	}
+}
+
+// splitter cuts text into the pieces an encoding's pre-tokenizer matches.
+type splitter struct {
+	re   *regexp.Regexp
+	text []byte
+	pos  int
+}
+
+// next returns the next piece, or nil at the end of the text.
+func (s *splitter) next() []byte {
+	if s.pos >= len(s.text) {
+		return nil
+	}
+	rest := s.text[s.pos:]
+	loc := s.re.FindIndex(rest)
+	if loc == nil || loc[0] != 0 || loc[1] == 0 {
+		// The patterns match any text; a single character is a fallback
+		_, size := utf8.DecodeRune(rest)
+		loc = []int{0, size}
+	}
+	piece := rest[:loc[1]]
+	// \s+(?!\S): a run of spaces before something else leaves its last
+	// space to start the next piece
+	if loc[1] < len(rest) && isSpaceRun(piece) {
+		if _, size := utf8.DecodeLastRune(piece); size < len(piece) {
+			piece = piece[:len(piece)-size]
+		}
+	}
+	s.pos += len(piece)
+	return piece
+}
+
+// isSpaceRun tells whether piece is whitespace only, and doesn't end in a
+// line break, as the runs \s*[\r\n]+ matches do.
+func isSpaceRun(piece []byte) bool {
+	if last := piece[len(piece)-1]; last == '\n' || last == '\r' {
+		return false
+	}
+	return len(bytes.TrimFunc(piece, unicode.IsSpace)) == 0
+}
@@ -0,0 +1,73 @@
+package tokens
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Price is what a model charges for Per tokens of input.
+type Price struct {
+	Amount float64
+	Per    int64
+}
+
+// ParsePrice reads a price as written in the costs config: an amount, then
+// "per" or "/" and a number of tokens with an optional K or M suffix, as in
+// "2.50per1M", "$2.50 / 1M", or "0.01per1K". A bare amount is per million
+// tokens.
+func ParsePrice(s string) (Price, error) {
+	text := strings.ToLower(strings.Join(strings.Fields(s), ""))
+	amount, per, ok := strings.Cut(text, "per")
+	if !ok {
+		amount, per, ok = strings.Cut(text, "/")
+	}
+	if !ok {
+		per = "1m"
+	}
+	a, err := strconv.ParseFloat(strings.TrimPrefix(amount, "$"), 64)
+	if err != nil || a < 0 || math.IsInf(a, 0) || math.IsNaN(a) {
+		return Price{}, fmt.Errorf("invalid price %q: want an amount per a number of tokens, e.g. 2.50per1M", s)
+	}
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(per, "k"):
+		multiplier, per = 1000, strings.TrimSuffix(per, "k")
+	case strings.HasSuffix(per, "m"):
+		multiplier, per = 1000000, strings.TrimSuffix(per, "m")
+	}
+	n := int64(1)
+	if per != "" {
+		n, err = strconv.ParseInt(per, 10, 64)
+		if err != nil || n <= 0 {
+			return Price{}, fmt.Errorf("invalid price %q: want an amount per a number of tokens, e.g. 2.50per1M", s)
+		}
+	}
+	return Price{Amount: a, Per: n * multiplier}, nil
+}
+
+// Cost returns what n tokens of input cost at p.
+func (p Price) Cost(n int64) float64 {
+	return p.Amount * float64(n) / float64(p.Per)
+}
+
+// FormatCost renders an amount in dollars: cents with thousands separators
+// from a dollar up, and four decimals below, where cents would round small
+// outputs away.
+func FormatCost(amount float64) string {
+	if amount < 1 {
+		return fmt.Sprintf("$%.4f", amount)
+	}
+	s := fmt.Sprintf("%.2f", amount)
+	whole, cents, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return "$" + b.String() + "." + cents
+}
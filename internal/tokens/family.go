@@ -0,0 +1,313 @@
+package tokens
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Heuristic is the family counted as BytesPerToken bytes per token, with no
+// tokenizer data; Estimate counts it.
+const Heuristic = "chars/4"
+
+// Family is a tokenizer family counted with a byte-pair encoding, whose
+// ranks are downloaded from URL on first use and cached. SHA256 pins the
+// data, as tiktoken does: a download or cached file with another hash is
+// rejected.
+type Family struct {
+	Name    string
+	URL     string
+	SHA256  string
+	pattern string
+}
+
+// Families are the byte-pair encodings token_families can name, besides
+// Heuristic.
+var Families = []Family{
+	{
+		Name:    "cl100k_base",
+		URL:     "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		SHA256:  "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+		pattern: cl100kPattern,
+	},
+	{
+		Name:    "o200k_base",
+		URL:     "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		SHA256:  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+		pattern: o200kPattern,
+	},
+}
+
+// verify returns an error unless data hashes to f.SHA256.
+func (f *Family) verify(data []byte) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != f.SHA256 {
+		return fmt.Errorf("%s data has SHA-256 %s, want %s", f.Name, got, f.SHA256)
+	}
+	return nil
+}
+
+// modelFamilies maps model name prefixes to the family that tokenizes them,
+// after tiktoken's table. More specific prefixes come first, so gpt-4o
+// isn't taken for gpt-4.
+var modelFamilies = []struct {
+	prefix string
+	family string
+}{
+	{"gpt-4o", "o200k_base"},
+	{"chatgpt-4o", "o200k_base"},
+	{"gpt-4.1", "o200k_base"},
+	{"gpt-4.5", "o200k_base"},
+	{"gpt-5", "o200k_base"},
+	{"o1", "o200k_base"},
+	{"o3", "o200k_base"},
+	{"o4", "o200k_base"},
+	{"gpt-4", "cl100k_base"},
+	{"gpt-3.5", "cl100k_base"},
+	{"gpt-35", "cl100k_base"},
+	{"text-embedding-3", "cl100k_base"},
+	{"text-embedding-ada", "cl100k_base"},
+}
+
+// ModelFamily returns the family that tokenizes the named model, as in
+// "gpt-4o-input", or "" for a model none of Families is known to fit. A
+// prefix only matches whole: o1 matches "o1-mini" but not "o1x".
+func ModelFamily(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, m := range modelFamilies {
+		if !strings.HasPrefix(model, m.prefix) {
+			continue
+		}
+		if rest := model[len(m.prefix):]; rest == "" || strings.ContainsRune("-.:_/ ", rune(rest[0])) {
+			return m.family
+		}
+	}
+	return ""
+}
+
+// FamilyNames returns the names token_families accepts.
+func FamilyNames() []string {
+	names := make([]string, 0, len(Families)+1)
+	for _, f := range Families {
+		names = append(names, f.Name)
+	}
+	return append(names, Heuristic)
+}
+
+// FamilyCount is the token count of a text for one family. Estimated is set
+// when the count is the Heuristic one, because the family's tokenizer data
+// couldn't be loaded, and Note then tells why.
+type FamilyCount struct {
+	Family    string
+	Tokens    int64
+	Estimated bool
+	Note      string
+}
+
+// fetchTimeout bounds the download of tokenizer data, so an offline run
+// falls back to the heuristic quickly.
+const fetchTimeout = 20 * time.Second
+
+// fetch downloads url; tests replace it.
+var fetch = func(url string) ([]byte, error) {
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// loaded holds the encodings loaded so far, or why they couldn't be, by
+// family name.
+var (
+	loadedMu sync.Mutex
+	loaded   = map[string]*loadResult{}
+)
+
+type loadResult struct {
+	enc *Encoding
+	err error
+}
+
+// CacheDir returns the folder tokenizer data is cached in: textify/tokenizers
+// in the user's cache folder. Placing a family's <name>.tiktoken file there
+// makes it available offline.
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "textify", "tokenizers"), nil
+}
+
+// Count counts text for each of families, in order. A family whose data
+// isn't in cacheDir is downloaded there; when that fails, as it does
+// offline, its count falls back to the Heuristic estimate with a note. An
+// empty cacheDir never caches.
+func Count(families []string, text []byte, cacheDir string) []FamilyCount {
+	counts, _ := CountReader(families, bytes.NewReader(text), cacheDir)
+	return counts
+}
+
+// countChunk is how much text CountReader counts at a time; tests lower it.
+var countChunk = 1 << 20
+
+// CountReader is Count for the text read from r, which it counts a chunk at
+// a time so that an output of any size takes bounded memory. A chunk ends
+// where no pre-tokenizer piece can straddle the cut, so the counts are those
+// of the whole text. Text with no such place for 16 chunks, such as a huge
+// minified line, is cut anyway, which can miscount a token there.
+func CountReader(families []string, r io.Reader, cacheDir string) ([]FamilyCount, error) {
+	counts := make([]FamilyCount, len(families))
+	encodings := make([]*Encoding, len(families))
+	for i, name := range families {
+		counts[i].Family = name
+		if name == Heuristic {
+			continue
+		}
+		enc, err := loadFamily(name, cacheDir)
+		if err != nil {
+			counts[i].Estimated = true
+			counts[i].Note = fmt.Sprintf("%s estimate; tokenizer data unavailable: %v", Heuristic, err)
+			continue
+		}
+		encodings[i] = enc
+	}
+	countText := func(text []byte) {
+		for i, enc := range encodings {
+			if enc != nil {
+				counts[i].Tokens += enc.Count(text)
+			}
+		}
+	}
+
+	var size int64
+	var pending []byte
+	chunk := make([]byte, countChunk)
+	for {
+		n, err := r.Read(chunk)
+		pending = append(pending, chunk[:n]...)
+		size += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) < countChunk {
+			continue
+		}
+		cut := pieceBoundary(pending)
+		if cut == 0 && len(pending) >= 16*countChunk {
+			cut = len(pending)
+		}
+		if cut > 0 {
+			countText(pending[:cut])
+			pending = append(pending[:0], pending[cut:]...)
+		}
+	}
+	countText(pending)
+	for i, enc := range encodings {
+		if enc == nil {
+			counts[i].Tokens = Estimate(size)
+		}
+	}
+	return counts, nil
+}
+
+// pieceBoundary returns the last offset in text where every family's
+// pre-tokenizer ends a piece whatever follows, or 0 if there is none: before
+// an ASCII letter or digit that starts a line, or before a space between
+// ASCII punctuation or an alphanumeric and an ASCII letter.
+func pieceBoundary(text []byte) int {
+	for i := len(text) - 2; i > 0; i-- {
+		switch {
+		case text[i-1] == '\n' && isASCIIAlnum(text[i]):
+			return i
+		case text[i] == ' ' && text[i-1] > ' ' && text[i-1] < 0x7f && isASCIILetter(text[i+1]):
+			return i
+		}
+	}
+	return 0
+}
+
+func isASCIILetter(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func isASCIIAlnum(b byte) bool {
+	return isASCIILetter(b) || '0' <= b && b <= '9'
+}
+
+// loadFamily returns the encoding of the named family, from the cache, or
+// downloaded and then cached. Each family is loaded once per run, even if
+// that fails.
+func loadFamily(name, cacheDir string) (*Encoding, error) {
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+	if r, ok := loaded[name]; ok {
+		return r.enc, r.err
+	}
+	enc, err := readFamily(name, cacheDir)
+	loaded[name] = &loadResult{enc: enc, err: err}
+	return enc, err
+}
+
+func readFamily(name, cacheDir string) (*Encoding, error) {
+	var family *Family
+	for i := range Families {
+		if Families[i].Name == name {
+			family = &Families[i]
+		}
+	}
+	if family == nil {
+		return nil, fmt.Errorf("unknown token family %q", name)
+	}
+
+	cached := ""
+	if cacheDir != "" {
+		cached = filepath.Join(cacheDir, name+".tiktoken")
+		if data, err := os.ReadFile(cached); err == nil && family.verify(data) == nil {
+			if enc, err := LoadEncoding(name, family.pattern, bytes.NewReader(data)); err == nil {
+				return enc, nil
+			}
+		}
+		// A damaged or tampered cache is downloaded again
+	}
+
+	data, err := fetch(family.URL)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing unverified is cached, or counted with
+	if err := family.verify(data); err != nil {
+		return nil, err
+	}
+	enc, err := LoadEncoding(name, family.pattern, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cached != "" {
+		// Caching is best effort: the counts are right either way
+		if err := os.MkdirAll(cacheDir, 0755); err == nil {
+			tmp := cached + ".tmp"
+			if err := os.WriteFile(tmp, data, 0644); err == nil {
+				os.Rename(tmp, cached)
+			}
+		}
+	}
+	return enc, nil
+}
@@ -0,0 +1,78 @@
+// Package tokens estimates or counts how many LLM tokens text takes and how
+// that compares to the context windows of common models.
+package tokens
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BytesPerToken is the heuristic used for estimates: about four bytes of
+// source text per token.
+const BytesPerToken = 4
+
+// Estimate returns the estimated token count for n bytes of text.
+func Estimate(n int64) int64 {
+	return (n + BytesPerToken - 1) / BytesPerToken
+}
+
+// Window is a model's context window, in tokens.
+type Window struct {
+	Name   string
+	Tokens int
+}
+
+// DefaultWindows are the context windows reported when the config adds none.
+var DefaultWindows = []Window{
+	{Name: "gpt-4", Tokens: 8192},
+	{Name: "gpt-4-32k", Tokens: 32768},
+	{Name: "gpt-4o", Tokens: 128000},
+	{Name: "claude-3.5-sonnet", Tokens: 200000},
+}
+
+// Windows returns the default windows merged with custom ones (name to
+// tokens), sorted by size and then name. A custom window replaces a default
+// one of the same name.
+func Windows(custom map[string]int) []Window {
+	var windows []Window
+	for _, w := range DefaultWindows {
+		if _, ok := custom[w.Name]; !ok {
+			windows = append(windows, w)
+		}
+	}
+	for name, size := range custom {
+		windows = append(windows, Window{Name: name, Tokens: size})
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		if windows[i].Tokens != windows[j].Tokens {
+			return windows[i].Tokens < windows[j].Tokens
+		}
+		return windows[i].Name < windows[j].Name
+	})
+	return windows
+}
+
+// Label renders the window's size compactly, e.g. "8k", "128k", or "1M".
+func (w Window) Label() string {
+	switch {
+	case w.Tokens >= 1000000 && w.Tokens%1000000 == 0:
+		return fmt.Sprintf("%dM", w.Tokens/1000000)
+	case w.Tokens >= 1000 && w.Tokens%1000 == 0:
+		return fmt.Sprintf("%dk", w.Tokens/1000)
+	case w.Tokens >= 1024 && w.Tokens%1024 == 0:
+		return fmt.Sprintf("%dk", w.Tokens/1024)
+	case w.Tokens >= 1000:
+		return fmt.Sprintf("%dk", (w.Tokens+500)/1000)
+	default:
+		return fmt.Sprint(w.Tokens)
+	}
+}
+
+// Usage returns the share of the window that n tokens take, in percent,
+// and whether they fit.
+func (w Window) Usage(n int64) (percent int64, fits bool) {
+	if w.Tokens <= 0 {
+		return 0, false
+	}
+	return n * 100 / int64(w.Tokens), n <= int64(w.Tokens)
+}
@@ -0,0 +1,267 @@
+package tokens
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	for n, expected := range map[int64]int64{0: 0, 1: 1, 4: 1, 5: 2, 400: 100} {
+		if got := Estimate(n); got != expected {
+			t.Errorf("Estimate(%d): expected %d, got %d", n, expected, got)
+		}
+	}
+}
+
+func TestWindows(t *testing.T) {
+	windows := Windows(map[string]int{"local-llama": 32768, "gpt-4o": 100000})
+	var names []string
+	for _, w := range windows {
+		names = append(names, w.Name+" ("+w.Label()+")")
+	}
+	expected := []string{"gpt-4 (8k)", "gpt-4-32k (32k)", "local-llama (32k)", "gpt-4o (100k)", "claude-3.5-sonnet (200k)"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Unexpected windows.\nExpected: %q\nGot:      %q", expected, names)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	w := Window{Name: "gpt-4o", Tokens: 128000}
+	if percent, fits := w.Usage(80000); percent != 62 || !fits {
+		t.Errorf("Expected 62%% and a fit, got %d%% (%v)", percent, fits)
+	}
+	if percent, fits := w.Usage(256000); percent != 200 || fits {
+		t.Errorf("Expected 200%% and no fit, got %d%% (%v)", percent, fits)
+	}
+	for size, expected := range map[int]string{128000: "128k", 8192: "8k", 1000000: "1M", 500: "500"} {
+		if label := (Window{Tokens: size}).Label(); label != expected {
+			t.Errorf("Label for %d: expected %s, got %s", size, expected, label)
+		}
+	}
+}
+
+// testRanks is a tiny encoding in the .tiktoken format: every byte, then a
+// few merges.
+func testRanks() string {
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, token := range []string{"he", "ll", "hell", " w", "or", " wor", "ld"} {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+	return b.String()
+}
+
+func TestEncodingCount(t *testing.T) {
+	enc, err := LoadEncoding("test", cl100kPattern, strings.NewReader(testRanks()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "hello" is hell+o, " world" is wor+ld after " w"+"or", "!" is one byte
+	for text, expected := range map[string]int64{"": 0, "hello": 2, " world": 2, "hello world!": 5, "hé": 3} {
+		if got := enc.Count([]byte(text)); got != expected {
+			t.Errorf("Count(%q): expected %d, got %d", text, expected, got)
+		}
+	}
+
+	if _, err := LoadEncoding("test", cl100kPattern, strings.NewReader("aGk= 1\n")); err == nil {
+		t.Error("Expected an error for an encoding missing byte ranks")
+	}
+}
+
+func TestSplitter(t *testing.T) {
+	split := func(pattern, text string) []string {
+		s := splitter{re: regexp.MustCompile(pattern), text: []byte(text)}
+		var pieces []string
+		for p := s.next(); p != nil; p = s.next() {
+			pieces = append(pieces, string(p))
+		}
+		return pieces
+	}
+	got := split(cl100kPattern, "hello world  foo\n\n  bar's 12345")
+	expected := []string{"hello", " world", " ", " foo", "\n\n", " ", " bar", "'s", " ", "123", "45"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected cl100k pieces.\nExpected: %q\nGot:      %q", expected, got)
+	}
+	// o200k splits words at capitals, and keeps slashes after punctuation
+	got = split(o200kPattern, "HelloWorld ./x")
+	expected = []string{"Hello", "World", " ./", "x"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected o200k pieces.\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+func TestCountFallsBack(t *testing.T) {
+	cacheDir, err := os.MkdirTemp("", "tokens_test_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	defer pinTestRanks()()
+	defer func(f func(string) ([]byte, error)) { fetch = f }(fetch)
+	downloads := 0
+	fetch = func(url string) ([]byte, error) {
+		downloads++
+		if strings.Contains(url, "cl100k") {
+			return []byte(testRanks()), nil
+		}
+		return nil, fmt.Errorf("offline")
+	}
+	defer func() { loaded = map[string]*loadResult{} }()
+
+	counts := Count([]string{"cl100k_base", "o200k_base", Heuristic}, []byte("hello world!"), cacheDir)
+	expected := []FamilyCount{
+		{Family: "cl100k_base", Tokens: 5},
+		{Family: "o200k_base", Tokens: 3, Estimated: true, Note: "chars/4 estimate; tokenizer data unavailable: offline"},
+		{Family: Heuristic, Tokens: 3},
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Unexpected counts.\nExpected: %+v\nGot:      %+v", expected, counts)
+	}
+
+	// The downloaded data is cached, and read from there by later runs
+	if _, err := os.Stat(filepath.Join(cacheDir, "cl100k_base.tiktoken")); err != nil {
+		t.Errorf("Expected the tokenizer data cached: %v", err)
+	}
+	loaded = map[string]*loadResult{}
+	Count([]string{"cl100k_base"}, []byte("hello"), cacheDir)
+	if downloads != 2 {
+		t.Errorf("Expected 2 downloads, got %d", downloads)
+	}
+}
+
+// pinTestRanks pins testRanks as the data of every family, and returns a
+// function that restores the real hashes.
+func pinTestRanks() func() {
+	sum := sha256.Sum256([]byte(testRanks()))
+	pinned := make([]string, len(Families))
+	for i := range Families {
+		pinned[i] = Families[i].SHA256
+		Families[i].SHA256 = hex.EncodeToString(sum[:])
+	}
+	return func() {
+		for i := range Families {
+			Families[i].SHA256 = pinned[i]
+		}
+	}
+}
+
+func TestCountRejectsUnpinnedData(t *testing.T) {
+	cacheDir, err := os.MkdirTemp("", "tokens_test_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	defer pinTestRanks()()
+	defer func(f func(string) ([]byte, error)) { fetch = f }(fetch)
+	tampered := testRanks() + base64.StdEncoding.EncodeToString([]byte("hello")) + " 999\n"
+	fetch = func(url string) ([]byte, error) {
+		return []byte(tampered), nil
+	}
+	defer func() { loaded = map[string]*loadResult{} }()
+
+	counts := Count([]string{"cl100k_base"}, []byte("hello"), cacheDir)
+	if !counts[0].Estimated || !strings.Contains(counts[0].Note, "SHA-256") {
+		t.Errorf("Expected a download that doesn't match the pinned hash to be rejected, got %+v", counts[0])
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "cl100k_base.tiktoken")); !os.IsNotExist(err) {
+		t.Errorf("Expected rejected data not to be cached: %v", err)
+	}
+
+	// A cached file that doesn't match is downloaded again
+	if err := os.WriteFile(filepath.Join(cacheDir, "cl100k_base.tiktoken"), []byte(tampered), 0644); err != nil {
+		t.Fatal(err)
+	}
+	downloads := 0
+	fetch = func(url string) ([]byte, error) {
+		downloads++
+		return []byte(testRanks()), nil
+	}
+	loaded = map[string]*loadResult{}
+	counts = Count([]string{"cl100k_base"}, []byte("hello"), cacheDir)
+	if counts[0].Estimated || counts[0].Tokens != 2 || downloads != 1 {
+		t.Errorf("Expected the tampered cache to be replaced by a download, got %+v after %d downloads", counts[0], downloads)
+	}
+}
+
+func TestCountReaderChunks(t *testing.T) {
+	defer pinTestRanks()()
+	defer func(f func(string) ([]byte, error)) { fetch = f }(fetch)
+	fetch = func(url string) ([]byte, error) {
+		return []byte(testRanks()), nil
+	}
+	defer func() { loaded = map[string]*loadResult{} }()
+	defer func(n int) { countChunk = n }(countChunk)
+
+	text := strings.Repeat("func hello() {\n\treturn \"world\"  // it's 12345\n}\n\n  HelloWorld ./x\r\n", 40) +
+		strings.Repeat("x", 300)
+	families := []string{"cl100k_base", "o200k_base", Heuristic}
+	whole := Count(families, []byte(text), "")
+	for _, n := range []int{1, 7, 64} {
+		countChunk = n
+		chunked, err := CountReader(families, strings.NewReader(text), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(chunked, whole) {
+			t.Errorf("Chunks of %d: expected %+v, got %+v", n, whole, chunked)
+		}
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	for s, expected := range map[string]Price{
+		"2.50per1M":  {Amount: 2.5, Per: 1000000},
+		"$2.50 / 1M": {Amount: 2.5, Per: 1000000},
+		"0.01per1K":  {Amount: 0.01, Per: 1000},
+		"3 per 1000": {Amount: 3, Per: 1000},
+		"15":         {Amount: 15, Per: 1000000},
+		"0.5 per M":  {Amount: 0.5, Per: 1000000},
+	} {
+		got, err := ParsePrice(s)
+		if err != nil || got != expected {
+			t.Errorf("ParsePrice(%q): expected %+v, got %+v (%v)", s, expected, got, err)
+		}
+	}
+	for _, s := range []string{"cheap", "-1per1M", "2per0K", "2perday"} {
+		if _, err := ParsePrice(s); err == nil {
+			t.Errorf("ParsePrice(%q): expected an error", s)
+		}
+	}
+
+	p := Price{Amount: 2.5, Per: 1000000}
+	for n, expected := range map[int64]string{35120: "$0.0878", 400000: "$1.00", 1234567890: "$3,086.42"} {
+		if got := FormatCost(p.Cost(n)); got != expected {
+			t.Errorf("Cost of %d tokens: expected %s, got %s", n, expected, got)
+		}
+	}
+}
+
+func TestModelFamily(t *testing.T) {
+	for model, expected := range map[string]string{
+		"gpt-4o-input":        "o200k_base",
+		"GPT-4o-mini":         "o200k_base",
+		"gpt-4.1":             "o200k_base",
+		"o1-mini":             "o200k_base",
+		"gpt-4-turbo-input":   "cl100k_base",
+		"gpt-3.5-turbo":       "cl100k_base",
+		"claude-sonnet-input": "",
+		"o1x":                 "",
+		"gpt-40":              "",
+	} {
+		if got := ModelFamily(model); got != expected {
+			t.Errorf("ModelFamily(%q): expected %q, got %q", model, expected, got)
+		}
+	}
+}
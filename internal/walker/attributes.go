@@ -0,0 +1,95 @@
+package walker
+
+import (
+	"path"
+	"strings"
+)
+
+// attrState is the state of an attribute given by a .gitattributes line.
+type attrState int
+
+const (
+	// attrUnspecified is the state before any line matches, or after
+	// "!attr" resets it.
+	attrUnspecified attrState = iota
+	attrSet
+	attrUnset
+)
+
+// attrRule is a .gitattributes line that gives export-ignore a state.
+type attrRule struct {
+	pattern string
+	state   attrState
+}
+
+// parseExportIgnore returns the lines of a .gitattributes file that set,
+// unset ("-export-ignore"), or reset ("!export-ignore") export-ignore, in
+// order. Negative patterns are forbidden in attributes files and macro
+// definitions say nothing about paths, so both are skipped.
+func parseExportIgnore(data string) []attrRule {
+	var rules []attrRule
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "!") || strings.HasPrefix(fields[0], "[attr]") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			name, _, _ := strings.Cut(attr, "=")
+			switch name {
+			case "export-ignore":
+				rules = append(rules, attrRule{fields[0], attrSet})
+			case "-export-ignore":
+				rules = append(rules, attrRule{fields[0], attrUnset})
+			case "!export-ignore":
+				rules = append(rules, attrRule{fields[0], attrUnspecified})
+			}
+		}
+	}
+	return rules
+}
+
+// exportIgnored reports whether relPath has the export-ignore attribute set
+// by the .gitattributes files of the root and the directories on its way,
+// as git archive would see it. Patterns are relative to the directory of
+// their file and follow .gitignore's rules, except that a pattern matching
+// a directory says nothing about the paths inside it. Lines are applied from
+// the root down, in order, so the last match wins and deeper files override
+// shallower ones.
+func (w *Walker) exportIgnored(relPath string, isDir bool) bool {
+	state := attrUnspecified
+	dir := "."
+	for {
+		rel := relPath
+		if dir != "." {
+			rel = strings.TrimPrefix(relPath, dir+"/")
+		}
+		for _, rule := range w.attributes(dir) {
+			if matchPattern(rule.pattern, rel, isDir) {
+				state = rule.state
+			}
+		}
+		next, _, more := strings.Cut(rel, "/")
+		if !more {
+			break
+		}
+		dir = path.Join(dir, next)
+	}
+	return state == attrSet
+}
+
+// attributes returns the export-ignore rules of the .gitattributes file in
+// relDir, read the first time they are needed.
+func (w *Walker) attributes(relDir string) []attrRule {
+	if rules, ok := w.attrs[relDir]; ok {
+		return rules
+	}
+	if w.attrs == nil {
+		w.attrs = make(map[string][]attrRule)
+	}
+	var rules []attrRule
+	if data, err := w.readFile(path.Join(relDir, ".gitattributes")); err == nil {
+		rules = parseExportIgnore(string(data))
+	}
+	w.attrs[relDir] = rules
+	return rules
+}
@@ -0,0 +1,113 @@
+package walker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+func TestExportIgnoreSemantics(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_attributes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(tempDir, ".gitattributes"), []byte(
+		"# Left out of release archives\n"+
+			"/tests export-ignore\n"+
+			"*.md   export-ignore\n"+
+			"README.md -export-ignore\n"+
+			".github/ export-ignore\n"+
+			"ci/** export-ignore\n"+
+			"docs/*.txt text export-ignore\n"+
+			"*.sh export-ignore=yes\n"+
+			"!neg export-ignore\n"+
+			"[attr]nodist export-ignore\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "sub", ".gitattributes"), []byte("*.md !export-ignore\nlocal.go export-ignore\n"), 0644)
+
+	w := &Walker{Root: tempDir}
+	cases := []struct {
+		relPath string
+		isDir   bool
+		want    bool
+	}{
+		// A pattern matching a directory doesn't match what is inside it
+		{"tests", true, true},
+		{"tests/main_test.go", false, false},
+		{"sub/tests", true, false},
+
+		// The last matching line wins
+		{"CHANGES.md", false, true},
+		{"docs/guide.md", false, true},
+		{"README.md", false, false},
+
+		// A trailing slash only matches directories; "dir/**" only what is
+		// inside the directory
+		{".github", true, true},
+		{".github", false, false},
+		{"ci", true, false},
+		{"ci/deploy/run.yml", false, true},
+
+		{"docs/notes.txt", false, true},
+		{"docs/old/notes.txt", false, false},
+		{"build.sh", false, true},
+
+		// Negative patterns and macros are ignored
+		{"neg", false, false},
+		{"nodist", false, false},
+
+		// A deeper file overrides the root's, for paths inside its directory
+		{"sub/guide.md", false, false},
+		{"sub/local.go", false, true},
+		{"local.go", false, false},
+	}
+	for _, c := range cases {
+		if got := w.exportIgnored(c.relPath, c.isDir); got != c.want {
+			t.Errorf("exportIgnored(%q, dir=%v) = %v, want %v", c.relPath, c.isDir, got, c.want)
+		}
+	}
+}
+
+func TestUseExportIgnore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_export_ignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"fixtures", "src"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{"fixtures/big.json", "src/main.go", "src/keep.txt", "Makefile"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(tempDir, ".gitattributes"), []byte("fixtures export-ignore\n*.txt export-ignore\nMakefile export-ignore\n"), 0644)
+
+	cfg := &config.Config{
+		UseExportIgnore: true,
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Include: []string{"src/keep.txt"}, Exclude: []string{".gitattributes"}},
+		},
+	}
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	// Include patterns still win
+	expected := []string{
+		"file .gitattributes: excluded",
+		"file Makefile: export-ignore",
+		"dir fixtures: export-ignore",
+		"dir src: +",
+		"file src/keep.txt: +",
+		"file src/main.go: +",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
@@ -0,0 +1,84 @@
+package walker
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NameLess reports whether the entry name a sorts before b among its
+// siblings. Without natural, names compare byte by byte, which is how
+// os.ReadDir sorts them. With natural, they compare case-insensitively, and
+// runs of digits compare by value, so "File2.go" sorts before "file10.go".
+// Names that differ only in case or leading zeros fall back to bytes, so the
+// order is the same on every machine and locale.
+func NameLess(a, b string, natural bool) bool {
+	if !natural {
+		return a < b
+	}
+	if c := compareNatural(a, b); c != 0 {
+		return c < 0
+	}
+	return a < b
+}
+
+// PathLess reports whether the slash-separated path a comes before b in walk
+// order: depth-first, with the entries of each directory sorted by NameLess.
+// This is the order of the tree.
+func PathLess(a, b string, natural bool) bool {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return NameLess(as[i], bs[i], natural)
+		}
+	}
+	return len(as) < len(bs)
+}
+
+// compareNatural compares a and b case-insensitively, with runs of digits
+// compared by value, and returns -1, 0, or +1.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			var na, nb string
+			na, a = digitRun(a)
+			nb, b = digitRun(b)
+			na, nb = strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(na) != len(nb) {
+				return compareInts(len(na), len(nb))
+			}
+			if na != nb {
+				return strings.Compare(na, nb)
+			}
+			continue
+		}
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if ra, rb = unicode.ToLower(ra), unicode.ToLower(rb); ra != rb {
+			return compareInts(int(ra), int(rb))
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	return compareInts(len(a), len(b))
+}
+
+// digitRun splits s after its leading run of ASCII digits.
+func digitRun(s string) (run, rest string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
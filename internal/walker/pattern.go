@@ -0,0 +1,87 @@
+package walker
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/ignore"
+)
+
+// checkPatternMatch reports whether an entry matches any of the include or
+// exclude patterns, which follow .gitignore conventions:
+//
+//   - A pattern without a slash ("*.log", "cache") matches the name of an
+//     entry at any depth.
+//   - A pattern with a leading or inner slash ("/build", "internal/*.go") is
+//     anchored: it matches the whole path relative to the project root.
+//   - A trailing slash ("cache/") only matches directories.
+//   - "**" matches any number of directories: "**/foo" is foo at any depth,
+//     "foo/**" is everything inside foo, and "a/**/b" is b anywhere below a.
+//
+// Patterns and paths are compared with forward slashes, so configs written
+// on one OS match the same files on another.
+func checkPatternMatch(relPath string, isDir bool, patterns []string) bool {
+	for _, p := range patterns {
+		if matchPattern(filepath.ToSlash(p), relPath, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchingPatterns returns the patterns that match an entry, as
+// checkPatternMatch matches them. All of them are tried, so that each gets
+// credit for the entry, even after the first match.
+func matchingPatterns(relPath string, isDir bool, patterns []string) []string {
+	var matched []string
+	for _, p := range patterns {
+		if matchPattern(filepath.ToSlash(p), relPath, isDir) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// matchPattern matches a single gitignore-style pattern against a
+// slash-separated relative path.
+func matchPattern(pattern, relPath string, isDir bool) bool {
+	if strings.HasSuffix(pattern, "/") {
+		if !isDir {
+			return false
+		}
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return false
+	}
+	// Each segment also matches itself literally (see ignore.MatchSegment), so
+	// real names holding glob characters ("[id].tsx") can still be named
+	if !anchored && !strings.Contains(pattern, "/") {
+		return ignore.MatchSegment(pattern, path.Base(relPath))
+	}
+	return ignore.MatchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+// couldMatchBelow reports whether any of the patterns could match an entry
+// inside directory relDir. Bare patterns match names at any depth, so they
+// always could; anchored ones only if relDir matches a prefix of them.
+func couldMatchBelow(relDir string, patterns []string) bool {
+	dirParts := strings.Split(relDir, "/")
+	for _, p := range patterns {
+		p = strings.TrimSuffix(filepath.ToSlash(p), "/")
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			return true
+		}
+		if ignore.PrefixCouldMatch(strings.Split(strings.TrimPrefix(p, "/"), "/"), dirParts) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package walker
+
+import "testing"
+
+func TestPatternSemantics(t *testing.T) {
+	cases := []struct {
+		pattern string
+		relPath string
+		isDir   bool
+		want    bool
+	}{
+		// Bare patterns match a name at any depth
+		{"foo", "foo", true, true},
+		{"foo", "src/foo", false, true},
+		{"*.log", "logs/app/today.log", false, true},
+
+		// A leading slash anchors to the project root
+		{"/build", "build", true, true},
+		{"/build", "web/build", true, false},
+		{"/*.go", "main.go", false, true},
+		{"/*.go", "cmd/main.go", false, false},
+
+		// An inner slash anchors too
+		{"internal/*.go", "internal/app.go", false, true},
+		{"internal/*.go", "pkg/internal/app.go", false, false},
+		{"internal/*.go", "internal/sub/app.go", false, false},
+
+		// A trailing slash only matches directories
+		{"cache/", "cache", true, true},
+		{"cache/", "a/b/cache", true, true},
+		{"cache/", "cache", false, false},
+		{"/cache/", "a/cache", true, false},
+
+		// ** matches any number of directories
+		{"**/foo", "foo", false, true},
+		{"**/foo", "a/b/foo", true, true},
+		{"**/foo", "a/foo/bar", false, false},
+		{"foo/**", "foo/a/b.go", false, true},
+		{"foo/**", "foo", true, false},
+		{"a/**/b", "a/b", true, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**/b", "c/a/x/b", false, false},
+
+		// Names and paths with glob characters match themselves literally
+		{"[id].tsx", "pages/[id].tsx", false, true},
+		{"[id].tsx", "pages/i.tsx", false, true},
+		{"pages/[id].tsx", "pages/[id].tsx", false, true},
+		{"/src/a*b.go", "src/a*b.go", false, true},
+		{"pages/[id].tsx", "pages/x.tsx", false, false},
+		{"app/[slug]/*.tsx", "app/[slug]/page.tsx", false, true},
+	}
+	for _, c := range cases {
+		if got := checkPatternMatch(c.relPath, c.isDir, []string{c.pattern}); got != c.want {
+			t.Errorf("pattern %q against %q (dir: %v): expected %v, got %v", c.pattern, c.relPath, c.isDir, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package walker
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// DefaultRule applies at the root when the config has no "." rule: enabled,
+// with no extension filter.
+var DefaultRule = config.DirRule{Enabled: true, Extensions: []string{}}
+
+// resolveStep returns the rule for directory dir given the rule in effect in
+// its parent: the directory's own rule if the config has one, otherwise the
+// parent's. Rules replace each other; they are not merged.
+func resolveStep(dirs map[string]config.DirRule, parentRule config.DirRule, parentRuleDir, dir string) (config.DirRule, string) {
+	if rule, ok := dirs[dir]; ok {
+		return rule, dir
+	}
+	return parentRule, parentRuleDir
+}
+
+// Layer is one directory on the way from the root to a resolved directory.
+type Layer struct {
+	// Dir is the directory ("." for the root).
+	Dir string
+
+	// Rule is the directory's own rule, or nil if it inherits.
+	Rule *config.DirRule
+}
+
+// Resolution is how the walker arrives at the rule for a directory.
+type Resolution struct {
+	// Layers lists the root and each directory down to the target, in order.
+	Layers []Layer
+
+	// Rule is the rule in effect inside the directory, and RuleDir the key
+	// it comes from ("" when it is DefaultRule).
+	Rule    config.DirRule
+	RuleDir string
+
+	// DisabledAt is the first directory on the way whose rule is disabled,
+	// or "" if the walk reaches the target.
+	DisabledAt string
+}
+
+// Resolve applies the config's rules from the root down to dir (slash-separated,
+// relative to the root) exactly as Walk does when it enters each directory.
+func Resolve(dirs map[string]config.DirRule, dir string) Resolution {
+	dir = path.Clean(dir)
+	chain := []string{"."}
+	if dir != "." {
+		parts := strings.Split(dir, "/")
+		for i := range parts {
+			chain = append(chain, strings.Join(parts[:i+1], "/"))
+		}
+	}
+
+	res := Resolution{Rule: DefaultRule}
+	for _, d := range chain {
+		layer := Layer{Dir: d}
+		if rule, ok := dirs[d]; ok {
+			layer.Rule = &rule
+		}
+		res.Layers = append(res.Layers, layer)
+
+		res.Rule, res.RuleDir = resolveStep(dirs, res.Rule, res.RuleDir, d)
+		if !res.Rule.Enabled && res.DisabledAt == "" {
+			res.DisabledAt = d
+		}
+	}
+	return res
+}
+
+// Decide evaluates the rules for the entry at relPath (slash-separated,
+// relative to Root) as Walk would if it reached it, without reading the
+// filesystem: each directory on the way is decided in turn, and the first
+// one Walk wouldn't enter is returned instead, with its own RelPath.
+// modified_since, which needs the file's time, is not applied, and Entry is
+// nil.
+func (w *Walker) Decide(relPath string, isDir bool) Decision {
+	relPath = path.Clean(relPath)
+	rule, ruleDir := resolveStep(w.Dirs, DefaultRule, ".", ".")
+	if !rule.Enabled || relPath == "." {
+		d := Decision{Path: w.Root, RelPath: ".", Rule: rule, RuleDir: ruleDir, Include: rule.Enabled}
+		if !d.Include {
+			d.Reason = ReasonDisabled
+		}
+		return d
+	}
+
+	parts := strings.Split(relPath, "/")
+	excluded := notExcluded
+	for i := range parts {
+		rel := strings.Join(parts[:i+1], "/")
+		last := i == len(parts)-1
+		entryIsDir := isDir || !last
+		if w.SkipPaths[rel] || checkPatternMatch(rel, entryIsDir, w.SystemExcludes) {
+			return Decision{Path: filepath.Join(w.Root, filepath.FromSlash(rel)), RelPath: rel, Rule: rule, RuleDir: ruleDir, Reason: ReasonSystem}
+		}
+		d := w.decide(rel, entryIsDir, nil, rule, ruleDir, excluded)
+		if last || !d.Include {
+			return d
+		}
+		excluded = w.childExclusion(excluded, rel, rule)
+		rule, ruleDir = resolveStep(w.Dirs, rule, ruleDir, rel)
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,115 @@
+package walker
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+
+	"github.com/monochromegane/go-gitignore"
+)
+
+func TestResolve(t *testing.T) {
+	dirs := map[string]config.DirRule{
+		".":               {Enabled: true, Extensions: []string{"go"}},
+		"internal":        {Enabled: true, Extensions: []string{"go", "md"}},
+		"internal/legacy": {Enabled: false},
+	}
+
+	res := Resolve(dirs, "internal/scanner")
+	var layers []string
+	for _, l := range res.Layers {
+		layers = append(layers, l.Dir)
+	}
+	if !reflect.DeepEqual(layers, []string{".", "internal", "internal/scanner"}) {
+		t.Errorf("Unexpected layers %q", layers)
+	}
+	if res.Layers[2].Rule != nil {
+		t.Error("Expected internal/scanner to have no rule of its own")
+	}
+	if res.RuleDir != "internal" || !reflect.DeepEqual(res.Rule, dirs["internal"]) || res.DisabledAt != "" {
+		t.Errorf("Expected the internal rule to apply, got %+v from %q", res.Rule, res.RuleDir)
+	}
+
+	res = Resolve(dirs, "internal/legacy/old")
+	if res.DisabledAt != "internal/legacy" {
+		t.Errorf("Expected internal/legacy to stop the walk, got %q", res.DisabledAt)
+	}
+
+	// Without a root rule the walker's defaults apply
+	res = Resolve(map[string]config.DirRule{}, "src")
+	if res.RuleDir != "" || !reflect.DeepEqual(res.Rule, DefaultRule) {
+		t.Errorf("Expected the default rule, got %+v from %q", res.Rule, res.RuleDir)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	root := filepath.FromSlash("/proj")
+	w := &Walker{
+		Root: root,
+		Dirs: map[string]config.DirRule{
+			".": {
+				Enabled:           true,
+				Extensions:        []string{"go", "md"},
+				ExcludeExtensions: []string{"md"},
+				Exclude:           []string{"secret.go"},
+				Include:           []string{"keep.tmp.go", "Makefile", "secret.go"},
+				ExcludeDirs:       []string{"testdata"},
+			},
+			"vendor": {Enabled: false},
+			"logs":   {Enabled: true, IgnoreGit: true},
+		},
+		Matcher:        gitignore.NewGitIgnoreFromReader(root, strings.NewReader("gen/\nlogs/\n*.tmp.go\n")),
+		MaxDepth:       -1,
+		SkipArtifacts:  true,
+		SystemExcludes: []string{".git/"},
+		Only:           []string{"*", "*/*", "*/*/*"},
+	}
+
+	tests := []struct {
+		path   string
+		isDir  bool
+		reason string // "" when included
+		at     string // the entry decided, when not path itself
+	}{
+		// Excludes win over include patterns
+		{"secret.go", false, ReasonExcluded, ""},
+		// Include patterns win over extension rules and .gitignore
+		{"Makefile", false, "", ""},
+		{"keep.tmp.go", false, "", ""},
+		{"x.tmp.go", false, ReasonGitignored, ""},
+		{"gen/other.go", false, ReasonGitignored, "gen"},
+		// A rule's ignore_git opts its directory out of .gitignore
+		{"logs/app.go", false, "", ""},
+		// Disabled rules stop the walk at their directory
+		{"vendor/lib.go", false, ReasonDisabled, "vendor"},
+		{"testdata/case.go", false, ReasonDirExcluded, "testdata"},
+		{"dist/app.go", false, ReasonArtifact, "dist"},
+		{"app.min.js", false, ReasonArtifact, ""},
+		// Excluded extensions are checked before allowed ones
+		{"README.md", false, ReasonExtExcluded, ""},
+		{"notes.txt", false, ReasonExtNotAllowed, ""},
+		{"a/b/c/deep.go", false, ReasonNotSelected, ""},
+		{".git/config", false, ReasonSystem, ".git"},
+		{"src/main.go", false, "", ""},
+		{"src", true, "", ""},
+	}
+	for _, tt := range tests {
+		d := w.Decide(tt.path, tt.isDir)
+		at := tt.at
+		if at == "" {
+			at = tt.path
+		}
+		if d.RelPath != at || d.Include != (tt.reason == "") || d.Reason != tt.reason {
+			t.Errorf("Decide(%q): expected %q at %q, got include=%v reason=%q at %q", tt.path, tt.reason, at, d.Include, d.Reason, d.RelPath)
+		}
+	}
+
+	// The root rule can disable everything
+	w.Dirs = map[string]config.DirRule{".": {Enabled: false}}
+	if d := w.Decide("main.go", false); d.Include || d.Reason != ReasonDisabled {
+		t.Errorf("Expected a disabled root to skip everything, got %+v", d)
+	}
+}
@@ -0,0 +1,715 @@
+package walker
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+	"github.com/JohnEsleyer/textify/internal/ignore"
+
+	"github.com/monochromegane/go-gitignore"
+)
+
+// Skip reasons reported in Decision.Reason.
+const (
+	ReasonDisabled      = "disabled"
+	ReasonExcluded      = "excluded"
+	ReasonGitignored    = "gitignored"
+	ReasonExtExcluded   = "excluded extension"
+	ReasonExtNotAllowed = "extension not allowed"
+	ReasonTooOld        = "too old"
+	ReasonMaxDepth      = "max depth"
+	ReasonArtifact      = "build artifact"
+	ReasonVendored      = "vendored"
+	ReasonNotSelected   = "not selected"
+	ReasonDirExcluded   = "excluded directory"
+	ReasonUnchanged     = "unchanged"
+	ReasonOtherLanguage = "other language"
+	ReasonExportIgnore  = "export-ignore"
+	ReasonSymlinkedDir  = "symlinked directory"
+	ReasonSymlinkLoop   = "symlink loop"
+
+	// ReasonSystem is only reported by Decide; Walk doesn't report entries
+	// that system excludes hide.
+	ReasonSystem = "system exclude"
+)
+
+// Build artifacts skipped by default, since they often slip past .gitignore
+// in throwaway projects. They can be kept with include_artifacts or an
+// include pattern.
+var (
+	artifactDirs     = []string{"dist", "build", ".next"}
+	artifactPatterns = []string{"*.map", "*.min.js", "*.min.css", "*.bundle.js"}
+)
+
+// wellKnownFiles are name patterns of project files that carry context but
+// no extension an extensions list would name. They are allowed under any
+// extensions list while IncludeWellKnown is set.
+var wellKnownFiles = []string{"Dockerfile", "Makefile", ".gitignore", ".dockerignore", "*.env.example", "LICENSE", "Procfile", "Caddyfile"}
+
+// Decision is the outcome of evaluating the rules for a single entry.
+type Decision struct {
+	// Path is the entry's absolute path; RelPath is relative to the root,
+	// always with forward slashes.
+	Path    string
+	RelPath string
+
+	Entry fs.DirEntry
+
+	// Rule is the effective rule of the directory containing the entry, and
+	// RuleDir is the key of that rule in the config ("." for the root).
+	Rule    config.DirRule
+	RuleDir string
+
+	// Include is true for files that passed every rule and for directories
+	// that will be descended into.
+	Include bool
+
+	// Reason explains why an entry was skipped. Empty when Include is true.
+	Reason string
+
+	// Forced is true when the entry matched the rule's include patterns.
+	Forced bool
+
+	// Matched lists the patterns of Rule that matched the entry: its exclude
+	// patterns when Reason is ReasonExcluded, otherwise the include patterns
+	// that forced it. Include patterns aren't tried on excluded entries.
+	Matched []string
+}
+
+// Visitor receives the walker's decisions. Directories are reported before
+// their contents, and siblings are reported in name order (see NameLess).
+type Visitor interface {
+	OnDir(d Decision)
+	OnFile(d Decision)
+}
+
+// Walker evaluates the rule pipeline for every entry under Root exactly once
+// and reports each decision to its visitors.
+type Walker struct {
+	Root    string
+	Dirs    map[string]config.DirRule
+	Matcher gitignore.IgnoreMatcher
+
+	// FS, if set, holds the tree instead of the directory at Root, which
+	// then only names the entries' Path. Symlinks are never followed in it.
+	FS fs.FS
+
+	// ModifiedSince skips files last modified before it, unless zero.
+	ModifiedSince time.Time
+
+	// MaxDepth limits how deep files are included, counted from the root:
+	// 0 means root files only. Negative means unlimited. Per-rule max_depth
+	// limits also apply; whichever is stricter wins.
+	MaxDepth int
+
+	// Only, if not empty, restricts files to those matching one of its
+	// paths or patterns.
+	Only []string
+
+	// Languages, if not nil, restricts files to these extensions
+	// (lowercase), those of the configured languages.
+	Languages map[string]bool
+
+	// Changed, if not nil, restricts files to the relative paths it holds,
+	// such as the files git reports as changed since a ref.
+	Changed map[string]bool
+
+	// SkipArtifacts leaves out build artifacts (see artifactDirs and
+	// artifactPatterns) unless they are force-included.
+	SkipArtifacts bool
+
+	// SkipVendored prunes config.VendoredDirs unless include patterns reach
+	// into them or they have a rule of their own.
+	SkipVendored bool
+
+	// IncludeWellKnown lets wellKnownFiles past the rules' extensions lists.
+	IncludeWellKnown bool
+
+	// ExcludeDirs are directory names pruned at any depth, along with each
+	// rule's own exclude_dirs.
+	ExcludeDirs []string
+
+	// IncludeOverridesDirExcludes lets include patterns reach inside
+	// directories pruned by ExcludeDirs. Only forced files are kept there.
+	IncludeOverridesDirExcludes bool
+
+	// SystemExcludes are gitignore-style patterns for entries that are never
+	// reported, checked before any rule.
+	SystemExcludes []string
+
+	// AlwaysInclude are directories, relative to Root with forward slashes,
+	// whose subtrees .gitignore doesn't apply to. Every other rule still does.
+	AlwaysInclude []string
+
+	// SkipPaths are relative paths that are never reported (e.g., the cache
+	// file), whatever the system excludes say.
+	SkipPaths map[string]bool
+
+	// ExportIgnore skips what the .gitattributes files mark export-ignore,
+	// unless it is force-included. attrs caches their rules by directory.
+	ExportIgnore bool
+	attrs        map[string][]attrRule
+
+	// FollowSymlinks walks symlinked directories as if they were the
+	// directories they point to, under the link's path. Without it they are
+	// skipped. Links back into a directory being walked are always skipped.
+	FollowSymlinks bool
+
+	// NaturalSort orders the entries of each directory with NameLess's
+	// natural order instead of by bytes.
+	NaturalSort bool
+}
+
+// New returns a walker for root using the config's rules and the root's
+// ignore files (see ignore.NewMatcher).
+func New(root string, cfg *config.Config) *Walker {
+	w := newWalker(root, cfg)
+	w.Matcher = ignore.NewMatcher(root, cfg.EffectiveIgnoreSources())
+	return w
+}
+
+// NewFS is New for a tree held in fsys, such as an fstest.MapFS, whose "."
+// is the project root; root only names it. Its ignore files are read from
+// fsys too (see ignore.NewMatcherFS).
+func NewFS(fsys fs.FS, root string, cfg *config.Config) *Walker {
+	w := newWalker(root, cfg)
+	w.FS = fsys
+	w.Matcher = ignore.NewMatcherFS(fsys, root, cfg.EffectiveIgnoreSources())
+	return w
+}
+
+func newWalker(root string, cfg *config.Config) *Walker {
+	// Load already rejected unknown languages
+	languages, _ := cfg.LanguageExtensions()
+	return &Walker{
+		Root:     root,
+		Dirs:     cfg.Dirs,
+		MaxDepth: -1,
+		Only:     cfg.Only,
+
+		Languages: languages,
+
+		SkipArtifacts: !cfg.IncludeArtifacts,
+		SkipVendored:  !cfg.IncludeVendored,
+		ExportIgnore:  cfg.UseExportIgnore,
+
+		IncludeWellKnown: cfg.EffectiveIncludeWellKnown(),
+
+		FollowSymlinks: cfg.FollowSymlinks,
+		NaturalSort:    cfg.TreeSort == config.TreeSortNatural,
+
+		ExcludeDirs:                 cfg.ExcludeDirs,
+		IncludeOverridesDirExcludes: cfg.IncludeOverridesDirExcludes,
+
+		SystemExcludes: cfg.EffectiveSystemExcludes(),
+		AlwaysInclude:  cfg.AlwaysIncludePaths(),
+	}
+}
+
+// dirFrame is one directory on the walk stack.
+type dirFrame struct {
+	fullPath string
+	rule     config.DirRule
+	ruleDir  string
+	entries  []fs.DirEntry
+	next     int
+
+	// excluded is set inside a directory that was entered only because
+	// include patterns may reach into it.
+	excluded exclusion
+
+	// realPath is the directory's path with symlinks resolved, set only
+	// with FollowSymlinks.
+	realPath string
+}
+
+// exclusion is why only forced files are kept inside a directory.
+type exclusion int
+
+const (
+	notExcluded exclusion = iota
+
+	// vendoredExclusion is inside a vendored folder that include patterns
+	// naming paths in it reach into.
+	vendoredExclusion
+
+	// dirExclusion is inside a folder named by exclude_dirs, entered with
+	// include_overrides_dir_excludes.
+	dirExclusion
+)
+
+// Walk traverses the tree depth-first using an explicit stack rather than
+// recursion, so pathologically deep trees cannot exhaust the goroutine stack.
+// Entries are visited in the same order a recursive walk would visit them.
+func (w *Walker) Walk(visitors ...Visitor) error {
+	return w.WalkContext(context.Background(), visitors...)
+}
+
+// WalkContext is Walk, stopping with ctx's error before the next entry once
+// ctx is done. The visitors have seen every entry up to that point.
+func (w *Walker) WalkContext(ctx context.Context, visitors ...Visitor) error {
+	// Initial rule (Root ".")
+	var stack []*dirFrame
+
+	// open counts the frames on the stack by realPath, so a link back to any
+	// of them is caught, even one reached through other links
+	open := make(map[string]int)
+	push := func(f *dirFrame) {
+		stack = append(stack, f)
+		open[f.realPath]++
+	}
+
+	frame, err := w.enterDir(w.Root, DefaultRule, ".")
+	if err != nil {
+		return err
+	}
+	if frame != nil {
+		push(frame)
+	}
+
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		top := stack[len(stack)-1]
+		if top.next >= len(top.entries) {
+			stack = stack[:len(stack)-1]
+			if open[top.realPath]--; open[top.realPath] == 0 {
+				delete(open, top.realPath)
+			}
+			continue
+		}
+		entry := top.entries[top.next]
+		top.next++
+
+		entryPath := filepath.Join(top.fullPath, entry.Name())
+		relEntryPath := RelSlash(w.Root, entryPath)
+
+		// A symlink to a directory is a directory to the rules; links to
+		// files are read through, like any file
+		isDir, linkedDir := entry.IsDir(), false
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if info, err := w.stat(relEntryPath); err == nil && info.IsDir() {
+				isDir, linkedDir = true, true
+			}
+		}
+
+		// System excludes are invisible to visitors
+		if w.SkipPaths[relEntryPath] || checkPatternMatch(relEntryPath, isDir, w.SystemExcludes) {
+			continue
+		}
+
+		d := w.decide(relEntryPath, isDir, entry.Info, top.rule, top.ruleDir, top.excluded)
+		d.Path, d.Entry = entryPath, entry
+		if d.Include && linkedDir {
+			if !w.followSymlinks() {
+				d.Include, d.Reason = false, ReasonSymlinkedDir
+			} else if top.loopsBack(entryPath, open) {
+				d.Include, d.Reason = false, ReasonSymlinkLoop
+			}
+		}
+		if !isDir {
+			for _, v := range visitors {
+				v.OnFile(d)
+			}
+			continue
+		}
+
+		for _, v := range visitors {
+			v.OnDir(d)
+		}
+		if !d.Include {
+			continue
+		}
+
+		child, err := w.enterDir(entryPath, top.rule, top.ruleDir)
+		if err != nil {
+			return err
+		}
+		if child != nil {
+			child.excluded = w.childExclusion(top.excluded, relEntryPath, top.rule)
+			push(child)
+		}
+	}
+	return nil
+}
+
+// enterDir resolves the rule for a directory and reads its entries. It returns
+// nil if the directory is disabled.
+func (w *Walker) enterDir(fullPath string, currentRule config.DirRule, ruleDir string) (*dirFrame, error) {
+	// Check if the directory we are currently IN has a specific rule
+	currentRule, ruleDir = resolveStep(w.Dirs, currentRule, ruleDir, RelSlash(w.Root, fullPath))
+
+	// 1. CHECK ENABLED STATUS
+	// If the directory is explicitly disabled in config, stop everything here.
+	if !currentRule.Enabled {
+		return nil, nil // Skip this directory and its children
+	}
+
+	entries, err := w.readDir(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if w.NaturalSort {
+		// ReadDir already sorts by bytes
+		sort.SliceStable(entries, func(i, j int) bool {
+			return NameLess(entries[i].Name(), entries[j].Name(), true)
+		})
+	}
+	frame := &dirFrame{fullPath: fullPath, rule: currentRule, ruleDir: ruleDir, entries: entries}
+	if w.followSymlinks() {
+		if frame.realPath, err = filepath.EvalSymlinks(fullPath); err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
+
+// followSymlinks reports whether symlinked directories are walked, which
+// they never are in FS.
+func (w *Walker) followSymlinks() bool {
+	return w.FollowSymlinks && w.FS == nil
+}
+
+// readDir reads the directory at fullPath, from FS if set.
+func (w *Walker) readDir(fullPath string) ([]fs.DirEntry, error) {
+	if w.FS != nil {
+		return fs.ReadDir(w.FS, RelSlash(w.Root, fullPath))
+	}
+	return os.ReadDir(fullPath)
+}
+
+// stat returns the entry at relPath, following symlinks, from FS if set.
+func (w *Walker) stat(relPath string) (fs.FileInfo, error) {
+	if w.FS != nil {
+		return fs.Stat(w.FS, relPath)
+	}
+	return os.Stat(filepath.Join(w.Root, filepath.FromSlash(relPath)))
+}
+
+// readFile reads the file at relPath, from FS if set.
+func (w *Walker) readFile(relPath string) ([]byte, error) {
+	if w.FS != nil {
+		return fs.ReadFile(w.FS, relPath)
+	}
+	return os.ReadFile(filepath.Join(w.Root, filepath.FromSlash(relPath)))
+}
+
+// loopsBack reports whether the symlinked directory at linkPath points to a
+// directory being walked, one of open, or to one holding the frame's
+// directory, so that following it would walk the same files forever.
+func (f *dirFrame) loopsBack(linkPath string, open map[string]int) bool {
+	target, err := filepath.EvalSymlinks(linkPath)
+	if err != nil {
+		return true
+	}
+	return open[target] > 0 || strings.HasPrefix(f.realPath, strings.TrimSuffix(target, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// decide applies the rules to a single entry, given the rule of the
+// directory holding it. inExcluded is set for entries inside a directory
+// pruned by exclude_dirs or as vendored that include patterns reach into.
+// info is only called for modified_since; if it is nil, that filter isn't
+// applied.
+func (w *Walker) decide(relEntryPath string, isDir bool, info func() (fs.FileInfo, error), currentRule config.DirRule, ruleDir string, inExcluded exclusion) Decision {
+	entryPath := filepath.Join(w.Root, filepath.FromSlash(relEntryPath))
+	d := Decision{Path: entryPath, RelPath: relEntryPath, Rule: currentRule, RuleDir: ruleDir}
+	skip := func(reason string) Decision {
+		d.Reason = reason
+		return d
+	}
+	name := path.Base(relEntryPath)
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+
+	// -----------------------------
+	// 1. USER EXCLUDES (Specific Files/Patterns)
+	// Priority: High. If excluded here, it is skipped regardless of include rules.
+	// -----------------------------
+	if matched := matchingPatterns(relEntryPath, isDir, currentRule.Exclude); len(matched) > 0 {
+		d.Matched = matched
+		return skip(ReasonExcluded)
+	}
+
+	// -----------------------------
+	// 2. FORCE INCLUDE (Specific Files/Patterns)
+	// Priority: Overrides .gitignore and extension rules
+	// -----------------------------
+	d.Matched = matchingPatterns(relEntryPath, isDir, currentRule.Include)
+	isForced := len(d.Matched) > 0
+	d.Forced = isForced
+
+	// Directories excluded by name are pruned wherever they appear. Include
+	// patterns only reach inside them with include_overrides_dir_excludes,
+	// and then only the files they match are kept.
+	if isDir && (inExcluded == dirExclusion || w.excludesDir(name, currentRule)) {
+		if !w.IncludeOverridesDirExcludes || (!isForced && !couldMatchBelow(relEntryPath, currentRule.Include)) {
+			return skip(ReasonDirExcluded)
+		}
+	}
+	// Vendored folders are pruned at once, unless include patterns naming
+	// paths inside them reach in; then only the files they match are kept
+	if isDir && !isForced && (inExcluded == vendoredExclusion || w.vendored(relEntryPath)) && !couldMatchBelow(relEntryPath, anchored(currentRule.Include)) {
+		return skip(ReasonVendored)
+	}
+	if !isDir && inExcluded != notExcluded && !isForced {
+		if inExcluded == vendoredExclusion {
+			return skip(ReasonVendored)
+		}
+		return skip(ReasonDirExcluded)
+	}
+
+	if isDir {
+		// Check if this specific SUBDIRECTORY has a rule that disables it
+		subRule, hasRule := w.Dirs[relEntryPath]
+		if hasRule && !subRule.Enabled {
+			return skip(ReasonDisabled)
+		}
+
+		// If not forced, respect gitignore for directories, unless the
+		// directory's own rule or the current one opts out of it. The way
+		// to an always-included directory is never pruned.
+		ignoreGit := currentRule.IgnoreGit || (hasRule && subRule.IgnoreGit) ||
+			w.alwaysIncluded(relEntryPath) || w.leadsToAlwaysIncluded(relEntryPath)
+		if !isForced && !ignoreGit && w.Matcher.Match(entryPath, true) {
+			return skip(ReasonGitignored)
+		}
+
+		// An export-ignored directory is left out whole, as git archive
+		// leaves it out
+		if !isForced && w.ExportIgnore && w.exportIgnored(relEntryPath, true) {
+			return skip(ReasonExportIgnore)
+		}
+
+		// Artifact folders are skipped unless forced or given their own rule
+		if !isForced && !hasRule && w.SkipArtifacts && contains(artifactDirs, name) {
+			return skip(ReasonArtifact)
+		}
+
+		// Prune directories whose contents would be deeper than allowed
+		if w.exceedsDepth(relEntryPath, ruleDir, currentRule) {
+			return skip(ReasonMaxDepth)
+		}
+
+		d.Include = true
+		return d
+	}
+
+	// -----------------------------
+	// FILE PROCESSING LOGIC
+	// -----------------------------
+
+	// 3. SELECTION (config 'only')
+	if len(w.Only) > 0 && !matchesPath(relEntryPath, w.Only) {
+		return skip(ReasonNotSelected)
+	}
+	if w.Changed != nil && !w.Changed[relEntryPath] {
+		return skip(ReasonUnchanged)
+	}
+
+	// 4. GITIGNORE CHECK
+	// If not forced, check if ignored by git
+	if !isForced && !currentRule.IgnoreGit && !w.alwaysIncluded(relEntryPath) && w.Matcher.Match(entryPath, false) {
+		return skip(ReasonGitignored)
+	}
+	if !isForced && w.ExportIgnore && w.exportIgnored(relEntryPath, false) {
+		return skip(ReasonExportIgnore)
+	}
+
+	// 5. BUILD ARTIFACTS (source maps, minified and bundled files)
+	if !isForced && w.SkipArtifacts && checkPatternMatch(relEntryPath, false, artifactPatterns) {
+		return skip(ReasonArtifact)
+	}
+
+	// 6. EXTENSION EXCLUDES (Blocklist)
+	if !isForced && len(currentRule.ExcludeExtensions) > 0 {
+		if matchesExtension(name, ext, currentRule.ExcludeExtensions) {
+			return skip(ReasonExtExcluded)
+		}
+	}
+
+	// 7. EXTENSION INCLUDES (Allowlist)
+	// If Extensions list is provided, file MUST match one of them (unless
+	// forced or well-known)
+	if !isForced && len(currentRule.Extensions) > 0 {
+		if !matchesExtension(name, ext, currentRule.Extensions) && !(w.IncludeWellKnown && wellKnown(name)) {
+			return skip(ReasonExtNotAllowed)
+		}
+	}
+
+	// 8. LANGUAGES
+	if !isForced && w.Languages != nil && !w.Languages[strings.ToLower(ext)] {
+		return skip(ReasonOtherLanguage)
+	}
+
+	// 9. MODIFIED SINCE
+	// Only files are filtered; directories are always traversed
+	if !w.ModifiedSince.IsZero() && info != nil {
+		fi, err := info()
+		if err != nil || fi.ModTime().Before(w.ModifiedSince) {
+			return skip(ReasonTooOld)
+		}
+	}
+
+	d.Include = true
+	return d
+}
+
+// wellKnown reports whether a file name is one of wellKnownFiles.
+func wellKnown(name string) bool {
+	for _, pattern := range wellKnownFiles {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// excludesDir reports whether a directory name is in the global or the
+// rule's exclude_dirs.
+func (w *Walker) excludesDir(name string, rule config.DirRule) bool {
+	return contains(w.ExcludeDirs, name) || contains(rule.ExcludeDirs, name)
+}
+
+// childExclusion returns the exclusion inside the directory relDir, entered
+// from a directory with the given exclusion and rule.
+func (w *Walker) childExclusion(parent exclusion, relDir string, rule config.DirRule) exclusion {
+	if parent == dirExclusion || w.excludesDir(path.Base(relDir), rule) {
+		return dirExclusion
+	}
+	if parent == vendoredExclusion || w.vendored(relDir) {
+		return vendoredExclusion
+	}
+	return notExcluded
+}
+
+// vendored reports whether SkipVendored prunes the directory relDir: it is
+// named in config.VendoredDirs and has no rule of its own, which only a user
+// writes.
+func (w *Walker) vendored(relDir string) bool {
+	if !w.SkipVendored || !contains(config.VendoredDirs, path.Base(relDir)) {
+		return false
+	}
+	_, hasRule := w.Dirs[relDir]
+	return !hasRule
+}
+
+// anchored returns the patterns that name a path rather than a bare file
+// name, which would otherwise match inside every vendored folder.
+func anchored(patterns []string) []string {
+	var out []string
+	for _, p := range patterns {
+		if strings.Contains(strings.TrimSuffix(filepath.ToSlash(p), "/"), "/") {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// alwaysIncluded reports whether relPath is one of the AlwaysInclude
+// directories or inside one.
+func (w *Walker) alwaysIncluded(relPath string) bool {
+	for _, dir := range w.AlwaysInclude {
+		if dir == "." || relPath == dir || strings.HasPrefix(relPath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// leadsToAlwaysIncluded reports whether the directory relDir holds one of
+// the AlwaysInclude directories, so it must be entered to reach it.
+func (w *Walker) leadsToAlwaysIncluded(relDir string) bool {
+	for _, dir := range w.AlwaysInclude {
+		if strings.HasPrefix(dir, relDir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// exceedsDepth reports whether the children of directory relDir would be
+// deeper than the global MaxDepth or the rule's own max_depth, which counts
+// from the rule's directory.
+func (w *Walker) exceedsDepth(relDir, ruleDir string, rule config.DirRule) bool {
+	childDepth := strings.Count(relDir, "/") + 1
+	if w.MaxDepth >= 0 && childDepth > w.MaxDepth {
+		return true
+	}
+	if rule.MaxDepth != nil {
+		if ruleDir != "." {
+			childDepth -= strings.Count(ruleDir, "/") + 1
+		}
+		if childDepth > *rule.MaxDepth {
+			return true
+		}
+	}
+	return false
+}
+
+// RelSlash returns target relative to root using forward slashes, the form
+// used for every path in the output and for all config matching.
+func RelSlash(root, target string) string {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchesPath checks if a relative path matches any of the glob patterns.
+// Unlike checkPatternMatch, patterns never match a bare file name, so
+// "main.go" selects only the root main.go. A pattern equal to the path
+// matches it too, so the paths pick saves select files whose names hold
+// glob characters, such as "[slug].tsx".
+func matchesPath(relPath string, patterns []string) bool {
+	for _, p := range patterns {
+		p = filepath.ToSlash(p)
+		if p == relPath {
+			return true
+		}
+		if matched, _ := path.Match(p, relPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesExtension reports whether a file matches an extensions or
+// exclude_extensions list. A plain entry (go) is compared to the file's
+// extension, ext, without its dot. An entry starting with a dot or holding a
+// glob character is a suffix pattern matched against the whole file name, so
+// .test.ts and *.min.js pick out compound suffixes that the simple extension
+// (ts, js) can't tell apart.
+func matchesExtension(name, ext string, entries []string) bool {
+	for _, e := range entries {
+		if !strings.HasPrefix(e, ".") && !strings.ContainsAny(e, "*?[") {
+			if e == ext {
+				return true
+			}
+			continue
+		}
+		if !strings.HasPrefix(e, "*") {
+			e = "*" + e
+		}
+		if matched, _ := path.Match(e, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,815 @@
+package walker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/JohnEsleyer/textify/internal/config"
+)
+
+// recorder is a visitor that records every decision as "path:reason" (or
+// "path:+" when included).
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) OnDir(d Decision)  { r.record("dir", d) }
+func (r *recorder) OnFile(d Decision) { r.record("file", d) }
+
+func (r *recorder) record(kind string, d Decision) {
+	outcome := "+"
+	if !d.Include {
+		outcome = d.Reason
+	}
+	r.events = append(r.events, kind+" "+d.RelPath+": "+outcome)
+}
+
+func TestWalkReportsEveryDecision(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"build", "docs", "src/nested", "vendor"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{"README.md", "build/out.go", "docs/guide.md", "notes.txt", "src/main.go", "src/nested/util.go", "vendor/lib.go"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("build/\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "textify.yaml"), []byte(""), 0644)
+
+	cfg := &config.Config{
+		Dirs: map[string]config.DirRule{
+			".":      {Enabled: true, Extensions: []string{"go", "md"}, Exclude: []string{"README.md", ".gitignore"}},
+			"vendor": {Enabled: false},
+		},
+	}
+
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	// Golden decision log: system excludes (textify.yaml) are never reported
+	expected := []string{
+		"file .gitignore: excluded",
+		"file README.md: excluded",
+		"dir build: gitignored",
+		"dir docs: +",
+		"file docs/guide.md: +",
+		"file notes.txt: extension not allowed",
+		"dir src: +",
+		"file src/main.go: +",
+		"dir src/nested: +",
+		"file src/nested/util.go: +",
+		"dir vendor: disabled",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestBuildArtifactsSkippedByDefault(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"dist", ".next", "web"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{"dist/app.js", ".next/page.js", "web/app.js", "web/app.js.map", "web/lib.min.js", "web/vendor.min.js"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	cfg := &config.Config{
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Include: []string{"vendor.min.js"}},
+		},
+	}
+
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"dir .next: build artifact",
+		"dir dist: build artifact",
+		"dir web: +",
+		"file web/app.js: +",
+		"file web/app.js.map: build artifact",
+		"file web/lib.min.js: build artifact",
+		"file web/vendor.min.js: +",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+
+	// include_artifacts turns the group off
+	cfg.IncludeArtifacts = true
+	rec = &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	for _, event := range rec.events {
+		if strings.HasSuffix(event, ReasonArtifact) {
+			t.Errorf("Expected no artifacts to be skipped, got %q", event)
+		}
+	}
+}
+
+func TestVendoredSkippedByDefault(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_vendored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"node_modules/left-pad", "vendor/github.com/pkg", "web/third_party", "Pods"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{"main.go", "node_modules/left-pad/index.js", "vendor/github.com/pkg/pkg.go", "vendor/github.com/pkg/pkg_test.go", "vendor/modules.txt", "web/third_party/lib.js", "Pods/Pod.swift"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	cfg := &config.Config{
+		Dirs: map[string]config.DirRule{
+			// A bare pattern doesn't reach into vendored folders; a path does
+			".":    {Enabled: true, Include: []string{"*.js", "vendor/modules.txt", "vendor/github.com/pkg/pkg.go"}},
+			"Pods": {Enabled: true},
+		},
+	}
+
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"dir Pods: +",
+		"file Pods/Pod.swift: +",
+		"file main.go: +",
+		"dir node_modules: vendored",
+		"dir vendor: +",
+		"dir vendor/github.com: +",
+		"dir vendor/github.com/pkg: +",
+		"file vendor/github.com/pkg/pkg.go: +",
+		"file vendor/github.com/pkg/pkg_test.go: vendored",
+		"file vendor/modules.txt: +",
+		"dir web: +",
+		"dir web/third_party: vendored",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+
+	// include_vendored turns the group off
+	cfg.IncludeVendored = true
+	rec = &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	for _, event := range rec.events {
+		if strings.HasSuffix(event, ReasonVendored) {
+			t.Errorf("Expected no vendored folders to be skipped, got %q", event)
+		}
+	}
+}
+
+func TestOnlyMatchesFullPaths(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "cmd"), 0755)
+	for _, file := range []string{"main.go", "cmd/main.go", "cmd/util.go", "cmd/[slug].go", "cmd/s.go"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	// pick saves literal paths, glob characters and all
+	cfg := &config.Config{
+		Only: []string{"main.go", "cmd/u*", "cmd/[slug].go"},
+		Dirs: map[string]config.DirRule{".": {Enabled: true}},
+	}
+
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"dir cmd: +",
+		"file cmd/[slug].go: +",
+		"file cmd/main.go: not selected",
+		"file cmd/s.go: +",
+		"file cmd/util.go: +",
+		"file main.go: +",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_languages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, file := range []string{"app.ts", "view.tsx", "main.go", "script.js", "README.md"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	cfg := &config.Config{
+		Languages: []string{"TypeScript"},
+		Dirs:      map[string]config.DirRule{".": {Enabled: true, Include: []string{"README.md"}}},
+	}
+
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"file README.md: +",
+		"file app.ts: +",
+		"file main.go: other language",
+		"file script.js: other language",
+		"file view.tsx: +",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestExtensionSuffixPatterns(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_extension_suffixes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, file := range []string{"app.ts", "app.test.ts", "lib.js", "lib.spec.js", "main.go", "notes.md"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	cfg := &config.Config{
+		Dirs: map[string]config.DirRule{".": {
+			Enabled:           true,
+			Extensions:        []string{".ts", "js", "go"},
+			ExcludeExtensions: []string{"*.test.ts", ".spec.js"},
+		}},
+	}
+
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"file app.test.ts: excluded extension",
+		"file app.ts: +",
+		"file lib.js: +",
+		"file lib.spec.js: excluded extension",
+		"file main.go: +",
+		"file notes.md: extension not allowed",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestWellKnownFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_well_known")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, file := range []string{"Dockerfile", "Makefile", ".env.example", "Jenkinsfile", "main.go", "notes.md"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	cfg := &config.Config{
+		Dirs: map[string]config.DirRule{".": {Enabled: true, Extensions: []string{"go"}}},
+	}
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"file .env.example: +",
+		"file Dockerfile: +",
+		"file Jenkinsfile: extension not allowed",
+		"file Makefile: +",
+		"file main.go: +",
+		"file notes.md: extension not allowed",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+
+	// Turned off, only the extensions list counts
+	off := false
+	cfg.IncludeWellKnown = &off
+	rec = &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	for _, event := range rec.events {
+		if event != "file main.go: +" && !strings.HasSuffix(event, ": extension not allowed") {
+			t.Errorf("Expected only main.go included, got %q", event)
+		}
+	}
+}
+
+func TestIgnoreGitPerDirectory(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_ignore_git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"generated/nested", "src"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{"generated/api.go", "generated/api.pb.go", "generated/nested/types.pb.go", "src/main.go", "src/main.pb.go"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("generated/\n*.pb.go\n"), 0644)
+
+	cfg := &config.Config{
+		Dirs: map[string]config.DirRule{
+			".":         {Enabled: true, Exclude: []string{".gitignore"}},
+			"generated": {Enabled: true, IgnoreGit: true},
+		},
+	}
+
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"file .gitignore: excluded",
+		"dir generated: +",
+		"file generated/api.go: +",
+		"file generated/api.pb.go: +",
+		"dir generated/nested: +",
+		"file generated/nested/types.pb.go: +",
+		"dir src: +",
+		"file src/main.go: +",
+		"file src/main.pb.go: gitignored",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestAlwaysIncludeDirs(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_always_include")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"docs/api", "gen/specs", "src"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{"docs/index.md", "docs/api/ref.md", "docs/draft.md", "gen/out.md", "gen/specs/api.md", "src/notes.md"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("docs/\ngen/\n*.md\n"), 0644)
+
+	cfg := &config.Config{
+		AlwaysIncludeDirs: []string{"docs", "./gen/specs/"},
+		Dirs: map[string]config.DirRule{
+			".": {Enabled: true, Exclude: []string{".gitignore", "docs/draft.md"}},
+		},
+	}
+
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	// gen/ is entered only to reach gen/specs; excludes still win
+	expected := []string{
+		"file .gitignore: excluded",
+		"dir docs: +",
+		"dir docs/api: +",
+		"file docs/api/ref.md: +",
+		"file docs/draft.md: excluded",
+		"file docs/index.md: +",
+		"dir gen: +",
+		"file gen/out.md: gitignored",
+		"dir gen/specs: +",
+		"file gen/specs/api.md: +",
+		"dir src: +",
+		"file src/notes.md: gitignored",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestPatternsUseForwardSlashes(t *testing.T) {
+	// Paths built with the native separator must match globs written with '/'
+	root := filepath.Join("project")
+	native := filepath.Join(root, "internal", "scanner", "scanner.go")
+	rel := RelSlash(root, native)
+	if rel != "internal/scanner/scanner.go" {
+		t.Fatalf("Expected a forward-slash relative path, got %q", rel)
+	}
+	if !checkPatternMatch(rel, false, []string{"internal/*/scanner.go"}) {
+		t.Error("Expected 'internal/*/scanner.go' to match")
+	}
+	if checkPatternMatch(rel, false, []string{"internal/*.go"}) {
+		t.Error("Expected '*' not to match across directories")
+	}
+}
+
+func TestExcludeDirs(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_exclude_dirs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"node_modules/lib", "web/node_modules/pkg", "py/__pycache__", "tools/.terraform"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{"node_modules/lib/index.js", "web/app.js", "web/node_modules/pkg/index.js", "web/node_modules/pkg/README.md", "py/__pycache__/mod.pyc", "tools/.terraform/state", "tools/main.tf"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	cfg := &config.Config{
+		ExcludeDirs: []string{"node_modules", "__pycache__"},
+		Dirs: map[string]config.DirRule{
+			".":     {Enabled: true, Include: []string{"web/node_modules/pkg/index.js"}},
+			"tools": {Enabled: true, ExcludeDirs: []string{".terraform"}},
+		},
+	}
+
+	// Include patterns can't resurrect anything inside excluded directories
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"dir node_modules: excluded directory",
+		"dir py: +",
+		"dir py/__pycache__: excluded directory",
+		"dir tools: +",
+		"dir tools/.terraform: excluded directory",
+		"file tools/main.tf: +",
+		"dir web: +",
+		"file web/app.js: +",
+		"dir web/node_modules: excluded directory",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+
+	// With the override, only the included file comes back
+	cfg.IncludeOverridesDirExcludes = true
+	rec = &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected = []string{
+		"dir node_modules: excluded directory",
+		"dir py: +",
+		"dir py/__pycache__: excluded directory",
+		"dir tools: +",
+		"dir tools/.terraform: excluded directory",
+		"file tools/main.tf: +",
+		"dir web: +",
+		"file web/app.js: +",
+		"dir web/node_modules: +",
+		"dir web/node_modules/pkg: +",
+		"file web/node_modules/pkg/README.md: excluded directory",
+		"file web/node_modules/pkg/index.js: +",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions with the override.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestSystemExcludes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{".git/objects", ".idea", "src"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{".git/config", ".git/objects/ab", ".idea/workspace.xml", "src/.DS_Store", "src/main.go"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	cfg := &config.Config{
+		Dirs:           map[string]config.DirRule{".": {Enabled: true}},
+		SystemExcludes: []string{".idea/", ".DS_Store"},
+	}
+
+	// Extra patterns add to the defaults
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{"dir src: +", "file src/main.go: +"}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+
+	// With the override, only the listed patterns apply
+	cfg.OverrideSystemExcludes = true
+	cfg.SystemExcludes = []string{".git/objects/"}
+	rec = &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected = []string{
+		"dir .git: +",
+		"file .git/config: +",
+		"dir .idea: +",
+		"file .idea/workspace.xml: +",
+		"dir src: +",
+		"file src/.DS_Store: +",
+		"file src/main.go: +",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestIgnoreSources(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_ignore_sources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"public", "docs", "src"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	for _, file := range []string{"README.md", "notes.log", "public/app.js", "public/vendor.js", "docs/guide.md", "src/debug.log", "src/main.go"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("public/\n*.log\n"), 0644)
+	// Docker's syntax: everything is ignored but the exceptions, and an
+	// exception reaches inside an ignored directory
+	os.WriteFile(filepath.Join(tempDir, ".dockerignore"), []byte("# build context\n*\n!src\n!/public/app.js\n!README.md\n"), 0644)
+
+	tests := []struct {
+		sources  []string
+		expected []string
+	}{
+		{nil, []string{
+			"file .dockerignore: +",
+			"file .gitignore: +",
+			"file README.md: +",
+			"dir docs: +",
+			"file docs/guide.md: +",
+			"file notes.log: gitignored",
+			"dir public: gitignored",
+			"dir src: +",
+			"file src/debug.log: gitignored",
+			"file src/main.go: +",
+		}},
+		{[]string{config.IgnoreDockerignore, config.IgnoreGitignore}, []string{
+			"file .dockerignore: gitignored",
+			"file .gitignore: gitignored",
+			"file README.md: +",
+			"dir docs: gitignored",
+			"file notes.log: gitignored",
+			"dir public: +",
+			"file public/app.js: +",
+			"file public/vendor.js: gitignored",
+			"dir src: +",
+			"file src/debug.log: +",
+			"file src/main.go: +",
+		}},
+		{[]string{config.IgnoreGitignore, config.IgnoreDockerignore}, []string{
+			"file .dockerignore: gitignored",
+			"file .gitignore: gitignored",
+			"file README.md: +",
+			"dir docs: gitignored",
+			"file notes.log: gitignored",
+			"dir public: gitignored",
+			"dir src: +",
+			"file src/debug.log: gitignored",
+			"file src/main.go: +",
+		}},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{
+			IgnoreSources: tt.sources,
+			Dirs:          map[string]config.DirRule{".": {Enabled: true}},
+		}
+		rec := &recorder{}
+		if err := New(tempDir, cfg).Walk(rec); err != nil {
+			t.Fatalf("Walk failed: %v", err)
+		}
+		if !reflect.DeepEqual(rec.events, tt.expected) {
+			t.Errorf("Unexpected decisions with %v.\nExpected: %q\nGot:      %q", tt.sources, tt.expected, rec.events)
+		}
+	}
+}
+
+func TestParentGitignores(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_parent_gitignores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// The scan root is two levels below the repository root
+	repo := filepath.Join(tempDir, "repo")
+	root := filepath.Join(repo, "services", "api")
+	for _, dir := range []string{filepath.Join(repo, ".git"), filepath.Join(root, "coverage"), filepath.Join(root, "tmp")} {
+		os.MkdirAll(dir, 0755)
+	}
+	for _, file := range []string{"main.go", "model.gen.go", "keep.gen.go", "debug.log", "coverage/out.txt", "tmp/scratch.go"} {
+		os.WriteFile(filepath.Join(root, file), []byte("x"), 0644)
+	}
+	// Patterns are relative to the folder of their file, and the deepest
+	// file that matches decides. A .gitignore outside the repository never
+	// applies.
+	os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("main.go\n"), 0644)
+	os.WriteFile(filepath.Join(repo, ".gitignore"), []byte("coverage/\n*.gen.go\n/services/api/tmp/\n*.log\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "services", ".gitignore"), []byte("!keep.gen.go\n"), 0644)
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("!debug.log\n"), 0644)
+
+	expected := []string{
+		"file .gitignore: +",
+		"dir coverage: gitignored",
+		"file debug.log: +",
+		"file keep.gen.go: +",
+		"file main.go: +",
+		"file model.gen.go: gitignored",
+		"dir tmp: gitignored",
+	}
+	cfg := &config.Config{Dirs: map[string]config.DirRule{".": {Enabled: true}}}
+	rec := &recorder{}
+	if err := New(root, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestBackslashRules(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_backslash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.MkdirAll(filepath.Join(tempDir, "internal", "scanner", "testdata"), 0755)
+	for _, file := range []string{"internal/scanner/scanner.go", "internal/scanner/testdata/input.go", "internal/walk.go"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+	configPath := filepath.Join(tempDir, config.FileName)
+	data := "dirs:\n  .:\n    enabled: true\n  'internal\\scanner':\n    enabled: true\n    exclude: ['testdata\\']\n"
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	expected := []string{
+		"dir internal: +",
+		"dir internal/scanner: +",
+		"file internal/scanner/scanner.go: +",
+		"dir internal/scanner/testdata: excluded",
+		"file internal/walk.go: +",
+	}
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}
+
+func TestTreeSort(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "walker_test_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, file := range []string{"File10.go", "file2.go", "File1.go", "b.go", "a.go", "v1.10.txt", "v1.9.txt"} {
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+
+	tests := []struct {
+		sort     string
+		expected []string
+	}{
+		{"", []string{"File1.go", "File10.go", "a.go", "b.go", "file2.go", "v1.10.txt", "v1.9.txt"}},
+		{config.TreeSortNatural, []string{"a.go", "b.go", "File1.go", "file2.go", "File10.go", "v1.9.txt", "v1.10.txt"}},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{
+			TreeSort: tt.sort,
+			Dirs:     map[string]config.DirRule{".": {Enabled: true}},
+		}
+		rec := &recorder{}
+		if err := New(tempDir, cfg).Walk(rec); err != nil {
+			t.Fatalf("Walk failed: %v", err)
+		}
+		var got []string
+		for _, event := range rec.events {
+			got = append(got, strings.TrimSuffix(strings.TrimPrefix(event, "file "), ": +"))
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("tree_sort %q: expected %q, got %q", tt.sort, tt.expected, got)
+		}
+	}
+}
+
+func TestNameLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"file2", "file10", true},
+		{"file10", "file2", false},
+		{"File2", "file10", true},
+		{"readme", "README", false},
+		{"README", "readme", true},
+		{"a01", "a1", true},
+		{"a1", "a01", false},
+		{"a1b", "a1", false},
+		{"x99999999999999999999", "x100000000000000000000", true},
+		{"école", "Zèbre", false},
+	}
+	for _, tt := range tests {
+		if got := NameLess(tt.a, tt.b, true); got != tt.less {
+			t.Errorf("NameLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.less)
+		}
+	}
+	if !PathLess("dir2/z.go", "dir10/a.go", true) || PathLess("dir2/z.go", "dir10/a.go", false) {
+		t.Error("Expected PathLess to compare folders in the chosen order")
+	}
+}
+
+func TestMutualSymlinksStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on Windows")
+	}
+	tempDir, err := os.MkdirTemp("", "walker_test_mutual")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, file := range []string{"a/x.go", "b/y.go"} {
+		os.MkdirAll(filepath.Join(tempDir, filepath.Dir(file)), 0755)
+		os.WriteFile(filepath.Join(tempDir, file), []byte("x"), 0644)
+	}
+	// Neither link points to a directory holding itself, only to each other
+	if err := os.Symlink("../b", filepath.Join(tempDir, "a", "l")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../a", filepath.Join(tempDir, "b", "m")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		FollowSymlinks: true,
+		Dirs:           map[string]config.DirRule{".": {Enabled: true}},
+	}
+	rec := &recorder{}
+	if err := New(tempDir, cfg).Walk(rec); err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{
+		"dir a: +",
+		"dir a/l: +",
+		"dir a/l/m: " + ReasonSymlinkLoop,
+		"file a/l/y.go: +",
+		"file a/x.go: +",
+		"dir b: +",
+		"dir b/m: +",
+		"dir b/m/l: " + ReasonSymlinkLoop,
+		"file b/m/x.go: +",
+		"file b/y.go: +",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Errorf("Unexpected decisions.\nExpected: %q\nGot:      %q", expected, rec.events)
+	}
+}